@@ -14,6 +14,7 @@ package bind // import "github.com/sagernet/gomobile/bind"
 
 import (
 	"bytes"
+	"go/ast"
 	"go/format"
 	"go/token"
 	"go/types"
@@ -27,6 +28,7 @@ type (
 		Writer io.Writer
 		Fset   *token.FileSet
 		Pkg    *types.Package
+		Files  []*ast.File
 		AllPkg []*types.Package
 	}
 
@@ -42,6 +44,7 @@ func GenGo(conf *GeneratorConfig) error {
 			Fset:    conf.Fset,
 			AllPkg:  conf.AllPkg,
 			Pkg:     conf.Pkg,
+			Files:   conf.Files,
 		},
 	}
 	g.Init()
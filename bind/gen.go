@@ -8,8 +8,10 @@ import (
 	"bytes"
 	"fmt"
 	"go/ast"
+	"go/format"
 	"go/token"
 	"go/types"
+	"hash/fnv"
 	"io"
 	"regexp"
 	"strings"
@@ -41,6 +43,11 @@ const (
 	// arguments that are used after the function returns.
 	// Retained byte slices need an intermediate copy.
 	modeRetained
+	// modePinned are for byte slices returned from functions with a
+	// //gobind:nocopy directive. The backing array is pinned and
+	// lent to the foreign side instead of copied; the foreign side
+	// releases the pin when it is done with the data.
+	modePinned
 )
 
 func (list ErrorList) Error() string {
@@ -99,6 +106,9 @@ type Generator struct {
 	allIntf []interfaceInfo
 
 	docs pkgDocs
+	// constexprs maps the name of each function marked with a
+	// //gomobile:constexpr directive to its declaration.
+	constexprs map[string]*ast.FuncDecl
 }
 
 // A pkgDocs maps the name of each exported package-level declaration to its extracted documentation.
@@ -108,6 +118,28 @@ type pkgDoc struct {
 	doc string
 	// Struct or interface fields and methods.
 	members map[string]string
+	// Default literals for function parameters, declared with
+	// //gobind:default directives.
+	defaults map[string]string
+	// Whether a //gobind:async directive requests a
+	// completion-handler companion for the function.
+	async bool
+	// Methods with //gobind:async directives.
+	asyncMembers map[string]bool
+	// Whether a //gobind:nocopy directive requests that returned
+	// byte slices lend their backing arrays instead of copying.
+	nocopy bool
+	// Methods with //gobind:nocopy directives.
+	nocopyMembers map[string]bool
+	// Whether a //gomobile:fastpath directive was placed on a
+	// package-level function. The directive applies only to methods;
+	// the generators reject this form.
+	fastpath bool
+	// Methods with //gomobile:fastpath directives.
+	fastpathMembers map[string]bool
+	// Concurrency contract for a type, declared with a
+	// //gomobile:threadsafe or //gomobile:confined directive.
+	threadSafety string
 }
 
 // pkgPrefix returns a prefix that disambiguates symbol names for binding
@@ -142,6 +174,12 @@ func (g *Generator) Init() {
 			hasExported = true
 			switch obj := obj.(type) {
 			case *types.Func:
+				if decl := g.constexprs[name]; decl != nil {
+					if c := g.foldConstexpr(obj, decl); c != nil {
+						g.constants = append(g.constants, c)
+					}
+					continue
+				}
 				if isCallable(obj) {
 					g.funcs = append(g.funcs, obj)
 				}
@@ -212,12 +250,32 @@ func (g *Generator) parseDocs() {
 				}
 			case *ast.FuncDecl:
 				d.addFunc(decl)
+				if decl.Recv == nil && ast.IsExported(decl.Name.Name) && hasDirective(decl.Doc, "gomobile:constexpr") {
+					if g.constexprs == nil {
+						g.constexprs = make(map[string]*ast.FuncDecl)
+					}
+					g.constexprs[decl.Name.Name] = decl
+				}
 			}
 		}
 	}
 	g.docs = d
 }
 
+// hasDirective reports whether the comment group contains the comment
+// directive //name.
+func hasDirective(doc *ast.CommentGroup, name string) bool {
+	if doc == nil {
+		return false
+	}
+	for _, c := range doc.List {
+		if c.Text == "//"+name || strings.HasPrefix(c.Text, "//"+name+" ") {
+			return true
+		}
+	}
+	return false
+}
+
 func (d pkgDocs) addValue(t *ast.ValueSpec, outerDoc *ast.CommentGroup) {
 	for _, n := range t.Names {
 		if !ast.IsExported(n.Name) {
@@ -251,9 +309,56 @@ func (d pkgDocs) addFunc(f *ast.FuncDecl) {
 			d[n] = pd
 		}
 		pd.members[fn] = doc.Text()
+		for _, c := range doc.List {
+			switch strings.TrimSpace(c.Text) {
+			case "//gobind:async":
+				if pd.asyncMembers == nil {
+					pd.asyncMembers = make(map[string]bool)
+				}
+				pd.asyncMembers[fn] = true
+			case "//gobind:nocopy":
+				if pd.nocopyMembers == nil {
+					pd.nocopyMembers = make(map[string]bool)
+				}
+				pd.nocopyMembers[fn] = true
+			case "//gomobile:fastpath":
+				if pd.fastpathMembers == nil {
+					pd.fastpathMembers = make(map[string]bool)
+				}
+				pd.fastpathMembers[fn] = true
+			}
+		}
 	} else {
 		// f is a function.
-		d[fn] = &pkgDoc{doc: doc.Text()}
+		pd := &pkgDoc{doc: doc.Text()}
+		// Directive comments are stripped from doc.Text; extract
+		// //gobind:default and //gobind:async directives from the raw
+		// comment list.
+		for _, c := range doc.List {
+			if strings.TrimSpace(c.Text) == "//gobind:async" {
+				pd.async = true
+				continue
+			}
+			if strings.TrimSpace(c.Text) == "//gobind:nocopy" {
+				pd.nocopy = true
+				continue
+			}
+			if strings.TrimSpace(c.Text) == "//gomobile:fastpath" {
+				pd.fastpath = true
+				continue
+			}
+			arg, ok := strings.CutPrefix(c.Text, "//gobind:default ")
+			if !ok {
+				continue
+			}
+			if name, val, ok := strings.Cut(strings.TrimSpace(arg), "="); ok {
+				if pd.defaults == nil {
+					pd.defaults = make(map[string]string)
+				}
+				pd.defaults[name] = val
+			}
+		}
+		d[fn] = pd
 	}
 }
 
@@ -271,6 +376,12 @@ func (d pkgDocs) addType(t *ast.TypeSpec, outerDoc *ast.CommentGroup) {
 	if doc != nil {
 		pd.doc = doc.Text()
 	}
+	switch {
+	case hasDirective(doc, "gomobile:threadsafe"):
+		pd.threadSafety = "threadsafe"
+	case hasDirective(doc, "gomobile:confined"):
+		pd.threadSafety = "confined"
+	}
 	var fields *ast.FieldList
 	switch t := t.Type.(type) {
 	case *ast.StructType:
@@ -326,6 +437,82 @@ func (d *pkgDoc) Member(n string) string {
 	return d.members[n]
 }
 
+// ThreadSafety returns "threadsafe" or "confined" for a type declared
+// with the corresponding //gomobile: directive, or the empty string if
+// the type declares no concurrency contract.
+func (d *pkgDoc) ThreadSafety() string {
+	if d == nil {
+		return ""
+	}
+	return d.threadSafety
+}
+
+// typeDoc returns the documentation for a type with a sentence
+// describing its declared concurrency contract, if any, appended.
+func typeDoc(d *pkgDoc) string {
+	var note string
+	switch d.ThreadSafety() {
+	case "threadsafe":
+		note = "Instances are safe for concurrent use by multiple threads."
+	case "confined":
+		note = "Instances must be confined to a single thread at a time."
+	}
+	if note == "" {
+		return d.Doc()
+	}
+	s := d.Doc()
+	if s != "" {
+		s += "\n"
+	}
+	return s + note + "\n"
+}
+
+// Default returns the default literal declared for the named parameter
+// with a //gobind:default directive, if any.
+func (d *pkgDoc) Default(param string) (string, bool) {
+	if d == nil {
+		return "", false
+	}
+	v, ok := d.defaults[param]
+	return v, ok
+}
+
+// Async reports whether the function carries a //gobind:async
+// directive.
+func (d *pkgDoc) Async() bool {
+	return d != nil && d.async
+}
+
+// AsyncMember reports whether the named method carries a
+// //gobind:async directive.
+func (d *pkgDoc) AsyncMember(name string) bool {
+	return d != nil && d.asyncMembers[name]
+}
+
+// NoCopy reports whether the function carries a //gobind:nocopy
+// directive.
+func (d *pkgDoc) NoCopy() bool {
+	return d != nil && d.nocopy
+}
+
+// NoCopyMember reports whether the named method carries a
+// //gobind:nocopy directive.
+func (d *pkgDoc) NoCopyMember(name string) bool {
+	return d != nil && d.nocopyMembers[name]
+}
+
+// Fastpath reports whether a //gomobile:fastpath directive was placed
+// on the package-level function, a form the generators reject.
+func (d *pkgDoc) Fastpath() bool {
+	return d != nil && d.fastpath
+}
+
+// FastpathMember reports whether the named method carries a
+// //gomobile:fastpath directive.
+func (d *pkgDoc) FastpathMember(name string) bool {
+	return d != nil && d.fastpathMembers[name]
+}
+
 // constructorType returns the type T for a function of the forms:
 //
 // func NewT...(...) *T
@@ -394,6 +581,12 @@ func (g *Generator) cgoType(t types.Type) string {
 			g.errorf("unsupported basic type: %s", t)
 		}
 	case *types.Slice:
+		if isByteSliceSlice(t) {
+			return "nbytesliceslice"
+		}
+		if isStringSlice(t) {
+			return "nstringslice"
+		}
 		switch e := t.Elem().(type) {
 		case *types.Basic:
 			switch e.Kind() {
@@ -405,6 +598,11 @@ func (g *Generator) cgoType(t types.Type) string {
 		default:
 			g.errorf("unsupported slice type: %s", t)
 		}
+	case *types.Map:
+		if isStringMap(t) {
+			return "nstringmap"
+		}
+		g.errorf("unsupported map type: %s", t)
 	case *types.Pointer:
 		if _, ok := t.Elem().(*types.Named); ok {
 			return g.cgoType(t.Elem())
@@ -504,10 +702,15 @@ func (g *Generator) isSupported(t types.Type) bool {
 		}
 		return false
 	case *types.Slice:
+		if isByteSliceSlice(t) || isStringSlice(t) {
+			return true
+		}
 		switch e := t.Elem().(type) {
 		case *types.Basic:
 			return e.Kind() == types.Uint8
 		}
+	case *types.Map:
+		return isStringMap(t)
 	case *types.Pointer:
 		switch t := t.Elem().(type) {
 		case *types.Named:
@@ -522,6 +725,136 @@ func (g *Generator) isSupported(t types.Type) bool {
 	return false
 }
 
+// foldConstexpr evaluates a function marked with a //gomobile:constexpr
+// directive at generation time and returns the result as a constant
+// bound in place of the function. The function must take no arguments
+// and consist of a single return of a constant expression of basic
+// type.
+func (g *Generator) foldConstexpr(f *types.Func, decl *ast.FuncDecl) *types.Const {
+	sig := f.Type().(*types.Signature)
+	if sig.Params().Len() > 0 || sig.Results().Len() != 1 {
+		g.errorf("gomobile:constexpr function %s must take no arguments and return a single value", f.Name())
+		return nil
+	}
+	res := sig.Results().At(0).Type()
+	if _, ok := res.Underlying().(*types.Basic); !ok {
+		g.errorf("gomobile:constexpr function %s must return a value of basic type, not %s", f.Name(), res)
+		return nil
+	}
+	var retExpr ast.Expr
+	if body := decl.Body; body != nil && len(body.List) == 1 {
+		if ret, ok := body.List[0].(*ast.ReturnStmt); ok && len(ret.Results) == 1 {
+			retExpr = ret.Results[0]
+		}
+	}
+	if retExpr == nil {
+		g.errorf("gomobile:constexpr function %s must consist of a single return statement", f.Name())
+		return nil
+	}
+	var buf bytes.Buffer
+	if err := format.Node(&buf, g.Fset, retExpr); err != nil {
+		g.errorf("gomobile:constexpr function %s: %v", f.Name(), err)
+		return nil
+	}
+	tv, err := types.Eval(g.Fset, g.Pkg, retExpr.Pos(), buf.String())
+	if err != nil || tv.Value == nil {
+		g.errorf("gomobile:constexpr function %s does not return a constant expression", f.Name())
+		return nil
+	}
+	return types.NewConst(f.Pos(), g.Pkg, f.Name(), res, tv.Value)
+}
+
+// defaultedParams returns the number of trailing parameters of f that
+// have a //gobind:default directive. Defaults are only honored on a
+// run of trailing parameters of basic type; a directive on any other
+// parameter is an error.
+func (g *Generator) defaultedParams(f *types.Func) int {
+	doc := g.docs[f.Name()]
+	params := f.Type().(*types.Signature).Params()
+	n := 0
+	for i := params.Len() - 1; i >= 0; i-- {
+		p := params.At(i)
+		if _, ok := doc.Default(p.Name()); !ok {
+			break
+		}
+		if _, ok := p.Type().(*types.Basic); !ok {
+			g.errorf("gobind:default on parameter %s of %s: unsupported type %s", p.Name(), f.Name(), p.Type())
+			return 0
+		}
+		n++
+	}
+	for i := params.Len() - n - 1; i >= 0; i-- {
+		if _, ok := doc.Default(params.At(i).Name()); ok {
+			g.errorf("gobind:default on parameter %s of %s: only trailing parameters may have defaults", params.At(i).Name(), f.Name())
+			return 0
+		}
+	}
+	return n
+}
+
+// isNoCopy reports whether f carries a //gobind:nocopy directive,
+// looked up on the function itself or, for a method, on its receiver
+// type.
+func (g *Generator) isNoCopy(f *types.Func) bool {
+	sig := f.Type().(*types.Signature)
+	if recv := sig.Recv(); recv != nil {
+		t := recv.Type()
+		if p, ok := t.(*types.Pointer); ok {
+			t = p.Elem()
+		}
+		if n, ok := t.(*types.Named); ok {
+			return g.docs[n.Obj().Name()].NoCopyMember(f.Name())
+		}
+		return false
+	}
+	return g.docs[f.Name()].NoCopy()
+}
+
+// isFastpath reports whether f is a method carrying a
+// //gomobile:fastpath directive on its receiver type. Fast-path
+// methods resolve their receiver without touching the Go reference
+// count: the receiver proxy is kept alive by the host language for
+// the duration of the call, so the increment and matching release the
+// regular crossing performs are redundant.
+func (g *Generator) isFastpath(f *types.Func) bool {
+	sig := f.Type().(*types.Signature)
+	recv := sig.Recv()
+	if recv == nil {
+		return false
+	}
+	t := recv.Type()
+	if p, ok := t.(*types.Pointer); ok {
+		t = p.Elem()
+	}
+	if n, ok := t.(*types.Named); ok {
+		return g.docs[n.Obj().Name()].FastpathMember(f.Name())
+	}
+	return false
+}
+
+// errorVars returns the exported package variables of type error. The
+// bindings assign each of them a stable code within the package error
+// domain, so foreign callers can match returned errors against the
+// sentinels they know.
+func (g *Generator) errorVars() []*types.Var {
+	var vars []*types.Var
+	for _, v := range g.vars {
+		if isErrorType(v.Type()) {
+			vars = append(vars, v)
+		}
+	}
+	return vars
+}
+
+// errorCode derives the code assigned to the exported error variable
+// named name. The code hashes the name so it does not shift when other
+// variables are added or removed.
+func errorCode(name string) int32 {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	return int32(h.Sum32() & 0x7fffffff)
+}
+
 var paramRE = regexp.MustCompile(`^p[0-9]*$`)
 
 // basicParamName replaces incompatible name with a p0-pN name.
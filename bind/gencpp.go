@@ -0,0 +1,526 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bind
+
+import (
+	"fmt"
+	"go/types"
+	"strings"
+)
+
+// CppGen generates the header-only C++17 wrapper selected with
+// -lang=cpp: classes owning their refnum with RAII semantics (movable,
+// not copyable, released through the exported DestroyRef), GoError
+// exceptions for Go errors and copying conversions between
+// std::string/std::vector<uint8_t> and the seq value structs. The
+// header is compiled against the library built by gomobile together
+// with a seq support implementation. Implementing Go interfaces from
+// C++ is not supported.
+type CppGen struct {
+	*Generator
+}
+
+// GenCppHeader generates the wrapper header for the package.
+func (g *CppGen) GenCppHeader() error {
+	pkgName := g.cppPkgName()
+	var pkgPath string
+	if g.Pkg != nil {
+		pkgPath = g.Pkg.Path()
+	}
+	g.Printf(gobindPreamble)
+	g.Printf("// C++ bindings for the Go package %s.\n\n", pkgPath)
+	g.Printf("#pragma once\n\n")
+	g.Printf("#include <cstdint>\n")
+	g.Printf("#include <cstdlib>\n")
+	g.Printf("#include <cstring>\n")
+	g.Printf("#include <stdexcept>\n")
+	g.Printf("#include <string>\n")
+	g.Printf("#include <utility>\n")
+	g.Printf("#include <vector>\n\n")
+
+	g.genCppExterns()
+
+	g.Printf("namespace %s {\n\n", pkgName)
+	g.Printf("%s", cppSupport)
+
+	for _, c := range g.constants {
+		if _, ok := c.Type().(*types.Basic); !ok {
+			continue
+		}
+		g.cppdoc(g.docs[c.Name()].Doc())
+		g.Printf("constexpr %s %s = %s;\n\n", g.cppConstType(c.Type()), strings.ToUpper(pySnake(c.Name())), c.Val().ExactString())
+	}
+
+	for _, s := range g.structs {
+		g.genCppClass(s)
+	}
+
+	for _, v := range g.vars {
+		g.genCppVar(v)
+	}
+
+	for _, f := range g.funcs {
+		if !g.isCppSigSupported(f.Type()) {
+			g.Printf("// skipped function %s with unsupported parameter or result types\n\n", f.Name())
+			continue
+		}
+		symbol := fmt.Sprintf("proxy%s__%s", g.pkgPrefix, f.Name())
+		g.genCppFunc(f, pySnake(f.Name()), g.docs[f.Name()].Doc(), symbol, "")
+	}
+
+	g.Printf("}  // namespace %s\n", pkgName)
+	if len(g.err) > 0 {
+		return g.err
+	}
+	return nil
+}
+
+// genCppExterns emits the extern "C" block with the bridge types and
+// the prototypes of every proxy symbol the wrappers call.
+func (g *CppGen) genCppExterns() {
+	g.Printf("extern \"C\" {\n\n")
+	// Guarded so headers generated for different packages can be
+	// included together.
+	g.Printf("#ifndef GOBIND_SEQ_TYPES\n")
+	g.Printf("#define GOBIND_SEQ_TYPES\n")
+	g.Printf("typedef struct { void *ptr; int len; } gobind_nstring;\n")
+	g.Printf("typedef struct { void *ptr; int len; } gobind_nbyteslice;\n")
+	g.Printf("typedef int64_t gobind_nint;\n")
+	g.Printf("#endif  // GOBIND_SEQ_TYPES\n\n")
+
+	addFn := func(symbol string, sig *types.Signature, method bool) {
+		params := sig.Params()
+		res := sig.Results()
+		var args []string
+		if method {
+			args = append(args, "int32_t refnum")
+		}
+		for i := 0; i < params.Len(); i++ {
+			t, _ := g.cppType(params.At(i).Type())
+			args = append(args, t.ffi)
+		}
+		ret := "void"
+		switch res.Len() {
+		case 1:
+			t, _ := g.cppType(res.At(0).Type())
+			ret = t.ffi
+		case 2:
+			t0, _ := g.cppType(res.At(0).Type())
+			t1, _ := g.cppType(res.At(1).Type())
+			g.Printf("typedef struct { %s r0; %s r1; } ret_%s;\n", t0.ffi, t1.ffi, symbol)
+			ret = "ret_" + symbol
+		}
+		g.Printf("%s %s(%s);\n", ret, symbol, strings.Join(args, ", "))
+	}
+
+	for _, f := range g.funcs {
+		if !g.isCppSigSupported(f.Type()) {
+			continue
+		}
+		addFn(fmt.Sprintf("proxy%s__%s", g.pkgPrefix, f.Name()), f.Type().(*types.Signature), false)
+	}
+	for _, v := range g.vars {
+		t, ok := g.cppType(v.Type())
+		if !ok {
+			continue
+		}
+		g.Printf("%s var_get%s_%s();\n", t.ffi, g.pkgPrefix, v.Name())
+		g.Printf("void var_set%s_%s(%s);\n", g.pkgPrefix, v.Name(), t.ffi)
+	}
+	for _, s := range g.structs {
+		n := s.obj.Name()
+		g.Printf("int32_t new_%s_%s();\n", g.pkgPrefix, n)
+		for _, f := range exportedFields(s.t) {
+			t, ok := g.cppType(f.Type())
+			if !ok {
+				continue
+			}
+			g.Printf("%s proxy%s_%s_%s_Get(int32_t refnum);\n", t.ffi, g.pkgPrefix, n, f.Name())
+			g.Printf("void proxy%s_%s_%s_Set(int32_t refnum, %s);\n", g.pkgPrefix, n, f.Name(), t.ffi)
+		}
+		for _, m := range exportedMethodSet(types.NewPointer(s.obj.Type())) {
+			if !g.isCppSigSupported(m.Type()) {
+				continue
+			}
+			addFn(fmt.Sprintf("proxy%s_%s_%s", g.pkgPrefix, n, m.Name()), m.Type().(*types.Signature), true)
+		}
+	}
+	g.Printf("void IncGoRef(int32_t refnum);\n")
+	g.Printf("void DestroyRef(int32_t refnum);\n")
+	g.Printf("void ReleaseByteSliceRef(void *ptr);\n\n")
+	g.Printf("}  // extern \"C\"\n\n")
+}
+
+func (g *CppGen) genCppClass(s structInfo) {
+	n := s.obj.Name()
+	doc := g.docs[n]
+	g.cppdoc(typeDoc(doc))
+	g.Printf("class %s {\n", n)
+	g.Printf(" public:\n")
+	g.Indent()
+	g.Printf("%s() : refnum_(new_%s_%s()) {}\n\n", n, g.pkgPrefix, n)
+	g.Printf("// Takes ownership of an existing refnum.\n")
+	g.Printf("explicit %s(int32_t refnum) : refnum_(refnum) {}\n\n", n)
+	g.Printf("~%s() {\n", n)
+	g.Printf("	if (refnum_ != 0) {\n")
+	g.Printf("		DestroyRef(refnum_);\n")
+	g.Printf("	}\n")
+	g.Printf("}\n\n")
+	g.Printf("%[1]s(const %[1]s&) = delete;\n", n)
+	g.Printf("%[1]s& operator=(const %[1]s&) = delete;\n\n", n)
+	g.Printf("%[1]s(%[1]s&& other) noexcept : refnum_(other.refnum_) {\n", n)
+	g.Printf("	other.refnum_ = 0;\n")
+	g.Printf("}\n\n")
+	g.Printf("%[1]s& operator=(%[1]s&& other) noexcept {\n", n)
+	g.Printf("	std::swap(refnum_, other.refnum_);\n")
+	g.Printf("	return *this;\n")
+	g.Printf("}\n\n")
+	g.Printf("int32_t refnum() const { return refnum_; }\n\n")
+	for _, f := range exportedFields(s.t) {
+		t, ok := g.cppType(f.Type())
+		if !ok {
+			g.Printf("// skipped field %s with unsupported type %s\n\n", f.Name(), f.Type())
+			continue
+		}
+		g.cppdoc(doc.Member(f.Name()))
+		g.Printf("%s %s() const {\n", t.ret, pySnake(f.Name()))
+		g.Printf("	return %s;\n", fmt.Sprintf(t.fromFfi, fmt.Sprintf("proxy%s_%s_%s_Get(internal::incref(refnum_))", g.pkgPrefix, n, f.Name())))
+		g.Printf("}\n\n")
+		g.Printf("void set_%s(%s v) {\n", pySnake(f.Name()), t.param)
+		g.Printf("	proxy%s_%s_%s_Set(internal::incref(refnum_), %s);\n", g.pkgPrefix, n, f.Name(), fmt.Sprintf(t.toFfi, "v"))
+		g.Printf("}\n\n")
+	}
+	for _, m := range exportedMethodSet(types.NewPointer(s.obj.Type())) {
+		if !g.isCppSigSupported(m.Type()) {
+			g.Printf("// skipped method %s.%s with unsupported parameter or result types\n\n", n, m.Name())
+			continue
+		}
+		symbol := fmt.Sprintf("proxy%s_%s_%s", g.pkgPrefix, n, m.Name())
+		recvArg := "internal::incref(refnum_)"
+		if g.isFastpath(m) {
+			// Fastpath methods peek at the receiver without
+			// consuming a reference.
+			recvArg = "refnum_"
+		}
+		g.genCppFunc(m, pySnake(m.Name()), doc.Member(m.Name()), symbol, recvArg)
+	}
+	g.Outdent()
+	g.Printf(" private:\n")
+	g.Printf("	int32_t refnum_;\n")
+	g.Printf("};\n\n")
+}
+
+func (g *CppGen) genCppVar(o *types.Var) {
+	t, ok := g.cppType(o.Type())
+	if !ok {
+		g.Printf("// skipped variable %s with unsupported type %s\n\n", o.Name(), o.Type())
+		return
+	}
+	g.cppdoc(g.docs[o.Name()].Doc())
+	g.Printf("inline %s get_%s() {\n", t.ret, pySnake(o.Name()))
+	g.Printf("	return %s;\n", fmt.Sprintf(t.fromFfi, fmt.Sprintf("var_get%s_%s()", g.pkgPrefix, o.Name())))
+	g.Printf("}\n\n")
+	g.Printf("inline void set_%s(%s v) {\n", pySnake(o.Name()), t.param)
+	g.Printf("	var_set%s_%s(%s);\n", g.pkgPrefix, o.Name(), fmt.Sprintf(t.toFfi, "v"))
+	g.Printf("}\n\n")
+}
+
+func (g *CppGen) genCppFunc(f *types.Func, name, doc, symbol string, recvArg string) {
+	sig := f.Type().(*types.Signature)
+	params := sig.Params()
+	res := sig.Results()
+	var decl, args []string
+	if recvArg != "" {
+		args = append(args, recvArg)
+	}
+	for i := 0; i < params.Len(); i++ {
+		t, _ := g.cppType(params.At(i).Type())
+		pn := pySnake(basicParamName(params, i))
+		decl = append(decl, fmt.Sprintf("%s %s", t.param, pn))
+		args = append(args, fmt.Sprintf(t.toFfi, pn))
+	}
+	retIdx := -1
+	throws := false
+	switch res.Len() {
+	case 1:
+		if isErrorType(res.At(0).Type()) {
+			throws = true
+		} else {
+			retIdx = 0
+		}
+	case 2:
+		retIdx = 0
+		throws = true
+	}
+	ret := "void"
+	var retType cppTypeInfo
+	if retIdx == 0 {
+		retType, _ = g.cppType(res.At(0).Type())
+		if g.isNoCopy(f) && isByteSlice(res.At(0).Type()) {
+			retType.fromFfi = "internal::from_nbyteslice_nocopy(%s)"
+		}
+		ret = retType.ret
+	}
+	g.cppdoc(doc)
+	if recvArg != "" {
+		g.Printf("%s %s(%s) const {\n", ret, name, strings.Join(decl, ", "))
+	} else {
+		g.Printf("inline %s %s(%s) {\n", ret, name, strings.Join(decl, ", "))
+	}
+	g.Indent()
+	call := fmt.Sprintf("%s(%s)", symbol, strings.Join(args, ", "))
+	switch {
+	case throws && retIdx == 0:
+		g.Printf("auto r = %s;\n", call)
+		g.Printf("if (r.r1 != kNullRefnum) {\n")
+		g.Printf("	throw GoError(r.r1);\n")
+		g.Printf("}\n")
+		g.Printf("return %s;\n", fmt.Sprintf(retType.fromFfi, "r.r0"))
+	case throws:
+		g.Printf("int32_t r = %s;\n", call)
+		g.Printf("if (r != kNullRefnum) {\n")
+		g.Printf("	throw GoError(r);\n")
+		g.Printf("}\n")
+	case retIdx == 0:
+		g.Printf("return %s;\n", fmt.Sprintf(retType.fromFfi, call))
+	default:
+		g.Printf("%s;\n", call)
+	}
+	g.Outdent()
+	g.Printf("}\n\n")
+}
+
+func (g *CppGen) cppPkgName() string {
+	if g.Pkg == nil {
+		return "universe"
+	}
+	return g.Pkg.Name()
+}
+
+func (g *CppGen) cppdoc(doc string) {
+	if doc == "" {
+		return
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(doc, "\n"), "\n") {
+		g.Printf("// %s\n", strings.TrimRight(line, " "))
+	}
+}
+
+func (g *CppGen) cppConstType(t types.Type) string {
+	b, ok := t.(*types.Basic)
+	if !ok {
+		return ""
+	}
+	switch b.Kind() {
+	case types.Bool, types.UntypedBool:
+		return "bool"
+	case types.String, types.UntypedString:
+		return "const char*"
+	case types.Float32:
+		return "float"
+	case types.Float64, types.UntypedFloat:
+		return "double"
+	default:
+		return "int64_t"
+	}
+}
+
+// cppTypeInfo describes how a Go type crosses the bridge: the extern
+// "C" spelling, the parameter and return spellings of the wrapper and
+// the conversions between them (with %s substituted by the value).
+type cppTypeInfo struct {
+	ffi     string
+	param   string
+	ret     string
+	toFfi   string
+	fromFfi string
+}
+
+func (g *CppGen) cppType(t types.Type) (cppTypeInfo, bool) {
+	num := func(ffi, cpp string) (cppTypeInfo, bool) {
+		toFfi, fromFfi := "%s", "%s"
+		if ffi != cpp {
+			toFfi = "static_cast<" + ffi + ">(%s)"
+			fromFfi = "static_cast<" + cpp + ">(%s)"
+		}
+		return cppTypeInfo{ffi: ffi, param: cpp, ret: cpp, toFfi: toFfi, fromFfi: fromFfi}, true
+	}
+	switch t := t.(type) {
+	case *types.Basic:
+		switch t.Kind() {
+		case types.Bool, types.UntypedBool:
+			return cppTypeInfo{ffi: "char", param: "bool", ret: "bool", toFfi: "%s ? 1 : 0", fromFfi: "%s != 0"}, true
+		case types.Int, types.UntypedInt, types.UntypedRune:
+			return num("gobind_nint", "int64_t")
+		case types.Int8:
+			return num("int8_t", "int8_t")
+		case types.Int16:
+			return num("int16_t", "int16_t")
+		case types.Int32:
+			return num("int32_t", "int32_t")
+		case types.Int64:
+			return num("int64_t", "int64_t")
+		case types.Uint8:
+			return num("uint8_t", "uint8_t")
+		case types.Float32:
+			return num("float", "float")
+		case types.Float64, types.UntypedFloat:
+			return num("double", "double")
+		case types.String, types.UntypedString:
+			return cppTypeInfo{
+				ffi:     "gobind_nstring",
+				param:   "const std::string&",
+				ret:     "std::string",
+				toFfi:   "internal::to_nstring(%s)",
+				fromFfi: "internal::from_nstring(%s)",
+			}, true
+		}
+	case *types.Slice:
+		if e, ok := t.Elem().(*types.Basic); ok && e.Kind() == types.Uint8 {
+			return cppTypeInfo{
+				ffi:     "gobind_nbyteslice",
+				param:   "const std::vector<uint8_t>&",
+				ret:     "std::vector<uint8_t>",
+				toFfi:   "internal::to_nbyteslice(%s)",
+				fromFfi: "internal::from_nbyteslice(%s)",
+			}, true
+		}
+	case *types.Pointer:
+		if n, ok := t.Elem().(*types.Named); ok {
+			return g.cppNamedType(n)
+		}
+	case *types.Named:
+		if isErrorType(t) {
+			return num("int32_t", "int32_t")
+		}
+		return g.cppNamedType(t)
+	}
+	return cppTypeInfo{}, false
+}
+
+func (g *CppGen) cppNamedType(t *types.Named) (cppTypeInfo, bool) {
+	n := t.Obj()
+	if n.Pkg() != g.Pkg {
+		return cppTypeInfo{}, false
+	}
+	if _, ok := t.Underlying().(*types.Interface); ok {
+		// Interfaces are passed as raw refnums; C++ cannot implement
+		// them.
+		return cppTypeInfo{ffi: "int32_t", param: "int32_t", ret: "int32_t", toFfi: "%s", fromFfi: "%s"}, true
+	}
+	return cppTypeInfo{
+		ffi:     "int32_t",
+		param:   "const " + n.Name() + "&",
+		ret:     n.Name(),
+		toFfi:   "internal::incref(%s.refnum())",
+		fromFfi: n.Name() + "(%s)",
+	}, true
+}
+
+// isCppSigSupported reports whether the C++ generator can handle the
+// signature.
+func (g *CppGen) isCppSigSupported(t types.Type) bool {
+	if !g.isSigSupported(t) {
+		return false
+	}
+	sig := t.(*types.Signature)
+	params := sig.Params()
+	for i := 0; i < params.Len(); i++ {
+		if _, ok := g.cppType(params.At(i).Type()); !ok {
+			return false
+		}
+	}
+	res := sig.Results()
+	for i := 0; i < res.Len(); i++ {
+		if _, ok := g.cppType(res.At(i).Type()); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+const cppSupport = `// The refnum a nil Go object or error crosses the bridge as.
+constexpr int32_t kNullRefnum = 41;
+
+// GoError is thrown when a bound Go function returns a non-nil error.
+// The refnum identifies the Go error value on the Go side of the
+// bridge.
+class GoError : public std::runtime_error {
+ public:
+	explicit GoError(int32_t refnum)
+			: std::runtime_error("go error (refnum " + std::to_string(refnum) + ")"),
+				refnum_(refnum) {}
+
+	int32_t refnum() const { return refnum_; }
+
+ private:
+	int32_t refnum_;
+};
+
+namespace internal {
+
+// Pins the Go object before its refnum crosses into Go; the Go side
+// consumes one reference per crossing.
+inline int32_t incref(int32_t refnum) {
+	IncGoRef(refnum);
+	return refnum;
+}
+
+inline gobind_nstring to_nstring(const std::string& s) {
+	gobind_nstring ns;
+	ns.ptr = nullptr;
+	ns.len = static_cast<int>(s.size());
+	if (!s.empty()) {
+		ns.ptr = std::malloc(s.size());
+		std::memcpy(ns.ptr, s.data(), s.size());
+	}
+	return ns;
+}
+
+inline std::string from_nstring(gobind_nstring ns) {
+	if (ns.ptr == nullptr || ns.len == 0) {
+		return std::string();
+	}
+	std::string s(static_cast<const char*>(ns.ptr), ns.len);
+	std::free(ns.ptr);
+	return s;
+}
+
+inline gobind_nbyteslice to_nbyteslice(const std::vector<uint8_t>& b) {
+	gobind_nbyteslice bs;
+	bs.ptr = nullptr;
+	bs.len = static_cast<int>(b.size());
+	if (!b.empty()) {
+		bs.ptr = std::malloc(b.size());
+		std::memcpy(bs.ptr, b.data(), b.size());
+	}
+	return bs;
+}
+
+inline std::vector<uint8_t> from_nbyteslice(gobind_nbyteslice bs) {
+	if (bs.ptr == nullptr || bs.len == 0) {
+		return std::vector<uint8_t>();
+	}
+	const uint8_t* p = static_cast<const uint8_t*>(bs.ptr);
+	std::vector<uint8_t> b(p, p + bs.len);
+	std::free(bs.ptr);
+	return b;
+}
+
+// Results of //gobind:nocopy functions lend Go's backing array;
+// release the pin instead of freeing.
+inline std::vector<uint8_t> from_nbyteslice_nocopy(gobind_nbyteslice bs) {
+	if (bs.ptr == nullptr || bs.len == 0) {
+		return std::vector<uint8_t>();
+	}
+	const uint8_t* p = static_cast<const uint8_t*>(bs.ptr);
+	std::vector<uint8_t> b(p, p + bs.len);
+	ReleaseByteSliceRef(bs.ptr);
+	return b;
+}
+
+}  // namespace internal
+
+`
@@ -0,0 +1,516 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bind
+
+import (
+	"fmt"
+	"go/types"
+	"strings"
+)
+
+// DartGen generates the Dart package selected with -lang=dart: typed
+// Dart classes over dart:ffi bindings to the proxy functions exported
+// by the generated Go stubs, and a pubspec declaring a Flutter FFI
+// plugin so the c-shared library is bundled per platform. Byte slices
+// cross the bridge as Uint8Lists and Go errors are thrown as GoError;
+// implementing Go interfaces from Dart is not supported.
+type DartGen struct {
+	*Generator
+}
+
+// GenDart generates the Dart library source for the package.
+func (g *DartGen) GenDart() error {
+	pkgName := g.dartPkgName()
+	var pkgPath string
+	if g.Pkg != nil {
+		pkgPath = g.Pkg.Path()
+	}
+	g.Printf(gobindPreamble)
+	g.Printf("/// Dart bindings for the Go package %s.\n", pkgPath)
+	g.Printf("library %s;\n\n", pkgName)
+	g.Printf(dartSupport, pkgName)
+
+	for _, c := range g.constants {
+		if _, ok := c.Type().(*types.Basic); !ok {
+			continue
+		}
+		g.dartdoc(g.docs[c.Name()].Doc(), "")
+		g.Printf("const %s = %s;\n\n", lowerFirst(c.Name()), c.Val().ExactString())
+	}
+
+	for _, v := range g.vars {
+		g.genDartVar(v)
+	}
+
+	for _, s := range g.structs {
+		g.genDartStruct(s)
+	}
+
+	for _, f := range g.funcs {
+		if !g.isDartSigSupported(f.Type()) {
+			g.Printf("// skipped function %s with unsupported parameter or result types\n\n", f.Name())
+			continue
+		}
+		symbol := fmt.Sprintf("proxy%s__%s", g.pkgPrefix, f.Name())
+		g.genDartLookup(symbol, f.Type().(*types.Signature), false)
+		g.genDartFunc(f, lowerFirst(f.Name()), g.docs[f.Name()].Doc(), symbol, "")
+	}
+	if len(g.err) > 0 {
+		return g.err
+	}
+	return nil
+}
+
+func (g *DartGen) genDartVar(o *types.Var) {
+	t, ok := g.dartType(o.Type())
+	if !ok {
+		g.Printf("// skipped variable %s with unsupported type %s\n\n", o.Name(), o.Type())
+		return
+	}
+	getSym := fmt.Sprintf("var_get%s_%s", g.pkgPrefix, o.Name())
+	setSym := fmt.Sprintf("var_set%s_%s", g.pkgPrefix, o.Name())
+	g.Printf("final _%s = _lib.lookupFunction<%s Function(), %s Function()>('%s');\n", getSym, t.native, t.ffi, getSym)
+	g.Printf("final _%s = _lib.lookupFunction<Void Function(%s), void Function(%s)>('%s');\n\n", setSym, t.native, t.ffi, setSym)
+	g.dartdoc(g.docs[o.Name()].Doc(), "")
+	g.Printf("%s get %s => %s;\n\n", t.dart, lowerFirst(o.Name()), fmt.Sprintf(t.fromFfi, "_"+getSym+"()"))
+	g.Printf("set %s(%s v) => _%s(%s);\n\n", lowerFirst(o.Name()), t.dart, setSym, fmt.Sprintf(t.toFfi, "v"))
+}
+
+func (g *DartGen) genDartStruct(s structInfo) {
+	n := s.obj.Name()
+	doc := g.docs[n]
+
+	// Lookups and return structs are emitted at the top level before
+	// the class; Dart requires them outside the class body.
+	ctorSym := fmt.Sprintf("new_%s_%s", g.pkgPrefix, n)
+	g.Printf("final _%s = _lib.lookupFunction<Int32 Function(), int Function()>('%s');\n", ctorSym, ctorSym)
+	fields := exportedFields(s.t)
+	for _, f := range fields {
+		t, ok := g.dartType(f.Type())
+		if !ok {
+			continue
+		}
+		getSym := fmt.Sprintf("proxy%s_%s_%s_Get", g.pkgPrefix, n, f.Name())
+		setSym := fmt.Sprintf("proxy%s_%s_%s_Set", g.pkgPrefix, n, f.Name())
+		g.Printf("final _%s = _lib.lookupFunction<%s Function(Int32), %s Function(int)>('%s');\n", getSym, t.native, t.ffi, getSym)
+		g.Printf("final _%s = _lib.lookupFunction<Void Function(Int32, %s), void Function(int, %s)>('%s');\n", setSym, t.native, t.ffi, setSym)
+	}
+	methods := exportedMethodSet(types.NewPointer(s.obj.Type()))
+	for _, m := range methods {
+		if !g.isDartSigSupported(m.Type()) {
+			continue
+		}
+		symbol := fmt.Sprintf("proxy%s_%s_%s", g.pkgPrefix, n, m.Name())
+		g.genDartLookup(symbol, m.Type().(*types.Signature), true)
+	}
+	g.Printf("\n")
+
+	g.dartdoc(typeDoc(doc), "")
+	g.Printf("class %s {\n", n)
+	g.Indent()
+	g.Printf("final int _refnum;\n\n")
+	g.Printf("%s() : _refnum = _%s() {\n", n, ctorSym)
+	g.Printf("	_attachFinalizer(this, _refnum);\n")
+	g.Printf("}\n\n")
+	g.Printf("%s._fromRefnum(this._refnum) {\n", n)
+	g.Printf("	_attachFinalizer(this, _refnum);\n")
+	g.Printf("}\n\n")
+	for _, f := range fields {
+		t, ok := g.dartType(f.Type())
+		if !ok {
+			g.Printf("// skipped field %s with unsupported type %s\n\n", f.Name(), f.Type())
+			continue
+		}
+		getSym := fmt.Sprintf("proxy%s_%s_%s_Get", g.pkgPrefix, n, f.Name())
+		setSym := fmt.Sprintf("proxy%s_%s_%s_Set", g.pkgPrefix, n, f.Name())
+		g.dartdoc(doc.Member(f.Name()), "")
+		g.Printf("%s get %s => %s;\n\n", t.dart, lowerFirst(f.Name()), fmt.Sprintf(t.fromFfi, "_"+getSym+"(_incref(_refnum))"))
+		g.Printf("set %s(%s v) => _%s(_incref(_refnum), %s);\n\n", lowerFirst(f.Name()), t.dart, setSym, fmt.Sprintf(t.toFfi, "v"))
+	}
+	for _, m := range methods {
+		if !g.isDartSigSupported(m.Type()) {
+			g.Printf("// skipped method %s.%s with unsupported parameter or result types\n\n", n, m.Name())
+			continue
+		}
+		symbol := fmt.Sprintf("proxy%s_%s_%s", g.pkgPrefix, n, m.Name())
+		recvArg := "_incref(_refnum)"
+		if g.isFastpath(m) {
+			// Fastpath methods peek at the receiver without
+			// consuming a reference.
+			recvArg = "_refnum"
+		}
+		g.genDartFunc(m, lowerFirst(m.Name()), doc.Member(m.Name()), symbol, recvArg)
+	}
+	g.Outdent()
+	g.Printf("}\n\n")
+}
+
+// genDartLookup emits the return struct, if the signature needs one,
+// and the lookupFunction binding for symbol.
+func (g *DartGen) genDartLookup(symbol string, sig *types.Signature, method bool) {
+	params := sig.Params()
+	res := sig.Results()
+	var native, ffi []string
+	if method {
+		native = append(native, "Int32")
+		ffi = append(ffi, "int")
+	}
+	for i := 0; i < params.Len(); i++ {
+		t, _ := g.dartType(params.At(i).Type())
+		native = append(native, t.native)
+		ffi = append(ffi, t.ffi)
+	}
+	retNative, retFfi := "Void", "void"
+	switch res.Len() {
+	case 1:
+		t, _ := g.dartType(res.At(0).Type())
+		retNative, retFfi = t.native, t.ffi
+	case 2:
+		t0, _ := g.dartType(res.At(0).Type())
+		t1, _ := g.dartType(res.At(1).Type())
+		g.Printf("final class _Ret_%s extends Struct {\n", symbol)
+		g.Indent()
+		g.genDartStructField(t0, "r0")
+		g.genDartStructField(t1, "r1")
+		g.Outdent()
+		g.Printf("}\n")
+		retNative, retFfi = "_Ret_"+symbol, "_Ret_"+symbol
+	}
+	g.Printf("final _%s = _lib.lookupFunction<%s Function(%s), %s Function(%s)>('%s');\n",
+		symbol, retNative, strings.Join(native, ", "), retFfi, strings.Join(ffi, ", "), symbol)
+}
+
+func (g *DartGen) genDartStructField(t dartTypeInfo, name string) {
+	switch t.native {
+	case "_NString", "_NByteSlice":
+		g.Printf("external %s %s;\n", t.native, name)
+	default:
+		g.Printf("@%s()\n", t.native)
+		g.Printf("external int %s;\n", name)
+	}
+}
+
+func (g *DartGen) genDartFunc(f *types.Func, name, doc, symbol string, recvArg string) {
+	sig := f.Type().(*types.Signature)
+	params := sig.Params()
+	res := sig.Results()
+	var decl, args []string
+	if recvArg != "" {
+		args = append(args, recvArg)
+	}
+	for i := 0; i < params.Len(); i++ {
+		t, _ := g.dartType(params.At(i).Type())
+		pn := basicParamName(params, i)
+		decl = append(decl, fmt.Sprintf("%s %s", t.dart, pn))
+		args = append(args, fmt.Sprintf(t.toFfi, pn))
+	}
+	ret := "void"
+	retIdx := -1
+	throws := false
+	switch res.Len() {
+	case 1:
+		if isErrorType(res.At(0).Type()) {
+			throws = true
+		} else {
+			retIdx = 0
+		}
+	case 2:
+		retIdx = 0
+		throws = true
+	}
+	var retType dartTypeInfo
+	if retIdx == 0 {
+		retType, _ = g.dartType(res.At(0).Type())
+		if g.isNoCopy(f) && isByteSlice(res.At(0).Type()) {
+			// //gobind:nocopy results lend Go's backing array;
+			// release the pin instead of freeing.
+			retType.fromFfi = "_fromNByteSliceNoCopy(%s)"
+		}
+		ret = retType.dart
+	}
+	g.dartdoc(doc, "")
+	g.Printf("%s %s(%s) {\n", ret, name, strings.Join(decl, ", "))
+	g.Indent()
+	call := fmt.Sprintf("_%s(%s)", symbol, strings.Join(args, ", "))
+	switch {
+	case throws && retIdx == 0:
+		g.Printf("final r = %s;\n", call)
+		g.Printf("if (r.r1 != _nullRefnum) {\n")
+		g.Printf("	throw GoError(r.r1);\n")
+		g.Printf("}\n")
+		g.Printf("return %s;\n", fmt.Sprintf(retType.fromFfi, "r.r0"))
+	case throws:
+		g.Printf("final r = %s;\n", call)
+		g.Printf("if (r != _nullRefnum) {\n")
+		g.Printf("	throw GoError(r);\n")
+		g.Printf("}\n")
+	case retIdx == 0:
+		g.Printf("return %s;\n", fmt.Sprintf(retType.fromFfi, call))
+	default:
+		g.Printf("%s;\n", call)
+	}
+	g.Outdent()
+	g.Printf("}\n\n")
+}
+
+// GenPubspec generates the pubspec.yaml declaring a Flutter FFI
+// plugin, so Flutter bundles the c-shared library built by gomobile
+// for each platform.
+func (g *DartGen) GenPubspec() error {
+	var pkgPath string
+	if g.Pkg != nil {
+		pkgPath = g.Pkg.Path()
+	}
+	g.Printf("name: %s\n", g.dartPkgName())
+	g.Printf("description: Flutter bindings for the Go package %s, generated by gobind.\n", pkgPath)
+	g.Printf("version: 0.0.1\n\n")
+	g.Printf("environment:\n")
+	g.Printf("  sdk: ^3.0.0\n")
+	g.Printf("  flutter: '>=3.3.0'\n\n")
+	g.Printf("dependencies:\n")
+	g.Printf("  flutter:\n")
+	g.Printf("    sdk: flutter\n")
+	g.Printf("  ffi: ^2.0.0\n\n")
+	g.Printf("flutter:\n")
+	g.Printf("  plugin:\n")
+	g.Printf("    platforms:\n")
+	for _, p := range []string{"android", "ios", "linux", "macos"} {
+		g.Printf("      %s:\n", p)
+		g.Printf("        ffiPlugin: true\n")
+	}
+	return nil
+}
+
+func (g *DartGen) dartPkgName() string {
+	if g.Pkg == nil {
+		return "universe"
+	}
+	return g.Pkg.Name()
+}
+
+func (g *DartGen) dartdoc(doc, indent string) {
+	if doc == "" {
+		return
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(doc, "\n"), "\n") {
+		g.Printf("%s/// %s\n", indent, strings.TrimRight(line, " "))
+	}
+}
+
+// dartTypeInfo describes how a Go type crosses the FFI bridge: the
+// dart:ffi native type, the Dart type it maps to in the lookup, the
+// public Dart type and the conversions between them (with %s
+// substituted by the value).
+type dartTypeInfo struct {
+	native  string
+	ffi     string
+	dart    string
+	toFfi   string
+	fromFfi string
+}
+
+func (g *DartGen) dartType(t types.Type) (dartTypeInfo, bool) {
+	num := func(native string) (dartTypeInfo, bool) {
+		return dartTypeInfo{native: native, ffi: "int", dart: "int", toFfi: "%s", fromFfi: "%s"}, true
+	}
+	switch t := t.(type) {
+	case *types.Basic:
+		switch t.Kind() {
+		case types.Bool, types.UntypedBool:
+			return dartTypeInfo{native: "Int8", ffi: "int", dart: "bool", toFfi: "%s ? 1 : 0", fromFfi: "%s != 0"}, true
+		case types.Int, types.UntypedInt, types.UntypedRune:
+			return num("Int64") // nint
+		case types.Int8:
+			return num("Int8")
+		case types.Int16:
+			return num("Int16")
+		case types.Int32:
+			return num("Int32")
+		case types.Int64:
+			return num("Int64")
+		case types.Uint8:
+			return num("Uint8")
+		case types.Float32:
+			return dartTypeInfo{native: "Float", ffi: "double", dart: "double", toFfi: "%s", fromFfi: "%s"}, true
+		case types.Float64, types.UntypedFloat:
+			return dartTypeInfo{native: "Double", ffi: "double", dart: "double", toFfi: "%s", fromFfi: "%s"}, true
+		case types.String, types.UntypedString:
+			return dartTypeInfo{native: "_NString", ffi: "_NString", dart: "String", toFfi: "_toNString(%s)", fromFfi: "_fromNString(%s)"}, true
+		}
+	case *types.Slice:
+		if e, ok := t.Elem().(*types.Basic); ok && e.Kind() == types.Uint8 {
+			return dartTypeInfo{native: "_NByteSlice", ffi: "_NByteSlice", dart: "Uint8List", toFfi: "_toNByteSlice(%s)", fromFfi: "_fromNByteSlice(%s)"}, true
+		}
+	case *types.Pointer:
+		if n, ok := t.Elem().(*types.Named); ok {
+			return g.dartNamedType(n)
+		}
+	case *types.Named:
+		if isErrorType(t) {
+			return num("Int32")
+		}
+		return g.dartNamedType(t)
+	}
+	return dartTypeInfo{}, false
+}
+
+func (g *DartGen) dartNamedType(t *types.Named) (dartTypeInfo, bool) {
+	n := t.Obj()
+	if n.Pkg() != g.Pkg {
+		return dartTypeInfo{}, false
+	}
+	if _, ok := t.Underlying().(*types.Interface); ok {
+		// Interfaces are passed as raw refnums; Dart cannot implement
+		// them.
+		return dartTypeInfo{native: "Int32", ffi: "int", dart: "int", toFfi: "_incref(%s)", fromFfi: "%s"}, true
+	}
+	return dartTypeInfo{
+		native:  "Int32",
+		ffi:     "int",
+		dart:    n.Name(),
+		toFfi:   "_incref(%s._refnum)",
+		fromFfi: n.Name() + "._fromRefnum(%s)",
+	}, true
+}
+
+// isDartSigSupported reports whether the Dart generator can handle the
+// signature.
+func (g *DartGen) isDartSigSupported(t types.Type) bool {
+	if !g.isSigSupported(t) {
+		return false
+	}
+	sig := t.(*types.Signature)
+	params := sig.Params()
+	for i := 0; i < params.Len(); i++ {
+		if _, ok := g.dartType(params.At(i).Type()); !ok {
+			return false
+		}
+	}
+	res := sig.Results()
+	for i := 0; i < res.Len(); i++ {
+		if _, ok := g.dartType(res.At(i).Type()); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+const dartSupport = `import 'dart:convert' show utf8;
+import 'dart:ffi';
+import 'dart:io' show Platform;
+import 'dart:typed_data';
+
+import 'package:ffi/ffi.dart';
+
+final DynamicLibrary _lib = _open();
+
+DynamicLibrary _open() {
+	if (Platform.isAndroid || Platform.isLinux) {
+		return DynamicLibrary.open('lib%[1]s.so');
+	}
+	return DynamicLibrary.process();
+}
+
+/// The refnum a nil Go object or error crosses the bridge as.
+const int _nullRefnum = 41;
+
+final _incGoRef = _lib.lookupFunction<Void Function(Int32), void Function(int)>('IncGoRef');
+
+final _releaseByteSliceRef = _lib.lookupFunction<Void Function(Pointer<Void>), void Function(Pointer<Void>)>('ReleaseByteSliceRef');
+
+final _destroyRef = _lib.lookupFunction<Void Function(Int32), void Function(int)>('DestroyRef');
+
+final Finalizer<int> _goFinalizer = Finalizer<int>((refnum) => _destroyRef(refnum));
+
+/// Releases the Go object when the proxy is collected. Nil objects
+/// cross the bridge as the null refnum, which is not tracked.
+void _attachFinalizer(Object proxy, int refnum) {
+	if (refnum != _nullRefnum) {
+		_goFinalizer.attach(proxy, refnum);
+	}
+}
+
+/// Pins the Go object before its refnum crosses into Go; the Go side
+/// consumes one reference per crossing.
+int _incref(int refnum) {
+	_incGoRef(refnum);
+	return refnum;
+}
+
+/// GoError is thrown when a bound Go function returns a non-nil error.
+/// The refnum identifies the Go error value on the Go side of the
+/// bridge.
+class GoError implements Exception {
+	final int refnum;
+
+	GoError(this.refnum) {
+		_attachFinalizer(this, refnum);
+	}
+
+	@override
+	String toString() => 'GoError(refnum ' + refnum.toString() + ')';
+}
+
+final class _NString extends Struct {
+	external Pointer<Void> ptr;
+	@Int32()
+	external int len;
+}
+
+final class _NByteSlice extends Struct {
+	external Pointer<Void> ptr;
+	@Int32()
+	external int len;
+}
+
+_NString _toNString(String s) {
+	final bytes = utf8.encode(s);
+	final p = malloc<Uint8>(bytes.length);
+	p.asTypedList(bytes.length).setAll(0, bytes);
+	final ns = Struct.create<_NString>();
+	ns.ptr = p.cast();
+	ns.len = bytes.length;
+	return ns;
+}
+
+String _fromNString(_NString ns) {
+	if (ns.ptr == nullptr || ns.len == 0) {
+		return '';
+	}
+	final s = utf8.decode(ns.ptr.cast<Uint8>().asTypedList(ns.len));
+	malloc.free(ns.ptr);
+	return s;
+}
+
+_NByteSlice _toNByteSlice(Uint8List b) {
+	final p = malloc<Uint8>(b.length);
+	p.asTypedList(b.length).setAll(0, b);
+	final bs = Struct.create<_NByteSlice>();
+	bs.ptr = p.cast();
+	bs.len = b.length;
+	return bs;
+}
+
+Uint8List _fromNByteSlice(_NByteSlice bs) {
+	if (bs.ptr == nullptr || bs.len == 0) {
+		return Uint8List(0);
+	}
+	final b = Uint8List.fromList(bs.ptr.cast<Uint8>().asTypedList(bs.len));
+	malloc.free(bs.ptr);
+	return b;
+}
+
+/// Results of //gobind:nocopy functions lend Go's backing array;
+/// release the pin instead of freeing.
+Uint8List _fromNByteSliceNoCopy(_NByteSlice bs) {
+	if (bs.ptr == nullptr || bs.len == 0) {
+		return Uint8List(0);
+	}
+	final b = Uint8List.fromList(bs.ptr.cast<Uint8>().asTypedList(bs.len));
+	_releaseByteSliceRef(bs.ptr);
+	return b;
+}
+
+`
@@ -0,0 +1,105 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bind
+
+// DesktopGen generates the C header and stubs satisfying the cgo
+// references of the generated Go bindings on desktop platforms
+// (GOOS=linux and windows), where neither the Android nor the Darwin
+// support headers apply. The desktop FFI backends cannot implement Go
+// interfaces, so the cproxy callbacks for bound interfaces are
+// generated as stubs that abort when reached.
+type DesktopGen struct {
+	*Generator
+}
+
+// Init initializes the generator. It must be called before any
+// generation function.
+func (g *DesktopGen) Init() {
+	g.Generator.Init()
+}
+
+// GenH generates the desktop header declaring the cproxy callbacks
+// referenced by the generated Go bindings.
+func (g *DesktopGen) GenH() error {
+	var pkgPath string
+	if g.Pkg != nil {
+		pkgPath = g.Pkg.Path()
+	}
+	g.Printf(desktopHPreamble, pkgPath, g.pkgPrefix)
+	for _, i := range g.interfaces {
+		if !i.summary.implementable {
+			continue
+		}
+		for _, m := range i.summary.callable {
+			if !g.isSigSupported(m.Type()) {
+				g.Printf("// skipped method %s.%s with unsupported parameter or return types\n\n", i.obj.Name(), m.Name())
+				continue
+			}
+			g.genInterfaceMethodSignature(m, i.obj.Name(), true, basicParamName)
+			g.Printf("\n")
+		}
+	}
+	g.Printf("#endif\n")
+	if len(g.err) > 0 {
+		return g.err
+	}
+	return nil
+}
+
+// GenC generates the desktop stub definitions for the cproxy
+// callbacks. Desktop bindings pass only Go refnums, so a cproxy call
+// means a foreign refnum was forged and the stubs abort.
+func (g *DesktopGen) GenC(pname string) error {
+	var pkgPath string
+	if g.Pkg != nil {
+		pkgPath = g.Pkg.Path()
+	}
+	g.Printf(desktopCPreamble, pkgPath, pname)
+	for _, i := range g.interfaces {
+		if !i.summary.implementable {
+			continue
+		}
+		for _, m := range i.summary.callable {
+			if !g.isSigSupported(m.Type()) {
+				g.Printf("// skipped method %s.%s with unsupported parameter or return types\n\n", i.obj.Name(), m.Name())
+				continue
+			}
+			g.genInterfaceMethodSignature(m, i.obj.Name(), false, basicParamName)
+			g.Indent()
+			g.Printf("LOG_FATAL(\"cproxy%s_%s_%s: desktop bindings cannot implement Go interfaces\");\n", g.pkgPrefix, i.obj.Name(), m.Name())
+			g.Outdent()
+			g.Printf("}\n\n")
+		}
+	}
+	if len(g.err) > 0 {
+		return g.err
+	}
+	return nil
+}
+
+const (
+	desktopCPreamble = gobindPreamble + `// C stubs for the Go bindings on desktop platforms.
+//
+//   autogenerated by gobind -lang=go %[1]s
+
+//go:build (linux && !android) || windows
+
+#include <stdint.h>
+#include "seq_desktop.h"
+#include "%[2]s_desktop.h"
+`
+
+	desktopHPreamble = gobindPreamble + `// C callback declarations for the Go bindings on desktop platforms.
+//
+//   autogenerated by gobind -lang=go %[1]s
+
+#ifndef __GO%[2]s_DESKTOP_H__
+#define __GO%[2]s_DESKTOP_H__
+
+#include <stdint.h>
+#include "seq_desktop.h"
+
+`
+)
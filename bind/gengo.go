@@ -82,9 +82,24 @@ func (g *goGen) genFuncBody(o *types.Func, selectorLHS string) {
 	}
 	g.Printf(")\n")
 
+	noCopy := g.isNoCopy(o)
+	if noCopy {
+		found := false
+		for i := 0; i < res.Len(); i++ {
+			found = found || isByteSlice(res.At(i).Type())
+		}
+		if !found {
+			g.errorf("gobind:nocopy on %s: the function must return a []byte", o.Name())
+			noCopy = false
+		}
+	}
 	for i := 0; i < res.Len(); i++ {
 		pn := fmt.Sprintf("res_%d", i)
-		g.genWrite("_"+pn, pn, res.At(i).Type(), modeRetained)
+		mode := modeRetained
+		if noCopy && isByteSlice(res.At(i).Type()) {
+			mode = modePinned
+		}
+		g.genWrite("_"+pn, pn, res.At(i).Type(), mode)
 	}
 	if res.Len() > 0 {
 		g.Printf("return ")
@@ -111,17 +126,35 @@ func (g *goGen) genWrite(toVar, fromVar string, t types.Type, mode varMode) {
 			g.Printf("%s := C.%s(%s)\n", toVar, g.cgoType(t), fromVar)
 		}
 	case *types.Slice:
+		if isByteSliceSlice(t) {
+			g.Printf("%s := fromByteSliceSlice(%s)\n", toVar, fromVar)
+			break
+		}
+		if isStringSlice(t) {
+			g.Printf("%s := fromStringSlice(%s)\n", toVar, fromVar)
+			break
+		}
 		switch e := t.Elem().(type) {
 		case *types.Basic:
 			switch e.Kind() {
 			case types.Uint8: // Byte.
-				g.Printf("%s := fromSlice(%s, %v)\n", toVar, fromVar, mode == modeRetained)
+				if mode == modePinned {
+					g.Printf("%s := fromSliceRef(%s)\n", toVar, fromVar)
+				} else {
+					g.Printf("%s := fromSlice(%s, %v)\n", toVar, fromVar, mode == modeRetained)
+				}
 			default:
 				g.errorf("unsupported type: %s", t)
 			}
 		default:
 			g.errorf("unsupported type: %s", t)
 		}
+	case *types.Map:
+		if isStringMap(t) {
+			g.Printf("%s := fromStringMap(%s)\n", toVar, fromVar)
+			break
+		}
+		g.errorf("unsupported type: %s", t)
 	case *types.Pointer:
 		// TODO(crawshaw): test *int
 		// TODO(crawshaw): test **Generator
@@ -192,6 +225,10 @@ func (g *goGen) genFunc(o *types.Func) {
 		g.Printf("// skipped function %s with unsupported parameter or result types\n", o.Name())
 		return
 	}
+	if g.docs[o.Name()].Fastpath() {
+		g.errorf("gomobile:fastpath on %s: the directive applies only to methods", o.Name())
+		return
+	}
 	g.genFuncSignature(o, "")
 	g.Indent()
 	g.genFuncBody(o, g.pkgName(g.Pkg))
@@ -204,6 +241,20 @@ func (g *goGen) genStruct(obj *types.TypeName, T *types.Struct) {
 	methods := exportedMethodSet(types.NewPointer(obj.Type()))
 
 	for _, f := range fields {
+		if nt, ok := embeddedBoundStruct(f); ok && g.validPkg(nt.Obj().Pkg()) {
+			// Embedded structs are held by value and cannot be set, but
+			// the embedded part is exposed through a pointer to it.
+			g.Printf("//export proxy%s_%s_%s_Get\n", g.pkgPrefix, obj.Name(), f.Name())
+			g.Printf("func proxy%s_%s_%s_Get(refnum C.int32_t) C.int32_t {\n", g.pkgPrefix, obj.Name(), f.Name())
+			g.Indent()
+			g.Printf("ref := _seq.FromRefNum(int32(refnum))\n")
+			g.Printf("v := &ref.Get().(*%s%s).%s\n", g.pkgName(g.Pkg), obj.Name(), f.Name())
+			g.genWrite("_v", "v", types.NewPointer(f.Type()), modeRetained)
+			g.Printf("return _v\n")
+			g.Outdent()
+			g.Printf("}\n\n")
+			continue
+		}
 		if t := f.Type(); !g.isSupported(t) {
 			g.Printf("// skipped field %s.%s with unsupported type: %s\n\n", obj.Name(), f.Name(), t)
 			continue
@@ -233,10 +284,24 @@ func (g *goGen) genStruct(obj *types.TypeName, T *types.Struct) {
 			g.Printf("// skipped method %s.%s with unsupported parameter or return types\n\n", obj.Name(), m.Name())
 			continue
 		}
+		fastpath := g.isFastpath(m)
+		if fastpath {
+			if res := m.Type().(*types.Signature).Results(); res.Len() > 0 && isErrorType(res.At(res.Len()-1).Type()) {
+				g.errorf("gomobile:fastpath on %s.%s: the method must not return an error", obj.Name(), m.Name())
+				continue
+			}
+		}
 		g.genFuncSignature(m, obj.Name())
 		g.Indent()
 		g.Printf("ref := _seq.FromRefNum(int32(refnum))\n")
-		g.Printf("v := ref.Get().(*%s%s)\n", g.pkgName(g.Pkg), obj.Name())
+		if fastpath {
+			// The caller did not increment the reference count; the
+			// receiver proxy pins the object for the duration of the
+			// call.
+			g.Printf("v := ref.Peek().(*%s%s)\n", g.pkgName(g.Pkg), obj.Name())
+		} else {
+			g.Printf("v := ref.Get().(*%s%s)\n", g.pkgName(g.Pkg), obj.Name())
+		}
 		g.genFuncBody(m, "v.")
 		g.Outdent()
 		g.Printf("}\n\n")
@@ -395,6 +460,14 @@ func (g *goGen) genRead(toVar, fromVar string, typ types.Type, mode varMode) {
 			g.Printf("%s := %s(%s)\n", toVar, t.Underlying().String(), fromVar)
 		}
 	case *types.Slice:
+		if isByteSliceSlice(t) {
+			g.Printf("%s := toByteSliceSlice(%s)\n", toVar, fromVar)
+			break
+		}
+		if isStringSlice(t) {
+			g.Printf("%s := toStringSlice(%s)\n", toVar, fromVar)
+			break
+		}
 		switch e := t.Elem().(type) {
 		case *types.Basic:
 			switch e.Kind() {
@@ -406,6 +479,12 @@ func (g *goGen) genRead(toVar, fromVar string, typ types.Type, mode varMode) {
 		default:
 			g.errorf("unsupported type: %s", t)
 		}
+	case *types.Map:
+		if isStringMap(t) {
+			g.Printf("%s := toStringMap(%s)\n", toVar, fromVar)
+			break
+		}
+		g.errorf("unsupported type: %s", t)
 	case *types.Pointer:
 		switch u := t.Elem().(type) {
 		case *types.Named:
@@ -545,6 +624,15 @@ func (g *goGen) gen() error {
 	for _, v := range g.vars {
 		g.genVar(v)
 	}
+	if evars := g.errorVars(); len(evars) > 0 && g.Pkg != nil {
+		g.Printf("func init() {\n")
+		g.Indent()
+		for _, v := range evars {
+			g.Printf("_seq.RegisterErrorCode(%s%s, %q, %d)\n", g.pkgName(v.Pkg()), v.Name(), g.Pkg.Path(), errorCode(v.Name()))
+		}
+		g.Outdent()
+		g.Printf("}\n\n")
+	}
 	for _, f := range g.funcs {
 		g.genFunc(f)
 	}
@@ -10,6 +10,7 @@ import (
 	"go/types"
 	"html"
 	"math"
+	"path"
 	"reflect"
 	"regexp"
 	"strings"
@@ -25,6 +26,23 @@ type JavaGen struct {
 	// package name to create the full Java package name.
 	JavaPkg string
 
+	// RejectNulls makes the generated JNI code throw a
+	// NullPointerException for null strings and byte slices passed
+	// from Java, instead of silently converting them to empty values.
+	RejectNulls bool
+
+	// Annotations emits androidx.annotation @NonNull and @Nullable
+	// annotations on reference-typed parameters and results, derived
+	// from Go nil semantics, so Kotlin consumers see null-checked
+	// types instead of platform types.
+	Annotations bool
+
+	// UncheckedExceptions makes Go errors surface as unchecked
+	// exceptions. Generated methods lose their throws clause and
+	// errors without a generated exception class are thrown wrapped
+	// in a Seq.GoRuntimeException.
+	UncheckedExceptions bool
+
 	*Generator
 
 	jstructs map[*types.TypeName]*javaClassInfo
@@ -228,12 +246,41 @@ func (g *JavaGen) GenClass(idx int) error {
 	return nil
 }
 
-func (g *JavaGen) genProxyImpl(name string) {
+func (g *JavaGen) genProxyImpl(name string, closer bool) {
 	g.Printf("public final int refnum;\n\n")
+	if closer {
+		g.Printf("private Object goRef;\n\n")
+	}
 	g.Printf("@Override public final int incRefnum() {\n")
 	g.Printf("      Seq.incGoRef(refnum, this);\n")
 	g.Printf("      return refnum;\n")
 	g.Printf("}\n\n")
+	if closer {
+		g.Printf("// close releases the Go reference without waiting for the garbage\n")
+		g.Printf("// collector; it is idempotent. Any other use of the proxy after\n")
+		g.Printf("// close is invalid.\n")
+		g.Printf("@Override public final void close() { Seq.closeGoRef(goRef); }\n\n")
+	}
+}
+
+// closeClash reports whether the generated class gets a niladic close
+// method from its Go method set, taking the place of the close
+// generated for AutoCloseable. compat reports whether that method
+// still satisfies the AutoCloseable contract, returning nothing or
+// only an error.
+func (g *JavaGen) closeClash(methods []*types.Func) (clash, compat bool) {
+	for _, m := range methods {
+		if !g.isSigSupported(m.Type()) {
+			continue
+		}
+		sig := m.Type().(*types.Signature)
+		if javaNameReplacer(lowerFirst(m.Name())) != "close" || sig.Params().Len() != 0 {
+			continue
+		}
+		res := sig.Results()
+		return true, res.Len() == 0 || res.Len() == 1 && isErrorType(res.At(0).Type())
+	}
+	return false, false
 }
 
 func (g *JavaGen) genStruct(s structInfo) {
@@ -259,6 +306,11 @@ func (g *JavaGen) genStruct(s structInfo) {
 	} else {
 		impls = append(impls, "Seq.Proxy")
 	}
+	closeClash, closeCompat := g.closeClash(methods)
+	closer := jinf == nil && !closeClash
+	if jinf == nil && (!closeClash || closeCompat) {
+		impls = append(impls, "AutoCloseable")
+	}
 
 	pT := types.NewPointer(s.obj.Type())
 	for _, iface := range g.allIntf {
@@ -277,12 +329,18 @@ func (g *JavaGen) genStruct(s structInfo) {
 	}
 
 	doc := g.docs[n]
-	g.javadoc(doc.Doc())
+	g.javadoc(typeDoc(doc))
+	isError := jinf == nil && implementsError(s.obj.Type())
+	g.keepAnnotation()
 	g.Printf("public final class %s", n)
 	if jinf != nil {
 		if jinf.extends != nil {
 			g.Printf(" extends %s", g.javaTypeName(jinf.extends.Name))
 		}
+	} else if isError {
+		// Structs implementing error become exception classes, so
+		// Java code can catch the specific failure.
+		g.Printf(" extends %s", g.exceptionSuper())
 	}
 	if len(impls) > 0 {
 		g.Printf(" implements %s", strings.Join(impls, ", "))
@@ -291,26 +349,46 @@ func (g *JavaGen) genStruct(s structInfo) {
 	g.Indent()
 
 	g.Printf("static { %s.touch(); }\n\n", g.className())
-	g.genProxyImpl(n)
+	g.genProxyImpl(n, closer)
 	cons := g.constructors[s.obj]
 	for _, f := range cons {
 		if !g.isConsSigSupported(f.Type()) {
 			g.Printf("// skipped constructor %s.%s with unsupported parameter or return types\n\n", n, f.Name())
 			continue
 		}
-		g.genConstructor(f, n, jinf != nil)
+		g.genConstructor(f, n, jinf != nil, closer)
+	}
+	track := "Seq.trackGoRef(refnum, this);"
+	if closer {
+		track = "goRef = " + track
 	}
 	if jinf == nil || jinf.genNoargCon {
 		// constructor for Go instantiated instances.
-		g.Printf("%s(int refnum) { this.refnum = refnum; Seq.trackGoRef(refnum, this); }\n\n", n)
+		g.Printf("%s(int refnum) { this.refnum = refnum; %s }\n\n", n, track)
 		if len(cons) == 0 {
 			// Generate default no-arg constructor
-			g.Printf("public %s() { this.refnum = __New(); Seq.trackGoRef(refnum, this); }\n\n", n)
+			g.Printf("public %s() { this.refnum = __New(); %s }\n\n", n, track)
 			g.Printf("private static native int __New();\n\n")
 		}
 	}
 
+	if isError {
+		g.Printf("@Override public String getMessage() { return error(); }\n\n")
+	}
+
 	for _, f := range fields {
+		if nt, ok := embeddedBoundStruct(f); ok && g.validPkg(nt.Obj().Pkg()) {
+			// Embedded structs are held by value; expose the embedded
+			// part through a read-only accessor. The accessor proxies
+			// the field in place, so it never returns null.
+			g.javadoc(doc.Member(f.Name()))
+			ann := ""
+			if g.Annotations {
+				ann = "@androidx.annotation.NonNull "
+			}
+			g.Printf("public final native %s%s get%s();\n\n", ann, g.javaType(types.NewPointer(f.Type())), f.Name())
+			continue
+		}
 		if t := f.Type(); !g.isSupported(t) {
 			g.Printf("// skipped field %s.%s with unsupported type: %s\n\n", n, f.Name(), t)
 			continue
@@ -318,9 +396,9 @@ func (g *JavaGen) genStruct(s structInfo) {
 
 		fdoc := doc.Member(f.Name())
 		g.javadoc(fdoc)
-		g.Printf("public final native %s get%s();\n", g.javaType(f.Type()), f.Name())
+		g.Printf("public final native %s%s get%s();\n", g.returnAnnotation(f.Type()), g.javaType(f.Type()), f.Name())
 		g.javadoc(fdoc)
-		g.Printf("public final native void set%s(%s v);\n\n", f.Name(), g.javaType(f.Type()))
+		g.Printf("public final native void set%s(%s%s v);\n\n", f.Name(), g.paramAnnotation(f.Type()), g.javaType(f.Type()))
 	}
 
 	var isStringer bool
@@ -341,6 +419,9 @@ func (g *JavaGen) genStruct(s structInfo) {
 		}
 		g.Printf("public native ")
 		g.genFuncSignature(m, jm, hasThis)
+		if jinf == nil && hasByteSliceParams(m) {
+			g.genByteBufferOverload(m, false)
+		}
 		t := m.Type().(*types.Signature)
 		isStringer = isStringer || (m.Name() == "String" && t.Params().Len() == 0 && t.Results().Len() == 1 &&
 			types.Identical(t.Results().At(0).Type(), types.Typ[types.String]))
@@ -348,12 +429,86 @@ func (g *JavaGen) genStruct(s structInfo) {
 
 	if jinf == nil {
 		g.genObjectMethods(n, fields, isStringer)
+		if doc.ThreadSafety() == "confined" {
+			g.genSynchronizedWrapper(n, fields, methods)
+		}
 	}
 
 	g.Outdent()
 	g.Printf("}\n\n")
 }
 
+// genSynchronizedWrapper generates a nested Synchronized class for a
+// struct declared //gomobile:confined, serializing all calls through a
+// per-instance lock so consumers can opt in to sharing a proxy between
+// threads.
+func (g *JavaGen) genSynchronizedWrapper(n string, fields []*types.Var, methods []*types.Func) {
+	g.Printf("/**\n * Synchronized wraps a %s and serializes all calls to it through a per-instance lock.\n */\n", n)
+	g.Printf("public static final class Synchronized {\n")
+	g.Indent()
+	g.Printf("private final %s delegate;\n\n", n)
+	g.Printf("public Synchronized(%s delegate) { this.delegate = delegate; }\n\n", n)
+	for _, f := range fields {
+		if _, ok := embeddedBoundStruct(f); ok {
+			continue
+		}
+		if !g.isSupported(f.Type()) {
+			continue
+		}
+		jt := g.javaType(f.Type())
+		g.Printf("public synchronized %s%s get%s() { return delegate.get%s(); }\n", g.returnAnnotation(f.Type()), jt, f.Name(), f.Name())
+		g.Printf("public synchronized void set%s(%s%s v) { delegate.set%s(v); }\n\n", f.Name(), g.paramAnnotation(f.Type()), jt, f.Name())
+	}
+	for _, m := range methods {
+		if !g.isSigSupported(m.Type()) {
+			continue
+		}
+		sig := m.Type().(*types.Signature)
+		params := sig.Params()
+		res := sig.Results()
+		ret := "void"
+		retAnn := ""
+		returnsError := false
+		switch res.Len() {
+		case 2:
+			returnsError = true
+			ret = g.javaType(res.At(0).Type())
+			retAnn = g.returnAnnotation(res.At(0).Type())
+		case 1:
+			if isErrorType(res.At(0).Type()) {
+				returnsError = true
+			} else {
+				ret = g.javaType(res.At(0).Type())
+				retAnn = g.returnAnnotation(res.At(0).Type())
+			}
+		}
+		name := javaNameReplacer(lowerFirst(m.Name()))
+		var args []string
+		g.Printf("public synchronized %s%s %s(", retAnn, ret, name)
+		for i := 0; i < params.Len(); i++ {
+			if i > 0 {
+				g.Printf(", ")
+			}
+			g.Printf("%s%s %s", g.paramAnnotation(params.At(i).Type()), g.javaType(params.At(i).Type()), g.paramName(params, i))
+			args = append(args, g.paramName(params, i))
+		}
+		g.Printf(")")
+		if returnsError {
+			g.Printf("%s", g.throwsClause())
+		}
+		g.Printf(" {\n")
+		g.Indent()
+		if ret != "void" {
+			g.Printf("return ")
+		}
+		g.Printf("delegate.%s(%s);\n", name, strings.Join(args, ", "))
+		g.Outdent()
+		g.Printf("}\n\n")
+	}
+	g.Outdent()
+	g.Printf("}\n\n")
+}
+
 // isConsSigSupported reports whether the generators can handle a given
 // constructor signature.
 func (g *JavaGen) isConsSigSupported(t types.Type) bool {
@@ -395,6 +550,72 @@ func (g *JavaGen) javadoc(doc string) {
 }
 
 // hasThis reports whether a method has an implicit "this" parameter.
+// paramAnnotation returns the nullability annotation, if any, for a
+// parameter of type t. Null strings, slices and maps are substituted
+// with empty Go values at the boundary, and null proxies pass through
+// as nil, so parameters are nullable unless the reject null policy
+// turns a null into a NullPointerException.
+func (g *JavaGen) paramAnnotation(t types.Type) string {
+	if !g.Annotations || isJavaPrimitive(t) {
+		return ""
+	}
+	if g.RejectNulls && isNullSubstituted(t) {
+		return "@androidx.annotation.NonNull "
+	}
+	return "@androidx.annotation.Nullable "
+}
+
+// returnAnnotation returns the nullability annotation, if any, for a
+// result of type t. Go strings always marshal to non-null Java
+// strings; nil slices, maps, interfaces and struct pointers surface
+// as null.
+func (g *JavaGen) returnAnnotation(t types.Type) string {
+	if !g.Annotations || isJavaPrimitive(t) {
+		return ""
+	}
+	if b, ok := t.Underlying().(*types.Basic); ok && b.Info()&types.IsString != 0 {
+		return "@androidx.annotation.NonNull "
+	}
+	return "@androidx.annotation.Nullable "
+}
+
+// keepAnnotation emits @androidx.annotation.Keep on a generated class
+// when annotations are enabled. The classes are instantiated from JNI,
+// so lint and R8 must treat them as entry points without custom keep
+// rules.
+func (g *JavaGen) keepAnnotation() {
+	if g.Annotations {
+		g.Printf("@androidx.annotation.Keep\n")
+	}
+}
+
+// throwsClause returns the throws clause for a method with an error
+// result; it is empty when errors surface as unchecked exceptions.
+func (g *JavaGen) throwsClause() string {
+	if g.UncheckedExceptions {
+		return ""
+	}
+	return " throws Exception"
+}
+
+// throwFunc returns the name of the JNI helper that throws an error
+// converted to an exception object.
+func (g *JavaGen) throwFunc() string {
+	if g.UncheckedExceptions {
+		return "go_seq_maybe_throw_unchecked"
+	}
+	return "go_seq_maybe_throw_exception"
+}
+
+// exceptionSuper returns the superclass of the exception classes
+// generated for Go error types.
+func (g *JavaGen) exceptionSuper() string {
+	if g.UncheckedExceptions {
+		return "RuntimeException"
+	}
+	return "Exception"
+}
+
 func (g *JavaGen) hasThis(sName string, m *types.Func) bool {
 	sig := m.Type().(*types.Signature)
 	params := sig.Params()
@@ -423,7 +644,7 @@ func (g *JavaGen) hasThis(sName string, m *types.Func) bool {
 	return true
 }
 
-func (g *JavaGen) genConstructor(f *types.Func, n string, jcls bool) {
+func (g *JavaGen) genConstructor(f *types.Func, n string, jcls, closer bool) {
 	g.javadoc(g.docs[f.Name()].Doc())
 	g.Printf("public %s(", n)
 	g.genFuncArgs(f, nil, false)
@@ -450,6 +671,9 @@ func (g *JavaGen) genConstructor(f *types.Func, n string, jcls bool) {
 		g.Printf(g.paramName(params, i))
 	}
 	g.Printf(");\n")
+	if closer {
+		g.Printf("goRef = ")
+	}
 	g.Printf("Seq.trackGoRef(refnum, this);\n")
 	g.Outdent()
 	g.Printf("}\n\n")
@@ -476,7 +700,7 @@ func (g *JavaGen) genFuncArgs(f *types.Func, jm *java.Func, hasThis bool) {
 		v := params.At(i)
 		name := g.paramName(params, i)
 		jt := g.javaType(v.Type())
-		g.Printf("%s %s", jt, name)
+		g.Printf("%s%s %s", g.paramAnnotation(v.Type()), jt, name)
 	}
 }
 
@@ -571,7 +795,8 @@ func (g *JavaGen) genInterface(iface interfaceInfo) {
 		}
 	}
 	doc := g.docs[iface.obj.Name()]
-	g.javadoc(doc.Doc())
+	g.javadoc(typeDoc(doc))
+	g.keepAnnotation()
 	g.Printf("public interface %s", g.javaTypeName(iface.obj.Name()))
 	if len(exts) > 0 {
 		g.Printf(" extends %s", strings.Join(exts, ", "))
@@ -641,7 +866,12 @@ func (g *JavaGen) jniType(T types.Type) string {
 			return "TODO"
 		}
 	case *types.Slice:
+		if isByteSliceSlice(T) || isStringSlice(T) {
+			return "jobject"
+		}
 		return "jbyteArray"
+	case *types.Map:
+		return "jobject"
 
 	case *types.Pointer:
 		if _, ok := T.Elem().(*types.Named); ok {
@@ -701,8 +931,19 @@ func (g *JavaGen) javaType(T types.Type) string {
 	case *types.Basic:
 		return g.javaBasicType(T)
 	case *types.Slice:
+		if isByteSliceSlice(T) {
+			return "java.util.List<byte[]>"
+		}
+		if isStringSlice(T) {
+			return "java.util.List<String>"
+		}
 		elem := g.javaType(T.Elem())
 		return elem + "[]"
+	case *types.Map:
+		if isStringMap(T) {
+			return "java.util.Map<String, String>"
+		}
+		g.errorf("unsupported map type: %s", T)
 
 	case *types.Pointer:
 		if _, ok := T.Elem().(*types.Named); ok {
@@ -814,7 +1055,7 @@ func (g *JavaGen) genFuncSignature(o *types.Func, jm *java.Func, hasThis bool) {
 	res := sig.Results()
 
 	var returnsError bool
-	var ret string
+	var ret, retAnn string
 	switch res.Len() {
 	case 2:
 		if !isErrorType(res.At(1).Type()) {
@@ -823,12 +1064,14 @@ func (g *JavaGen) genFuncSignature(o *types.Func, jm *java.Func, hasThis bool) {
 		}
 		returnsError = true
 		ret = g.javaType(res.At(0).Type())
+		retAnn = g.returnAnnotation(res.At(0).Type())
 	case 1:
 		if isErrorType(res.At(0).Type()) {
 			returnsError = true
 			ret = "void"
 		} else {
 			ret = g.javaType(res.At(0).Type())
+			retAnn = g.returnAnnotation(res.At(0).Type())
 		}
 	case 0:
 		ret = "void"
@@ -837,7 +1080,7 @@ func (g *JavaGen) genFuncSignature(o *types.Func, jm *java.Func, hasThis bool) {
 		return
 	}
 
-	g.Printf("%s ", ret)
+	g.Printf("%s%s ", retAnn, ret)
 	if jm != nil {
 		g.Printf(jm.Name)
 	} else {
@@ -854,12 +1097,284 @@ func (g *JavaGen) genFuncSignature(o *types.Func, jm *java.Func, hasThis bool) {
 			}
 			g.Printf(" throws %s", jm.Throws)
 		} else {
-			g.Printf(" throws Exception")
+			g.Printf("%s", g.throwsClause())
 		}
 	}
 	g.Printf(";\n")
 }
 
+// genFuncDefaults generates overloads that omit trailing parameters
+// declared with a //gobind:default directive, substituting the
+// declared literal.
+func (g *JavaGen) genFuncDefaults(o *types.Func) {
+	nd := g.defaultedParams(o)
+	if nd == 0 {
+		return
+	}
+	sig := o.Type().(*types.Signature)
+	params := sig.Params()
+	res := sig.Results()
+	ret := "void"
+	retAnn := ""
+	returnsError := false
+	switch res.Len() {
+	case 2:
+		returnsError = true
+		ret = g.javaType(res.At(0).Type())
+		retAnn = g.returnAnnotation(res.At(0).Type())
+	case 1:
+		if isErrorType(res.At(0).Type()) {
+			returnsError = true
+		} else {
+			ret = g.javaType(res.At(0).Type())
+			retAnn = g.returnAnnotation(res.At(0).Type())
+		}
+	}
+	doc := g.docs[o.Name()]
+	name := javaNameReplacer(lowerFirst(o.Name()))
+	for k := 1; k <= nd; k++ {
+		var args []string
+		g.Printf("public static %s%s %s(", retAnn, ret, name)
+		for i := 0; i < params.Len()-k; i++ {
+			if i > 0 {
+				g.Printf(", ")
+			}
+			g.Printf("%s%s %s", g.paramAnnotation(params.At(i).Type()), g.javaType(params.At(i).Type()), g.paramName(params, i))
+			args = append(args, g.paramName(params, i))
+		}
+		for i := params.Len() - k; i < params.Len(); i++ {
+			def, _ := doc.Default(params.At(i).Name())
+			args = append(args, g.javaLiteral(params.At(i).Type().(*types.Basic), def))
+		}
+		g.Printf(")")
+		if returnsError {
+			g.Printf("%s", g.throwsClause())
+		}
+		g.Printf(" {\n")
+		g.Indent()
+		if ret != "void" {
+			g.Printf("return ")
+		}
+		g.Printf("%s(%s);\n", name, strings.Join(args, ", "))
+		g.Outdent()
+		g.Printf("}\n\n")
+	}
+}
+
+// hasByteSliceParams reports whether o takes at least one []byte
+// parameter, making it eligible for a direct ByteBuffer overload.
+func hasByteSliceParams(o *types.Func) bool {
+	params := o.Type().(*types.Signature).Params()
+	for i := 0; i < params.Len(); i++ {
+		if isByteSlice(params.At(i).Type()) {
+			return true
+		}
+	}
+	return false
+}
+
+// genByteBufferOverload generates an overload of o taking direct
+// java.nio.ByteBuffer arguments in place of []byte parameters, for
+// zero-copy call paths such as packet processing. The wrapper
+// validates that the buffers are direct and hands their position and
+// limit to a private native entry point, which passes the buffer
+// memory straight to Go without a copy. The Go code must not retain
+// the slice beyond the call, the same contract as for ordinary []byte
+// parameters.
+func (g *JavaGen) genByteBufferOverload(o *types.Func, static bool) {
+	sig := o.Type().(*types.Signature)
+	params := sig.Params()
+	res := sig.Results()
+	ret := "void"
+	retAnn := ""
+	returnsError := false
+	switch res.Len() {
+	case 2:
+		returnsError = true
+		ret = g.javaType(res.At(0).Type())
+		retAnn = g.returnAnnotation(res.At(0).Type())
+	case 1:
+		if isErrorType(res.At(0).Type()) {
+			returnsError = true
+		} else {
+			ret = g.javaType(res.At(0).Type())
+			retAnn = g.returnAnnotation(res.At(0).Type())
+		}
+	}
+	mod := "public "
+	if static {
+		mod = "public static "
+	}
+	name := javaNameReplacer(lowerFirst(o.Name()))
+	throws := ""
+	if returnsError {
+		throws = g.throwsClause()
+	}
+	g.Printf("%s%s%s %s(", mod, retAnn, ret, name)
+	for i := 0; i < params.Len(); i++ {
+		if i > 0 {
+			g.Printf(", ")
+		}
+		pn := g.paramName(params, i)
+		if isByteSlice(params.At(i).Type()) {
+			g.Printf("java.nio.ByteBuffer %s", pn)
+		} else {
+			g.Printf("%s%s %s", g.paramAnnotation(params.At(i).Type()), g.javaType(params.At(i).Type()), pn)
+		}
+	}
+	g.Printf(")%s {\n", throws)
+	g.Indent()
+	var args []string
+	for i := 0; i < params.Len(); i++ {
+		pn := g.paramName(params, i)
+		if isByteSlice(params.At(i).Type()) {
+			g.Printf("if (!%s.isDirect()) {\n", pn)
+			g.Printf("	throw new IllegalArgumentException(\"%s must be a direct ByteBuffer\");\n", pn)
+			g.Printf("}\n")
+			args = append(args, pn, pn+".position()", pn+".limit()")
+		} else {
+			args = append(args, pn)
+		}
+	}
+	if ret != "void" {
+		g.Printf("return ")
+	}
+	g.Printf("__%sDirect(%s);\n", name, strings.Join(args, ", "))
+	g.Outdent()
+	g.Printf("}\n\n")
+
+	g.Printf("private ")
+	if static {
+		g.Printf("static ")
+	}
+	g.Printf("native %s __%sDirect(", ret, name)
+	for i := 0; i < params.Len(); i++ {
+		if i > 0 {
+			g.Printf(", ")
+		}
+		pn := g.paramName(params, i)
+		if isByteSlice(params.At(i).Type()) {
+			g.Printf("java.nio.ByteBuffer %s, int %sPosition, int %sLimit", pn, pn, pn)
+		} else {
+			g.Printf("%s %s", g.javaType(params.At(i).Type()), pn)
+		}
+	}
+	g.Printf(")%s;\n\n", throws)
+}
+
+// genJNIDirectFunc generates the JNI entry point for the direct
+// ByteBuffer overload of o. []byte parameters arrive as a direct
+// buffer with its position and limit; the slice passed to Go aliases
+// the buffer memory for the duration of the call.
+func (g *JavaGen) genJNIDirectFunc(o *types.Func, sName string) {
+	sig := o.Type().(*types.Signature)
+	params := sig.Params()
+	res := sig.Results()
+
+	ret := "void"
+	switch res.Len() {
+	case 2:
+		ret = g.jniType(res.At(0).Type())
+	case 1:
+		if !isErrorType(res.At(0).Type()) {
+			ret = g.jniType(res.At(0).Type())
+		}
+	}
+	g.Printf("JNIEXPORT %s JNICALL\n", ret)
+	g.Printf("Java_%s_", g.jniPkgName())
+	if sName != "" {
+		g.Printf(java.JNIMangle(g.javaTypeName(sName)))
+	} else {
+		g.Printf(java.JNIMangle(g.className()))
+	}
+	name := javaNameReplacer(lowerFirst(o.Name()))
+	g.Printf("_%s(JNIEnv* env, ", java.JNIMangle("__"+name+"Direct"))
+	if sName != "" {
+		g.Printf("jobject __this__")
+	} else {
+		g.Printf("jclass _clazz")
+	}
+	for i := 0; i < params.Len(); i++ {
+		pn := g.paramName(params, i)
+		if isByteSlice(params.At(i).Type()) {
+			g.Printf(", jobject %s, jint %s_position, jint %s_limit", pn, pn, pn)
+		} else {
+			g.Printf(", %s %s", g.jniType(params.At(i).Type()), pn)
+		}
+	}
+	g.Printf(") {\n")
+	g.Indent()
+	if sName != "" {
+		if g.isFastpath(o) {
+			g.Printf("int32_t o = go_seq_peek_refnum_go(env, __this__, proxy_class_%s_%s_refnum);\n", g.pkgPrefix, sName)
+		} else {
+			g.Printf("int32_t o = go_seq_to_refnum_go(env, __this__);\n")
+		}
+	}
+	for i := 0; i < params.Len(); i++ {
+		pn := g.paramName(params, i)
+		t := params.At(i).Type()
+		if isByteSlice(t) {
+			g.Printf("nbyteslice _%s = {0};\n", pn)
+			g.Printf("_%s.ptr = (char *)(*env)->GetDirectBufferAddress(env, %s) + %s_position;\n", pn, pn, pn)
+			g.Printf("_%s.len = %s_limit - %s_position;\n", pn, pn, pn)
+		} else {
+			g.genJavaToC(pn, t, modeTransient)
+		}
+	}
+	resPrefix := ""
+	if res.Len() > 0 {
+		if res.Len() == 1 {
+			g.Printf("%s r0 = ", g.cgoType(res.At(0).Type()))
+		} else {
+			resPrefix = "res."
+			g.Printf("struct proxy%s_%s_%s_return res = ", g.pkgPrefix, sName, o.Name())
+		}
+	}
+	g.Printf("proxy%s_%s_%s(", g.pkgPrefix, sName, o.Name())
+	if sName != "" {
+		g.Printf("o")
+	}
+	for i := 0; i < params.Len(); i++ {
+		if i > 0 || sName != "" {
+			g.Printf(", ")
+		}
+		g.Printf("_%s", g.paramName(params, i))
+	}
+	g.Printf(");\n")
+	for i := 0; i < params.Len(); i++ {
+		if t := params.At(i).Type(); !isByteSlice(t) {
+			g.genRelease(g.paramName(params, i), t, modeTransient)
+		}
+	}
+	for i := 0; i < res.Len(); i++ {
+		g.genCToJava(fmt.Sprintf("_r%d", i), fmt.Sprintf("%sr%d", resPrefix, i), res.At(i).Type(), modeRetained)
+	}
+	// Go backwards so that any exception is thrown before
+	// the return.
+	for i := res.Len() - 1; i >= 0; i-- {
+		t := res.At(i).Type()
+		if !isErrorType(t) {
+			g.Printf("return _r%d;\n", i)
+		} else {
+			g.Printf("%s(env, _r%d);\n", g.throwFunc(), i)
+		}
+	}
+	g.Outdent()
+	g.Printf("}\n\n")
+}
+
+// javaLiteral returns the Java spelling of a Go literal of basic type.
+func (g *JavaGen) javaLiteral(t *types.Basic, v string) string {
+	switch t.Kind() {
+	case types.Int, types.Int64, types.UntypedInt:
+		return v + "L"
+	case types.Float32:
+		return v + "f"
+	}
+	return v
+}
+
 func (g *JavaGen) genVar(o *types.Var) {
 	if t := o.Type(); !g.isSupported(t) {
 		g.Printf("// skipped variable %s with unsupported type: %s\n\n", o.Name(), t)
@@ -870,11 +1385,11 @@ func (g *JavaGen) genVar(o *types.Var) {
 	doc := g.docs[o.Name()].Doc()
 	// setter
 	g.javadoc(doc)
-	g.Printf("public static native void set%s(%s v);\n", o.Name(), jType)
+	g.Printf("public static native void set%s(%s%s v);\n", o.Name(), g.paramAnnotation(o.Type()), jType)
 
 	// getter
 	g.javadoc(doc)
-	g.Printf("public static native %s get%s();\n\n", jType, o.Name())
+	g.Printf("public static native %s%s get%s();\n\n", g.returnAnnotation(o.Type()), jType, o.Name())
 }
 
 // genCRetClear clears the result value from a JNI call if an exception was
@@ -890,7 +1405,7 @@ func (g *JavaGen) genCRetClear(varName string, t types.Type, exc string) {
 		default:
 			g.Printf("%s = 0;\n", varName)
 		}
-	case *types.Slice, *types.Named, *types.Pointer:
+	case *types.Slice, *types.Map, *types.Named, *types.Pointer:
 		g.Printf("%s = NULL;\n", varName)
 	}
 	g.Outdent()
@@ -907,6 +1422,14 @@ func (g *JavaGen) genJavaToC(varName string, t types.Type, mode varMode) {
 			g.Printf("%s _%s = (%s)%s;\n", g.cgoType(t), varName, g.cgoType(t), varName)
 		}
 	case *types.Slice:
+		if isByteSliceSlice(t) {
+			g.Printf("nbytesliceslice _%s = go_seq_from_java_bytelist(env, %s);\n", varName, varName)
+			break
+		}
+		if isStringSlice(t) {
+			g.Printf("nstringslice _%s = go_seq_from_java_stringlist(env, %s);\n", varName, varName)
+			break
+		}
 		switch e := t.Elem().(type) {
 		case *types.Basic:
 			switch e.Kind() {
@@ -918,6 +1441,12 @@ func (g *JavaGen) genJavaToC(varName string, t types.Type, mode varMode) {
 		default:
 			g.errorf("unsupported type: %s", t)
 		}
+	case *types.Map:
+		if !isStringMap(t) {
+			g.errorf("unsupported map type: %s", t)
+			break
+		}
+		g.Printf("nstringmap _%s = go_seq_from_java_stringmap(env, %s);\n", varName, varName)
 	case *types.Named:
 		switch u := t.Underlying().(type) {
 		case *types.Interface:
@@ -944,17 +1473,35 @@ func (g *JavaGen) genCToJava(toName, fromName string, t types.Type, mode varMode
 			g.Printf("%s %s = (%s)%s;\n", g.jniType(t), toName, g.jniType(t), fromName)
 		}
 	case *types.Slice:
+		if isByteSliceSlice(t) {
+			g.Printf("jobject %s = go_seq_to_java_bytelist(env, %s);\n", toName, fromName)
+			break
+		}
+		if isStringSlice(t) {
+			g.Printf("jobject %s = go_seq_to_java_stringlist(env, %s);\n", toName, fromName)
+			break
+		}
 		switch e := t.Elem().(type) {
 		case *types.Basic:
 			switch e.Kind() {
 			case types.Uint8: // Byte.
-				g.Printf("jbyteArray %s = go_seq_to_java_bytearray(env, %s, %d);\n", toName, fromName, toCFlag(mode == modeRetained))
+				if mode == modePinned {
+					g.Printf("jbyteArray %s = go_seq_to_java_bytearray_nocopy(env, %s);\n", toName, fromName)
+				} else {
+					g.Printf("jbyteArray %s = go_seq_to_java_bytearray(env, %s, %d);\n", toName, fromName, toCFlag(mode == modeRetained))
+				}
 			default:
 				g.errorf("unsupported type: %s", t)
 			}
 		default:
 			g.errorf("unsupported type: %s", t)
 		}
+	case *types.Map:
+		if !isStringMap(t) {
+			g.errorf("unsupported map type: %s", t)
+			break
+		}
+		g.Printf("jobject %s = go_seq_to_java_stringmap(env, %s);\n", toName, fromName)
 	case *types.Pointer:
 		// TODO(crawshaw): test *int
 		// TODO(crawshaw): test **Generator
@@ -984,6 +1531,12 @@ func (g *JavaGen) genFromRefnum(toName, fromName string, t types.Type, o *types.
 		return
 	}
 	p := pkgPrefix(oPkg)
+	if isErrorType(o.Type()) {
+		// Pick the exception class generated for the dynamic error
+		// type, if any, falling back to the generic error proxy.
+		g.Printf("jobject %s = go_seq_wrap_error(env, %s, proxy_class_%s_%s, proxy_class_%s_%s_cons);\n", toName, fromName, p, o.Name(), p, o.Name())
+		return
+	}
 	g.Printf("jobject %s = go_seq_from_refnum(env, %s, ", toName, fromName)
 	if isJava {
 		g.Printf("NULL, NULL")
@@ -1076,6 +1629,22 @@ func (g *JavaGen) genConst(o *types.Const) {
 }
 
 func (g *JavaGen) genJNIField(o *types.TypeName, f *types.Var) {
+	if nt, ok := embeddedBoundStruct(f); ok && g.validPkg(nt.Obj().Pkg()) {
+		// Getter only; embedded structs are held by value.
+		pt := types.NewPointer(f.Type())
+		n := java.JNIMangle(g.javaTypeName(o.Name()))
+		g.Printf("JNIEXPORT %s JNICALL\n", g.jniType(pt))
+		g.Printf("Java_%s_%s_get%s(JNIEnv *env, jobject this) {\n", g.jniPkgName(), n, java.JNIMangle(f.Name()))
+		g.Indent()
+		g.Printf("int32_t o = go_seq_to_refnum_go(env, this);\n")
+		g.Printf("%s r0 = ", g.cgoType(pt))
+		g.Printf("proxy%s_%s_%s_Get(o);\n", g.pkgPrefix, o.Name(), f.Name())
+		g.genCToJava("_r0", "r0", pt, modeRetained)
+		g.Printf("return _r0;\n")
+		g.Outdent()
+		g.Printf("}\n\n")
+		return
+	}
 	if t := f.Type(); !g.isSupported(t) {
 		g.Printf("// skipped field %s with unsupported type: %s\n\n", o.Name(), t)
 		return
@@ -1150,6 +1719,7 @@ func (g *JavaGen) genJNIConstructor(f *types.Func, sName string) {
 	}
 	g.Printf(") {\n")
 	g.Indent()
+	g.genNullArgChecks(params, 0, false)
 	for i := 0; i < params.Len(); i++ {
 		name := g.paramName(params, i)
 		g.genJavaToC(name, params.At(i).Type(), modeTransient)
@@ -1174,7 +1744,7 @@ func (g *JavaGen) genJNIConstructor(f *types.Func, sName string) {
 	if res.Len() == 2 {
 		g.Printf("int32_t refnum = res.r0;\n")
 		g.genCToJava("_err", "res.r1", res.At(1).Type(), modeRetained)
-		g.Printf("go_seq_maybe_throw_exception(env, _err);\n")
+		g.Printf("%s(env, _err);\n", g.throwFunc())
 	}
 	g.Printf("return refnum;\n")
 	g.Outdent()
@@ -1199,11 +1769,44 @@ func (g *JavaGen) genJNIFunc(o *types.Func, sName string, jm *java.Func, proxy,
 	g.Printf("}\n\n")
 }
 
+// genNullArgChecks rejects null strings and slices passed from Java
+// when the RejectNulls policy is in effect, throwing a
+// NullPointerException before any conversion takes place. voidRet
+// reports whether the JNI function returns void.
+func (g *JavaGen) genNullArgChecks(params *types.Tuple, first int, voidRet bool) {
+	if !g.RejectNulls {
+		return
+	}
+	for i := first; i < params.Len(); i++ {
+		if !isNullSubstituted(params.At(i).Type()) {
+			continue
+		}
+		name := g.paramName(params, i)
+		g.Printf("if (%s == NULL) {\n", name)
+		g.Indent()
+		g.Printf("go_seq_throw_null(env, %q);\n", name)
+		if voidRet {
+			g.Printf("return;\n")
+		} else {
+			g.Printf("return 0;\n")
+		}
+		g.Outdent()
+		g.Printf("}\n")
+	}
+}
+
 func (g *JavaGen) genJNIFuncBody(o *types.Func, sName string, jm *java.Func, isjava bool) {
 	sig := o.Type().(*types.Signature)
 	res := sig.Results()
 	if sName != "" {
-		g.Printf("int32_t o = go_seq_to_refnum_go(env, __this__);\n")
+		if g.isFastpath(o) {
+			if isjava {
+				g.errorf("gomobile:fastpath on %s.%s: not supported for methods of Java-extended types", sName, o.Name())
+			}
+			g.Printf("int32_t o = go_seq_peek_refnum_go(env, __this__, proxy_class_%s_%s_refnum);\n", g.pkgPrefix, sName)
+		} else {
+			g.Printf("int32_t o = go_seq_to_refnum_go(env, __this__);\n")
+		}
 	}
 	params := sig.Params()
 	first := 0
@@ -1212,6 +1815,8 @@ func (g *JavaGen) genJNIFuncBody(o *types.Func, sName string, jm *java.Func, isj
 		first = 1
 		g.Printf("int32_t _%s = go_seq_to_refnum(env, __this__);\n", g.paramName(params, 0))
 	}
+	voidRet := res.Len() == 0 || res.Len() == 1 && isErrorType(res.At(0).Type())
+	g.genNullArgChecks(params, first, voidRet)
 	for i := first; i < params.Len(); i++ {
 		name := g.paramName(params, i)
 		g.genJavaToC(name, params.At(i).Type(), modeTransient)
@@ -1240,10 +1845,15 @@ func (g *JavaGen) genJNIFuncBody(o *types.Func, sName string, jm *java.Func, isj
 	for i := first; i < params.Len(); i++ {
 		g.genRelease(g.paramName(params, i), params.At(i).Type(), modeTransient)
 	}
+	noCopy := g.isNoCopy(o)
 	for i := 0; i < res.Len(); i++ {
 		tn := fmt.Sprintf("_r%d", i)
 		t := res.At(i).Type()
-		g.genCToJava(tn, fmt.Sprintf("%sr%d", resPrefix, i), t, modeRetained)
+		mode := modeRetained
+		if noCopy && isByteSlice(t) {
+			mode = modePinned
+		}
+		g.genCToJava(tn, fmt.Sprintf("%sr%d", resPrefix, i), t, mode)
 	}
 	// Go backwards so that any exception is thrown before
 	// the return.
@@ -1252,7 +1862,7 @@ func (g *JavaGen) genJNIFuncBody(o *types.Func, sName string, jm *java.Func, isj
 		if !isErrorType(t) {
 			g.Printf("return _r%d;\n", i)
 		} else {
-			g.Printf("go_seq_maybe_throw_exception(env, _r%d);\n", i)
+			g.Printf("%s(env, _r%d);\n", g.throwFunc(), i)
 		}
 	}
 }
@@ -1333,6 +1943,18 @@ func (g *JavaGen) genMethodInterfaceProxy(oName string, m *types.Func) {
 	g.Printf("}\n\n")
 }
 
+// hasFastpathMethods reports whether any method of the struct carries
+// a //gomobile:fastpath directive, requiring the cached refnum field
+// ID of its proxy class.
+func (g *JavaGen) hasFastpathMethods(s structInfo) bool {
+	for _, m := range exportedMethodSet(types.NewPointer(s.obj.Type())) {
+		if g.isSigSupported(m.Type()) && g.isFastpath(m) {
+			return true
+		}
+	}
+	return false
+}
+
 func (g *JavaGen) GenH() error {
 	pkgPath := ""
 	if g.Pkg != nil {
@@ -1355,6 +1977,9 @@ func (g *JavaGen) GenH() error {
 	for _, s := range g.structs {
 		g.Printf("extern jclass proxy_class_%s_%s;\n", g.pkgPrefix, s.obj.Name())
 		g.Printf("extern jmethodID proxy_class_%s_%s_cons;\n", g.pkgPrefix, s.obj.Name())
+		if g.hasFastpathMethods(s) {
+			g.Printf("extern jfieldID proxy_class_%s_%s_refnum;\n", g.pkgPrefix, s.obj.Name())
+		}
 	}
 	g.Printf("#endif\n")
 	if len(g.err) > 0 {
@@ -1439,7 +2064,12 @@ func (g *JavaGen) jniSigType(T types.Type) string {
 			return "TODO"
 		}
 	case *types.Slice:
+		if isByteSliceSlice(T) || isStringSlice(T) {
+			return "Ljava/util/List;"
+		}
 		return "[" + g.jniSigType(T.Elem())
+	case *types.Map:
+		return "Ljava/util/Map;"
 	case *types.Pointer:
 		if _, ok := T.Elem().(*types.Named); ok {
 			return g.jniSigType(T.Elem())
@@ -1486,6 +2116,9 @@ func (g *JavaGen) GenC() error {
 	for _, s := range g.structs {
 		g.Printf("jclass proxy_class_%s_%s;\n", g.pkgPrefix, s.obj.Name())
 		g.Printf("jmethodID proxy_class_%s_%s_cons;\n", g.pkgPrefix, s.obj.Name())
+		if g.hasFastpathMethods(s) {
+			g.Printf("jfieldID proxy_class_%s_%s_refnum;\n", g.pkgPrefix, s.obj.Name())
+		}
 	}
 	g.Printf("\n")
 	g.Printf("JNIEXPORT void JNICALL\n")
@@ -1503,6 +2136,15 @@ func (g *JavaGen) GenC() error {
 		g.Printf("clazz = (*env)->FindClass(env, %q);\n", g.jniClassSigPrefix(s.obj.Pkg())+g.javaTypeName(s.obj.Name()))
 		g.Printf("proxy_class_%s_%s = (*env)->NewGlobalRef(env, clazz);\n", g.pkgPrefix, s.obj.Name())
 		g.Printf("proxy_class_%s_%s_cons = (*env)->GetMethodID(env, clazz, \"<init>\", \"(I)V\");\n", g.pkgPrefix, s.obj.Name())
+		if g.hasFastpathMethods(s) {
+			g.Printf("proxy_class_%s_%s_refnum = (*env)->GetFieldID(env, clazz, \"refnum\", \"I\");\n", g.pkgPrefix, s.obj.Name())
+		}
+		if _, ok := g.jstructs[s.obj]; !ok && implementsError(s.obj.Type()) {
+			// Register the exception class so errors of this type are
+			// thrown as it, keyed by the name seq.ErrorTypeName returns.
+			name := path.Base(s.obj.Pkg().Path()) + "." + s.obj.Name()
+			g.Printf("go_seq_register_error_class(%q, proxy_class_%s_%s, proxy_class_%s_%s_cons);\n", name, g.pkgPrefix, s.obj.Name(), g.pkgPrefix, s.obj.Name())
+		}
 	}
 	for _, iface := range g.interfaces {
 		pkg := iface.obj.Pkg()
@@ -1544,6 +2186,9 @@ func (g *JavaGen) GenC() error {
 	g.Printf("}\n\n")
 	for _, f := range g.funcs {
 		g.genJNIFunc(f, "", nil, false, false)
+		if g.isSigSupported(f.Type()) && hasByteSliceParams(f) {
+			g.genJNIDirectFunc(f, "")
+		}
 	}
 	for _, s := range g.structs {
 		sName := s.obj.Name()
@@ -1567,6 +2212,9 @@ func (g *JavaGen) GenC() error {
 				jm = jinf.lookupMethod(m, g.hasThis(s.obj.Name(), m))
 			}
 			g.genJNIFunc(m, sName, jm, false, jinf != nil)
+			if jinf == nil && g.isSigSupported(m.Type()) && hasByteSliceParams(m) {
+				g.genJNIDirectFunc(m, sName)
+			}
 		}
 		for _, f := range exportedFields(s.t) {
 			g.genJNIField(s.obj, f)
@@ -1594,6 +2242,7 @@ func (g *JavaGen) GenJava() error {
 	}
 	g.Printf(javaPreamble, g.javaPkgName(g.Pkg), g.className(), g.gobindOpts(), pkgPath)
 
+	g.keepAnnotation()
 	g.Printf("public abstract class %s {\n", g.className())
 	g.Indent()
 	g.Printf("static {\n")
@@ -1616,14 +2265,25 @@ func (g *JavaGen) GenJava() error {
 
 	for _, iface := range g.interfaces {
 		n := iface.obj.Name()
+		closeClash, closeCompat := g.closeClash(iface.summary.callable)
+		closer := !closeClash
+		impls := "Seq.Proxy"
+		if !closeClash || closeCompat {
+			impls += ", AutoCloseable"
+		}
+		g.keepAnnotation()
 		g.Printf("private static final class proxy%s", n)
 		if isErrorType(iface.obj.Type()) {
-			g.Printf(" extends Exception")
+			g.Printf(" extends %s", g.exceptionSuper())
 		}
-		g.Printf(" implements Seq.Proxy, %s {\n", g.javaTypeName(n))
+		g.Printf(" implements %s, %s {\n", impls, g.javaTypeName(n))
 		g.Indent()
-		g.genProxyImpl("proxy" + n)
-		g.Printf("proxy%s(int refnum) { this.refnum = refnum; Seq.trackGoRef(refnum, this); }\n\n", n)
+		g.genProxyImpl("proxy"+n, closer)
+		track := "Seq.trackGoRef(refnum, this);"
+		if closer {
+			track = "goRef = " + track
+		}
+		g.Printf("proxy%s(int refnum) { this.refnum = refnum; %s }\n\n", n, track)
 
 		if isErrorType(iface.obj.Type()) {
 			g.Printf("@Override public String getMessage() { return error(); }\n\n")
@@ -1658,6 +2318,10 @@ func (g *JavaGen) GenJava() error {
 		g.javadoc(g.docs[f.Name()].Doc())
 		g.Printf("public static native ")
 		g.genFuncSignature(f, nil, false)
+		g.genFuncDefaults(f)
+		if hasByteSliceParams(f) {
+			g.genByteBufferOverload(f, true)
+		}
 	}
 
 	g.Outdent()
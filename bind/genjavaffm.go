@@ -0,0 +1,632 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bind
+
+import (
+	"fmt"
+	"go/types"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// FfmGen generates the desktop Java bindings selected with
+// -lang=java-ffm: a single class using the java.lang.foreign FFM API
+// (Java 22+) with downcall MethodHandles against the c-shared library,
+// instead of the JNI bridge the Android backend uses. Proxies hold
+// refnums, Go errors are thrown as GoException, and strings and byte
+// slices are copied across the bridge with malloc'd buffers the Go
+// side frees. Implementing Go interfaces from Java is not supported
+// with this backend.
+type FfmGen struct {
+	*Generator
+}
+
+// GenJavaFfm generates the Java source for the package.
+func (g *FfmGen) GenJavaFfm() error {
+	pkgName := g.ffmPkgName()
+	className := g.ffmClassName()
+	var pkgPath string
+	if g.Pkg != nil {
+		pkgPath = g.Pkg.Path()
+	}
+	g.Printf(gobindPreamble)
+	g.Printf("// Java FFM bindings for the Go package %s.\n\n", pkgPath)
+	g.Printf("package %s;\n\n", pkgName)
+	g.Printf("import java.lang.foreign.*;\n")
+	g.Printf("import java.lang.invoke.MethodHandle;\n\n")
+	g.Printf("public final class %s {\n", className)
+	g.Indent()
+	g.Printf("private %s() {}\n\n", className)
+	g.Printf("static {\n")
+	g.Printf("	System.loadLibrary(%q);\n", pkgName)
+	g.Printf("}\n\n")
+	g.Printf("%s", ffmSupport)
+
+	// Downcall handles.
+	for _, f := range g.funcs {
+		if !g.isFfmSigSupported(f.Type()) {
+			continue
+		}
+		g.genFfmHandle(fmt.Sprintf("proxy%s__%s", g.pkgPrefix, f.Name()), f.Type().(*types.Signature), false)
+	}
+	for _, s := range g.structs {
+		n := s.obj.Name()
+		g.Printf("private static final MethodHandle h_new_%s_%s = downcall(\"new_%s_%s\", FunctionDescriptor.of(ValueLayout.JAVA_INT));\n",
+			g.pkgPrefix, n, g.pkgPrefix, n)
+		for _, f := range exportedFields(s.t) {
+			t, ok := g.ffmType(f.Type())
+			if !ok {
+				continue
+			}
+			g.Printf("private static final MethodHandle h_proxy%s_%s_%s_Get = downcall(\"proxy%s_%s_%s_Get\", FunctionDescriptor.of(%s, ValueLayout.JAVA_INT));\n",
+				g.pkgPrefix, n, f.Name(), g.pkgPrefix, n, f.Name(), t.layout)
+			g.Printf("private static final MethodHandle h_proxy%s_%s_%s_Set = downcall(\"proxy%s_%s_%s_Set\", FunctionDescriptor.ofVoid(ValueLayout.JAVA_INT, %s));\n",
+				g.pkgPrefix, n, f.Name(), g.pkgPrefix, n, f.Name(), t.layout)
+		}
+		for _, m := range exportedMethodSet(types.NewPointer(s.obj.Type())) {
+			if !g.isFfmSigSupported(m.Type()) {
+				continue
+			}
+			g.genFfmHandle(fmt.Sprintf("proxy%s_%s_%s", g.pkgPrefix, n, m.Name()), m.Type().(*types.Signature), true)
+		}
+	}
+	g.Printf("\n")
+
+	for _, c := range g.constants {
+		if _, ok := c.Type().(*types.Basic); !ok {
+			continue
+		}
+		g.ffmdoc(g.docs[c.Name()].Doc())
+		g.Printf("public static final %s %s = %s;\n\n", g.ffmConstType(c.Type()), javaNameReplacer(lowerFirst(c.Name())), c.Val().ExactString())
+	}
+
+	for _, f := range g.funcs {
+		if !g.isFfmSigSupported(f.Type()) {
+			g.Printf("// skipped function %s with unsupported parameter or result types\n\n", f.Name())
+			continue
+		}
+		symbol := fmt.Sprintf("proxy%s__%s", g.pkgPrefix, f.Name())
+		g.genFfmFunc(f, javaNameReplacer(lowerFirst(f.Name())), g.docs[f.Name()].Doc(), symbol, "")
+	}
+
+	for _, s := range g.structs {
+		g.genFfmClass(s)
+	}
+
+	g.Outdent()
+	g.Printf("}\n")
+	if len(g.err) > 0 {
+		return g.err
+	}
+	return nil
+}
+
+// genFfmHandle emits the return-struct layout, when the signature
+// needs one, and the downcall MethodHandle for symbol.
+func (g *FfmGen) genFfmHandle(symbol string, sig *types.Signature, method bool) {
+	params := sig.Params()
+	res := sig.Results()
+	var args []string
+	if method {
+		args = append(args, "ValueLayout.JAVA_INT")
+	}
+	for i := 0; i < params.Len(); i++ {
+		t, _ := g.ffmType(params.At(i).Type())
+		args = append(args, t.layout)
+	}
+	desc := ""
+	switch res.Len() {
+	case 0:
+		desc = "FunctionDescriptor.ofVoid(" + strings.Join(args, ", ") + ")"
+	case 1:
+		t, _ := g.ffmType(res.At(0).Type())
+		desc = "FunctionDescriptor.of(" + strings.Join(append([]string{t.layout}, args...), ", ") + ")"
+	case 2:
+		g.genFfmRetLayout(symbol, res)
+		desc = fmt.Sprintf("FunctionDescriptor.of(ret_%s, %s)", symbol, strings.Join(args, ", "))
+	}
+	g.Printf("private static final MethodHandle h_%s = downcall(%q, %s);\n", symbol, symbol, desc)
+}
+
+// genFfmRetLayout emits the StructLayout matching the C struct cgo
+// generates for the two results of symbol, including the padding
+// needed for natural alignment.
+func (g *FfmGen) genFfmRetLayout(symbol string, res *types.Tuple) {
+	t0, _ := g.ffmType(res.At(0).Type())
+	t1, _ := g.ffmType(res.At(1).Type())
+	fields := []string{t0.layout + ".withName(\"r0\")"}
+	off := t0.size
+	if pad := (t1.align - off%t1.align) % t1.align; pad > 0 {
+		fields = append(fields, fmt.Sprintf("MemoryLayout.paddingLayout(%d)", pad))
+		off += pad
+	}
+	fields = append(fields, t1.layout+".withName(\"r1\")")
+	off += t1.size
+	align := t0.align
+	if t1.align > align {
+		align = t1.align
+	}
+	if pad := (align - off%align) % align; pad > 0 {
+		fields = append(fields, fmt.Sprintf("MemoryLayout.paddingLayout(%d)", pad))
+	}
+	g.Printf("private static final StructLayout ret_%s = MemoryLayout.structLayout(%s);\n", symbol, strings.Join(fields, ", "))
+}
+
+func (g *FfmGen) genFfmClass(s structInfo) {
+	n := s.obj.Name()
+	doc := g.docs[n]
+	g.ffmdoc(typeDoc(doc))
+	g.Printf("public static final class %s {\n", n)
+	g.Indent()
+	g.Printf("final int refnum;\n\n")
+	g.Printf("public %s() {\n", n)
+	g.Printf("	this.refnum = invokeInt(h_new_%s_%s);\n", g.pkgPrefix, n)
+	g.Printf("	destroyOnCollect(this, this.refnum);\n")
+	g.Printf("}\n\n")
+	g.Printf("%s(int refnum) {\n", n)
+	g.Printf("	this.refnum = refnum;\n")
+	g.Printf("	destroyOnCollect(this, refnum);\n")
+	g.Printf("}\n\n")
+	for _, f := range exportedFields(s.t) {
+		t, ok := g.ffmType(f.Type())
+		if !ok {
+			g.Printf("// skipped field %s with unsupported type %s\n\n", f.Name(), f.Type())
+			continue
+		}
+		getH := fmt.Sprintf("h_proxy%s_%s_%s_Get", g.pkgPrefix, n, f.Name())
+		setH := fmt.Sprintf("h_proxy%s_%s_%s_Set", g.pkgPrefix, n, f.Name())
+		name := javaNameReplacer(lowerFirst(f.Name()))
+		g.ffmdoc(doc.Member(f.Name()))
+		g.Printf("public %s get%s() {\n", t.java, f.Name())
+		g.Indent()
+		g.genFfmCall(t, getH, []string{"incref(refnum)"}, false)
+		g.Outdent()
+		g.Printf("}\n\n")
+		g.Printf("public void set%s(%s %s) {\n", f.Name(), t.java, name)
+		g.Indent()
+		g.genFfmVoidCall(setH, []string{"incref(refnum)", fmt.Sprintf(t.toFfm, name)})
+		g.Outdent()
+		g.Printf("}\n\n")
+	}
+	for _, m := range exportedMethodSet(types.NewPointer(s.obj.Type())) {
+		if !g.isFfmSigSupported(m.Type()) {
+			g.Printf("// skipped method %s.%s with unsupported parameter or result types\n\n", n, m.Name())
+			continue
+		}
+		symbol := fmt.Sprintf("proxy%s_%s_%s", g.pkgPrefix, n, m.Name())
+		recvArg := "incref(refnum)"
+		if g.isFastpath(m) {
+			// Fastpath methods peek at the receiver without
+			// consuming a reference.
+			recvArg = "refnum"
+		}
+		g.genFfmFunc(m, javaNameReplacer(lowerFirst(m.Name())), doc.Member(m.Name()), symbol, recvArg)
+	}
+	g.Outdent()
+	g.Printf("}\n\n")
+}
+
+// genFfmCall emits a return statement invoking handle with args and
+// converting the result; the invocation runs inside a confined arena
+// when the signature moves strings or byte slices.
+func (g *FfmGen) genFfmCall(t ffmTypeInfo, handle string, args []string, _ bool) {
+	expr := fmt.Sprintf("(%s) invoke(%s%s)", t.carrier, handle, argList(args))
+	g.Printf("return %s;\n", fmt.Sprintf(t.fromFfm, expr))
+}
+
+func (g *FfmGen) genFfmVoidCall(handle string, args []string) {
+	g.Printf("invokeVoid(%s%s);\n", handle, argList(args))
+}
+
+func argList(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	return ", " + strings.Join(args, ", ")
+}
+
+func (g *FfmGen) genFfmFunc(f *types.Func, name, doc, symbol string, recvArg string) {
+	sig := f.Type().(*types.Signature)
+	params := sig.Params()
+	res := sig.Results()
+	var decl, args []string
+	if recvArg != "" {
+		args = append(args, recvArg)
+	}
+	for i := 0; i < params.Len(); i++ {
+		t, _ := g.ffmType(params.At(i).Type())
+		pn := javaNameReplacer(basicParamName(params, i))
+		decl = append(decl, fmt.Sprintf("%s %s", t.java, pn))
+		args = append(args, fmt.Sprintf(t.toFfm, pn))
+	}
+	retIdx := -1
+	throws := false
+	switch res.Len() {
+	case 1:
+		if isErrorType(res.At(0).Type()) {
+			throws = true
+		} else {
+			retIdx = 0
+		}
+	case 2:
+		retIdx = 0
+		throws = true
+	}
+	ret := "void"
+	var retType ffmTypeInfo
+	if retIdx == 0 {
+		retType, _ = g.ffmType(res.At(0).Type())
+		if g.isNoCopy(f) && isByteSlice(res.At(0).Type()) {
+			// //gobind:nocopy results lend Go's backing array;
+			// release the pin instead of freeing.
+			retType.fromFfm = "fromNByteSliceNoCopy(%s)"
+		}
+		ret = retType.java
+	}
+	g.ffmdoc(doc)
+	static := "static "
+	if recvArg != "" {
+		static = ""
+	}
+	if throws {
+		g.Printf("public %s%s %s(%s) throws GoException {\n", static, ret, name, strings.Join(decl, ", "))
+	} else {
+		g.Printf("public %s%s %s(%s) {\n", static, ret, name, strings.Join(decl, ", "))
+	}
+	g.Indent()
+	switch {
+	case throws && retIdx == 0:
+		g.Printf("MemorySegment r = (MemorySegment) invokeRet(h_%s%s);\n", symbol, argList(args))
+		g.Printf("int errRef = r.get(ValueLayout.JAVA_INT, ret_%s.byteOffset(MemoryLayout.PathElement.groupElement(\"r1\")));\n", symbol)
+		g.Printf("if (errRef != NULL_REFNUM) {\n")
+		g.Printf("	throw new GoException(errRef);\n")
+		g.Printf("}\n")
+		off := fmt.Sprintf("ret_%s.byteOffset(MemoryLayout.PathElement.groupElement(\"r0\"))", symbol)
+		var expr string
+		if retType.carrier == "MemorySegment" {
+			expr = fmt.Sprintf("r.asSlice(%s, %d)", off, retType.size)
+		} else {
+			expr = fmt.Sprintf("r.get(%s, %s)", retType.layout, off)
+		}
+		g.Printf("%s;\n", "return "+fmt.Sprintf(retType.fromFfm, expr))
+	case throws:
+		g.Printf("int errRef = (int) invoke(h_%s%s);\n", symbol, argList(args))
+		g.Printf("if (errRef != NULL_REFNUM) {\n")
+		g.Printf("	throw new GoException(errRef);\n")
+		g.Printf("}\n")
+	case retIdx == 0:
+		g.genFfmCall(retType, "h_"+symbol, args, false)
+	default:
+		g.genFfmVoidCall("h_"+symbol, args)
+	}
+	g.Outdent()
+	g.Printf("}\n\n")
+}
+
+func (g *FfmGen) ffmPkgName() string {
+	if g.Pkg == nil {
+		return "universe"
+	}
+	return g.Pkg.Name()
+}
+
+func (g *FfmGen) ffmClassName() string {
+	n := g.ffmPkgName()
+	firstRune, size := utf8.DecodeRuneInString(n)
+	return string(unicode.ToUpper(firstRune)) + n[size:]
+}
+
+func (g *FfmGen) ffmdoc(doc string) {
+	if doc == "" {
+		return
+	}
+	g.Printf("/** %s */\n", strings.TrimSpace(strings.ReplaceAll(doc, "\n", " ")))
+}
+
+func (g *FfmGen) ffmConstType(t types.Type) string {
+	b, ok := t.(*types.Basic)
+	if !ok {
+		return ""
+	}
+	switch b.Kind() {
+	case types.Bool, types.UntypedBool:
+		return "boolean"
+	case types.String, types.UntypedString:
+		return "String"
+	case types.Float32:
+		return "float"
+	case types.Float64, types.UntypedFloat:
+		return "double"
+	default:
+		return "long"
+	}
+}
+
+// ffmTypeInfo describes how a Go type crosses the FFM bridge: the
+// ValueLayout (or StructLayout expression), its size and alignment,
+// the Java carrier type of the MethodHandle, the public Java type and
+// the conversions between them (with %s substituted by the value).
+type ffmTypeInfo struct {
+	layout  string
+	size    int
+	align   int
+	carrier string
+	java    string
+	toFfm   string
+	fromFfm string
+}
+
+func (g *FfmGen) ffmType(t types.Type) (ffmTypeInfo, bool) {
+	prim := func(layout string, size int, carrier, java string) (ffmTypeInfo, bool) {
+		toFfm, fromFfm := "%s", "%s"
+		if carrier != java {
+			toFfm = "(" + carrier + ") %s"
+			fromFfm = "(" + java + ") %s"
+		}
+		return ffmTypeInfo{layout: layout, size: size, align: size, carrier: carrier, java: java, toFfm: toFfm, fromFfm: fromFfm}, true
+	}
+	switch t := t.(type) {
+	case *types.Basic:
+		switch t.Kind() {
+		case types.Bool, types.UntypedBool:
+			return ffmTypeInfo{layout: "ValueLayout.JAVA_BYTE", size: 1, align: 1, carrier: "byte", java: "boolean", toFfm: "(byte) (%s ? 1 : 0)", fromFfm: "%s != 0"}, true
+		case types.Int, types.Int64, types.UntypedInt, types.UntypedRune:
+			return prim("ValueLayout.JAVA_LONG", 8, "long", "long")
+		case types.Int8:
+			return prim("ValueLayout.JAVA_BYTE", 1, "byte", "byte")
+		case types.Int16:
+			return prim("ValueLayout.JAVA_SHORT", 2, "short", "short")
+		case types.Int32:
+			return prim("ValueLayout.JAVA_INT", 4, "int", "int")
+		case types.Uint8:
+			return prim("ValueLayout.JAVA_BYTE", 1, "byte", "byte")
+		case types.Float32:
+			return prim("ValueLayout.JAVA_FLOAT", 4, "float", "float")
+		case types.Float64, types.UntypedFloat:
+			return prim("ValueLayout.JAVA_DOUBLE", 8, "double", "double")
+		case types.String, types.UntypedString:
+			return ffmTypeInfo{
+				layout:  "NSTRING",
+				size:    16,
+				align:   8,
+				carrier: "MemorySegment",
+				java:    "String",
+				toFfm:   "toNString(%s)",
+				fromFfm: "fromNString(%s)",
+			}, true
+		}
+	case *types.Slice:
+		if e, ok := t.Elem().(*types.Basic); ok && e.Kind() == types.Uint8 {
+			return ffmTypeInfo{
+				layout:  "NBYTESLICE",
+				size:    16,
+				align:   8,
+				carrier: "MemorySegment",
+				java:    "byte[]",
+				toFfm:   "toNByteSlice(%s)",
+				fromFfm: "fromNByteSlice(%s)",
+			}, true
+		}
+	case *types.Pointer:
+		if n, ok := t.Elem().(*types.Named); ok {
+			return g.ffmNamedType(n)
+		}
+	case *types.Named:
+		if isErrorType(t) {
+			return prim("ValueLayout.JAVA_INT", 4, "int", "int")
+		}
+		return g.ffmNamedType(t)
+	}
+	return ffmTypeInfo{}, false
+}
+
+func (g *FfmGen) ffmNamedType(t *types.Named) (ffmTypeInfo, bool) {
+	n := t.Obj()
+	if n.Pkg() != g.Pkg {
+		return ffmTypeInfo{}, false
+	}
+	if _, ok := t.Underlying().(*types.Interface); ok {
+		// Interfaces are passed as raw refnums; this backend cannot
+		// implement them.
+		return ffmTypeInfo{layout: "ValueLayout.JAVA_INT", size: 4, align: 4, carrier: "int", java: "int", toFfm: "incref(%s)", fromFfm: "%s"}, true
+	}
+	return ffmTypeInfo{
+		layout:  "ValueLayout.JAVA_INT",
+		size:    4,
+		align:   4,
+		carrier: "int",
+		java:    n.Name(),
+		toFfm:   "incref(%s.refnum)",
+		fromFfm: "new " + n.Name() + "(%s)",
+	}, true
+}
+
+// isFfmSigSupported reports whether the FFM generator can handle the
+// signature.
+func (g *FfmGen) isFfmSigSupported(t types.Type) bool {
+	if !g.isSigSupported(t) {
+		return false
+	}
+	sig := t.(*types.Signature)
+	params := sig.Params()
+	for i := 0; i < params.Len(); i++ {
+		if _, ok := g.ffmType(params.At(i).Type()); !ok {
+			return false
+		}
+	}
+	res := sig.Results()
+	for i := 0; i < res.Len(); i++ {
+		if _, ok := g.ffmType(res.At(i).Type()); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+const ffmSupport = `/**
+ * GoException is thrown when a bound Go function returns a non-nil
+ * error. The refnum identifies the Go error value on the Go side of
+ * the bridge.
+ */
+public static final class GoException extends Exception {
+	public final int refnum;
+
+	GoException(int refnum) {
+		super("go error (refnum " + refnum + ")");
+		this.refnum = refnum;
+		destroyOnCollect(this, refnum);
+	}
+}
+
+private static final Linker LINKER = Linker.nativeLinker();
+private static final SymbolLookup LOOKUP = SymbolLookup.loaderLookup().or(LINKER.defaultLookup());
+private static final Arena ARENA = Arena.ofAuto();
+
+// The refnum a nil Go object or error crosses the bridge as.
+private static final int NULL_REFNUM = 41;
+
+private static final MethodHandle h_IncGoRef = downcall("IncGoRef", FunctionDescriptor.ofVoid(ValueLayout.JAVA_INT));
+private static final MethodHandle h_ReleaseByteSliceRef = downcall("ReleaseByteSliceRef", FunctionDescriptor.ofVoid(ValueLayout.ADDRESS));
+private static final MethodHandle h_DestroyRef = downcall("DestroyRef", FunctionDescriptor.ofVoid(ValueLayout.JAVA_INT));
+
+private static final java.lang.ref.Cleaner CLEANER = java.lang.ref.Cleaner.create();
+
+// Releases the Go object when the proxy is collected. Nil objects
+// cross the bridge as the null refnum, which is not tracked.
+private static void destroyOnCollect(Object proxy, int refnum) {
+	if (refnum != NULL_REFNUM) {
+		CLEANER.register(proxy, () -> invokeVoid(h_DestroyRef, refnum));
+	}
+}
+
+// Pins the Go object before its refnum crosses into Go; the Go side
+// consumes one reference per crossing.
+private static int incref(int refnum) {
+	invokeVoid(h_IncGoRef, refnum);
+	return refnum;
+}
+
+private static final StructLayout NSTRING = MemoryLayout.structLayout(
+		ValueLayout.ADDRESS.withName("ptr"),
+		ValueLayout.JAVA_INT.withName("len"),
+		MemoryLayout.paddingLayout(4));
+private static final StructLayout NBYTESLICE = MemoryLayout.structLayout(
+		ValueLayout.ADDRESS.withName("ptr"),
+		ValueLayout.JAVA_INT.withName("len"),
+		MemoryLayout.paddingLayout(4));
+
+private static final MethodHandle MALLOC = LINKER.downcallHandle(
+		LINKER.defaultLookup().find("malloc").get(),
+		FunctionDescriptor.of(ValueLayout.ADDRESS, ValueLayout.JAVA_LONG));
+private static final MethodHandle FREE = LINKER.downcallHandle(
+		LINKER.defaultLookup().find("free").get(),
+		FunctionDescriptor.ofVoid(ValueLayout.ADDRESS));
+
+private static MethodHandle downcall(String symbol, FunctionDescriptor desc) {
+	return LINKER.downcallHandle(LOOKUP.find(symbol).get(), desc);
+}
+
+private static Object invoke(MethodHandle h, Object... args) {
+	try {
+		return h.invokeWithArguments(args);
+	} catch (Throwable t) {
+		throw new AssertionError(t);
+	}
+}
+
+private static void invokeVoid(MethodHandle h, Object... args) {
+	invoke(h, args);
+}
+
+private static int invokeInt(MethodHandle h, Object... args) {
+	return (int) invoke(h, args);
+}
+
+private static Object invokeRet(MethodHandle h, Object... args) {
+	Object[] all = new Object[args.length + 1];
+	all[0] = (SegmentAllocator) ARENA;
+	System.arraycopy(args, 0, all, 1, args.length);
+	return invoke(h, all);
+}
+
+private static MemorySegment mallocSegment(long size) {
+	try {
+		return ((MemorySegment) MALLOC.invoke(size)).reinterpret(size);
+	} catch (Throwable t) {
+		throw new AssertionError(t);
+	}
+}
+
+private static void free(MemorySegment p) {
+	try {
+		FREE.invoke(p);
+	} catch (Throwable t) {
+		throw new AssertionError(t);
+	}
+}
+
+private static MemorySegment toNString(String s) {
+	byte[] b = s.getBytes(java.nio.charset.StandardCharsets.UTF_8);
+	MemorySegment ns = ARENA.allocate(NSTRING);
+	if (b.length > 0) {
+		MemorySegment buf = mallocSegment(b.length);
+		MemorySegment.copy(b, 0, buf, ValueLayout.JAVA_BYTE, 0, b.length);
+		ns.set(ValueLayout.ADDRESS, 0, buf);
+	}
+	ns.set(ValueLayout.JAVA_INT, 8, b.length);
+	return ns;
+}
+
+private static String fromNString(MemorySegment ns) {
+	MemorySegment ptr = ns.get(ValueLayout.ADDRESS, 0);
+	int len = ns.get(ValueLayout.JAVA_INT, 8);
+	if (ptr.equals(MemorySegment.NULL) || len == 0) {
+		return "";
+	}
+	byte[] b = new byte[len];
+	MemorySegment.copy(ptr.reinterpret(len), ValueLayout.JAVA_BYTE, 0, b, 0, len);
+	free(ptr);
+	return new String(b, java.nio.charset.StandardCharsets.UTF_8);
+}
+
+private static MemorySegment toNByteSlice(byte[] b) {
+	MemorySegment bs = ARENA.allocate(NBYTESLICE);
+	if (b.length > 0) {
+		MemorySegment buf = mallocSegment(b.length);
+		MemorySegment.copy(b, 0, buf, ValueLayout.JAVA_BYTE, 0, b.length);
+		bs.set(ValueLayout.ADDRESS, 0, buf);
+	}
+	bs.set(ValueLayout.JAVA_INT, 8, b.length);
+	return bs;
+}
+
+private static byte[] fromNByteSlice(MemorySegment bs) {
+	MemorySegment ptr = bs.get(ValueLayout.ADDRESS, 0);
+	int len = bs.get(ValueLayout.JAVA_INT, 8);
+	if (ptr.equals(MemorySegment.NULL) || len == 0) {
+		return new byte[0];
+	}
+	byte[] b = new byte[len];
+	MemorySegment.copy(ptr.reinterpret(len), ValueLayout.JAVA_BYTE, 0, b, 0, len);
+	free(ptr);
+	return b;
+}
+
+// Results of //gobind:nocopy functions lend Go's backing array;
+// release the pin instead of freeing.
+private static byte[] fromNByteSliceNoCopy(MemorySegment bs) {
+	MemorySegment ptr = bs.get(ValueLayout.ADDRESS, 0);
+	int len = bs.get(ValueLayout.JAVA_INT, 8);
+	if (ptr.equals(MemorySegment.NULL) || len == 0) {
+		return new byte[0];
+	}
+	byte[] b = new byte[len];
+	MemorySegment.copy(ptr.reinterpret(len), ValueLayout.JAVA_BYTE, 0, b, 0, len);
+	invokeVoid(h_ReleaseByteSliceRef, ptr);
+	return b;
+}
+
+`
@@ -0,0 +1,172 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bind
+
+import (
+	"fmt"
+	"go/types"
+	"strings"
+)
+
+const kotlinPreamble = gobindPreamble + `// Kotlin conveniences over the generated Java bindings for %[1]s.%[2]s.
+//
+//   autogenerated by gobind %[3]s %[4]s
+package %[1]s
+
+import kotlinx.coroutines.Dispatchers
+import kotlinx.coroutines.withContext
+
+`
+
+// GenKotlin emits a Kotlin layer over the generated Java bindings,
+// selected with -lang=kotlin: suspend wrappers running error-returning
+// functions and methods on Dispatchers.IO, and data-class snapshots of
+// struct fields for value-style consumption. The Java bindings are
+// generated alongside; Kotlin calls them directly for everything else.
+func (g *JavaGen) GenKotlin() error {
+	pkgPath := ""
+	if g.Pkg != nil {
+		pkgPath = g.Pkg.Path()
+	}
+	g.Printf(kotlinPreamble, g.javaPkgName(g.Pkg), g.className(), g.gobindOpts(), pkgPath)
+
+	for _, f := range g.funcs {
+		if !g.isSigSupported(f.Type()) || !returnsError(f) {
+			continue
+		}
+		g.genKotlinSuspendFunc(f, "", g.className()+".")
+	}
+
+	for _, s := range g.structs {
+		if g.jstructs[s.obj] != nil {
+			continue
+		}
+		n := g.javaTypeName(s.obj.Name())
+		g.genKotlinSnapshot(n, s)
+		for _, m := range exportedMethodSet(types.NewPointer(s.obj.Type())) {
+			if !g.isSigSupported(m.Type()) || !returnsError(m) {
+				continue
+			}
+			g.genKotlinSuspendFunc(m, n+".", "")
+		}
+	}
+	if len(g.err) > 0 {
+		return g.err
+	}
+	return nil
+}
+
+// genKotlinSuspendFunc generates a suspend wrapper for the throwing
+// function o, declared on the extension receiver recv (with a trailing
+// dot) or calling through the static class prefix call.
+func (g *JavaGen) genKotlinSuspendFunc(o *types.Func, recv, call string) {
+	sig := o.Type().(*types.Signature)
+	params := sig.Params()
+	res := sig.Results()
+	ret := "Unit"
+	if res.Len() == 2 {
+		ret = g.kotlinType(res.At(0).Type())
+	}
+	name := javaNameReplacer(lowerFirst(o.Name()))
+	var decl, args []string
+	for i := 0; i < params.Len(); i++ {
+		pn := kotlinName(g.paramName(params, i))
+		decl = append(decl, fmt.Sprintf("%s: %s", pn, g.kotlinType(params.At(i).Type())))
+		args = append(args, pn)
+	}
+	g.Printf("/** Runs %s on Dispatchers.IO, suspending instead of blocking the caller. */\n", name)
+	g.Printf("suspend fun %s%sAwait(%s): %s = withContext(Dispatchers.IO) {\n",
+		recv, kotlinName(name), strings.Join(decl, ", "), ret)
+	g.Printf("	%s%s(%s)\n", call, kotlinName(name), strings.Join(args, ", "))
+	g.Printf("}\n\n")
+}
+
+// genKotlinSnapshot generates a data class holding the exported fields
+// of the struct and an extension copying a proxy into one.
+func (g *JavaGen) genKotlinSnapshot(n string, s structInfo) {
+	type field struct{ name, typ string }
+	var fields []field
+	for _, f := range exportedFields(s.t) {
+		if _, ok := embeddedBoundStruct(f); ok {
+			continue
+		}
+		if !g.isSupported(f.Type()) {
+			continue
+		}
+		fields = append(fields, field{kotlinName(javaNameReplacer(lowerFirst(f.Name()))), g.kotlinType(f.Type())})
+	}
+	if len(fields) == 0 {
+		return
+	}
+	g.Printf("/** Immutable snapshot of the exported fields of a %s. */\n", n)
+	g.Printf("data class %sSnapshot(\n", n)
+	for _, f := range fields {
+		g.Printf("	val %s: %s,\n", f.name, f.typ)
+	}
+	g.Printf(")\n\n")
+	g.Printf("/** Copies the exported fields of this proxy into a snapshot. */\n")
+	g.Printf("fun %s.snapshot(): %sSnapshot = %sSnapshot(\n", n, n, n)
+	for _, f := range fields {
+		g.Printf("	%s = this.%s,\n", f.name, f.name)
+	}
+	g.Printf(")\n\n")
+}
+
+// returnsError reports whether the last result of f is an error.
+func returnsError(f *types.Func) bool {
+	res := f.Type().(*types.Signature).Results()
+	return res.Len() > 0 && isErrorType(res.At(res.Len()-1).Type())
+}
+
+// kotlinType returns the Kotlin spelling of the Java type the bindings
+// use for t.
+func (g *JavaGen) kotlinType(t types.Type) string {
+	switch jt := g.javaType(t); jt {
+	case "boolean":
+		return "Boolean"
+	case "byte":
+		return "Byte"
+	case "short":
+		return "Short"
+	case "int":
+		return "Int"
+	case "long":
+		return "Long"
+	case "float":
+		return "Float"
+	case "double":
+		return "Double"
+	case "byte[]":
+		return "ByteArray"
+	case "byte[][]":
+		return "Array<ByteArray>"
+	case "String[]":
+		return "Array<String>"
+	default:
+		return jt
+	}
+}
+
+// kotlinName escapes Kotlin keywords with backticks.
+func kotlinName(s string) string {
+	if kotlinKeywords[s] {
+		return "`" + s + "`"
+	}
+	return s
+}
+
+var kotlinKeywords = func() map[string]bool {
+	words := []string{
+		"as", "break", "class", "continue", "do", "else", "false",
+		"for", "fun", "if", "in", "interface", "is", "null", "object",
+		"package", "return", "super", "this", "throw", "true", "try",
+		"typealias", "typeof", "val", "var", "when", "while",
+	}
+	m := make(map[string]bool)
+	for _, w := range words {
+		m[w] = true
+	}
+	return m
+}()
@@ -0,0 +1,494 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bind
+
+import (
+	"fmt"
+	"go/types"
+	"strings"
+)
+
+// LuaGen generates the LuaJIT FFI module selected with -lang=lua: a
+// single Lua file that cdefs the C symbols exported by the c-shared
+// library, loads it with ffi.load and wraps structs and interfaces in
+// metatable-based classes holding refnums. Go errors are raised as
+// GoError values carrying the error refnum. The module requires LuaJIT
+// (or a Lua with a compatible ffi library); implementing Go interfaces
+// from Lua is not supported.
+type LuaGen struct {
+	*Generator
+}
+
+// GenLua generates the Lua module source.
+func (g *LuaGen) GenLua() error {
+	pkgName := "universe"
+	pkgPath := ""
+	if g.Pkg != nil {
+		pkgName = g.Pkg.Name()
+		pkgPath = g.Pkg.Path()
+	}
+	g.Printf("-- Code generated by gobind. DO NOT EDIT.\n\n")
+	g.Printf("-- LuaJIT FFI bindings for the Go package %s.\n\n", pkgPath)
+	g.Printf("local ffi = require('ffi')\n\n")
+
+	g.genLuaCdef()
+	g.Printf("local lib = ffi.load(%q)\n", pkgName)
+	g.Printf("local C = ffi.C\n\n")
+	g.Printf("local M = {}\n\n")
+	g.Printf("%s", luaSupport)
+
+	// Predeclare the class tables so methods and functions can refer
+	// to classes defined later in the file.
+	for _, s := range g.structs {
+		g.Printf("local %[1]s = {}\n%[1]s.__index = %[1]s\n", s.obj.Name())
+	}
+	for _, i := range g.interfaces {
+		g.Printf("local %[1]s = {}\n%[1]s.__index = %[1]s\n", i.obj.Name())
+	}
+	if len(g.structs) > 0 || len(g.interfaces) > 0 {
+		g.Printf("\n")
+	}
+
+	for _, c := range g.constants {
+		if _, ok := c.Type().(*types.Basic); !ok {
+			continue
+		}
+		g.luadoc(g.docs[c.Name()].Doc())
+		g.Printf("M.%s = %s\n", strings.ToUpper(pySnake(c.Name())), c.Val().ExactString())
+	}
+	if len(g.constants) > 0 {
+		g.Printf("\n")
+	}
+
+	for _, s := range g.structs {
+		g.genLuaStruct(s)
+	}
+	for _, i := range g.interfaces {
+		g.genLuaInterface(i)
+	}
+
+	for _, v := range g.vars {
+		g.genLuaVar(v)
+	}
+
+	for _, f := range g.funcs {
+		if !g.isLuaSigSupported(f.Type()) {
+			g.Printf("-- skipped function %s with unsupported parameter or result types\n\n", f.Name())
+			continue
+		}
+		symbol := fmt.Sprintf("proxy%s__%s", g.pkgPrefix, f.Name())
+		g.genLuaFunc(f, "M."+pySnake(f.Name()), g.docs[f.Name()].Doc(), symbol, "")
+	}
+
+	g.Printf("return M\n")
+	if len(g.err) > 0 {
+		return g.err
+	}
+	return nil
+}
+
+// genLuaCdef emits the ffi.cdef block declaring the exported symbols.
+func (g *LuaGen) genLuaCdef() {
+	g.Printf("ffi.cdef[[\n")
+	g.Printf("typedef int64_t nint;\n")
+	g.Printf("typedef struct nstring { void *ptr; int len; } nstring;\n")
+	g.Printf("typedef struct nbyteslice { void *ptr; int len; } nbyteslice;\n")
+	g.Printf("void *malloc(size_t size);\n")
+	g.Printf("void free(void *ptr);\n")
+	g.Printf("void DestroyRef(int32_t refnum);\n")
+	g.Printf("void IncGoRef(int32_t refnum);\n")
+	g.Printf("void ReleaseByteSliceRef(void *ptr);\n")
+	for _, f := range g.funcs {
+		if !g.isLuaSigSupported(f.Type()) {
+			continue
+		}
+		g.genLuaCdecl(fmt.Sprintf("proxy%s__%s", g.pkgPrefix, f.Name()), f.Type().(*types.Signature), false)
+	}
+	for _, v := range g.vars {
+		t, ok := g.luaType(v.Type())
+		if !ok {
+			continue
+		}
+		g.Printf("%s var_get%s_%s();\n", t.c, g.pkgPrefix, v.Name())
+		g.Printf("void var_set%s_%s(%s v);\n", g.pkgPrefix, v.Name(), t.c)
+	}
+	for _, s := range g.structs {
+		n := s.obj.Name()
+		g.Printf("int32_t new_%s_%s();\n", g.pkgPrefix, n)
+		for _, f := range exportedFields(s.t) {
+			t, ok := g.luaType(f.Type())
+			if !ok {
+				continue
+			}
+			g.Printf("%s proxy%s_%s_%s_Get(int32_t refnum);\n", t.c, g.pkgPrefix, n, f.Name())
+			g.Printf("void proxy%s_%s_%s_Set(int32_t refnum, %s v);\n", g.pkgPrefix, n, f.Name(), t.c)
+		}
+		for _, m := range exportedMethodSet(types.NewPointer(s.obj.Type())) {
+			if !g.isLuaSigSupported(m.Type()) {
+				continue
+			}
+			g.genLuaCdecl(fmt.Sprintf("proxy%s_%s_%s", g.pkgPrefix, n, m.Name()), m.Type().(*types.Signature), true)
+		}
+	}
+	for _, i := range g.interfaces {
+		for _, m := range makeIfaceSummary(i.t).callable {
+			if !g.isLuaSigSupported(m.Type()) {
+				continue
+			}
+			g.genLuaCdecl(fmt.Sprintf("proxy%s_%s_%s", g.pkgPrefix, i.obj.Name(), m.Name()), m.Type().(*types.Signature), true)
+		}
+	}
+	g.Printf("]]\n\n")
+}
+
+// genLuaCdecl emits the C declaration for symbol, including a return
+// struct typedef when the function also returns an error.
+func (g *LuaGen) genLuaCdecl(symbol string, sig *types.Signature, method bool) {
+	params := sig.Params()
+	res := sig.Results()
+	var args []string
+	if method {
+		args = append(args, "int32_t refnum")
+	}
+	for i := 0; i < params.Len(); i++ {
+		t, _ := g.luaType(params.At(i).Type())
+		args = append(args, fmt.Sprintf("%s %s", t.c, basicParamName(params, i)))
+	}
+	ret := "void"
+	switch res.Len() {
+	case 1:
+		t, _ := g.luaType(res.At(0).Type())
+		ret = t.c
+	case 2:
+		t0, _ := g.luaType(res.At(0).Type())
+		t1, _ := g.luaType(res.At(1).Type())
+		g.Printf("typedef struct ret_%[1]s { %[2]s r0; %[3]s r1; } ret_%[1]s;\n", symbol, t0.c, t1.c)
+		ret = fmt.Sprintf("ret_%s", symbol)
+	}
+	g.Printf("%s %s(%s);\n", ret, symbol, strings.Join(args, ", "))
+}
+
+func (g *LuaGen) genLuaVar(o *types.Var) {
+	t, ok := g.luaType(o.Type())
+	if !ok {
+		g.Printf("-- skipped variable %s with unsupported type %s\n\n", o.Name(), o.Type())
+		return
+	}
+	g.luadoc(g.docs[o.Name()].Doc())
+	g.Printf("function M.%s()\n", pySnake(o.Name()))
+	g.Printf("	return %s\n", fmt.Sprintf(t.fromFfi, fmt.Sprintf("lib.var_get%s_%s()", g.pkgPrefix, o.Name())))
+	g.Printf("end\n\n")
+	g.Printf("function M.set_%s(v)\n", pySnake(o.Name()))
+	g.Printf("	lib.var_set%s_%s(%s)\n", g.pkgPrefix, o.Name(), fmt.Sprintf(t.toFfi, "v"))
+	g.Printf("end\n\n")
+}
+
+func (g *LuaGen) genLuaStruct(s structInfo) {
+	n := s.obj.Name()
+	doc := g.docs[n]
+	g.luadoc(typeDoc(doc))
+	g.Printf("function %s.new()\n", n)
+	g.Printf("	return setmetatable({refnum = lib.new_%s_%s()}, %s)\n", g.pkgPrefix, n, n)
+	g.Printf("end\n\n")
+	g.genLuaDestroy(n)
+	for _, f := range exportedFields(s.t) {
+		t, ok := g.luaType(f.Type())
+		if !ok {
+			g.Printf("-- skipped field %s with unsupported type %s\n\n", f.Name(), f.Type())
+			continue
+		}
+		g.luadoc(doc.Member(f.Name()))
+		g.Printf("function %s:%s()\n", n, pySnake(f.Name()))
+		g.Printf("	return %s\n", fmt.Sprintf(t.fromFfi, fmt.Sprintf("lib.proxy%s_%s_%s_Get(incref(self.refnum))", g.pkgPrefix, n, f.Name())))
+		g.Printf("end\n\n")
+		g.Printf("function %s:set_%s(v)\n", n, pySnake(f.Name()))
+		g.Printf("	lib.proxy%s_%s_%s_Set(incref(self.refnum), %s)\n", g.pkgPrefix, n, f.Name(), fmt.Sprintf(t.toFfi, "v"))
+		g.Printf("end\n\n")
+	}
+	for _, m := range exportedMethodSet(types.NewPointer(s.obj.Type())) {
+		if !g.isLuaSigSupported(m.Type()) {
+			g.Printf("-- skipped method %s.%s with unsupported parameter or result types\n\n", n, m.Name())
+			continue
+		}
+		symbol := fmt.Sprintf("proxy%s_%s_%s", g.pkgPrefix, n, m.Name())
+		g.genLuaFunc(m, fmt.Sprintf("%s:%s", n, pySnake(m.Name())), doc.Member(m.Name()), symbol, g.luaRecv(m))
+	}
+	g.Printf("M.%[1]s = %[1]s\n\n", n)
+}
+
+func (g *LuaGen) genLuaInterface(i interfaceInfo) {
+	n := i.obj.Name()
+	doc := g.docs[n]
+	g.luadoc(typeDoc(doc))
+	g.Printf("-- Proxy for a Go value implementing the interface; it cannot\n")
+	g.Printf("-- be implemented from Lua.\n")
+	g.genLuaDestroy(n)
+	for _, m := range makeIfaceSummary(i.t).callable {
+		if !g.isLuaSigSupported(m.Type()) {
+			g.Printf("-- skipped method %s.%s with unsupported parameter or result types\n\n", n, m.Name())
+			continue
+		}
+		symbol := fmt.Sprintf("proxy%s_%s_%s", g.pkgPrefix, n, m.Name())
+		g.genLuaFunc(m, fmt.Sprintf("%s:%s", n, pySnake(m.Name())), doc.Member(m.Name()), symbol, g.luaRecv(m))
+	}
+	g.Printf("M.%[1]s = %[1]s\n\n", n)
+}
+
+// luaRecv returns the receiver refnum expression for m. Fastpath
+// methods peek at the receiver without consuming a reference.
+func (g *LuaGen) luaRecv(m *types.Func) string {
+	if g.isFastpath(m) {
+		return "self.refnum"
+	}
+	return "incref(self.refnum)"
+}
+
+func (g *LuaGen) genLuaDestroy(n string) {
+	g.Printf("-- Releases the Go object backing this proxy.\n")
+	g.Printf("function %s:destroy()\n", n)
+	g.Printf("	lib.DestroyRef(self.refnum)\n")
+	g.Printf("end\n\n")
+}
+
+// genLuaFunc emits the wrapper for symbol; recv is the receiver refnum
+// expression, or empty for package functions.
+func (g *LuaGen) genLuaFunc(f *types.Func, name, doc, symbol, recv string) {
+	sig := f.Type().(*types.Signature)
+	params := sig.Params()
+	res := sig.Results()
+	var decl, args []string
+	if recv != "" {
+		args = append(args, recv)
+	}
+	for i := 0; i < params.Len(); i++ {
+		t, _ := g.luaType(params.At(i).Type())
+		pn := pySnake(basicParamName(params, i))
+		decl = append(decl, pn)
+		args = append(args, fmt.Sprintf(t.toFfi, pn))
+	}
+	retIdx := -1
+	throws := false
+	switch res.Len() {
+	case 1:
+		if isErrorType(res.At(0).Type()) {
+			throws = true
+		} else {
+			retIdx = 0
+		}
+	case 2:
+		retIdx = 0
+		throws = true
+	}
+	g.luadoc(doc)
+	g.Printf("function %s(%s)\n", name, strings.Join(decl, ", "))
+	g.Indent()
+	call := fmt.Sprintf("lib.%s(%s)", symbol, strings.Join(args, ", "))
+	switch {
+	case throws && retIdx == 0:
+		retType := g.luaResultType(f, res.At(0).Type())
+		g.Printf("local r = %s\n", call)
+		g.Printf("if r.r1 ~= NULL_REFNUM then\n")
+		g.Printf("	error(go_error(r.r1))\n")
+		g.Printf("end\n")
+		g.Printf("return %s\n", fmt.Sprintf(retType.fromFfi, "r.r0"))
+	case throws:
+		g.Printf("local r = %s\n", call)
+		g.Printf("if r ~= NULL_REFNUM then\n")
+		g.Printf("	error(go_error(r))\n")
+		g.Printf("end\n")
+	case retIdx == 0:
+		retType := g.luaResultType(f, res.At(0).Type())
+		g.Printf("return %s\n", fmt.Sprintf(retType.fromFfi, call))
+	default:
+		g.Printf("%s\n", call)
+	}
+	g.Outdent()
+	g.Printf("end\n\n")
+}
+
+// luaResultType returns the type info for a result of f; the byte
+// slice results of //gobind:nocopy functions lend Go's backing array
+// and release the pin instead of freeing.
+func (g *LuaGen) luaResultType(f *types.Func, t types.Type) luaTypeInfo {
+	ti, _ := g.luaType(t)
+	if g.isNoCopy(f) && isByteSlice(t) {
+		ti.fromFfi = "from_nbyteslice_nocopy(%s)"
+	}
+	return ti
+}
+
+func (g *LuaGen) luadoc(doc string) {
+	if doc == "" {
+		return
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(doc, "\n"), "\n") {
+		g.Printf("-- %s\n", strings.TrimRight(line, " "))
+	}
+}
+
+// luaTypeInfo describes how a Go type crosses the FFI bridge: the cdef
+// C type and the conversions between Lua values and the FFI
+// representation (with %s substituted by the value).
+type luaTypeInfo struct {
+	c       string
+	toFfi   string
+	fromFfi string
+}
+
+func (g *LuaGen) luaType(t types.Type) (luaTypeInfo, bool) {
+	// LuaJIT boxes 64-bit integers as cdata; tonumber unboxes all
+	// numeric returns to plain Lua numbers.
+	num := func(c string) (luaTypeInfo, bool) {
+		return luaTypeInfo{c: c, toFfi: "%s", fromFfi: "tonumber(%s)"}, true
+	}
+	switch t := t.(type) {
+	case *types.Basic:
+		switch t.Kind() {
+		case types.Bool, types.UntypedBool:
+			return luaTypeInfo{c: "char", toFfi: "(%s and 1 or 0)", fromFfi: "%s ~= 0"}, true
+		case types.Int, types.UntypedInt, types.UntypedRune:
+			return num("nint")
+		case types.Int8:
+			return num("int8_t")
+		case types.Int16:
+			return num("int16_t")
+		case types.Int32:
+			return num("int32_t")
+		case types.Int64:
+			return num("int64_t")
+		case types.Uint8:
+			return num("uint8_t")
+		case types.Float32:
+			return num("float")
+		case types.Float64, types.UntypedFloat:
+			return num("double")
+		case types.String, types.UntypedString:
+			return luaTypeInfo{c: "nstring", toFfi: "to_nstring(%s)", fromFfi: "from_nstring(%s)"}, true
+		}
+	case *types.Slice:
+		if e, ok := t.Elem().(*types.Basic); ok && e.Kind() == types.Uint8 {
+			return luaTypeInfo{c: "nbyteslice", toFfi: "to_nbyteslice(%s)", fromFfi: "from_nbyteslice(%s)"}, true
+		}
+	case *types.Pointer:
+		if n, ok := t.Elem().(*types.Named); ok {
+			return g.luaNamedType(n)
+		}
+	case *types.Named:
+		if isErrorType(t) {
+			return luaTypeInfo{c: "int32_t", toFfi: "%s", fromFfi: "%s"}, true
+		}
+		return g.luaNamedType(t)
+	}
+	return luaTypeInfo{}, false
+}
+
+func (g *LuaGen) luaNamedType(t *types.Named) (luaTypeInfo, bool) {
+	n := t.Obj()
+	if n.Pkg() != g.Pkg {
+		return luaTypeInfo{}, false
+	}
+	return luaTypeInfo{
+		c:       "int32_t",
+		toFfi:   "incref(%s.refnum)",
+		fromFfi: fmt.Sprintf("setmetatable({refnum = %%s}, %s)", n.Name()),
+	}, true
+}
+
+// isLuaSigSupported reports whether the Lua generator can handle the
+// signature.
+func (g *LuaGen) isLuaSigSupported(t types.Type) bool {
+	if !g.isSigSupported(t) {
+		return false
+	}
+	sig := t.(*types.Signature)
+	params := sig.Params()
+	for i := 0; i < params.Len(); i++ {
+		if _, ok := g.luaType(params.At(i).Type()); !ok {
+			return false
+		}
+	}
+	res := sig.Results()
+	for i := 0; i < res.Len(); i++ {
+		if _, ok := g.luaType(res.At(i).Type()); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+const luaSupport = `-- The refnum a nil Go object or error crosses the bridge as.
+local NULL_REFNUM = 41
+
+-- Pins the Go object before its refnum crosses into Go; the Go side
+-- consumes one reference per crossing.
+local function incref(refnum)
+	lib.IncGoRef(refnum)
+	return refnum
+end
+
+-- GoError values are raised when a bound Go function returns a
+-- non-nil error. The refnum identifies the Go error value on the Go
+-- side of the bridge.
+local GoError = {}
+GoError.__index = GoError
+GoError.__tostring = function(e)
+	return 'go error (refnum ' .. e.refnum .. ')'
+end
+M.GoError = GoError
+
+local function go_error(refnum)
+	return setmetatable({refnum = tonumber(refnum)}, GoError)
+end
+
+local function to_nstring(s)
+	local ns = ffi.new('nstring')
+	if #s > 0 then
+		ns.ptr = C.malloc(#s)
+		ffi.copy(ns.ptr, s, #s)
+	end
+	ns.len = #s
+	return ns
+end
+
+local function from_nstring(ns)
+	if ns.ptr == nil or ns.len == 0 then
+		return ''
+	end
+	local s = ffi.string(ns.ptr, ns.len)
+	C.free(ns.ptr)
+	return s
+end
+
+local function to_nbyteslice(b)
+	local bs = ffi.new('nbyteslice')
+	if #b > 0 then
+		bs.ptr = C.malloc(#b)
+		ffi.copy(bs.ptr, b, #b)
+	end
+	bs.len = #b
+	return bs
+end
+
+local function from_nbyteslice(bs)
+	if bs.ptr == nil or bs.len == 0 then
+		return ''
+	end
+	local b = ffi.string(bs.ptr, bs.len)
+	C.free(bs.ptr)
+	return b
+end
+
+-- Results of //gobind:nocopy functions lend Go's backing array;
+-- release the pin instead of freeing.
+local function from_nbyteslice_nocopy(bs)
+	if bs.ptr == nil or bs.len == 0 then
+		return ''
+	end
+	local b = ffi.string(bs.ptr, bs.len)
+	lib.ReleaseByteSliceRef(bs.ptr)
+	return b
+end
+
+`
@@ -0,0 +1,722 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bind
+
+import (
+	"fmt"
+	"go/types"
+	"strings"
+)
+
+// NodeGen generates the N-API addon and TypeScript-typed JavaScript
+// wrapper selected with -lang=node. The addon converts N-API values to
+// the seq bridge types and calls the proxy functions exported by the
+// generated Go stubs, which must be linked into the addon together
+// with a desktop seq support implementation. Byte slices cross the
+// bridge as Buffers and Go errors are thrown with code GoError;
+// implementing Go interfaces from JavaScript is not supported.
+type NodeGen struct {
+	*Generator
+}
+
+// nodeFunc describes one function exported from the addon: the C
+// symbol of the Go proxy it calls, the name registered on the addon
+// object and the signature it bridges.
+type nodeFunc struct {
+	symbol   string
+	name     string
+	sig      *types.Signature
+	fastpath bool
+	noCopy   bool
+}
+
+// nodeFuncs collects every function the addon exports: package
+// functions, struct constructors, field accessors and methods.
+func (g *NodeGen) nodeFuncs() []nodeFunc {
+	var fns []nodeFunc
+	for _, f := range g.funcs {
+		if !g.isNodeSigSupported(f.Type()) {
+			continue
+		}
+		fns = append(fns, nodeFunc{
+			symbol: fmt.Sprintf("proxy%s__%s", g.pkgPrefix, f.Name()),
+			name:   f.Name(),
+			sig:    f.Type().(*types.Signature),
+			noCopy: g.isNoCopy(f),
+		})
+	}
+	for _, s := range g.structs {
+		n := s.obj.Name()
+		fns = append(fns, nodeFunc{
+			symbol: fmt.Sprintf("new_%s_%s", g.pkgPrefix, n),
+			name:   "new_" + n,
+			sig:    types.NewSignatureType(nil, nil, nil, nil, types.NewTuple(types.NewVar(0, nil, "", s.obj.Type())), false),
+		})
+		for _, f := range exportedFields(s.t) {
+			if _, ok := g.nodeType(f.Type()); !ok {
+				continue
+			}
+			fns = append(fns,
+				nodeFunc{
+					symbol: fmt.Sprintf("proxy%s_%s_%s_Get", g.pkgPrefix, n, f.Name()),
+					name:   fmt.Sprintf("%s_%s_Get", n, f.Name()),
+					sig:    types.NewSignatureType(nil, nil, nil, recvTuple(s.obj), types.NewTuple(types.NewVar(0, nil, "", f.Type())), false),
+				},
+				nodeFunc{
+					symbol: fmt.Sprintf("proxy%s_%s_%s_Set", g.pkgPrefix, n, f.Name()),
+					name:   fmt.Sprintf("%s_%s_Set", n, f.Name()),
+					sig:    types.NewSignatureType(nil, nil, nil, appendTuple(recvTuple(s.obj), types.NewVar(0, nil, "v", f.Type())), nil, false),
+				})
+		}
+		for _, m := range exportedMethodSet(types.NewPointer(s.obj.Type())) {
+			if !g.isNodeSigSupported(m.Type()) {
+				continue
+			}
+			sig := m.Type().(*types.Signature)
+			fns = append(fns, nodeFunc{
+				symbol:   fmt.Sprintf("proxy%s_%s_%s", g.pkgPrefix, n, m.Name()),
+				name:     fmt.Sprintf("%s_%s", n, m.Name()),
+				sig:      types.NewSignatureType(nil, nil, nil, appendTuple(recvTuple(s.obj), tupleVars(sig.Params())...), sig.Results(), false),
+				fastpath: g.isFastpath(m),
+				noCopy:   g.isNoCopy(m),
+			})
+		}
+	}
+	return fns
+}
+
+func recvTuple(obj *types.TypeName) *types.Tuple {
+	return types.NewTuple(types.NewVar(0, nil, "refnum", obj.Type()))
+}
+
+func appendTuple(t *types.Tuple, vars ...*types.Var) *types.Tuple {
+	return types.NewTuple(append(tupleVars(t), vars...)...)
+}
+
+func tupleVars(t *types.Tuple) []*types.Var {
+	var vars []*types.Var
+	for i := 0; i < t.Len(); i++ {
+		vars = append(vars, t.At(i))
+	}
+	return vars
+}
+
+// GenNodeC generates the N-API addon source for the package.
+func (g *NodeGen) GenNodeC() error {
+	var pkgPath string
+	if g.Pkg != nil {
+		pkgPath = g.Pkg.Path()
+	}
+	g.Printf(gobindPreamble)
+	g.Printf("// N-API addon bridging Node.js to the Go package %s.\n\n", pkgPath)
+	g.Printf("%s", nodeCSupport)
+
+	fns := g.nodeFuncs()
+	for _, f := range fns {
+		g.genNodeCFunc(f)
+	}
+
+	g.Printf("static napi_value node_module_init(napi_env env, napi_value exports) {\n")
+	g.Indent()
+	g.Printf("napi_property_descriptor props[] = {\n")
+	g.Indent()
+	for _, f := range fns {
+		g.Printf("{%q, NULL, node_fn_%s, NULL, NULL, NULL, napi_default, NULL},\n", f.name, f.symbol)
+	}
+	g.Outdent()
+	g.Printf("};\n")
+	g.Printf("napi_define_properties(env, exports, sizeof(props)/sizeof(props[0]), props);\n")
+	g.Printf("return exports;\n")
+	g.Outdent()
+	g.Printf("}\n\n")
+	g.Printf("NAPI_MODULE(NODE_GYP_MODULE_NAME, node_module_init)\n")
+	if len(g.err) > 0 {
+		return g.err
+	}
+	return nil
+}
+
+func (g *NodeGen) genNodeCFunc(f nodeFunc) {
+	params := f.sig.Params()
+	res := f.sig.Results()
+
+	// Declare the proxy prototype; the struct layout matches the one
+	// cgo generates for multiple results.
+	var protoArgs []string
+	for i := 0; i < params.Len(); i++ {
+		protoArgs = append(protoArgs, g.cgoType(params.At(i).Type()))
+	}
+	ret := "void"
+	switch res.Len() {
+	case 1:
+		ret = g.cgoType(res.At(0).Type())
+	case 2:
+		g.Printf("typedef struct { %s r0; %s r1; } ret_%s;\n", g.cgoType(res.At(0).Type()), g.cgoType(res.At(1).Type()), f.symbol)
+		ret = "ret_" + f.symbol
+	}
+	g.Printf("extern %s %s(%s);\n\n", ret, f.symbol, strings.Join(protoArgs, ", "))
+
+	g.Printf("static napi_value node_fn_%s(napi_env env, napi_callback_info info) {\n", f.symbol)
+	g.Indent()
+	if n := params.Len(); n > 0 {
+		g.Printf("size_t argc = %d;\n", n)
+		g.Printf("napi_value argv[%d];\n", n)
+		g.Printf("napi_get_cb_info(env, info, &argc, argv, NULL, NULL);\n")
+	}
+	var args []string
+	for i := 0; i < params.Len(); i++ {
+		t, _ := g.nodeType(params.At(i).Type())
+		cGet := fmt.Sprintf(t.cGet, i)
+		if i == 0 && f.fastpath {
+			// Fastpath methods peek at the receiver without
+			// consuming a reference.
+			cGet = fmt.Sprintf("(int32_t)node_to_int64(env, argv[%d])", i)
+		}
+		g.Printf("%s a%d = %s;\n", g.cgoType(params.At(i).Type()), i, cGet)
+		args = append(args, fmt.Sprintf("a%d", i))
+	}
+	call := fmt.Sprintf("%s(%s)", f.symbol, strings.Join(args, ", "))
+	switch res.Len() {
+	case 0:
+		g.Printf("%s;\n", call)
+		g.Printf("return NULL;\n")
+	case 1:
+		if isErrorType(res.At(0).Type()) {
+			g.Printf("int32_t r = %s;\n", call)
+			g.Printf("if (r != GO_NULL_REFNUM) {\n")
+			g.Printf("	node_throw_go_error(env, r);\n")
+			g.Printf("}\n")
+			g.Printf("return NULL;\n")
+		} else {
+			t := g.nodeResultType(f, res.At(0).Type())
+			g.Printf("%s r = %s;\n", g.cgoType(res.At(0).Type()), call)
+			g.Printf("return %s;\n", fmt.Sprintf(t.cMake, "r"))
+		}
+	case 2:
+		t := g.nodeResultType(f, res.At(0).Type())
+		g.Printf("ret_%s r = %s;\n", f.symbol, call)
+		g.Printf("if (r.r1 != GO_NULL_REFNUM) {\n")
+		g.Printf("	node_throw_go_error(env, r.r1);\n")
+		g.Printf("	return NULL;\n")
+		g.Printf("}\n")
+		g.Printf("return %s;\n", fmt.Sprintf(t.cMake, "r.r0"))
+	}
+	g.Outdent()
+	g.Printf("}\n\n")
+}
+
+// nodeResultType returns the type info for a result of f; the byte
+// slice results of //gobind:nocopy functions lend Go's backing array
+// and release the pin instead of freeing.
+func (g *NodeGen) nodeResultType(f nodeFunc, t types.Type) nodeTypeInfo {
+	ti, _ := g.nodeType(t)
+	if f.noCopy && isByteSlice(t) {
+		ti.cMake = "node_from_nbyteslice_nocopy(env, %s)"
+	}
+	return ti
+}
+
+// GenNodeJS generates the JavaScript wrapper module.
+func (g *NodeGen) GenNodeJS() error {
+	pkgName := g.nodePkgName()
+	g.Printf(gobindPreamble)
+	g.Printf("'use strict';\n\n")
+	g.Printf("const addon = require('./build/Release/%s.node');\n\n", pkgName)
+	g.Printf("%s", nodeJSSupport)
+
+	for _, c := range g.constants {
+		if _, ok := c.Type().(*types.Basic); !ok {
+			continue
+		}
+		g.Printf("const %s = %s;\n", c.Name(), c.Val().ExactString())
+	}
+	if len(g.constants) > 0 {
+		g.Printf("\n")
+	}
+
+	var exports []string
+	for _, c := range g.constants {
+		if _, ok := c.Type().(*types.Basic); ok {
+			exports = append(exports, c.Name())
+		}
+	}
+	for _, s := range g.structs {
+		n := s.obj.Name()
+		doc := g.docs[n]
+		g.Printf("class %s {\n", n)
+		g.Indent()
+		g.Printf("constructor(refnum) {\n")
+		g.Printf("	this._refnum = refnum === undefined ? addon.new_%s() : refnum;\n", n)
+		g.Printf("}\n\n")
+		for _, f := range exportedFields(s.t) {
+			t, ok := g.nodeType(f.Type())
+			if !ok {
+				continue
+			}
+			g.Printf("get %s() {\n", lowerFirst(f.Name()))
+			g.Printf("	return %s;\n", fmt.Sprintf(t.fromAddon, fmt.Sprintf("addon.%s_%s_Get(this._refnum)", n, f.Name())))
+			g.Printf("}\n\n")
+			g.Printf("set %s(v) {\n", lowerFirst(f.Name()))
+			g.Printf("	addon.%s_%s_Set(this._refnum, %s);\n", n, f.Name(), fmt.Sprintf(t.toAddon, "v"))
+			g.Printf("}\n\n")
+		}
+		for _, m := range exportedMethodSet(types.NewPointer(s.obj.Type())) {
+			if !g.isNodeSigSupported(m.Type()) {
+				g.Printf("// skipped method %s.%s with unsupported parameter or result types\n\n", n, m.Name())
+				continue
+			}
+			g.genNodeJSFunc(m, doc.Member(m.Name()), fmt.Sprintf("addon.%s_%s", n, m.Name()), true)
+		}
+		g.Outdent()
+		g.Printf("}\n\n")
+		exports = append(exports, n)
+	}
+	for _, f := range g.funcs {
+		if !g.isNodeSigSupported(f.Type()) {
+			g.Printf("// skipped function %s with unsupported parameter or result types\n\n", f.Name())
+			continue
+		}
+		g.genNodeJSFunc(f, g.docs[f.Name()].Doc(), "addon."+f.Name(), false)
+		exports = append(exports, lowerFirst(f.Name()))
+		if returnsError(f) {
+			exports = append(exports, lowerFirst(f.Name())+"Async")
+		}
+	}
+
+	g.Printf("module.exports = {\n")
+	g.Indent()
+	for _, e := range exports {
+		g.Printf("%s,\n", e)
+	}
+	g.Outdent()
+	g.Printf("};\n")
+	if len(g.err) > 0 {
+		return g.err
+	}
+	return nil
+}
+
+func (g *NodeGen) genNodeJSFunc(f *types.Func, doc, call string, method bool) {
+	sig := f.Type().(*types.Signature)
+	params := sig.Params()
+	res := sig.Results()
+
+	var decl, args []string
+	if method {
+		args = append(args, "this._refnum")
+	}
+	for i := 0; i < params.Len(); i++ {
+		t, _ := g.nodeType(params.At(i).Type())
+		pn := basicParamName(params, i)
+		decl = append(decl, pn)
+		args = append(args, fmt.Sprintf(t.toAddon, pn))
+	}
+	name := lowerFirst(f.Name())
+	if doc != "" {
+		g.Printf("/** %s */\n", strings.TrimSpace(strings.ReplaceAll(doc, "\n", " ")))
+	}
+	if method {
+		g.Printf("%s(%s) {\n", name, strings.Join(decl, ", "))
+	} else {
+		g.Printf("function %s(%s) {\n", name, strings.Join(decl, ", "))
+	}
+	g.Indent()
+	expr := fmt.Sprintf("%s(%s)", call, strings.Join(args, ", "))
+	retIdx := -1
+	if res.Len() == 2 || res.Len() == 1 && !isErrorType(res.At(0).Type()) {
+		retIdx = 0
+	}
+	if retIdx == 0 {
+		t, _ := g.nodeType(res.At(0).Type())
+		g.Printf("return %s;\n", fmt.Sprintf(t.fromAddon, expr))
+	} else {
+		g.Printf("%s;\n", expr)
+	}
+	g.Outdent()
+	g.Printf("}\n\n")
+	if !method && returnsError(f) {
+		g.Printf("/** Async variant of %s, resolving off the current tick. */\n", name)
+		g.Printf("function %sAsync(...args) {\n", name)
+		g.Printf("	return new Promise((resolve, reject) => {\n")
+		g.Printf("		setImmediate(() => {\n")
+		g.Printf("			try { resolve(%s(...args)); } catch (e) { reject(e); }\n", name)
+		g.Printf("		});\n")
+		g.Printf("	});\n")
+		g.Printf("}\n\n")
+	}
+}
+
+// GenNodeTS generates the TypeScript declarations for the wrapper.
+func (g *NodeGen) GenNodeTS() error {
+	g.Printf(gobindPreamble)
+	for _, c := range g.constants {
+		if t, ok := c.Type().(*types.Basic); ok {
+			g.Printf("export const %s: %s;\n", c.Name(), g.tsBasicType(t))
+		}
+	}
+	if len(g.constants) > 0 {
+		g.Printf("\n")
+	}
+	for _, s := range g.structs {
+		n := s.obj.Name()
+		g.Printf("export class %s {\n", n)
+		g.Indent()
+		g.Printf("constructor();\n")
+		for _, f := range exportedFields(s.t) {
+			if t, ok := g.nodeType(f.Type()); ok {
+				g.Printf("%s: %s;\n", lowerFirst(f.Name()), t.ts)
+			}
+		}
+		for _, m := range exportedMethodSet(types.NewPointer(s.obj.Type())) {
+			if g.isNodeSigSupported(m.Type()) {
+				g.genNodeTSFunc(m, "")
+			}
+		}
+		g.Outdent()
+		g.Printf("}\n\n")
+	}
+	for _, f := range g.funcs {
+		if !g.isNodeSigSupported(f.Type()) {
+			continue
+		}
+		g.genNodeTSFunc(f, "export function ")
+		if returnsError(f) {
+			g.genNodeTSAsync(f)
+		}
+	}
+	if len(g.err) > 0 {
+		return g.err
+	}
+	return nil
+}
+
+func (g *NodeGen) genNodeTSFunc(f *types.Func, prefix string) {
+	sig := f.Type().(*types.Signature)
+	params := sig.Params()
+	var decl []string
+	for i := 0; i < params.Len(); i++ {
+		t, _ := g.nodeType(params.At(i).Type())
+		decl = append(decl, fmt.Sprintf("%s: %s", basicParamName(params, i), t.ts))
+	}
+	g.Printf("%s%s(%s): %s;\n", prefix, lowerFirst(f.Name()), strings.Join(decl, ", "), g.tsReturnType(sig))
+}
+
+func (g *NodeGen) genNodeTSAsync(f *types.Func) {
+	sig := f.Type().(*types.Signature)
+	params := sig.Params()
+	var decl []string
+	for i := 0; i < params.Len(); i++ {
+		t, _ := g.nodeType(params.At(i).Type())
+		decl = append(decl, fmt.Sprintf("%s: %s", basicParamName(params, i), t.ts))
+	}
+	g.Printf("export function %sAsync(%s): Promise<%s>;\n", lowerFirst(f.Name()), strings.Join(decl, ", "), g.tsReturnType(sig))
+}
+
+func (g *NodeGen) tsReturnType(sig *types.Signature) string {
+	res := sig.Results()
+	if res.Len() == 2 || res.Len() == 1 && !isErrorType(res.At(0).Type()) {
+		t, _ := g.nodeType(res.At(0).Type())
+		return t.ts
+	}
+	return "void"
+}
+
+func (g *NodeGen) tsBasicType(t *types.Basic) string {
+	switch t.Kind() {
+	case types.Bool, types.UntypedBool:
+		return "boolean"
+	case types.String, types.UntypedString:
+		return "string"
+	default:
+		return "number"
+	}
+}
+
+// GenNodeGyp generates the binding.gyp build file for the addon.
+func (g *NodeGen) GenNodeGyp() error {
+	pkgName := g.nodePkgName()
+	g.Printf("{\n")
+	g.Printf("	\"targets\": [\n")
+	g.Printf("		{\n")
+	g.Printf("			\"target_name\": %q,\n", pkgName)
+	g.Printf("			\"sources\": [%q],\n", pkgName+"_node.c")
+	g.Printf("			\"libraries\": [\"-L<(module_root_dir)\", \"-l%s\"]\n", pkgName)
+	g.Printf("		}\n")
+	g.Printf("	]\n")
+	g.Printf("}\n")
+	return nil
+}
+
+// GenNodePackageJSON generates the package manifest for the addon.
+func (g *NodeGen) GenNodePackageJSON() error {
+	var pkgPath string
+	pkgName := g.nodePkgName()
+	if g.Pkg != nil {
+		pkgPath = g.Pkg.Path()
+	}
+	g.Printf("{\n")
+	g.Printf("	\"name\": %q,\n", pkgName)
+	g.Printf("	\"version\": \"0.0.1\",\n")
+	g.Printf("	\"description\": \"Node.js bindings for the Go package %s, generated by gobind.\",\n", pkgPath)
+	g.Printf("	\"main\": \"index.js\",\n")
+	g.Printf("	\"types\": \"index.d.ts\",\n")
+	g.Printf("	\"gypfile\": true\n")
+	g.Printf("}\n")
+	return nil
+}
+
+func (g *NodeGen) nodePkgName() string {
+	if g.Pkg == nil {
+		return "universe"
+	}
+	return g.Pkg.Name()
+}
+
+// nodeTypeInfo describes how a Go type crosses the N-API bridge: the
+// C conversions in the addon (with %d substituted by the argument
+// index for cGet and %s by the value for cMake), the JavaScript
+// conversions in the wrapper and the TypeScript spelling.
+type nodeTypeInfo struct {
+	cGet      string
+	cMake     string
+	toAddon   string
+	fromAddon string
+	ts        string
+}
+
+func (g *NodeGen) nodeType(t types.Type) (nodeTypeInfo, bool) {
+	num := func() (nodeTypeInfo, bool) {
+		return nodeTypeInfo{
+			cGet:      "node_to_int64(env, argv[%d])",
+			cMake:     "node_from_int64(env, (int64_t)%s)",
+			toAddon:   "%s",
+			fromAddon: "%s",
+			ts:        "number",
+		}, true
+	}
+	switch t := t.(type) {
+	case *types.Basic:
+		switch t.Kind() {
+		case types.Bool, types.UntypedBool:
+			return nodeTypeInfo{
+				cGet:      "node_to_bool(env, argv[%d])",
+				cMake:     "node_from_bool(env, %s)",
+				toAddon:   "%s",
+				fromAddon: "%s",
+				ts:        "boolean",
+			}, true
+		case types.Int, types.Int8, types.Int16, types.Int32, types.Int64, types.Uint8, types.UntypedInt, types.UntypedRune:
+			return num()
+		case types.Float32, types.Float64, types.UntypedFloat:
+			return nodeTypeInfo{
+				cGet:      "node_to_double(env, argv[%d])",
+				cMake:     "node_from_double(env, %s)",
+				toAddon:   "%s",
+				fromAddon: "%s",
+				ts:        "number",
+			}, true
+		case types.String, types.UntypedString:
+			return nodeTypeInfo{
+				cGet:      "node_to_nstring(env, argv[%d])",
+				cMake:     "node_from_nstring(env, %s)",
+				toAddon:   "%s",
+				fromAddon: "%s",
+				ts:        "string",
+			}, true
+		}
+	case *types.Slice:
+		if e, ok := t.Elem().(*types.Basic); ok && e.Kind() == types.Uint8 {
+			return nodeTypeInfo{
+				cGet:      "node_to_nbyteslice(env, argv[%d])",
+				cMake:     "node_from_nbyteslice(env, %s)",
+				toAddon:   "%s",
+				fromAddon: "%s",
+				ts:        "Buffer",
+			}, true
+		}
+	case *types.Pointer:
+		if n, ok := t.Elem().(*types.Named); ok {
+			return g.nodeNamedType(n)
+		}
+	case *types.Named:
+		if isErrorType(t) {
+			return num()
+		}
+		return g.nodeNamedType(t)
+	}
+	return nodeTypeInfo{}, false
+}
+
+func (g *NodeGen) nodeNamedType(t *types.Named) (nodeTypeInfo, bool) {
+	n := t.Obj()
+	if n.Pkg() != g.Pkg {
+		return nodeTypeInfo{}, false
+	}
+	if _, ok := t.Underlying().(*types.Interface); ok {
+		// Interfaces are passed as raw refnums; JavaScript cannot
+		// implement them.
+		return nodeTypeInfo{
+			cGet:      "node_incref((int32_t)node_to_int64(env, argv[%d]))",
+			cMake:     "node_from_int64(env, %s)",
+			toAddon:   "%s",
+			fromAddon: "%s",
+			ts:        "number",
+		}, true
+	}
+	return nodeTypeInfo{
+		cGet:      "node_incref((int32_t)node_to_int64(env, argv[%d]))",
+		cMake:     "node_from_int64(env, %s)",
+		toAddon:   "%s._refnum",
+		fromAddon: "new " + n.Name() + "(%s)",
+		ts:        n.Name(),
+	}, true
+}
+
+// isNodeSigSupported reports whether the Node generator can handle the
+// signature.
+func (g *NodeGen) isNodeSigSupported(t types.Type) bool {
+	if !g.isSigSupported(t) {
+		return false
+	}
+	sig := t.(*types.Signature)
+	params := sig.Params()
+	for i := 0; i < params.Len(); i++ {
+		if _, ok := g.nodeType(params.At(i).Type()); !ok {
+			return false
+		}
+	}
+	res := sig.Results()
+	for i := 0; i < res.Len(); i++ {
+		if _, ok := g.nodeType(res.At(i).Type()); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+const nodeCSupport = `#include <node_api.h>
+#include <stdint.h>
+#include <stdlib.h>
+#include <string.h>
+#include <stdio.h>
+
+typedef struct nstring { void *ptr; int len; } nstring;
+typedef struct nbyteslice { void *ptr; int len; } nbyteslice;
+typedef int64_t nint;
+
+// The refnum a nil Go object or error crosses the bridge as.
+#define GO_NULL_REFNUM 41
+
+extern void IncGoRef(int32_t refnum);
+extern void ReleaseByteSliceRef(void *ptr);
+
+// Pins the Go object before its refnum crosses into Go; the Go side
+// consumes one reference per crossing.
+static int32_t node_incref(int32_t refnum) {
+	IncGoRef(refnum);
+	return refnum;
+}
+
+static int64_t node_to_int64(napi_env env, napi_value v) {
+	int64_t r = 0;
+	napi_get_value_int64(env, v, &r);
+	return r;
+}
+
+static napi_value node_from_int64(napi_env env, int64_t v) {
+	napi_value r;
+	napi_create_int64(env, v, &r);
+	return r;
+}
+
+static double node_to_double(napi_env env, napi_value v) {
+	double r = 0;
+	napi_get_value_double(env, v, &r);
+	return r;
+}
+
+static napi_value node_from_double(napi_env env, double v) {
+	napi_value r;
+	napi_create_double(env, v, &r);
+	return r;
+}
+
+static char node_to_bool(napi_env env, napi_value v) {
+	bool r = false;
+	napi_get_value_bool(env, v, &r);
+	return r ? 1 : 0;
+}
+
+static napi_value node_from_bool(napi_env env, char v) {
+	napi_value r;
+	napi_get_boolean(env, v != 0, &r);
+	return r;
+}
+
+static nstring node_to_nstring(napi_env env, napi_value v) {
+	size_t len = 0;
+	napi_get_value_string_utf8(env, v, NULL, 0, &len);
+	char *buf = (char *)malloc(len + 1);
+	napi_get_value_string_utf8(env, v, buf, len + 1, &len);
+	nstring ns = {buf, (int)len};
+	return ns;
+}
+
+static napi_value node_from_nstring(napi_env env, nstring ns) {
+	napi_value v;
+	napi_create_string_utf8(env, ns.ptr != NULL ? (const char *)ns.ptr : "", ns.len, &v);
+	if (ns.ptr != NULL) {
+		free(ns.ptr);
+	}
+	return v;
+}
+
+static nbyteslice node_to_nbyteslice(napi_env env, napi_value v) {
+	void *data = NULL;
+	size_t len = 0;
+	napi_get_buffer_info(env, v, &data, &len);
+	void *copy = NULL;
+	if (len > 0) {
+		copy = malloc(len);
+		memcpy(copy, data, len);
+	}
+	nbyteslice bs = {copy, (int)len};
+	return bs;
+}
+
+static napi_value node_from_nbyteslice(napi_env env, nbyteslice bs) {
+	napi_value v;
+	napi_create_buffer_copy(env, bs.len, bs.ptr, NULL, &v);
+	if (bs.ptr != NULL) {
+		free(bs.ptr);
+	}
+	return v;
+}
+
+// Results of //gobind:nocopy functions lend Go's backing array;
+// release the pin instead of freeing.
+static napi_value node_from_nbyteslice_nocopy(napi_env env, nbyteslice bs) {
+	napi_value v;
+	napi_create_buffer_copy(env, bs.len, bs.ptr, NULL, &v);
+	if (bs.ptr != NULL) {
+		ReleaseByteSliceRef(bs.ptr);
+	}
+	return v;
+}
+
+static void node_throw_go_error(napi_env env, int32_t refnum) {
+	char msg[64];
+	snprintf(msg, sizeof(msg), "go error (refnum %d)", refnum);
+	napi_throw_error(env, "GoError", msg);
+}
+
+`
+
+const nodeJSSupport = `/**
+ * Proxies hold a refnum identifying the Go object; they are created by
+ * the bindings and by the exported class constructors.
+ */
+
+`
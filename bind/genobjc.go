@@ -24,6 +24,19 @@ import (
 type ObjcGen struct {
 	Prefix string // prefix arg passed by flag.
 
+	// RejectNulls makes the generated Objective-C code raise an
+	// exception for nil strings and byte slices passed in, instead of
+	// silently converting them to empty values.
+	RejectNulls bool
+
+	// SwiftNames annotates the generated headers with NS_SWIFT_NAME so
+	// the imported Swift API reads idiomatically without an overlay:
+	// classes drop the name prefix, New<Type> functions become
+	// initializers and the internal initWithRef: is hidden with
+	// NS_SWIFT_UNAVAILABLE. Not compatible with the generated Swift
+	// overlay, which references the prefixed names.
+	SwiftNames bool
+
 	*Generator
 
 	// fields set by init.
@@ -207,6 +220,22 @@ func (g *ObjcGen) GenH() error {
 		g.Printf("\n")
 	}
 
+	// error domain and codes
+	if evars := g.errorVars(); len(evars) > 0 && g.Pkg != nil {
+		g.Printf("// %sErrorDomain is the NSError domain of errors returned by this package.\n", g.namePrefix)
+		g.Printf("FOUNDATION_EXPORT NSErrorDomain const %sErrorDomain;\n\n", g.namePrefix)
+		g.Printf("// %sErrorCode enumerates the exported error variables of this package.\n", g.namePrefix)
+		g.Printf("// An error matching one of them, with the semantics of Go's errors.Is,\n")
+		g.Printf("// carries the corresponding code.\n")
+		g.Printf("typedef NS_ERROR_ENUM(%sErrorDomain, %sErrorCode) {\n", g.namePrefix, g.namePrefix)
+		g.Indent()
+		for _, v := range evars {
+			g.Printf("%sErrorCode%s = %d,\n", g.namePrefix, v.Name(), errorCode(v.Name()))
+		}
+		g.Outdent()
+		g.Printf("};\n\n")
+	}
+
 	// var
 	if len(g.vars) > 0 {
 		g.Printf("@interface %s : NSObject\n", g.namePrefix)
@@ -294,6 +323,11 @@ func (g *ObjcGen) GenM() error {
 		g.Printf("\n")
 	}
 
+	// error domain
+	if evars := g.errorVars(); len(evars) > 0 && g.Pkg != nil {
+		g.Printf("NSErrorDomain const %sErrorDomain = @%q;\n\n", g.namePrefix, g.Pkg.Path())
+	}
+
 	// vars
 	if len(g.vars) > 0 {
 		g.Printf("@implementation %s\n", g.namePrefix)
@@ -624,6 +658,77 @@ func (s *funcSummary) returnsVal() bool {
 	return (len(s.retParams) == 1 && !isErrorType(s.retParams[0].typ)) || (len(s.retParams) == 2 && isNullableType(s.retParams[0].typ))
 }
 
+// asyncCompatible reports whether the signature fits the
+// completion-handler pattern requested by a //gobind:async directive:
+// an error as the last result, optionally preceded by a single value.
+func (s *funcSummary) asyncCompatible() bool {
+	n := len(s.retParams)
+	return n > 0 && isErrorType(s.retParams[n-1].typ)
+}
+
+// asyncHandlerIndex returns the 1-based position of the completion
+// handler among the companion's parameters, for NS_SWIFT_ASYNC.
+func (s *funcSummary) asyncHandlerIndex() int {
+	n := len(s.params) + 1
+	if s.hasself {
+		n--
+	}
+	return n
+}
+
+// asyncHandlerType returns the block type of the completion handler
+// for the async companion: the value result, if any, followed by the
+// error. Exactly one of the two is non-nil when the handler runs. The
+// name, if any, is embedded in the declarator, as required for C
+// function parameters.
+func (s *funcSummary) asyncHandlerType(g *ObjcGen, name string) string {
+	if name != "" {
+		name = " " + name
+	}
+	if len(s.retParams) == 2 {
+		p := s.retParams[0]
+		typ := g.objcType(p.typ)
+		if strings.Contains(typ, "*") {
+			typ = strings.Replace(typ, "_Nonnull", "_Nullable", 1)
+		}
+		return fmt.Sprintf("void (^ _Nonnull%s)(%s %s, NSError* _Nullable error)", name, typ, p.name)
+	}
+	return fmt.Sprintf("void (^ _Nonnull%s)(NSError* _Nullable error)", name)
+}
+
+// asAsyncFunc returns the declaration of the completion-handler
+// companion of a package-level function.
+func (s *funcSummary) asAsyncFunc(g *ObjcGen) string {
+	var params []string
+	for _, p := range s.params {
+		params = append(params, g.objcParamType(p.typ)+" "+p.name)
+	}
+	params = append(params, s.asyncHandlerType(g, "completionHandler"))
+	return fmt.Sprintf("void %s%sWithCompletionHandler(%s)", g.namePrefix, s.name, strings.Join(params, ", "))
+}
+
+// asAsyncMethod returns the declaration of the completion-handler
+// companion of a method.
+func (s *funcSummary) asAsyncMethod(g *ObjcGen) string {
+	name := objcNameReplacer(lowerFirst(s.name))
+	skip := 0
+	if s.hasself {
+		skip = 1
+	}
+	var params []string
+	for i, p := range s.params[skip:] {
+		var key string
+		if i != 0 {
+			key = p.name
+		}
+		params = append(params, fmt.Sprintf("%s:(%s)%s", key, g.objcParamType(p.typ), p.name))
+	}
+	if len(params) == 0 {
+		return fmt.Sprintf("(void)%sWithCompletionHandler:(%s)completionHandler", name, s.asyncHandlerType(g, ""))
+	}
+	return fmt.Sprintf("(void)%s%s completionHandler:(%s)completionHandler", name, strings.Join(params, " "), s.asyncHandlerType(g, ""))
+}
+
 func (g *ObjcGen) paramName(params *types.Tuple, pos int) string {
 	name := basicParamName(params, pos)
 	return objcNameReplacer(name)
@@ -636,8 +741,55 @@ func (g *ObjcGen) genFuncH(obj *types.Func) {
 	}
 	if s := g.funcSummary(nil, obj); s != nil {
 		g.objcdoc(g.docs[obj.Name()].Doc())
-		g.Printf("FOUNDATION_EXPORT %s;\n", s.asFunc(g))
+		g.Printf("FOUNDATION_EXPORT %s%s;\n", s.asFunc(g), g.swiftNameFuncAttr(s))
+		if g.docs[obj.Name()].Async() {
+			if s.asyncCompatible() {
+				g.Printf("FOUNDATION_EXPORT %s NS_SWIFT_ASYNC(%d);\n", s.asAsyncFunc(g), s.asyncHandlerIndex())
+			} else {
+				g.errorf("gobind:async on %s: the function must return an error", obj.Name())
+			}
+		}
+	}
+}
+
+// swiftUnavailableAttr hides internal machinery like initWithRef: from
+// Swift when -swiftnames is set.
+func (g *ObjcGen) swiftUnavailableAttr() string {
+	if !g.SwiftNames {
+		return ""
+	}
+	return ` NS_SWIFT_UNAVAILABLE("internal; use the provided initializers and functions")`
+}
+
+// swiftNameFuncAttr returns the NS_SWIFT_NAME attribute for a package
+// function when -swiftnames is set: the prefix is dropped, every
+// imported parameter keeps its label, and New<Type> functions with a
+// single result of that bound type read as the type's initializer.
+func (g *ObjcGen) swiftNameFuncAttr(s *funcSummary) string {
+	if !g.SwiftNames {
+		return ""
+	}
+	var labels []string
+	for _, p := range s.params {
+		labels = append(labels, p.name+":")
+	}
+	skip := 0
+	if s.returnsVal() {
+		skip = 1
 	}
+	for _, p := range s.retParams[skip:] {
+		labels = append(labels, p.name+":")
+	}
+	sig := fmt.Sprintf("%s(%s)", objcNameReplacer(lowerFirst(s.name)), strings.Join(labels, ""))
+	if rest := strings.TrimPrefix(s.name, "New"); rest != s.name && rest != "" && len(s.retParams) == 1 && s.returnsVal() {
+		for _, st := range g.structs {
+			if st.obj.Name() == rest {
+				sig = fmt.Sprintf("%s.init(%s)", rest, strings.Join(labels, ""))
+				break
+			}
+		}
+	}
+	return fmt.Sprintf(" NS_SWIFT_NAME(%s)", sig)
 }
 
 func (g *ObjcGen) genFuncM(obj *types.Func) {
@@ -650,6 +802,78 @@ func (g *ObjcGen) genFuncM(obj *types.Func) {
 	g.genFunc(s, "")
 	g.Outdent()
 	g.Printf("}\n")
+	if g.docs[obj.Name()].Async() && s.asyncCompatible() {
+		g.Printf("\n%s {\n", s.asAsyncFunc(g))
+		g.Indent()
+		g.genAsyncBody(s, "")
+		g.Outdent()
+		g.Printf("}\n")
+	}
+}
+
+// genAsyncBody generates the body of a completion-handler companion:
+// the synchronous wrapper runs on a default-priority global queue and
+// its results are reported through the handler.
+func (g *ObjcGen) genAsyncBody(s *funcSummary, objName string) {
+	skip := 0
+	if s.hasself {
+		skip = 1
+	}
+	skipRet := 0
+	if s.returnsVal() {
+		skipRet = 1
+	}
+	var call string
+	if objName != "" {
+		var parts []string
+		for i, p := range s.params[skip:] {
+			var key string
+			if i != 0 {
+				key = p.name
+			}
+			parts = append(parts, fmt.Sprintf("%s:%s", key, p.name))
+		}
+		for _, p := range s.retParams[skipRet:] {
+			var key string
+			if len(parts) > 0 {
+				key = p.name
+			}
+			parts = append(parts, fmt.Sprintf("%s:&%s", key, p.name))
+		}
+		call = fmt.Sprintf("[self %s%s]", objcNameReplacer(lowerFirst(s.name)), strings.Join(parts, " "))
+	} else {
+		var args []string
+		for _, p := range s.params {
+			args = append(args, p.name)
+		}
+		for _, p := range s.retParams[skipRet:] {
+			args = append(args, "&"+p.name)
+		}
+		call = fmt.Sprintf("%s%s(%s)", g.namePrefix, s.name, strings.Join(args, ", "))
+	}
+	g.Printf("dispatch_async(dispatch_get_global_queue(DISPATCH_QUEUE_PRIORITY_DEFAULT, 0), ^{\n")
+	g.Indent()
+	g.Printf("NSError *error = nil;\n")
+	switch {
+	case len(s.retParams) == 2 && s.returnsVal():
+		g.Printf("%s %s = %s;\n", s.ret, s.retParams[0].name, call)
+		g.Printf("completionHandler(%s, error);\n", s.retParams[0].name)
+	case len(s.retParams) == 2:
+		p := s.retParams[0]
+		zero := "0"
+		typ := g.objcType(p.typ)
+		if strings.Contains(typ, "*") {
+			zero = "nil"
+		}
+		g.Printf("%s %s = %s;\n", typ, p.name, zero)
+		g.Printf("%s;\n", call)
+		g.Printf("completionHandler(%s, error);\n", p.name)
+	default:
+		g.Printf("%s;\n", call)
+		g.Printf("completionHandler(error);\n")
+	}
+	g.Outdent()
+	g.Printf("});\n")
 }
 
 func (g *ObjcGen) genGetter(oName string, f *types.Var) {
@@ -688,6 +912,14 @@ func (g *ObjcGen) genWrite(varName string, t types.Type, mode varMode) {
 			g.Printf("%s _%s = (%s)%s;\n", g.cgoType(t), varName, g.cgoType(t), varName)
 		}
 	case *types.Slice:
+		if isByteSliceSlice(t) {
+			g.Printf("nbytesliceslice _%s = go_seq_from_objc_byte2array(%s);\n", varName, varName)
+			break
+		}
+		if isStringSlice(t) {
+			g.Printf("nstringslice _%s = go_seq_from_objc_string2array(%s);\n", varName, varName)
+			break
+		}
 		switch e := t.Elem().(type) {
 		case *types.Basic:
 			switch e.Kind() {
@@ -699,6 +931,12 @@ func (g *ObjcGen) genWrite(varName string, t types.Type, mode varMode) {
 		default:
 			g.errorf("unsupported type: %s", t)
 		}
+	case *types.Map:
+		if isStringMap(t) {
+			g.Printf("nstringmap _%s = go_seq_from_objc_stringdict(%s);\n", varName, varName)
+			break
+		}
+		g.errorf("unsupported type: %s", t)
 	case *types.Named:
 		switch u := t.Underlying().(type) {
 		case *types.Interface:
@@ -755,17 +993,35 @@ func (g *ObjcGen) genRead(toName, fromName string, t types.Type, mode varMode) {
 			g.Printf("%s %s = (%s)%s;\n", g.objcType(t), toName, g.objcType(t), fromName)
 		}
 	case *types.Slice:
+		if isByteSliceSlice(t) {
+			g.Printf("NSArray<NSData *> *%s = go_seq_to_objc_byte2array(%s);\n", toName, fromName)
+			break
+		}
+		if isStringSlice(t) {
+			g.Printf("NSArray<NSString *> *%s = go_seq_to_objc_string2array(%s);\n", toName, fromName)
+			break
+		}
 		switch e := t.Elem().(type) {
 		case *types.Basic:
 			switch e.Kind() {
 			case types.Uint8: // Byte.
-				g.Printf("NSData *%s = go_seq_to_objc_bytearray(%s, %d);\n", toName, fromName, toCFlag(mode == modeRetained))
+				if mode == modePinned {
+					g.Printf("NSData *%s = go_seq_to_objc_bytearray_nocopy(%s);\n", toName, fromName)
+				} else {
+					g.Printf("NSData *%s = go_seq_to_objc_bytearray(%s, %d);\n", toName, fromName, toCFlag(mode == modeRetained))
+				}
 			default:
 				g.errorf("unsupported type: %s", t)
 			}
 		default:
 			g.errorf("unsupported type: %s", t)
 		}
+	case *types.Map:
+		if isStringMap(t) {
+			g.Printf("NSDictionary<NSString *, NSString *> *%s = go_seq_to_objc_stringdict(%s);\n", toName, fromName)
+			break
+		}
+		g.errorf("unsupported type: %s", t)
 	case *types.Pointer:
 		switch t := t.Elem().(type) {
 		case *types.Named:
@@ -788,12 +1044,32 @@ func (g *ObjcGen) genRead(toName, fromName string, t types.Type, mode varMode) {
 func (g *ObjcGen) genFunc(s *funcSummary, objName string) {
 	skip := 0
 	if objName != "" {
-		g.Printf("int32_t refnum = go_seq_go_to_refnum(self._ref);\n")
+		if g.docs[objName].FastpathMember(s.goname) {
+			// The receiver keeps its GoSeqRef alive for the duration
+			// of the call, so the pin the regular crossing adds is
+			// redundant; the Go side resolves the refnum without
+			// releasing it.
+			g.Printf("int32_t refnum = self._ref.refnum;\n")
+		} else {
+			g.Printf("int32_t refnum = go_seq_go_to_refnum(self._ref);\n")
+		}
 		if s.hasself {
 			skip = 1
 			g.Printf("int32_t _self = go_seq_to_refnum(self);\n")
 		}
 	}
+	if g.RejectNulls {
+		for _, p := range s.params[skip:] {
+			if !isNullSubstituted(p.typ) {
+				continue
+			}
+			g.Printf("if (%s == nil) {\n", p.name)
+			g.Indent()
+			g.Printf("LOG_FATAL(@\"%s must not be nil\");\n", p.name)
+			g.Outdent()
+			g.Printf("}\n")
+		}
+	}
 	for _, p := range s.params[skip:] {
 		g.genWrite(p.name, p.typ, modeTransient)
 	}
@@ -824,8 +1100,18 @@ func (g *ObjcGen) genFunc(s *funcSummary, objName string) {
 		g.genRelease(p.name, p.typ, modeTransient)
 	}
 
+	noCopy := false
+	if objName != "" {
+		noCopy = g.docs[objName].NoCopyMember(s.goname)
+	} else {
+		noCopy = g.docs[s.goname].NoCopy()
+	}
 	for i, r := range s.retParams {
-		g.genRead("_"+r.name, fmt.Sprintf("%sr%d", resPrefix, i), r.typ, modeRetained)
+		mode := modeRetained
+		if noCopy && isByteSlice(r.typ) {
+			mode = modePinned
+		}
+		g.genRead("_"+r.name, fmt.Sprintf("%sr%d", resPrefix, i), r.typ, mode)
 	}
 	skip = 0
 	if s.returnsVal() {
@@ -865,7 +1151,10 @@ func (g *ObjcGen) genFunc(s *funcSummary, objName string) {
 
 func (g *ObjcGen) genInterfaceInterface(obj *types.TypeName, summary ifaceSummary, isProtocol bool) {
 	doc := g.docs[obj.Name()]
-	g.objcdoc(doc.Doc())
+	g.objcdoc(typeDoc(doc))
+	if g.SwiftNames && !isProtocol {
+		g.Printf("NS_SWIFT_NAME(%s)\n", obj.Name())
+	}
 	g.Printf("@interface %[1]s%[2]s : ", g.namePrefix, obj.Name())
 	if isErrorType(obj.Type()) {
 		g.Printf("NSError")
@@ -880,7 +1169,7 @@ func (g *ObjcGen) genInterfaceInterface(obj *types.TypeName, summary ifaceSummar
 	g.Printf(" {\n}\n")
 	g.Printf("@property(strong, readonly) _Nonnull id _ref;\n")
 	g.Printf("\n")
-	g.Printf("- (nonnull instancetype)initWithRef:(_Nonnull id)ref;\n")
+	g.Printf("- (nonnull instancetype)initWithRef:(_Nonnull id)ref%s;\n", g.swiftUnavailableAttr())
 	for _, m := range summary.callable {
 		if !g.isSigSupported(m.Type()) {
 			g.Printf("// skipped method %s.%s with unsupported parameter or return types\n\n", obj.Name(), m.Name())
@@ -900,6 +1189,7 @@ func (g *ObjcGen) genInterfaceH(obj *types.TypeName, t *types.Interface) {
 		g.genInterfaceInterface(obj, summary, false)
 		return
 	}
+	g.objcdoc(typeDoc(doc))
 	g.Printf("@protocol %s%s <NSObject>\n", g.namePrefix, obj.Name())
 	for _, m := range makeIfaceSummary(t).callable {
 		if !g.isSigSupported(m.Type()) {
@@ -925,7 +1215,14 @@ func (g *ObjcGen) genInterfaceM(obj *types.TypeName, t *types.Interface) bool {
 	if isErrorType(obj.Type()) {
 		g.Printf("if (self) {\n")
 		g.Printf("	__ref = ref;\n")
-		g.Printf("	self = [super initWithDomain:@\"go\" code:1 userInfo:@{NSLocalizedDescriptionKey: [self error]}];\n")
+		g.Printf("	NSString *domain = @\"go\";\n")
+		g.Printf("	NSInteger code = 1;\n")
+		g.Printf("	go_seq_objc_error_info(go_seq_go_to_refnum(ref), &domain, &code);\n")
+		g.Printf("	NSDictionary *userInfo = @{\n")
+		g.Printf("		NSLocalizedDescriptionKey: [self error],\n")
+		g.Printf("		GoSeqErrorChainKey: go_seq_objc_error_chain_refnum(go_seq_go_to_refnum(ref)),\n")
+		g.Printf("	};\n")
+		g.Printf("	self = [super initWithDomain:domain code:code userInfo:userInfo];\n")
 		g.Printf("}\n")
 	} else {
 		g.Printf("self = [super init];\n")
@@ -1053,7 +1350,10 @@ func (g *ObjcGen) genRelease(varName string, t types.Type, mode varMode) {
 
 func (g *ObjcGen) genStructH(obj *types.TypeName, t *types.Struct) {
 	doc := g.docs[obj.Name()]
-	g.objcdoc(doc.Doc())
+	g.objcdoc(typeDoc(doc))
+	if g.SwiftNames {
+		g.Printf("NS_SWIFT_NAME(%s)\n", obj.Name())
+	}
 	g.Printf("@interface %s%s : ", g.namePrefix, obj.Name())
 	oinf := g.ostructs[obj]
 	var prots []string
@@ -1101,7 +1401,7 @@ func (g *ObjcGen) genStructH(obj *types.TypeName, t *types.Struct) {
 	g.Printf("}\n")
 	g.Printf("@property(strong, readonly) _Nonnull id _ref;\n")
 	g.Printf("\n")
-	g.Printf("- (nonnull instancetype)initWithRef:(_Nonnull id)ref;\n")
+	g.Printf("- (nonnull instancetype)initWithRef:(_Nonnull id)ref%s;\n", g.swiftUnavailableAttr())
 	cons := g.constructors[obj]
 	if oinf == nil {
 		for _, f := range cons {
@@ -1119,6 +1419,13 @@ func (g *ObjcGen) genStructH(obj *types.TypeName, t *types.Struct) {
 
 	// accessors to exported fields.
 	for _, f := range exportedFields(t) {
+		if nt, ok := embeddedBoundStruct(f); ok && g.validPkg(nt.Obj().Pkg()) {
+			// Embedded structs are held by value; expose the embedded
+			// part through a read-only accessor.
+			g.objcdoc(doc.Member(f.Name()))
+			g.Printf("@property (nonatomic, readonly) %s %s;\n", g.objcType(types.NewPointer(f.Type())), objcNameReplacer(lowerFirst(f.Name())))
+			continue
+		}
 		if t := f.Type(); !g.isSupported(t) {
 			g.Printf("// skipped field %s.%s with unsupported type: %s\n\n", obj.Name(), f.Name(), t)
 			continue
@@ -1139,15 +1446,37 @@ func (g *ObjcGen) genStructH(obj *types.TypeName, t *types.Struct) {
 		s := g.funcSummary(obj, m)
 		g.objcdoc(doc.Member(m.Name()))
 		g.Printf("- %s;\n", s.asMethod(g))
+		if doc.AsyncMember(m.Name()) {
+			if s.asyncCompatible() {
+				g.Printf("- %s NS_SWIFT_ASYNC(%d);\n", s.asAsyncMethod(g), s.asyncHandlerIndex())
+			} else {
+				g.errorf("gobind:async on %s.%s: the method must return an error", obj.Name(), m.Name())
+			}
+		}
 	}
 	g.Printf("@end\n")
 }
 
+// objcdoc emits a Go doc comment as a HeaderDoc block, prefixing every
+// line with " * " so Xcode Quick Help renders multi-line documentation
+// and paragraph breaks.
 func (g *ObjcGen) objcdoc(doc string) {
+	doc = strings.TrimRight(doc, "\n")
 	if doc == "" {
 		return
 	}
-	g.Printf("/**\n * %s */\n", doc)
+	g.Printf("/**\n")
+	for _, line := range strings.Split(doc, "\n") {
+		// A terminator inside the doc would end the block early.
+		line = strings.ReplaceAll(line, "*/", "* /")
+		line = strings.TrimRight(line, " \t")
+		if line == "" {
+			g.Printf(" *\n")
+			continue
+		}
+		g.Printf(" * %s\n", line)
+	}
+	g.Printf(" */\n")
 }
 
 func (g *ObjcGen) genStructM(obj *types.TypeName, t *types.Struct) {
@@ -1190,6 +1519,10 @@ func (g *ObjcGen) genStructM(obj *types.TypeName, t *types.Struct) {
 	}
 
 	for _, f := range fields {
+		if nt, ok := embeddedBoundStruct(f); ok && g.validPkg(nt.Obj().Pkg()) {
+			g.genGetter(obj.Name(), types.NewVar(f.Pos(), f.Pkg(), f.Name(), types.NewPointer(f.Type())))
+			continue
+		}
 		if !g.isSupported(f.Type()) {
 			g.Printf("// skipped unsupported field %s with type %s\n\n", f.Name(), f.Type())
 			continue
@@ -1209,6 +1542,13 @@ func (g *ObjcGen) genStructM(obj *types.TypeName, t *types.Struct) {
 		g.genFunc(s, obj.Name())
 		g.Outdent()
 		g.Printf("}\n\n")
+		if g.docs[obj.Name()].AsyncMember(m.Name()) && s.asyncCompatible() {
+			g.Printf("- %s {\n", s.asAsyncMethod(g))
+			g.Indent()
+			g.genAsyncBody(s, obj.Name())
+			g.Outdent()
+			g.Printf("}\n\n")
+		}
 	}
 	g.Printf("@end\n\n")
 }
@@ -1344,6 +1684,12 @@ func (g *ObjcGen) objcType(typ types.Type) string {
 			return "TODO"
 		}
 	case *types.Slice:
+		if isByteSliceSlice(typ) {
+			return "NSArray<NSData *>* _Nullable"
+		}
+		if isStringSlice(typ) {
+			return "NSArray<NSString *>* _Nullable"
+		}
 		elem := g.objcType(typ.Elem())
 		// Special case: NSData seems to be a better option for byte slice.
 		if elem == "byte" {
@@ -1353,6 +1699,12 @@ func (g *ObjcGen) objcType(typ types.Type) string {
 		// Investigate the performance implication.
 		g.errorf("unsupported type: %s", typ)
 		return "TODO"
+	case *types.Map:
+		if isStringMap(typ) {
+			return "NSDictionary<NSString *, NSString *>* _Nullable"
+		}
+		g.errorf("unsupported type: %s", typ)
+		return "TODO"
 	case *types.Pointer:
 		if _, ok := typ.Elem().(*types.Named); ok {
 			return g.objcType(typ.Elem()) + "* _Nullable"
@@ -0,0 +1,533 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bind
+
+import (
+	"fmt"
+	"go/types"
+	"strings"
+)
+
+// PhpGen generates the PHP bindings selected with -lang=php: a single
+// file using the FFI extension that cdefs the C symbols exported by
+// the c-shared library and wraps structs and interfaces in classes
+// holding refnums. Go errors are translated to GoError exceptions and
+// strings and byte slices are copied across the seq value structs.
+// The shared library path defaults to lib<pkg>.so and can be
+// overridden with the <PKG>_LIBRARY environment variable. Implementing
+// Go interfaces from PHP is not supported.
+type PhpGen struct {
+	*Generator
+}
+
+// GenPhp generates the PHP source.
+func (g *PhpGen) GenPhp() error {
+	pkgName := "universe"
+	pkgPath := ""
+	if g.Pkg != nil {
+		pkgName = g.Pkg.Name()
+		pkgPath = g.Pkg.Path()
+	}
+	nsName := strings.ToUpper(pkgName[:1]) + pkgName[1:]
+	g.Printf("<?php\n")
+	g.Printf("// Code generated by gobind. DO NOT EDIT.\n\n")
+	g.Printf("// PHP bindings for the Go package %s.\n\n", pkgPath)
+	g.Printf("declare(strict_types=1);\n\n")
+	g.Printf("namespace %s;\n\n", nsName)
+
+	g.Printf("// Bridge loads the shared library and holds the conversion\n")
+	g.Printf("// helpers shared by the generated wrappers.\n")
+	g.Printf("final class Bridge\n{\n")
+	g.Indent()
+	g.Printf("private static ?\\FFI $ffi = null;\n\n")
+	g.Printf("public static function ffi(): \\FFI\n{\n")
+	g.Printf("	if (self::$ffi === null) {\n")
+	g.Printf("		$lib = getenv(%q) ?: %q;\n", strings.ToUpper(pkgName)+"_LIBRARY", "lib"+pkgName+".so")
+	g.Printf("		self::$ffi = \\FFI::cdef(self::CDEF, $lib);\n")
+	g.Printf("	}\n")
+	g.Printf("	return self::$ffi;\n")
+	g.Printf("}\n\n")
+	g.genPhpCdef()
+	g.Printf("%s", phpSupport)
+	g.Outdent()
+	g.Printf("}\n\n")
+
+	g.Printf("// The refnum a nil Go object or error crosses the bridge as.\n")
+	g.Printf("const NULL_REFNUM = 41;\n\n")
+	g.Printf("// GoError is thrown when a bound Go function returns a non-nil\n")
+	g.Printf("// error. The refnum identifies the Go error value on the Go side\n")
+	g.Printf("// of the bridge.\n")
+	g.Printf("class GoError extends \\RuntimeException\n{\n")
+	g.Printf("	public int $refnum;\n\n")
+	g.Printf("	public function __construct(int $refnum)\n\t{\n")
+	g.Printf("		$this->refnum = $refnum;\n")
+	g.Printf("		parent::__construct(\"go error (refnum $refnum)\");\n")
+	g.Printf("	}\n")
+	g.Printf("}\n\n")
+
+	for _, c := range g.constants {
+		if _, ok := c.Type().(*types.Basic); !ok {
+			continue
+		}
+		g.phpdoc(g.docs[c.Name()].Doc())
+		g.Printf("const %s = %s;\n", strings.ToUpper(pySnake(c.Name())), c.Val().ExactString())
+	}
+	if len(g.constants) > 0 {
+		g.Printf("\n")
+	}
+
+	for _, s := range g.structs {
+		g.genPhpStruct(s)
+	}
+	for _, i := range g.interfaces {
+		g.genPhpInterface(i)
+	}
+
+	for _, v := range g.vars {
+		g.genPhpVar(v)
+	}
+
+	for _, f := range g.funcs {
+		if !g.isPhpSigSupported(f.Type()) {
+			g.Printf("// skipped function %s with unsupported parameter or result types\n\n", f.Name())
+			continue
+		}
+		symbol := fmt.Sprintf("proxy%s__%s", g.pkgPrefix, f.Name())
+		g.genPhpFunc(f, lowerFirst(f.Name()), g.docs[f.Name()].Doc(), symbol, "", false)
+	}
+	if len(g.err) > 0 {
+		return g.err
+	}
+	return nil
+}
+
+// genPhpCdef emits the CDEF constant declaring the exported symbols.
+func (g *PhpGen) genPhpCdef() {
+	g.Printf("private const CDEF = <<<'CDEF'\n")
+	g.Printf("typedef int64_t nint;\n")
+	g.Printf("typedef struct nstring { void *ptr; int len; } nstring;\n")
+	g.Printf("typedef struct nbyteslice { void *ptr; int len; } nbyteslice;\n")
+	g.Printf("void *malloc(size_t size);\n")
+	g.Printf("void free(void *ptr);\n")
+	g.Printf("void DestroyRef(int32_t refnum);\n")
+	g.Printf("void IncGoRef(int32_t refnum);\n")
+	g.Printf("void ReleaseByteSliceRef(void *ptr);\n")
+	for _, f := range g.funcs {
+		if !g.isPhpSigSupported(f.Type()) {
+			continue
+		}
+		g.genPhpCdecl(fmt.Sprintf("proxy%s__%s", g.pkgPrefix, f.Name()), f.Type().(*types.Signature), false)
+	}
+	for _, v := range g.vars {
+		t, ok := g.phpType(v.Type())
+		if !ok {
+			continue
+		}
+		g.Printf("%s var_get%s_%s();\n", t.c, g.pkgPrefix, v.Name())
+		g.Printf("void var_set%s_%s(%s v);\n", g.pkgPrefix, v.Name(), t.c)
+	}
+	for _, s := range g.structs {
+		n := s.obj.Name()
+		g.Printf("int32_t new_%s_%s();\n", g.pkgPrefix, n)
+		for _, f := range exportedFields(s.t) {
+			t, ok := g.phpType(f.Type())
+			if !ok {
+				continue
+			}
+			g.Printf("%s proxy%s_%s_%s_Get(int32_t refnum);\n", t.c, g.pkgPrefix, n, f.Name())
+			g.Printf("void proxy%s_%s_%s_Set(int32_t refnum, %s v);\n", g.pkgPrefix, n, f.Name(), t.c)
+		}
+		for _, m := range exportedMethodSet(types.NewPointer(s.obj.Type())) {
+			if !g.isPhpSigSupported(m.Type()) {
+				continue
+			}
+			g.genPhpCdecl(fmt.Sprintf("proxy%s_%s_%s", g.pkgPrefix, n, m.Name()), m.Type().(*types.Signature), true)
+		}
+	}
+	for _, i := range g.interfaces {
+		for _, m := range makeIfaceSummary(i.t).callable {
+			if !g.isPhpSigSupported(m.Type()) {
+				continue
+			}
+			g.genPhpCdecl(fmt.Sprintf("proxy%s_%s_%s", g.pkgPrefix, i.obj.Name(), m.Name()), m.Type().(*types.Signature), true)
+		}
+	}
+	g.Printf("CDEF;\n\n")
+}
+
+// genPhpCdecl emits the C declaration for symbol, including a return
+// struct typedef when the function also returns an error.
+func (g *PhpGen) genPhpCdecl(symbol string, sig *types.Signature, method bool) {
+	params := sig.Params()
+	res := sig.Results()
+	var args []string
+	if method {
+		args = append(args, "int32_t refnum")
+	}
+	for i := 0; i < params.Len(); i++ {
+		t, _ := g.phpType(params.At(i).Type())
+		args = append(args, fmt.Sprintf("%s %s", t.c, basicParamName(params, i)))
+	}
+	ret := "void"
+	switch res.Len() {
+	case 1:
+		t, _ := g.phpType(res.At(0).Type())
+		ret = t.c
+	case 2:
+		t0, _ := g.phpType(res.At(0).Type())
+		t1, _ := g.phpType(res.At(1).Type())
+		g.Printf("typedef struct ret_%[1]s { %[2]s r0; %[3]s r1; } ret_%[1]s;\n", symbol, t0.c, t1.c)
+		ret = fmt.Sprintf("ret_%s", symbol)
+	}
+	g.Printf("%s %s(%s);\n", ret, symbol, strings.Join(args, ", "))
+}
+
+func (g *PhpGen) genPhpVar(o *types.Var) {
+	t, ok := g.phpType(o.Type())
+	if !ok {
+		g.Printf("// skipped variable %s with unsupported type %s\n\n", o.Name(), o.Type())
+		return
+	}
+	g.phpdoc(g.docs[o.Name()].Doc())
+	g.Printf("function get%s(): %s\n{\n", o.Name(), t.php)
+	g.Printf("	return %s;\n", fmt.Sprintf(t.fromFfi, fmt.Sprintf("Bridge::ffi()->var_get%s_%s()", g.pkgPrefix, o.Name())))
+	g.Printf("}\n\n")
+	g.Printf("function set%s(%s $v): void\n{\n", o.Name(), t.php)
+	g.Printf("	Bridge::ffi()->var_set%s_%s(%s);\n", g.pkgPrefix, o.Name(), fmt.Sprintf(t.toFfi, "$v"))
+	g.Printf("}\n\n")
+}
+
+func (g *PhpGen) genPhpStruct(s structInfo) {
+	n := s.obj.Name()
+	doc := g.docs[n]
+	g.phpdoc(typeDoc(doc))
+	g.Printf("class %s\n{\n", n)
+	g.Indent()
+	g.Printf("public int $refnum;\n\n")
+	g.Printf("public function __construct(?int $refnum = null)\n{\n")
+	g.Printf("	$this->refnum = $refnum ?? Bridge::ffi()->new_%s_%s();\n", g.pkgPrefix, n)
+	g.Printf("}\n\n")
+	g.genPhpDestroy()
+	for _, f := range exportedFields(s.t) {
+		t, ok := g.phpType(f.Type())
+		if !ok {
+			g.Printf("// skipped field %s with unsupported type %s\n\n", f.Name(), f.Type())
+			continue
+		}
+		g.phpdoc(doc.Member(f.Name()))
+		g.Printf("public function get%s(): %s\n{\n", f.Name(), t.php)
+		g.Printf("	return %s;\n", fmt.Sprintf(t.fromFfi, fmt.Sprintf("Bridge::ffi()->proxy%s_%s_%s_Get(Bridge::incref($this->refnum))", g.pkgPrefix, n, f.Name())))
+		g.Printf("}\n\n")
+		g.Printf("public function set%s(%s $v): void\n{\n", f.Name(), t.php)
+		g.Printf("	Bridge::ffi()->proxy%s_%s_%s_Set(Bridge::incref($this->refnum), %s);\n", g.pkgPrefix, n, f.Name(), fmt.Sprintf(t.toFfi, "$v"))
+		g.Printf("}\n\n")
+	}
+	for _, m := range exportedMethodSet(types.NewPointer(s.obj.Type())) {
+		if !g.isPhpSigSupported(m.Type()) {
+			g.Printf("// skipped method %s.%s with unsupported parameter or result types\n\n", n, m.Name())
+			continue
+		}
+		symbol := fmt.Sprintf("proxy%s_%s_%s", g.pkgPrefix, n, m.Name())
+		g.genPhpFunc(m, lowerFirst(m.Name()), doc.Member(m.Name()), symbol, g.phpRecv(m), true)
+	}
+	g.Outdent()
+	g.Printf("}\n\n")
+}
+
+func (g *PhpGen) genPhpInterface(i interfaceInfo) {
+	n := i.obj.Name()
+	doc := g.docs[n]
+	g.phpdoc(typeDoc(doc))
+	g.Printf("// Proxy for a Go value implementing the interface; it cannot\n")
+	g.Printf("// be implemented from PHP.\n")
+	g.Printf("class %s\n{\n", n)
+	g.Indent()
+	g.Printf("public int $refnum;\n\n")
+	g.Printf("public function __construct(int $refnum)\n{\n")
+	g.Printf("	$this->refnum = $refnum;\n")
+	g.Printf("}\n\n")
+	g.genPhpDestroy()
+	for _, m := range makeIfaceSummary(i.t).callable {
+		if !g.isPhpSigSupported(m.Type()) {
+			g.Printf("// skipped method %s.%s with unsupported parameter or result types\n\n", n, m.Name())
+			continue
+		}
+		symbol := fmt.Sprintf("proxy%s_%s_%s", g.pkgPrefix, i.obj.Name(), m.Name())
+		g.genPhpFunc(m, lowerFirst(m.Name()), doc.Member(m.Name()), symbol, g.phpRecv(m), true)
+	}
+	g.Outdent()
+	g.Printf("}\n\n")
+}
+
+func (g *PhpGen) genPhpDestroy() {
+	g.Printf("// Releases the Go object backing this proxy.\n")
+	g.Printf("public function destroy(): void\n{\n")
+	g.Printf("	Bridge::ffi()->DestroyRef($this->refnum);\n")
+	g.Printf("}\n\n")
+}
+
+// phpRecv returns the receiver refnum expression for m. Fastpath
+// methods peek at the receiver without consuming a reference.
+func (g *PhpGen) phpRecv(m *types.Func) string {
+	if g.isFastpath(m) {
+		return "$this->refnum"
+	}
+	return "Bridge::incref($this->refnum)"
+}
+
+// genPhpFunc emits the wrapper for symbol as a namespaced function or,
+// when recvArg is non-empty, as a public class method taking that
+// receiver refnum expression.
+func (g *PhpGen) genPhpFunc(f *types.Func, name, doc, symbol string, recvArg string, public bool) {
+	sig := f.Type().(*types.Signature)
+	params := sig.Params()
+	res := sig.Results()
+	var decl, args []string
+	if recvArg != "" {
+		args = append(args, recvArg)
+	}
+	for i := 0; i < params.Len(); i++ {
+		t, _ := g.phpType(params.At(i).Type())
+		pn := "$" + lowerFirst(basicParamName(params, i))
+		decl = append(decl, fmt.Sprintf("%s %s", t.php, pn))
+		args = append(args, fmt.Sprintf(t.toFfi, pn))
+	}
+	retIdx := -1
+	throws := false
+	switch res.Len() {
+	case 1:
+		if isErrorType(res.At(0).Type()) {
+			throws = true
+		} else {
+			retIdx = 0
+		}
+	case 2:
+		retIdx = 0
+		throws = true
+	}
+	retHint := "void"
+	if retIdx == 0 {
+		t, _ := g.phpType(res.At(0).Type())
+		retHint = t.php
+	}
+	g.phpdoc(doc)
+	qual := "function"
+	if public {
+		qual = "public function"
+	}
+	g.Printf("%s %s(%s): %s\n{\n", qual, name, strings.Join(decl, ", "), retHint)
+	g.Indent()
+	call := fmt.Sprintf("Bridge::ffi()->%s(%s)", symbol, strings.Join(args, ", "))
+	switch {
+	case throws && retIdx == 0:
+		retType := g.phpResultType(f, res.At(0).Type())
+		g.Printf("$r = %s;\n", call)
+		g.Printf("if ($r->r1 !== NULL_REFNUM) {\n")
+		g.Printf("	throw new GoError($r->r1);\n")
+		g.Printf("}\n")
+		g.Printf("return %s;\n", fmt.Sprintf(retType.fromFfi, "$r->r0"))
+	case throws:
+		g.Printf("$r = %s;\n", call)
+		g.Printf("if ($r !== NULL_REFNUM) {\n")
+		g.Printf("	throw new GoError($r);\n")
+		g.Printf("}\n")
+	case retIdx == 0:
+		retType := g.phpResultType(f, res.At(0).Type())
+		g.Printf("return %s;\n", fmt.Sprintf(retType.fromFfi, call))
+	default:
+		g.Printf("%s;\n", call)
+	}
+	g.Outdent()
+	g.Printf("}\n\n")
+}
+
+// phpResultType returns the type info for a result of f; the byte
+// slice results of //gobind:nocopy functions lend Go's backing array
+// and release the pin instead of freeing.
+func (g *PhpGen) phpResultType(f *types.Func, t types.Type) phpTypeInfo {
+	ti, _ := g.phpType(t)
+	if g.isNoCopy(f) && isByteSlice(t) {
+		ti.fromFfi = "Bridge::fromNByteSliceNoCopy(%s)"
+	}
+	return ti
+}
+
+func (g *PhpGen) phpdoc(doc string) {
+	if doc == "" {
+		return
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(doc, "\n"), "\n") {
+		g.Printf("// %s\n", strings.TrimRight(line, " "))
+	}
+}
+
+// phpTypeInfo describes how a Go type crosses the FFI bridge: the cdef
+// C type, the PHP type hint and the conversions between PHP values and
+// the FFI representation (with %s substituted by the value).
+type phpTypeInfo struct {
+	c       string
+	php     string
+	toFfi   string
+	fromFfi string
+}
+
+func (g *PhpGen) phpType(t types.Type) (phpTypeInfo, bool) {
+	num := func(c, php string) (phpTypeInfo, bool) {
+		return phpTypeInfo{c: c, php: php, toFfi: "%s", fromFfi: "%s"}, true
+	}
+	switch t := t.(type) {
+	case *types.Basic:
+		switch t.Kind() {
+		case types.Bool, types.UntypedBool:
+			return phpTypeInfo{c: "char", php: "bool", toFfi: "(%s ? 1 : 0)", fromFfi: "%s !== 0"}, true
+		case types.Int, types.UntypedInt, types.UntypedRune:
+			return num("nint", "int")
+		case types.Int8:
+			return num("int8_t", "int")
+		case types.Int16:
+			return num("int16_t", "int")
+		case types.Int32:
+			return num("int32_t", "int")
+		case types.Int64:
+			return num("int64_t", "int")
+		case types.Uint8:
+			return num("uint8_t", "int")
+		case types.Float32:
+			return num("float", "float")
+		case types.Float64, types.UntypedFloat:
+			return num("double", "float")
+		case types.String, types.UntypedString:
+			return phpTypeInfo{
+				c:       "nstring",
+				php:     "string",
+				toFfi:   "Bridge::toNString(%s)",
+				fromFfi: "Bridge::fromNString(%s)",
+			}, true
+		}
+	case *types.Slice:
+		if e, ok := t.Elem().(*types.Basic); ok && e.Kind() == types.Uint8 {
+			return phpTypeInfo{
+				c:       "nbyteslice",
+				php:     "string",
+				toFfi:   "Bridge::toNByteSlice(%s)",
+				fromFfi: "Bridge::fromNByteSlice(%s)",
+			}, true
+		}
+	case *types.Pointer:
+		if n, ok := t.Elem().(*types.Named); ok {
+			return g.phpNamedType(n)
+		}
+	case *types.Named:
+		if isErrorType(t) {
+			return num("int32_t", "int")
+		}
+		return g.phpNamedType(t)
+	}
+	return phpTypeInfo{}, false
+}
+
+func (g *PhpGen) phpNamedType(t *types.Named) (phpTypeInfo, bool) {
+	n := t.Obj()
+	if n.Pkg() != g.Pkg {
+		return phpTypeInfo{}, false
+	}
+	return phpTypeInfo{
+		c:       "int32_t",
+		php:     n.Name(),
+		toFfi:   "Bridge::incref(%s->refnum)",
+		fromFfi: fmt.Sprintf("new %s(%%s)", n.Name()),
+	}, true
+}
+
+// isPhpSigSupported reports whether the PHP generator can handle the
+// signature.
+func (g *PhpGen) isPhpSigSupported(t types.Type) bool {
+	if !g.isSigSupported(t) {
+		return false
+	}
+	sig := t.(*types.Signature)
+	params := sig.Params()
+	for i := 0; i < params.Len(); i++ {
+		if _, ok := g.phpType(params.At(i).Type()); !ok {
+			return false
+		}
+	}
+	res := sig.Results()
+	for i := 0; i < res.Len(); i++ {
+		if _, ok := g.phpType(res.At(i).Type()); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+const phpSupport = `// Pins the Go object before its refnum crosses into Go; the Go
+// side consumes one reference per crossing.
+public static function incref(int $refnum): int
+{
+	self::ffi()->IncGoRef($refnum);
+	return $refnum;
+}
+
+public static function toNString(string $s): \FFI\CData
+{
+	$ffi = self::ffi();
+	$ns = $ffi->new('nstring');
+	$len = strlen($s);
+	if ($len > 0) {
+		$buf = $ffi->malloc($len);
+		\FFI::memcpy($buf, $s, $len);
+		$ns->ptr = $buf;
+	}
+	$ns->len = $len;
+	return $ns;
+}
+
+public static function fromNString(\FFI\CData $ns): string
+{
+	if ($ns->ptr === null || $ns->len === 0) {
+		return '';
+	}
+	$s = \FFI::string($ns->ptr, $ns->len);
+	self::ffi()->free($ns->ptr);
+	return $s;
+}
+
+public static function toNByteSlice(string $b): \FFI\CData
+{
+	$ffi = self::ffi();
+	$bs = $ffi->new('nbyteslice');
+	$len = strlen($b);
+	if ($len > 0) {
+		$buf = $ffi->malloc($len);
+		\FFI::memcpy($buf, $b, $len);
+		$bs->ptr = $buf;
+	}
+	$bs->len = $len;
+	return $bs;
+}
+
+public static function fromNByteSlice(\FFI\CData $bs): string
+{
+	if ($bs->ptr === null || $bs->len === 0) {
+		return '';
+	}
+	$b = \FFI::string($bs->ptr, $bs->len);
+	self::ffi()->free($bs->ptr);
+	return $b;
+}
+
+// Results of //gobind:nocopy functions lend Go's backing array;
+// release the pin instead of freeing.
+public static function fromNByteSliceNoCopy(\FFI\CData $bs): string
+{
+	if ($bs->ptr === null || $bs->len === 0) {
+		return '';
+	}
+	$b = \FFI::string($bs->ptr, $bs->len);
+	self::ffi()->ReleaseByteSliceRef($bs->ptr);
+	return $b;
+}
+`
@@ -0,0 +1,509 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bind
+
+import (
+	"fmt"
+	"go/types"
+	"strings"
+	"unicode"
+)
+
+// PythonGen generates the ctypes-based Python bindings selected with
+// -lang=python. The bindings call the proxy functions exported by the
+// generated Go stubs from a c-shared library, mirroring structs and
+// interfaces as Python classes and raising GoError for Go errors.
+// Strings cross the bridge as UTF-8 and byte slices as bytes;
+// implementing Go interfaces from Python is not supported.
+type PythonGen struct {
+	*Generator
+}
+
+// GenPython generates the Python module for the package.
+func (g *PythonGen) GenPython() error {
+	var pkgPath, pkgName string
+	if g.Pkg != nil {
+		pkgPath = g.Pkg.Path()
+		pkgName = g.Pkg.Name()
+	} else {
+		pkgName = "universe"
+	}
+	g.Printf("# Code generated by gobind. DO NOT EDIT.\n\n")
+	g.Printf("\"\"\"Python bindings for the Go package %s, generated by gobind.\n\n", pkgPath)
+	g.Printf("Build the generated Go stubs with -buildmode=c-shared and load the\n")
+	g.Printf("resulting library before using the module:\n\n")
+	g.Printf("    import %s\n", pkgName)
+	g.Printf("    %s.load(\"./lib%s.so\")\n\n", pkgName, pkgName)
+	g.Printf("Go errors are raised as GoError. Implementing Go interfaces from\n")
+	g.Printf("Python is not supported.\n\"\"\"\n\n")
+	g.Printf("import ctypes\n")
+	g.Printf("import weakref\n\n")
+	g.Printf("%s", pySupport)
+
+	for _, c := range g.constants {
+		if _, ok := c.Type().(*types.Basic); !ok {
+			g.Printf("# skipped const %s with unsupported type: %s\n\n", c.Name(), c.Type())
+			continue
+		}
+		g.Printf("%s = %s\n", strings.ToUpper(pySnake(c.Name())), c.Val().ExactString())
+	}
+	if len(g.constants) > 0 {
+		g.Printf("\n")
+	}
+
+	for _, v := range g.vars {
+		g.genPyVar(v)
+	}
+	for _, s := range g.structs {
+		g.genPyStruct(s)
+	}
+	for _, i := range g.interfaces {
+		g.genPyInterface(i)
+	}
+	for _, f := range g.funcs {
+		if !g.isPySigSupported(f.Type()) {
+			g.Printf("# skipped function %s with unsupported parameter or result types\n\n", f.Name())
+			continue
+		}
+		g.genPyFunc(f, "", fmt.Sprintf("proxy%s__%s", g.pkgPrefix, f.Name()), 0)
+	}
+	if len(g.err) > 0 {
+		return g.err
+	}
+	return nil
+}
+
+// GenPyProject generates the pyproject.toml making the bindings pip
+// installable.
+func (g *PythonGen) GenPyProject() error {
+	var pkgPath, pkgName string
+	if g.Pkg != nil {
+		pkgPath = g.Pkg.Path()
+		pkgName = g.Pkg.Name()
+	}
+	g.Printf("# Code generated by gobind. DO NOT EDIT.\n\n")
+	g.Printf("[project]\n")
+	g.Printf("name = %q\n", pkgName)
+	g.Printf("version = \"0.0.1\"\n")
+	g.Printf("description = \"Python bindings for the Go package %s, generated by gobind.\"\n\n", pkgPath)
+	g.Printf("[build-system]\n")
+	g.Printf("requires = [\"setuptools\"]\n")
+	g.Printf("build-backend = \"setuptools.build_meta\"\n")
+	return nil
+}
+
+func (g *PythonGen) genPyVar(o *types.Var) {
+	t, ok := g.pyType(o.Type())
+	if !ok {
+		g.Printf("# skipped variable %s with unsupported type: %s\n\n", o.Name(), o.Type())
+		return
+	}
+	n := pySnake(o.Name())
+	g.Printf("def get_%s():\n", n)
+	g.Printf("	\"\"\"Returns the value of the Go package variable %s.\"\"\"\n", o.Name())
+	g.Printf("	r = _fn(\"var_get%s_%s\", %s, [])()\n", g.pkgPrefix, o.Name(), t.ctype)
+	g.Printf("	return %s\n\n", fmt.Sprintf(t.fromC, "r"))
+	g.Printf("def set_%s(v):\n", n)
+	g.Printf("	\"\"\"Sets the Go package variable %s.\"\"\"\n", o.Name())
+	g.Printf("	_fn(\"var_set%s_%s\", None, [%s])(%s)\n\n", g.pkgPrefix, o.Name(), t.ctype, fmt.Sprintf(t.toC, "v"))
+}
+
+func (g *PythonGen) genPyStruct(s structInfo) {
+	n := s.obj.Name()
+	doc := g.docs[n]
+	for _, m := range exportedMethodSet(types.NewPointer(s.obj.Type())) {
+		if g.isPySigSupported(m.Type()) {
+			g.genPyRetStruct(m, fmt.Sprintf("proxy%s_%s_%s", g.pkgPrefix, n, m.Name()))
+		}
+	}
+	g.Printf("class %s(_GoObject):\n", n)
+	g.Indent()
+	g.genPyDocstring(doc.Doc(), "Proxy for the Go struct "+n+".")
+	g.Printf("def __init__(self, _refnum=None):\n")
+	g.Printf("	if _refnum is None:\n")
+	g.Printf("		_refnum = _fn(\"new_%s_%s\", ctypes.c_int32, [])()\n", g.pkgPrefix, n)
+	g.Printf("	_GoObject.__init__(self, _refnum)\n\n")
+	for _, f := range exportedFields(s.t) {
+		t, ok := g.pyType(f.Type())
+		if !ok {
+			g.Printf("# skipped field %s with unsupported type: %s\n\n", f.Name(), f.Type())
+			continue
+		}
+		fn := pySnake(f.Name())
+		g.Printf("@property\n")
+		g.Printf("def %s(self):\n", fn)
+		g.Printf("	r = _fn(\"proxy%s_%s_%s_Get\", %s, [ctypes.c_int32])(_incref(self._refnum))\n", g.pkgPrefix, n, f.Name(), t.ctype)
+		g.Printf("	return %s\n\n", fmt.Sprintf(t.fromC, "r"))
+		g.Printf("@%s.setter\n", fn)
+		g.Printf("def %s(self, v):\n", fn)
+		g.Printf("	_fn(\"proxy%s_%s_%s_Set\", None, [ctypes.c_int32, %s])(_incref(self._refnum), %s)\n\n",
+			g.pkgPrefix, n, f.Name(), t.ctype, fmt.Sprintf(t.toC, "v"))
+	}
+	for _, m := range exportedMethodSet(types.NewPointer(s.obj.Type())) {
+		if !g.isPySigSupported(m.Type()) {
+			g.Printf("# skipped method %s.%s with unsupported parameter or result types\n\n", n, m.Name())
+			continue
+		}
+		g.genPyFunc(m, doc.Member(m.Name()), fmt.Sprintf("proxy%s_%s_%s", g.pkgPrefix, n, m.Name()), 1)
+	}
+	g.Outdent()
+	g.Printf("\n")
+}
+
+func (g *PythonGen) genPyInterface(i interfaceInfo) {
+	n := i.obj.Name()
+	doc := g.docs[n]
+	for _, m := range i.summary.callable {
+		if g.isPySigSupported(m.Type()) {
+			g.genPyRetStruct(m, fmt.Sprintf("proxy%s_%s_%s", g.pkgPrefix, n, m.Name()))
+		}
+	}
+	g.Printf("class %s(_GoObject):\n", n)
+	g.Indent()
+	g.genPyDocstring(doc.Doc(), "Proxy for Go objects implementing the "+n+" interface.")
+	g.Printf("def __init__(self, _refnum):\n")
+	g.Printf("	_GoObject.__init__(self, _refnum)\n\n")
+	for _, m := range i.summary.callable {
+		if !g.isPySigSupported(m.Type()) {
+			g.Printf("# skipped method %s.%s with unsupported parameter or result types\n\n", n, m.Name())
+			continue
+		}
+		g.genPyFunc(m, doc.Member(m.Name()), fmt.Sprintf("proxy%s_%s_%s", g.pkgPrefix, n, m.Name()), 1)
+	}
+	g.Outdent()
+	g.Printf("\n")
+}
+
+// genPyFunc generates a wrapper for f calling the exported symbol. The
+// indirection level indir is 0 for package functions and 1 for methods,
+// which pass the receiver refnum as the first C argument.
+func (g *PythonGen) genPyFunc(f *types.Func, doc, symbol string, indir int) {
+	sig := f.Type().(*types.Signature)
+	params := sig.Params()
+	res := sig.Results()
+
+	var decl, argts, args []string
+	if indir > 0 {
+		decl = append(decl, "self")
+		argts = append(argts, "ctypes.c_int32")
+		if g.isFastpath(f) {
+			// Fastpath methods peek at the receiver without
+			// consuming a reference.
+			args = append(args, "self._refnum")
+		} else {
+			args = append(args, "_incref(self._refnum)")
+		}
+	}
+	for i := 0; i < params.Len(); i++ {
+		t, _ := g.pyType(params.At(i).Type())
+		pn := pySnake(basicParamName(params, i))
+		decl = append(decl, pn)
+		argts = append(argts, t.ctype)
+		args = append(args, fmt.Sprintf(t.toC, pn))
+	}
+
+	name := pySnake(f.Name())
+	if doc == "" {
+		doc = g.docs[f.Name()].Doc()
+	}
+	restype := "None"
+	noCopy := g.isNoCopy(f)
+	var rets []pyTypeInfo
+	for i := 0; i < res.Len(); i++ {
+		t, _ := g.pyType(res.At(i).Type())
+		if noCopy && isByteSlice(res.At(i).Type()) {
+			t.fromC = "_from_nbytes_nocopy(%s)"
+		}
+		rets = append(rets, t)
+	}
+	switch len(rets) {
+	case 1:
+		restype = rets[0].ctype
+	case 2:
+		if indir == 0 {
+			g.genPyRetStruct(f, symbol)
+		}
+		restype = "_Ret_" + symbol
+	}
+
+	g.Printf("def %s(%s):\n", name, strings.Join(decl, ", "))
+	g.Indent()
+	g.genPyDocstring(doc, "")
+	g.Printf("r = _fn(%q, %s, [%s])(%s)\n", symbol, restype, strings.Join(argts, ", "), strings.Join(args, ", "))
+	switch {
+	case len(rets) == 2:
+		g.Printf("if r.r1 != _NULL_REFNUM:\n")
+		g.Printf("	raise GoError(r.r1)\n")
+		g.Printf("return %s\n", fmt.Sprintf(rets[0].fromC, "r.r0"))
+	case len(rets) == 1 && isErrorType(res.At(0).Type()):
+		g.Printf("if r != _NULL_REFNUM:\n")
+		g.Printf("	raise GoError(r)\n")
+	case len(rets) == 1:
+		g.Printf("return %s\n", fmt.Sprintf(rets[0].fromC, "r"))
+	default:
+		g.Printf("pass\n")
+	}
+	g.Outdent()
+	g.Printf("\n")
+}
+
+// genPyRetStruct generates the module level ctypes structure mirroring
+// the multi-result return struct of the exported symbol, if f has more
+// than one result. Methods need it emitted before their class body;
+// names inside a class body are not visible from method scope.
+func (g *PythonGen) genPyRetStruct(f *types.Func, symbol string) {
+	res := f.Type().(*types.Signature).Results()
+	if res.Len() < 2 {
+		return
+	}
+	r0, _ := g.pyType(res.At(0).Type())
+	r1, _ := g.pyType(res.At(1).Type())
+	g.Printf("class _Ret_%s(ctypes.Structure):\n", symbol)
+	g.Printf("	_fields_ = [(\"r0\", %s), (\"r1\", %s)]\n\n", r0.ctype, r1.ctype)
+}
+
+func (g *PythonGen) genPyDocstring(doc, fallback string) {
+	if doc == "" {
+		doc = fallback
+	}
+	if doc == "" {
+		return
+	}
+	doc = strings.TrimSpace(strings.ReplaceAll(doc, "\"\"\"", "'''"))
+	if strings.Contains(doc, "\n") {
+		g.Printf("\"\"\"%s\n\"\"\"\n", doc)
+	} else {
+		g.Printf("\"\"\"%s\"\"\"\n", doc)
+	}
+}
+
+// pyTypeInfo describes how a Go type crosses the ctypes bridge: the
+// ctypes spelling of the C value and the conversion expressions for
+// each direction, with %s substituted by the value.
+type pyTypeInfo struct {
+	ctype string
+	toC   string
+	fromC string
+}
+
+func (g *PythonGen) pyType(t types.Type) (pyTypeInfo, bool) {
+	plain := func(ct string) (pyTypeInfo, bool) {
+		return pyTypeInfo{ctype: ct, toC: "%s", fromC: "%s"}, true
+	}
+	switch t := t.(type) {
+	case *types.Basic:
+		switch t.Kind() {
+		case types.Bool, types.UntypedBool:
+			return pyTypeInfo{ctype: "ctypes.c_int8", toC: "(1 if %s else 0)", fromC: "(%s != 0)"}, true
+		case types.Int:
+			return plain("ctypes.c_int64") // nint
+		case types.Int8:
+			return plain("ctypes.c_int8")
+		case types.Int16:
+			return plain("ctypes.c_int16")
+		case types.Int32, types.UntypedRune:
+			return plain("ctypes.c_int32")
+		case types.Int64, types.UntypedInt:
+			return plain("ctypes.c_int64")
+		case types.Uint8:
+			return plain("ctypes.c_uint8")
+		case types.Float32:
+			return plain("ctypes.c_float")
+		case types.Float64, types.UntypedFloat:
+			return plain("ctypes.c_double")
+		case types.String, types.UntypedString:
+			return pyTypeInfo{ctype: "_NString", toC: "_to_nstring(%s)", fromC: "_from_nstring(%s)"}, true
+		}
+	case *types.Slice:
+		if e, ok := t.Elem().(*types.Basic); ok && e.Kind() == types.Uint8 {
+			return pyTypeInfo{ctype: "_NByteSlice", toC: "_to_nbytes(%s)", fromC: "_from_nbytes(%s)"}, true
+		}
+	case *types.Pointer:
+		if n, ok := t.Elem().(*types.Named); ok {
+			return g.pyNamedType(n)
+		}
+	case *types.Named:
+		if isErrorType(t) {
+			return plain("ctypes.c_int32")
+		}
+		return g.pyNamedType(t)
+	}
+	return pyTypeInfo{}, false
+}
+
+func (g *PythonGen) pyNamedType(t *types.Named) (pyTypeInfo, bool) {
+	n := t.Obj()
+	if n.Pkg() != g.Pkg {
+		return pyTypeInfo{}, false
+	}
+	return pyTypeInfo{
+		ctype: "ctypes.c_int32",
+		toC:   "_incref(%s._refnum)",
+		fromC: n.Name() + "(_refnum=%s)",
+	}, true
+}
+
+// isPySigSupported reports whether the Python generator can handle the
+// signature, which is stricter than isSigSupported: foreign types and
+// byte slice slices are not bridged.
+func (g *PythonGen) isPySigSupported(t types.Type) bool {
+	if !g.isSigSupported(t) {
+		return false
+	}
+	sig := t.(*types.Signature)
+	params := sig.Params()
+	for i := 0; i < params.Len(); i++ {
+		if _, ok := g.pyType(params.At(i).Type()); !ok {
+			return false
+		}
+	}
+	res := sig.Results()
+	for i := 0; i < res.Len(); i++ {
+		if _, ok := g.pyType(res.At(i).Type()); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// pySnake converts an exported Go name to Python snake_case.
+func pySnake(s string) string {
+	var b strings.Builder
+	var prev rune
+	for i, r := range s {
+		if unicode.IsUpper(r) {
+			if i > 0 && !unicode.IsUpper(prev) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+		prev = r
+	}
+	return b.String()
+}
+
+const pySupport = `_libc = ctypes.CDLL(None)
+_libc.free.argtypes = [ctypes.c_void_p]
+_libc.free.restype = None
+_libc.malloc.argtypes = [ctypes.c_size_t]
+_libc.malloc.restype = ctypes.c_void_p
+
+# The refnum a nil Go object or error crosses the bridge as.
+_NULL_REFNUM = 41
+
+_lib = None
+
+
+def load(path):
+	"""Loads the c-shared bindings library from path."""
+	global _lib
+	_lib = ctypes.CDLL(path)
+	return _lib
+
+
+def _fn(name, restype, argtypes):
+	if _lib is None:
+		raise RuntimeError("bindings library not loaded; call load() first")
+	f = getattr(_lib, name)
+	f.restype = restype
+	f.argtypes = argtypes
+	return f
+
+
+def _incref(refnum):
+	# Pin the Go object before its refnum crosses into Go; the Go
+	# side consumes one reference per crossing.
+	_fn("IncGoRef", None, [ctypes.c_int32])(refnum)
+	return refnum
+
+
+def _destroy_ref(refnum):
+	if _lib is not None:
+		_fn("DestroyRef", None, [ctypes.c_int32])(refnum)
+
+
+def _finalize(obj, refnum):
+	# Release the Go object when the proxy is collected. Nil objects
+	# cross the bridge as the null refnum, which is not tracked.
+	if refnum != _NULL_REFNUM:
+		weakref.finalize(obj, _destroy_ref, refnum)
+
+
+class _NString(ctypes.Structure):
+	_fields_ = [("ptr", ctypes.c_void_p), ("len", ctypes.c_int)]
+
+
+class _NByteSlice(ctypes.Structure):
+	_fields_ = [("ptr", ctypes.c_void_p), ("len", ctypes.c_int)]
+
+
+def _to_nstring(s):
+	b = s.encode("utf-8")
+	ns = _NString()
+	if len(b) == 0:
+		return ns
+	# The Go side takes ownership of the data and frees it.
+	ns.ptr = _libc.malloc(len(b))
+	ctypes.memmove(ns.ptr, b, len(b))
+	ns.len = len(b)
+	return ns
+
+
+def _from_nstring(ns):
+	if not ns.ptr or ns.len == 0:
+		return ""
+	b = ctypes.string_at(ns.ptr, ns.len)
+	_libc.free(ns.ptr)
+	return b.decode("utf-8")
+
+
+def _to_nbytes(b):
+	ns = _NByteSlice()
+	if b is None or len(b) == 0:
+		return ns
+	buf = ctypes.create_string_buffer(bytes(b), len(b))
+	ns._buf = buf  # keep the buffer alive for the duration of the call
+	ns.ptr = ctypes.cast(buf, ctypes.c_void_p)
+	ns.len = len(b)
+	return ns
+
+
+def _from_nbytes(ns):
+	if not ns.ptr or ns.len == 0:
+		return b""
+	b = ctypes.string_at(ns.ptr, ns.len)
+	_libc.free(ns.ptr)
+	return b
+
+
+def _from_nbytes_nocopy(ns):
+	# Results of //gobind:nocopy functions lend Go's backing array;
+	# release the pin instead of freeing.
+	if not ns.ptr or ns.len == 0:
+		return b""
+	b = ctypes.string_at(ns.ptr, ns.len)
+	_fn("ReleaseByteSliceRef", None, [ctypes.c_void_p])(ns.ptr)
+	return b
+
+
+class GoError(Exception):
+	"""A Go error returned through the bindings, identified by refnum."""
+
+	def __init__(self, refnum):
+		Exception.__init__(self, "go error (refnum %d)" % refnum)
+		self.refnum = refnum
+		_finalize(self, refnum)
+
+
+class _GoObject(object):
+	"""Base class for proxies of Go objects."""
+
+	def __init__(self, refnum):
+		self._refnum = refnum
+		_finalize(self, refnum)
+
+
+`
@@ -0,0 +1,549 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bind
+
+import (
+	"fmt"
+	"go/types"
+	"strings"
+)
+
+// RubyGen generates the Ruby gem selected with -lang=ruby: ffi-based
+// wrappers over the c-shared library with a class per struct and
+// interface, GoError exceptions for Go errors and copying String and
+// byte-String conversions across the seq value structs. The library
+// built by gomobile must be on the FFI library search path together
+// with a seq support implementation exporting DestroyRef. Implementing
+// Go interfaces from Ruby is not supported; interface proxies only
+// call back into Go.
+type RubyGen struct {
+	*Generator
+}
+
+// GenRuby generates the gem library source, lib/<pkg>.rb.
+func (g *RubyGen) GenRuby() error {
+	pkgName := g.rubyPkgName()
+	var pkgPath string
+	if g.Pkg != nil {
+		pkgPath = g.Pkg.Path()
+	}
+	g.Printf("# Code generated by gobind. DO NOT EDIT.\n\n")
+	g.Printf("# Ruby bindings for the Go package %s.\n\n", pkgPath)
+	g.Printf("require 'ffi'\n\n")
+	g.Printf("module %s\n", g.rubyModuleName())
+	g.Indent()
+	g.Printf("%s", rubySupport)
+
+	// Bridge module with the raw attached functions.
+	g.Printf("# Bridge attaches the C symbols exported by the Go library.\n")
+	g.Printf("module Bridge\n")
+	g.Indent()
+	g.Printf("extend FFI::Library\n")
+	g.Printf("ffi_lib %q\n\n", pkgName)
+	g.Printf("class NString < FFI::Struct\n")
+	g.Printf("	layout :ptr, :pointer, :len, :int\n")
+	g.Printf("end\n\n")
+	g.Printf("class NByteSlice < FFI::Struct\n")
+	g.Printf("	layout :ptr, :pointer, :len, :int\n")
+	g.Printf("end\n\n")
+	g.Printf("attach_function :DestroyRef, [:int32], :void\n")
+	g.Printf("attach_function :IncGoRef, [:int32], :void\n")
+	g.Printf("attach_function :ReleaseByteSliceRef, [:pointer], :void\n")
+
+	for _, f := range g.funcs {
+		if !g.isRubySigSupported(f.Type()) {
+			continue
+		}
+		g.genRubyAttach(fmt.Sprintf("proxy%s__%s", g.pkgPrefix, f.Name()), f.Type().(*types.Signature), false)
+	}
+	for _, v := range g.vars {
+		t, ok := g.rubyType(v.Type())
+		if !ok {
+			continue
+		}
+		g.Printf("attach_function :var_get%s_%s, [], %s\n", g.pkgPrefix, v.Name(), t.ffi)
+		g.Printf("attach_function :var_set%s_%s, [%s], :void\n", g.pkgPrefix, v.Name(), t.ffi)
+	}
+	for _, s := range g.structs {
+		n := s.obj.Name()
+		g.Printf("attach_function :new_%s_%s, [], :int32\n", g.pkgPrefix, n)
+		for _, f := range exportedFields(s.t) {
+			t, ok := g.rubyType(f.Type())
+			if !ok {
+				continue
+			}
+			g.Printf("attach_function :proxy%s_%s_%s_Get, [:int32], %s\n", g.pkgPrefix, n, f.Name(), t.ffi)
+			g.Printf("attach_function :proxy%s_%s_%s_Set, [:int32, %s], :void\n", g.pkgPrefix, n, f.Name(), t.ffi)
+		}
+		for _, m := range exportedMethodSet(types.NewPointer(s.obj.Type())) {
+			if !g.isRubySigSupported(m.Type()) {
+				continue
+			}
+			g.genRubyAttach(fmt.Sprintf("proxy%s_%s_%s", g.pkgPrefix, n, m.Name()), m.Type().(*types.Signature), true)
+		}
+	}
+	for _, i := range g.interfaces {
+		for _, m := range makeIfaceSummary(i.t).callable {
+			if !g.isRubySigSupported(m.Type()) {
+				continue
+			}
+			g.genRubyAttach(fmt.Sprintf("proxy%s_%s_%s", g.pkgPrefix, i.obj.Name(), m.Name()), m.Type().(*types.Signature), true)
+		}
+	}
+	g.Printf("%s", rubyBridgeSupport)
+	g.Outdent()
+	g.Printf("end\n\n")
+
+	for _, c := range g.constants {
+		if _, ok := c.Type().(*types.Basic); !ok {
+			continue
+		}
+		g.rubydoc(g.docs[c.Name()].Doc())
+		g.Printf("%s = %s\n", strings.ToUpper(pySnake(c.Name())), c.Val().ExactString())
+	}
+	if len(g.constants) > 0 {
+		g.Printf("\n")
+	}
+
+	for _, v := range g.vars {
+		g.genRubyVar(v)
+	}
+
+	for _, f := range g.funcs {
+		if !g.isRubySigSupported(f.Type()) {
+			g.Printf("# skipped function %s with unsupported parameter or result types\n\n", f.Name())
+			continue
+		}
+		symbol := fmt.Sprintf("proxy%s__%s", g.pkgPrefix, f.Name())
+		g.genRubyFunc(f, "self."+pySnake(f.Name()), g.docs[f.Name()].Doc(), symbol, "")
+	}
+
+	for _, s := range g.structs {
+		g.genRubyStruct(s)
+	}
+
+	for _, i := range g.interfaces {
+		g.genRubyInterface(i)
+	}
+
+	g.Outdent()
+	g.Printf("end\n")
+	if len(g.err) > 0 {
+		return g.err
+	}
+	return nil
+}
+
+// genRubyAttach emits the return struct, if the signature needs one,
+// and the attach_function call for symbol.
+func (g *RubyGen) genRubyAttach(symbol string, sig *types.Signature, method bool) {
+	params := sig.Params()
+	res := sig.Results()
+	var args []string
+	if method {
+		args = append(args, ":int32")
+	}
+	for i := 0; i < params.Len(); i++ {
+		t, _ := g.rubyType(params.At(i).Type())
+		args = append(args, t.ffi)
+	}
+	ret := ":void"
+	switch res.Len() {
+	case 1:
+		t, _ := g.rubyType(res.At(0).Type())
+		ret = t.ffi
+	case 2:
+		t0, _ := g.rubyType(res.At(0).Type())
+		t1, _ := g.rubyType(res.At(1).Type())
+		g.Printf("class Ret_%s < FFI::Struct\n", symbol)
+		g.Printf("	layout :r0, %s, :r1, %s\n", rubyLayoutType(t0.ffi), rubyLayoutType(t1.ffi))
+		g.Printf("end\n")
+		ret = fmt.Sprintf("Ret_%s.by_value", symbol)
+	}
+	g.Printf("attach_function :%s, [%s], %s\n", symbol, strings.Join(args, ", "), ret)
+}
+
+// rubyLayoutType converts an attach_function type to its layout
+// spelling: by-value struct types lose the .by_value suffix.
+func rubyLayoutType(ffi string) string {
+	return strings.TrimSuffix(ffi, ".by_value")
+}
+
+func (g *RubyGen) genRubyVar(o *types.Var) {
+	t, ok := g.rubyType(o.Type())
+	if !ok {
+		g.Printf("# skipped variable %s with unsupported type %s\n\n", o.Name(), o.Type())
+		return
+	}
+	g.rubydoc(g.docs[o.Name()].Doc())
+	g.Printf("def self.%s\n", pySnake(o.Name()))
+	g.Printf("	%s\n", fmt.Sprintf(t.fromFfi, fmt.Sprintf("Bridge.var_get%s_%s", g.pkgPrefix, o.Name())))
+	g.Printf("end\n\n")
+	g.Printf("def self.%s=(v)\n", pySnake(o.Name()))
+	g.Printf("	Bridge.var_set%s_%s(%s)\n", g.pkgPrefix, o.Name(), fmt.Sprintf(t.toFfi, "v"))
+	g.Printf("end\n\n")
+}
+
+func (g *RubyGen) genRubyStruct(s structInfo) {
+	n := s.obj.Name()
+	doc := g.docs[n]
+	g.rubydoc(typeDoc(doc))
+	g.Printf("class %s\n", n)
+	g.Indent()
+	g.Printf("attr_reader :refnum\n\n")
+	g.Printf("def initialize(refnum = nil)\n")
+	g.Printf("	@refnum = refnum || Bridge.new_%s_%s\n", g.pkgPrefix, n)
+	g.Printf("end\n\n")
+	g.Printf("# Releases the Go object backing this proxy.\n")
+	g.Printf("def destroy\n")
+	g.Printf("	Bridge.DestroyRef(@refnum)\n")
+	g.Printf("end\n\n")
+	for _, f := range exportedFields(s.t) {
+		t, ok := g.rubyType(f.Type())
+		if !ok {
+			g.Printf("# skipped field %s with unsupported type %s\n\n", f.Name(), f.Type())
+			continue
+		}
+		g.rubydoc(doc.Member(f.Name()))
+		g.Printf("def %s\n", pySnake(f.Name()))
+		g.Printf("	%s\n", fmt.Sprintf(t.fromFfi, fmt.Sprintf("Bridge.proxy%s_%s_%s_Get(Bridge.incref(@refnum))", g.pkgPrefix, n, f.Name())))
+		g.Printf("end\n\n")
+		g.Printf("def %s=(v)\n", pySnake(f.Name()))
+		g.Printf("	Bridge.proxy%s_%s_%s_Set(Bridge.incref(@refnum), %s)\n", g.pkgPrefix, n, f.Name(), fmt.Sprintf(t.toFfi, "v"))
+		g.Printf("end\n\n")
+	}
+	for _, m := range exportedMethodSet(types.NewPointer(s.obj.Type())) {
+		if !g.isRubySigSupported(m.Type()) {
+			g.Printf("# skipped method %s.%s with unsupported parameter or result types\n\n", n, m.Name())
+			continue
+		}
+		symbol := fmt.Sprintf("proxy%s_%s_%s", g.pkgPrefix, n, m.Name())
+		g.genRubyFunc(m, pySnake(m.Name()), doc.Member(m.Name()), symbol, g.rubyRecv(m))
+	}
+	g.Outdent()
+	g.Printf("end\n\n")
+}
+
+func (g *RubyGen) genRubyInterface(i interfaceInfo) {
+	n := i.obj.Name()
+	doc := g.docs[n]
+	g.rubydoc(typeDoc(doc))
+	g.Printf("# Proxy for a Go value implementing the interface; it cannot\n")
+	g.Printf("# be implemented from Ruby.\n")
+	g.Printf("class %s\n", n)
+	g.Indent()
+	g.Printf("attr_reader :refnum\n\n")
+	g.Printf("def initialize(refnum)\n")
+	g.Printf("	@refnum = refnum\n")
+	g.Printf("end\n\n")
+	g.Printf("# Releases the Go object backing this proxy.\n")
+	g.Printf("def destroy\n")
+	g.Printf("	Bridge.DestroyRef(@refnum)\n")
+	g.Printf("end\n\n")
+	for _, m := range makeIfaceSummary(i.t).callable {
+		if !g.isRubySigSupported(m.Type()) {
+			g.Printf("# skipped method %s.%s with unsupported parameter or result types\n\n", n, m.Name())
+			continue
+		}
+		symbol := fmt.Sprintf("proxy%s_%s_%s", g.pkgPrefix, n, m.Name())
+		g.genRubyFunc(m, pySnake(m.Name()), doc.Member(m.Name()), symbol, g.rubyRecv(m))
+	}
+	g.Outdent()
+	g.Printf("end\n\n")
+}
+
+// rubyRecv returns the receiver refnum expression for m. Fastpath
+// methods peek at the receiver without consuming a reference.
+func (g *RubyGen) rubyRecv(m *types.Func) string {
+	if g.isFastpath(m) {
+		return "@refnum"
+	}
+	return "Bridge.incref(@refnum)"
+}
+
+func (g *RubyGen) genRubyFunc(f *types.Func, name, doc, symbol string, recvArg string) {
+	sig := f.Type().(*types.Signature)
+	params := sig.Params()
+	res := sig.Results()
+	var decl, args []string
+	if recvArg != "" {
+		args = append(args, recvArg)
+	}
+	for i := 0; i < params.Len(); i++ {
+		t, _ := g.rubyType(params.At(i).Type())
+		pn := pySnake(basicParamName(params, i))
+		decl = append(decl, pn)
+		args = append(args, fmt.Sprintf(t.toFfi, pn))
+	}
+	retIdx := -1
+	throws := false
+	switch res.Len() {
+	case 1:
+		if isErrorType(res.At(0).Type()) {
+			throws = true
+		} else {
+			retIdx = 0
+		}
+	case 2:
+		retIdx = 0
+		throws = true
+	}
+	g.rubydoc(doc)
+	if len(decl) > 0 {
+		g.Printf("def %s(%s)\n", name, strings.Join(decl, ", "))
+	} else {
+		g.Printf("def %s\n", name)
+	}
+	g.Indent()
+	call := fmt.Sprintf("Bridge.%s(%s)", symbol, strings.Join(args, ", "))
+	if len(args) == 0 {
+		call = fmt.Sprintf("Bridge.%s", symbol)
+	}
+	switch {
+	case throws && retIdx == 0:
+		retType := g.rubyResultType(f, res.At(0).Type())
+		g.Printf("r = %s\n", call)
+		g.Printf("raise GoError, r[:r1] if r[:r1] != NULL_REFNUM\n")
+		g.Printf("%s\n", fmt.Sprintf(retType.fromFfi, "r[:r0]"))
+	case throws:
+		g.Printf("r = %s\n", call)
+		g.Printf("raise GoError, r if r != NULL_REFNUM\n")
+		g.Printf("nil\n")
+	case retIdx == 0:
+		retType := g.rubyResultType(f, res.At(0).Type())
+		g.Printf("%s\n", fmt.Sprintf(retType.fromFfi, call))
+	default:
+		g.Printf("%s\n", call)
+		g.Printf("nil\n")
+	}
+	g.Outdent()
+	g.Printf("end\n\n")
+}
+
+// rubyResultType returns the type info for a result of f; the byte
+// slice results of //gobind:nocopy functions lend Go's backing array
+// and release the pin instead of freeing.
+func (g *RubyGen) rubyResultType(f *types.Func, t types.Type) rubyTypeInfo {
+	ti, _ := g.rubyType(t)
+	if g.isNoCopy(f) && isByteSlice(t) {
+		ti.fromFfi = "Bridge.from_nbyteslice_nocopy(%s)"
+	}
+	return ti
+}
+
+// GenGemspec generates the gem specification.
+func (g *RubyGen) GenGemspec() error {
+	var pkgPath string
+	if g.Pkg != nil {
+		pkgPath = g.Pkg.Path()
+	}
+	g.Printf("# Code generated by gobind. DO NOT EDIT.\n\n")
+	g.Printf("Gem::Specification.new do |s|\n")
+	g.Printf("	s.name = %q\n", g.rubyPkgName())
+	g.Printf("	s.version = '0.0.1'\n")
+	g.Printf("	s.summary = 'Ruby bindings for the Go package %s, generated by gobind.'\n", pkgPath)
+	g.Printf("	s.authors = ['gobind']\n")
+	g.Printf("	s.files = Dir['lib/**/*.rb']\n")
+	g.Printf("	s.add_dependency 'ffi', '~> 1.15'\n")
+	g.Printf("end\n")
+	return nil
+}
+
+func (g *RubyGen) rubyPkgName() string {
+	if g.Pkg == nil {
+		return "universe"
+	}
+	return g.Pkg.Name()
+}
+
+func (g *RubyGen) rubyModuleName() string {
+	n := g.rubyPkgName()
+	return strings.ToUpper(n[:1]) + n[1:]
+}
+
+func (g *RubyGen) rubydoc(doc string) {
+	if doc == "" {
+		return
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(doc, "\n"), "\n") {
+		g.Printf("# %s\n", strings.TrimRight(line, " "))
+	}
+}
+
+// rubyTypeInfo describes how a Go type crosses the FFI bridge: the
+// attach_function type and the conversions between Ruby values and the
+// bridge representation (with %s substituted by the value).
+type rubyTypeInfo struct {
+	ffi     string
+	toFfi   string
+	fromFfi string
+}
+
+func (g *RubyGen) rubyType(t types.Type) (rubyTypeInfo, bool) {
+	num := func(ffi string) (rubyTypeInfo, bool) {
+		return rubyTypeInfo{ffi: ffi, toFfi: "%s", fromFfi: "%s"}, true
+	}
+	switch t := t.(type) {
+	case *types.Basic:
+		switch t.Kind() {
+		case types.Bool, types.UntypedBool:
+			return rubyTypeInfo{ffi: ":int8", toFfi: "%s ? 1 : 0", fromFfi: "%s != 0"}, true
+		case types.Int, types.UntypedInt, types.UntypedRune:
+			return num(":int64") // nint
+		case types.Int8:
+			return num(":int8")
+		case types.Int16:
+			return num(":int16")
+		case types.Int32:
+			return num(":int32")
+		case types.Int64:
+			return num(":int64")
+		case types.Uint8:
+			return num(":uint8")
+		case types.Float32:
+			return num(":float")
+		case types.Float64, types.UntypedFloat:
+			return num(":double")
+		case types.String, types.UntypedString:
+			return rubyTypeInfo{
+				ffi:     "NString.by_value",
+				toFfi:   "Bridge.to_nstring(%s)",
+				fromFfi: "Bridge.from_nstring(%s)",
+			}, true
+		}
+	case *types.Slice:
+		if e, ok := t.Elem().(*types.Basic); ok && e.Kind() == types.Uint8 {
+			return rubyTypeInfo{
+				ffi:     "NByteSlice.by_value",
+				toFfi:   "Bridge.to_nbyteslice(%s)",
+				fromFfi: "Bridge.from_nbyteslice(%s)",
+			}, true
+		}
+	case *types.Pointer:
+		if n, ok := t.Elem().(*types.Named); ok {
+			return g.rubyNamedType(n)
+		}
+	case *types.Named:
+		if isErrorType(t) {
+			return num(":int32")
+		}
+		return g.rubyNamedType(t)
+	}
+	return rubyTypeInfo{}, false
+}
+
+func (g *RubyGen) rubyNamedType(t *types.Named) (rubyTypeInfo, bool) {
+	n := t.Obj()
+	if n.Pkg() != g.Pkg {
+		return rubyTypeInfo{}, false
+	}
+	return rubyTypeInfo{
+		ffi:     ":int32",
+		toFfi:   "Bridge.incref(%s.refnum)",
+		fromFfi: n.Name() + ".new(%s)",
+	}, true
+}
+
+// isRubySigSupported reports whether the Ruby generator can handle the
+// signature.
+func (g *RubyGen) isRubySigSupported(t types.Type) bool {
+	if !g.isSigSupported(t) {
+		return false
+	}
+	sig := t.(*types.Signature)
+	params := sig.Params()
+	for i := 0; i < params.Len(); i++ {
+		if _, ok := g.rubyType(params.At(i).Type()); !ok {
+			return false
+		}
+	}
+	res := sig.Results()
+	for i := 0; i < res.Len(); i++ {
+		if _, ok := g.rubyType(res.At(i).Type()); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+const rubySupport = `# The refnum a nil Go object or error crosses the bridge as.
+NULL_REFNUM = 41
+
+# GoError is raised when a bound Go function returns a non-nil error.
+# The refnum identifies the Go error value on the Go side of the
+# bridge.
+class GoError < StandardError
+	attr_reader :refnum
+
+	def initialize(refnum)
+		@refnum = refnum
+		super("go error (refnum #{refnum})")
+	end
+end
+
+`
+
+const rubyBridgeSupport = `
+# Pins the Go object before its refnum crosses into Go; the Go side
+# consumes one reference per crossing.
+def self.incref(refnum)
+	IncGoRef(refnum)
+	refnum
+end
+
+module LibC
+	extend FFI::Library
+	ffi_lib FFI::Library::LIBC
+	attach_function :malloc, [:size_t], :pointer
+	attach_function :free, [:pointer], :void
+end
+
+def self.to_nstring(s)
+	ns = NString.new
+	bytes = s.to_s.b
+	unless bytes.empty?
+		buf = LibC.malloc(bytes.bytesize)
+		buf.put_bytes(0, bytes)
+		ns[:ptr] = buf
+	end
+	ns[:len] = bytes.bytesize
+	ns
+end
+
+def self.from_nstring(ns)
+	return '' if ns[:ptr].null? || ns[:len].zero?
+	s = ns[:ptr].read_string(ns[:len])
+	LibC.free(ns[:ptr])
+	s.force_encoding(Encoding::UTF_8)
+end
+
+def self.to_nbyteslice(b)
+	bs = NByteSlice.new
+	bytes = b.to_s.b
+	unless bytes.empty?
+		buf = LibC.malloc(bytes.bytesize)
+		buf.put_bytes(0, bytes)
+		bs[:ptr] = buf
+	end
+	bs[:len] = bytes.bytesize
+	bs
+end
+
+def self.from_nbyteslice(bs)
+	return ''.b if bs[:ptr].null? || bs[:len].zero?
+	b = bs[:ptr].read_string(bs[:len])
+	LibC.free(bs[:ptr])
+	b
+end
+
+# Results of //gobind:nocopy functions lend Go's backing array;
+# release the pin instead of freeing.
+def self.from_nbyteslice_nocopy(bs)
+	return ''.b if bs[:ptr].null? || bs[:len].zero?
+	b = bs[:ptr].read_string(bs[:len])
+	ReleaseByteSliceRef(bs[:ptr])
+	b
+end
+`
@@ -0,0 +1,540 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bind
+
+import (
+	"fmt"
+	"go/types"
+	"strings"
+)
+
+// RustGen generates the Rust crate selected with -lang=rust: safe
+// wrappers over the C ABI exported by the generated Go stubs, built as
+// a c-archive or c-shared library and linked into the Rust build.
+// Proxies release their refnum with Drop through the exported
+// DestroyRef, Go errors surface as Result<_, GoError>, and strings and
+// byte slices are copied across the bridge. Implementing Go interfaces
+// from Rust is not supported.
+type RustGen struct {
+	*Generator
+}
+
+// GenRust generates the crate source, src/lib.rs.
+func (g *RustGen) GenRust() error {
+	var pkgPath string
+	if g.Pkg != nil {
+		pkgPath = g.Pkg.Path()
+	}
+	g.Printf(gobindPreamble)
+	g.Printf("//! Rust bindings for the Go package %s.\n", pkgPath)
+	g.Printf("//!\n")
+	g.Printf("//! Link the library built with gomobile bind into the build, for\n")
+	g.Printf("//! example with cargo:rustc-link-lib from a build script, together\n")
+	g.Printf("//! with a seq support implementation exporting DestroyRef.\n\n")
+
+	g.genRustFfi()
+	g.Printf("%s", rustSupport)
+
+	for _, c := range g.constants {
+		if _, ok := c.Type().(*types.Basic); !ok {
+			continue
+		}
+		g.rustdoc(g.docs[c.Name()].Doc(), "")
+		g.Printf("pub const %s: %s = %s;\n\n", strings.ToUpper(pySnake(c.Name())), g.rustConstType(c.Type()), c.Val().ExactString())
+	}
+
+	for _, v := range g.vars {
+		g.genRustVar(v)
+	}
+
+	for _, f := range g.funcs {
+		if !g.isRustSigSupported(f.Type()) {
+			g.Printf("// skipped function %s with unsupported parameter or result types\n\n", f.Name())
+			continue
+		}
+		symbol := fmt.Sprintf("proxy%s__%s", g.pkgPrefix, f.Name())
+		g.genRustFunc(f, pySnake(f.Name()), g.docs[f.Name()].Doc(), symbol, "", "")
+	}
+
+	for _, s := range g.structs {
+		g.genRustStruct(s)
+	}
+	if len(g.err) > 0 {
+		return g.err
+	}
+	return nil
+}
+
+// genRustFfi emits the ffi module with the repr(C) bridge types and
+// the extern declarations for every proxy symbol the wrappers call.
+func (g *RustGen) genRustFfi() {
+	g.Printf("#[allow(non_snake_case, non_camel_case_types, dead_code)]\n")
+	g.Printf("mod ffi {\n")
+	g.Indent()
+	g.Printf("use std::os::raw::c_void;\n\n")
+	g.Printf("#[repr(C)]\n")
+	g.Printf("pub struct NString {\n")
+	g.Printf("	pub ptr: *mut c_void,\n")
+	g.Printf("	pub len: i32,\n")
+	g.Printf("}\n\n")
+	g.Printf("#[repr(C)]\n")
+	g.Printf("pub struct NByteSlice {\n")
+	g.Printf("	pub ptr: *mut c_void,\n")
+	g.Printf("	pub len: i32,\n")
+	g.Printf("}\n\n")
+
+	var externs []string
+	addFn := func(symbol string, sig *types.Signature, method bool) {
+		params := sig.Params()
+		res := sig.Results()
+		var args []string
+		if method {
+			args = append(args, "refnum: i32")
+		}
+		for i := 0; i < params.Len(); i++ {
+			t, _ := g.rustType(params.At(i).Type())
+			args = append(args, fmt.Sprintf("p%d: %s", i, t.ffi))
+		}
+		ret := ""
+		switch res.Len() {
+		case 1:
+			t, _ := g.rustType(res.At(0).Type())
+			ret = " -> " + t.ffi
+		case 2:
+			t0, _ := g.rustType(res.At(0).Type())
+			t1, _ := g.rustType(res.At(1).Type())
+			g.Printf("#[repr(C)]\n")
+			g.Printf("pub struct Ret_%s {\n", symbol)
+			g.Printf("	pub r0: %s,\n", t0.ffi)
+			g.Printf("	pub r1: %s,\n", t1.ffi)
+			g.Printf("}\n\n")
+			ret = " -> Ret_" + symbol
+		}
+		externs = append(externs, fmt.Sprintf("pub fn %s(%s)%s;", symbol, strings.Join(args, ", "), ret))
+	}
+
+	for _, f := range g.funcs {
+		if !g.isRustSigSupported(f.Type()) {
+			continue
+		}
+		addFn(fmt.Sprintf("proxy%s__%s", g.pkgPrefix, f.Name()), f.Type().(*types.Signature), false)
+	}
+	for _, v := range g.vars {
+		t, ok := g.rustType(v.Type())
+		if !ok {
+			continue
+		}
+		externs = append(externs,
+			fmt.Sprintf("pub fn var_get%s_%s() -> %s;", g.pkgPrefix, v.Name(), t.ffi),
+			fmt.Sprintf("pub fn var_set%s_%s(v: %s);", g.pkgPrefix, v.Name(), t.ffi))
+	}
+	for _, s := range g.structs {
+		n := s.obj.Name()
+		externs = append(externs, fmt.Sprintf("pub fn new_%s_%s() -> i32;", g.pkgPrefix, n))
+		for _, f := range exportedFields(s.t) {
+			t, ok := g.rustType(f.Type())
+			if !ok {
+				continue
+			}
+			externs = append(externs,
+				fmt.Sprintf("pub fn proxy%s_%s_%s_Get(refnum: i32) -> %s;", g.pkgPrefix, n, f.Name(), t.ffi),
+				fmt.Sprintf("pub fn proxy%s_%s_%s_Set(refnum: i32, v: %s);", g.pkgPrefix, n, f.Name(), t.ffi))
+		}
+		for _, m := range exportedMethodSet(types.NewPointer(s.obj.Type())) {
+			if !g.isRustSigSupported(m.Type()) {
+				continue
+			}
+			addFn(fmt.Sprintf("proxy%s_%s_%s", g.pkgPrefix, n, m.Name()), m.Type().(*types.Signature), true)
+		}
+	}
+	externs = append(externs, "pub fn IncGoRef(refnum: i32);")
+	externs = append(externs, "pub fn DestroyRef(refnum: i32);")
+	externs = append(externs, "pub fn ReleaseByteSliceRef(ptr: *mut c_void);")
+
+	g.Printf("extern \"C\" {\n")
+	g.Indent()
+	for _, e := range externs {
+		g.Printf("%s\n", e)
+	}
+	g.Outdent()
+	g.Printf("}\n")
+	g.Outdent()
+	g.Printf("}\n\n")
+}
+
+func (g *RustGen) genRustVar(o *types.Var) {
+	t, ok := g.rustType(o.Type())
+	if !ok {
+		g.Printf("// skipped variable %s with unsupported type %s\n\n", o.Name(), o.Type())
+		return
+	}
+	g.rustdoc(g.docs[o.Name()].Doc(), "")
+	g.Printf("pub fn get_%s() -> %s {\n", pySnake(o.Name()), t.ret)
+	// Parenthesized so casts and comparisons apply to the block
+	// expression.
+	g.Printf("	%s\n", fmt.Sprintf(t.fromFfi, fmt.Sprintf("(unsafe { ffi::var_get%s_%s() })", g.pkgPrefix, o.Name())))
+	g.Printf("}\n\n")
+	g.Printf("pub fn set_%s(v: %s) {\n", pySnake(o.Name()), t.param)
+	g.Printf("	unsafe { ffi::var_set%s_%s(%s) }\n", g.pkgPrefix, o.Name(), fmt.Sprintf(t.toFfi, "v"))
+	g.Printf("}\n\n")
+}
+
+func (g *RustGen) genRustStruct(s structInfo) {
+	n := s.obj.Name()
+	doc := g.docs[n]
+	g.rustdoc(typeDoc(doc), "")
+	g.Printf("pub struct %s {\n", n)
+	g.Printf("	refnum: i32,\n")
+	g.Printf("}\n\n")
+	g.Printf("impl Drop for %s {\n", n)
+	g.Printf("	fn drop(&mut self) {\n")
+	g.Printf("		unsafe { ffi::DestroyRef(self.refnum) }\n")
+	g.Printf("	}\n")
+	g.Printf("}\n\n")
+	g.Printf("impl %s {\n", n)
+	g.Indent()
+	g.Printf("pub fn new() -> %s {\n", n)
+	g.Printf("	%s { refnum: unsafe { ffi::new_%s_%s() } }\n", n, g.pkgPrefix, n)
+	g.Printf("}\n\n")
+	for _, f := range exportedFields(s.t) {
+		t, ok := g.rustType(f.Type())
+		if !ok {
+			g.Printf("// skipped field %s with unsupported type %s\n\n", f.Name(), f.Type())
+			continue
+		}
+		g.rustdoc(doc.Member(f.Name()), "")
+		g.Printf("pub fn %s(&self) -> %s {\n", pySnake(f.Name()), t.ret)
+		g.Printf("	%s\n", fmt.Sprintf(t.fromFfi, fmt.Sprintf("(unsafe { ffi::proxy%s_%s_%s_Get(incref(self.refnum)) })", g.pkgPrefix, n, f.Name())))
+		g.Printf("}\n\n")
+		g.Printf("pub fn set_%s(&mut self, v: %s) {\n", pySnake(f.Name()), t.param)
+		g.Printf("	unsafe { ffi::proxy%s_%s_%s_Set(incref(self.refnum), %s) }\n", g.pkgPrefix, n, f.Name(), fmt.Sprintf(t.toFfi, "v"))
+		g.Printf("}\n\n")
+	}
+	for _, m := range exportedMethodSet(types.NewPointer(s.obj.Type())) {
+		if !g.isRustSigSupported(m.Type()) {
+			g.Printf("// skipped method %s.%s with unsupported parameter or result types\n\n", n, m.Name())
+			continue
+		}
+		symbol := fmt.Sprintf("proxy%s_%s_%s", g.pkgPrefix, n, m.Name())
+		recvArg := "incref(self.refnum)"
+		if g.isFastpath(m) {
+			// Fastpath methods peek at the receiver without
+			// consuming a reference.
+			recvArg = "self.refnum"
+		}
+		g.genRustFunc(m, pySnake(m.Name()), doc.Member(m.Name()), symbol, "&self", recvArg)
+	}
+	g.Outdent()
+	g.Printf("}\n\n")
+}
+
+// genRustFunc generates the safe wrapper for symbol. recv and recvArg
+// are empty for package functions; methods pass "&self" and an
+// expression for the receiver refnum.
+func (g *RustGen) genRustFunc(f *types.Func, name, doc, symbol, recv, recvArg string) {
+	sig := f.Type().(*types.Signature)
+	params := sig.Params()
+	res := sig.Results()
+	var decl, args []string
+	if recv != "" {
+		decl = append(decl, recv)
+		args = append(args, recvArg)
+	}
+	for i := 0; i < params.Len(); i++ {
+		t, _ := g.rustType(params.At(i).Type())
+		pn := pySnake(basicParamName(params, i))
+		decl = append(decl, fmt.Sprintf("%s: %s", pn, t.param))
+		args = append(args, fmt.Sprintf(t.toFfi, pn))
+	}
+	retIdx := -1
+	throws := false
+	switch res.Len() {
+	case 1:
+		if isErrorType(res.At(0).Type()) {
+			throws = true
+		} else {
+			retIdx = 0
+		}
+	case 2:
+		retIdx = 0
+		throws = true
+	}
+	ret := ""
+	var retType rustTypeInfo
+	if retIdx == 0 {
+		retType, _ = g.rustType(res.At(0).Type())
+		if g.isNoCopy(f) && isByteSlice(res.At(0).Type()) {
+			retType.fromFfi = "from_nbyteslice_nocopy(%s)"
+		}
+	}
+	switch {
+	case throws && retIdx == 0:
+		ret = fmt.Sprintf(" -> Result<%s, GoError>", retType.ret)
+	case throws:
+		ret = " -> Result<(), GoError>"
+	case retIdx == 0:
+		ret = " -> " + retType.ret
+	}
+	g.rustdoc(doc, "")
+	g.Printf("pub fn %s(%s)%s {\n", name, strings.Join(decl, ", "), ret)
+	g.Indent()
+	call := fmt.Sprintf("unsafe { ffi::%s(%s) }", symbol, strings.Join(args, ", "))
+	switch {
+	case throws && retIdx == 0:
+		g.Printf("let r = %s;\n", call)
+		g.Printf("if r.r1 != NULL_REFNUM {\n")
+		g.Printf("	return Err(GoError { refnum: r.r1 });\n")
+		g.Printf("}\n")
+		g.Printf("Ok(%s)\n", fmt.Sprintf(retType.fromFfi, "r.r0"))
+	case throws:
+		g.Printf("let r = %s;\n", call)
+		g.Printf("if r != NULL_REFNUM {\n")
+		g.Printf("	return Err(GoError { refnum: r });\n")
+		g.Printf("}\n")
+		g.Printf("Ok(())\n")
+	case retIdx == 0:
+		// Parenthesized so casts and comparisons apply to the block
+		// expression.
+		g.Printf("%s\n", fmt.Sprintf(retType.fromFfi, "("+call+")"))
+	default:
+		g.Printf("%s;\n", call)
+	}
+	g.Outdent()
+	g.Printf("}\n\n")
+}
+
+// GenCargoToml generates the crate manifest.
+func (g *RustGen) GenCargoToml() error {
+	var pkgPath string
+	if g.Pkg != nil {
+		pkgPath = g.Pkg.Path()
+	}
+	g.Printf("[package]\n")
+	g.Printf("name = %q\n", g.rustPkgName())
+	g.Printf("version = \"0.0.1\"\n")
+	g.Printf("edition = \"2021\"\n")
+	g.Printf("description = \"Rust bindings for the Go package %s, generated by gobind.\"\n\n", pkgPath)
+	g.Printf("[dependencies]\n")
+	g.Printf("libc = \"0.2\"\n")
+	return nil
+}
+
+func (g *RustGen) rustPkgName() string {
+	if g.Pkg == nil {
+		return "universe"
+	}
+	return g.Pkg.Name()
+}
+
+func (g *RustGen) rustdoc(doc, indent string) {
+	if doc == "" {
+		return
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(doc, "\n"), "\n") {
+		g.Printf("%s/// %s\n", indent, strings.TrimRight(line, " "))
+	}
+}
+
+func (g *RustGen) rustConstType(t types.Type) string {
+	b, ok := t.(*types.Basic)
+	if !ok {
+		return ""
+	}
+	switch b.Kind() {
+	case types.Bool, types.UntypedBool:
+		return "bool"
+	case types.String, types.UntypedString:
+		return "&str"
+	case types.Float32:
+		return "f32"
+	case types.Float64, types.UntypedFloat:
+		return "f64"
+	default:
+		return "i64"
+	}
+}
+
+// rustTypeInfo describes how a Go type crosses the bridge: the ffi
+// module spelling, the parameter and return spellings of the safe
+// wrapper and the conversions between them (with %s substituted by the
+// value).
+type rustTypeInfo struct {
+	ffi     string
+	param   string
+	ret     string
+	toFfi   string
+	fromFfi string
+}
+
+func (g *RustGen) rustType(t types.Type) (rustTypeInfo, bool) {
+	num := func(ffi, rust string) (rustTypeInfo, bool) {
+		toFfi, fromFfi := "%s", "%s"
+		if ffi != rust {
+			toFfi = "%s as " + ffi
+			fromFfi = "%s as " + rust
+		}
+		return rustTypeInfo{ffi: ffi, param: rust, ret: rust, toFfi: toFfi, fromFfi: fromFfi}, true
+	}
+	switch t := t.(type) {
+	case *types.Basic:
+		switch t.Kind() {
+		case types.Bool, types.UntypedBool:
+			return rustTypeInfo{ffi: "i8", param: "bool", ret: "bool", toFfi: "if %s { 1 } else { 0 }", fromFfi: "%s != 0"}, true
+		case types.Int, types.UntypedInt, types.UntypedRune:
+			return num("i64", "i64") // nint
+		case types.Int8:
+			return num("i8", "i8")
+		case types.Int16:
+			return num("i16", "i16")
+		case types.Int32:
+			return num("i32", "i32")
+		case types.Int64:
+			return num("i64", "i64")
+		case types.Uint8:
+			return num("u8", "u8")
+		case types.Float32:
+			return rustTypeInfo{ffi: "f32", param: "f32", ret: "f32", toFfi: "%s", fromFfi: "%s"}, true
+		case types.Float64, types.UntypedFloat:
+			return rustTypeInfo{ffi: "f64", param: "f64", ret: "f64", toFfi: "%s", fromFfi: "%s"}, true
+		case types.String, types.UntypedString:
+			return rustTypeInfo{ffi: "NString", param: "&str", ret: "String", toFfi: "to_nstring(%s)", fromFfi: "from_nstring(%s)"}, true
+		}
+	case *types.Slice:
+		if e, ok := t.Elem().(*types.Basic); ok && e.Kind() == types.Uint8 {
+			return rustTypeInfo{ffi: "NByteSlice", param: "&[u8]", ret: "Vec<u8>", toFfi: "to_nbyteslice(%s)", fromFfi: "from_nbyteslice(%s)"}, true
+		}
+	case *types.Pointer:
+		if n, ok := t.Elem().(*types.Named); ok {
+			return g.rustNamedType(n)
+		}
+	case *types.Named:
+		if isErrorType(t) {
+			return rustTypeInfo{ffi: "i32", param: "i32", ret: "i32", toFfi: "%s", fromFfi: "%s"}, true
+		}
+		return g.rustNamedType(t)
+	}
+	return rustTypeInfo{}, false
+}
+
+func (g *RustGen) rustNamedType(t *types.Named) (rustTypeInfo, bool) {
+	n := t.Obj()
+	if n.Pkg() != g.Pkg {
+		return rustTypeInfo{}, false
+	}
+	if _, ok := t.Underlying().(*types.Interface); ok {
+		// Interfaces are passed as raw refnums; Rust cannot implement
+		// them.
+		return rustTypeInfo{ffi: "i32", param: "i32", ret: "i32", toFfi: "%s", fromFfi: "%s"}, true
+	}
+	return rustTypeInfo{
+		ffi:     "i32",
+		param:   "&" + n.Name(),
+		ret:     n.Name(),
+		toFfi:   "incref(%s.refnum)",
+		fromFfi: n.Name() + " { refnum: %s }",
+	}, true
+}
+
+// isRustSigSupported reports whether the Rust generator can handle the
+// signature.
+func (g *RustGen) isRustSigSupported(t types.Type) bool {
+	if !g.isSigSupported(t) {
+		return false
+	}
+	sig := t.(*types.Signature)
+	params := sig.Params()
+	for i := 0; i < params.Len(); i++ {
+		if _, ok := g.rustType(params.At(i).Type()); !ok {
+			return false
+		}
+	}
+	res := sig.Results()
+	for i := 0; i < res.Len(); i++ {
+		if _, ok := g.rustType(res.At(i).Type()); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+const rustSupport = `/// The refnum a nil Go object or error crosses the bridge as.
+const NULL_REFNUM: i32 = 41;
+
+/// Pins the Go object before its refnum crosses into Go; the Go side
+/// consumes one reference per crossing.
+fn incref(refnum: i32) -> i32 {
+	unsafe { ffi::IncGoRef(refnum) };
+	refnum
+}
+
+/// GoError is returned when a bound Go function returns a non-nil
+/// error. The refnum identifies the Go error value on the Go side of
+/// the bridge.
+#[derive(Debug)]
+pub struct GoError {
+	pub refnum: i32,
+}
+
+impl std::fmt::Display for GoError {
+	fn fmt(&self, f: &mut std::fmt::Formatter) -> std::fmt::Result {
+		write!(f, "go error (refnum {})", self.refnum)
+	}
+}
+
+impl std::error::Error for GoError {}
+
+fn to_nstring(s: &str) -> ffi::NString {
+	let len = s.len();
+	let ptr = unsafe { libc::malloc(len) };
+	if len > 0 {
+		unsafe {
+			std::ptr::copy_nonoverlapping(s.as_ptr(), ptr as *mut u8, len);
+		}
+	}
+	ffi::NString { ptr, len: len as i32 }
+}
+
+fn from_nstring(ns: ffi::NString) -> String {
+	if ns.ptr.is_null() || ns.len == 0 {
+		return String::new();
+	}
+	let bytes = unsafe { std::slice::from_raw_parts(ns.ptr as *const u8, ns.len as usize) };
+	let s = String::from_utf8_lossy(bytes).into_owned();
+	unsafe { libc::free(ns.ptr) };
+	s
+}
+
+fn to_nbyteslice(b: &[u8]) -> ffi::NByteSlice {
+	let len = b.len();
+	let ptr = unsafe { libc::malloc(len) };
+	if len > 0 {
+		unsafe {
+			std::ptr::copy_nonoverlapping(b.as_ptr(), ptr as *mut u8, len);
+		}
+	}
+	ffi::NByteSlice { ptr, len: len as i32 }
+}
+
+fn from_nbyteslice(bs: ffi::NByteSlice) -> Vec<u8> {
+	if bs.ptr.is_null() || bs.len == 0 {
+		return Vec::new();
+	}
+	let bytes = unsafe { std::slice::from_raw_parts(bs.ptr as *const u8, bs.len as usize) }.to_vec();
+	unsafe { libc::free(bs.ptr) };
+	bytes
+}
+
+/// Results of //gobind:nocopy functions lend Go's backing array;
+/// release the pin instead of freeing.
+fn from_nbyteslice_nocopy(bs: ffi::NByteSlice) -> Vec<u8> {
+	if bs.ptr.is_null() || bs.len == 0 {
+		return Vec::new();
+	}
+	let bytes = unsafe { std::slice::from_raw_parts(bs.ptr as *const u8, bs.len as usize) }.to_vec();
+	unsafe { ffi::ReleaseByteSliceRef(bs.ptr) };
+	bytes
+}
+
+`
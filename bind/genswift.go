@@ -0,0 +1,123 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bind
+
+import (
+	"fmt"
+	"strings"
+)
+
+// GenSwift emits the Swift API for the package, selected with
+// -lang=swift. It contains everything the Swift overlay provides —
+// the namespace with throwing wrappers for package functions, the
+// constants and the prefix-stripped type aliases — plus closure
+// adapters for single-method interfaces, so callbacks can be passed
+// as Swift closures instead of protocol-conforming classes. The ObjC
+// bridge is generated alongside; the Swift layer compiles against its
+// imported declarations.
+func (g *ObjcGen) GenSwift() error {
+	if err := g.GenSwiftOverlay(); err != nil {
+		return err
+	}
+	g.Printf("\n")
+	for _, i := range g.interfaces {
+		summary := makeIfaceSummary(i.t)
+		if !summary.implementable || len(summary.callable) != 1 {
+			continue
+		}
+		m := summary.callable[0]
+		if !g.isSigSupported(m.Type()) {
+			continue
+		}
+		s := g.funcSummary(nil, m)
+		if s == nil {
+			continue
+		}
+		g.genSwiftClosureAdapter(i, s)
+	}
+	if len(g.err) > 0 {
+		return g.err
+	}
+	return nil
+}
+
+// genSwiftClosureAdapter generates a class adapting a Swift closure to
+// the single-method interface described by s. Methods whose imported
+// signature cannot be reproduced exactly, such as basic-typed results
+// combined with an error, are skipped; conformance requires a precise
+// match with the declarations the Swift importer derives from ObjC.
+func (g *ObjcGen) genSwiftClosureAdapter(i interfaceInfo, s *funcSummary) {
+	throws := false
+	ret := ""
+	if n := len(s.retParams); n > 0 {
+		throws = isErrorType(s.retParams[n-1].typ)
+		if !throws {
+			if n > 1 {
+				return
+			}
+			ret = g.swiftType(s.retParams[0].typ)
+		} else if n == 2 {
+			if !s.returnsVal() {
+				// The value is returned through an output parameter;
+				// the imported signature is not idiomatic Swift.
+				return
+			}
+			// With a throwing method, the importer drops the
+			// nullability marker from the result.
+			ret = strings.TrimSuffix(g.swiftType(s.retParams[0].typ), "?")
+		}
+	}
+	var decl, sig []string
+	for k, p := range s.params {
+		label := "_"
+		if k > 0 {
+			label = swiftName(p.name)
+		}
+		decl = append(decl, fmt.Sprintf("%s %s: %s", label, swiftName(p.name), g.swiftType(p.typ)))
+		sig = append(sig, g.swiftType(p.typ))
+	}
+	fnType := "(" + strings.Join(sig, ", ") + ")"
+	if throws {
+		fnType += " throws"
+	}
+	if ret != "" {
+		fnType += " -> " + ret
+	} else {
+		fnType += " -> Void"
+	}
+	name := i.obj.Name()
+	g.Printf("/// %[1]sFn adapts a closure to the %[1]s interface.\n", name)
+	g.Printf("public final class %sFn: NSObject, %s%sProtocol {\n", name, g.namePrefix, name)
+	g.Indent()
+	g.Printf("private let fn: %s\n\n", fnType)
+	g.Printf("public init(_ fn: @escaping %s) {\n", fnType)
+	g.Printf("	self.fn = fn\n")
+	g.Printf("}\n\n")
+	g.Printf("public func %s(%s)", swiftName(lowerFirst(s.name)), strings.Join(decl, ", "))
+	if throws {
+		g.Printf(" throws")
+	}
+	if ret != "" {
+		g.Printf(" -> %s", ret)
+	}
+	g.Printf(" {\n")
+	g.Indent()
+	var args []string
+	for _, p := range s.params {
+		args = append(args, swiftName(p.name))
+	}
+	call := fmt.Sprintf("fn(%s)", strings.Join(args, ", "))
+	if throws {
+		call = "try " + call
+	}
+	if ret != "" {
+		call = "return " + call
+	}
+	g.Printf("%s\n", call)
+	g.Outdent()
+	g.Printf("}\n")
+	g.Outdent()
+	g.Printf("}\n\n")
+}
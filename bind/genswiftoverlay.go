@@ -0,0 +1,266 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bind
+
+import (
+	"fmt"
+	"go/types"
+	"strings"
+)
+
+// GenSwiftOverlay emits a small Swift overlay for the package: a
+// namespace with static wrappers for the package level functions and
+// constants, and prefix-stripped type aliases for the generated
+// classes and protocols. Error-returning functions are wrapped as
+// throwing Swift functions; the ObjC error convention only applies to
+// methods, so the plain C-style package functions do not get that
+// treatment from the Swift importer.
+func (g *ObjcGen) GenSwiftOverlay() error {
+	var pkgPath string
+	if g.Pkg != nil {
+		pkgPath = g.Pkg.Path()
+	}
+	g.Printf(objcPreamble, pkgPath, g.gobindOpts(), pkgPath)
+	g.Printf("import Foundation\n\n")
+
+	// Prefix-stripped aliases for the generated types. The aliases are
+	// only generated when they do not clash with the namespace below.
+	for _, s := range g.structs {
+		if s.obj.Name() == g.namePrefix {
+			continue
+		}
+		g.Printf("public typealias %s = %s%s\n", s.obj.Name(), g.namePrefix, s.obj.Name())
+	}
+	for _, i := range g.interfaces {
+		if i.obj.Name() == g.namePrefix {
+			continue
+		}
+		g.Printf("public typealias %s = %s%s\n", i.obj.Name(), g.namePrefix, i.obj.Name())
+	}
+	if len(g.structs) > 0 || len(g.interfaces) > 0 {
+		g.Printf("\n")
+	}
+
+	// Package variables are already exposed as class properties of the
+	// ObjC class named after the package; reuse it as the namespace in
+	// that case.
+	if len(g.vars) == 0 {
+		g.Printf("public enum %s {\n", g.namePrefix)
+		g.Printf("}\n\n")
+	}
+
+	g.Printf("public extension %s {\n", g.namePrefix)
+	g.Indent()
+	for _, c := range g.constants {
+		if _, ok := c.Type().(*types.Basic); !ok || !g.isSupported(c.Type()) {
+			g.Printf("// skipped const %s with unsupported type: %s\n\n", c.Name(), c.Type())
+			continue
+		}
+		g.Printf("static let %s = %s%s\n", swiftName(lowerFirst(c.Name())), g.namePrefix, c.Name())
+	}
+	if len(g.constants) > 0 {
+		g.Printf("\n")
+	}
+	for _, f := range g.funcs {
+		if !g.isSigSupported(f.Type()) {
+			g.Printf("// skipped function %s with unsupported parameter or return types\n\n", f.Name())
+			continue
+		}
+		s := g.funcSummary(nil, f)
+		if s == nil {
+			continue
+		}
+		g.genSwiftFunc(f, s)
+	}
+	g.Outdent()
+	g.Printf("}\n")
+
+	if len(g.err) > 0 {
+		return g.err
+	}
+	return nil
+}
+
+func (g *ObjcGen) genSwiftFunc(f *types.Func, s *funcSummary) {
+	nd := g.defaultedParams(f)
+	sigParams := f.Type().(*types.Signature).Params()
+	doc := g.docs[f.Name()]
+	var params []string
+	for i, p := range s.params {
+		def := ""
+		if i >= len(s.params)-nd {
+			v, _ := doc.Default(sigParams.At(i).Name())
+			def = " = " + v
+		}
+		params = append(params, fmt.Sprintf("_ %s: %s%s", swiftName(p.name), g.swiftType(p.typ), def))
+	}
+	throws := false
+	ret := ""
+	var retParam *paramInfo
+	if n := len(s.retParams); n > 0 {
+		throws = isErrorType(s.retParams[n-1].typ)
+		if !throws || n == 2 {
+			retParam = &s.retParams[0]
+			ret = g.swiftType(retParam.typ)
+		}
+	}
+	g.Printf("static func %s(%s)", swiftName(lowerFirst(s.name)), strings.Join(params, ", "))
+	if throws {
+		g.Printf(" throws")
+	}
+	if ret != "" {
+		g.Printf(" -> %s", ret)
+	}
+	g.Printf(" {\n")
+	g.Indent()
+	var args []string
+	for _, p := range s.params {
+		args = append(args, swiftName(p.name))
+	}
+	call := fmt.Sprintf("%s%s", g.namePrefix, s.name)
+	switch {
+	case !throws && ret == "":
+		g.Printf("%s(%s)\n", call, strings.Join(args, ", "))
+	case !throws:
+		g.Printf("return %s(%s)\n", call, strings.Join(args, ", "))
+	case throws && retParam == nil:
+		g.Printf("var err: NSError?\n")
+		g.Printf("if !%s(%s) {\n", call, strings.Join(append(args, "&err"), ", "))
+		g.Printf("	throw err ?? NSError(domain: \"go\", code: 1)\n")
+		g.Printf("}\n")
+	case throws && s.returnsVal():
+		// The value is the C return; the error is an output parameter.
+		g.Printf("var err: NSError?\n")
+		g.Printf("let ret = %s(%s)\n", call, strings.Join(append(args, "&err"), ", "))
+		g.Printf("if let err = err {\n")
+		g.Printf("	throw err\n")
+		g.Printf("}\n")
+		g.Printf("return ret\n")
+	default:
+		// Both the value and the error are output parameters.
+		g.Printf("var ret: %s = %s\n", ret, g.swiftZero(retParam.typ))
+		g.Printf("var err: NSError?\n")
+		g.Printf("if !%s(%s) {\n", call, strings.Join(append(args, "&ret", "&err"), ", "))
+		g.Printf("	throw err ?? NSError(domain: \"go\", code: 1)\n")
+		g.Printf("}\n")
+		g.Printf("return ret\n")
+	}
+	g.Outdent()
+	g.Printf("}\n\n")
+}
+
+// swiftType returns the Swift spelling of the type as the Swift
+// importer sees the generated ObjC declarations.
+func (g *ObjcGen) swiftType(typ types.Type) string {
+	if isErrorType(typ) {
+		return "NSError?"
+	}
+	switch typ := typ.(type) {
+	case *types.Basic:
+		switch typ.Kind() {
+		case types.Bool, types.UntypedBool:
+			return "Bool"
+		case types.Int:
+			return "Int"
+		case types.Int8:
+			return "Int8"
+		case types.Int16:
+			return "Int16"
+		case types.Int32, types.UntypedRune: // types.Rune
+			return "Int32"
+		case types.Int64, types.UntypedInt:
+			return "Int64"
+		case types.Uint8:
+			return "UInt8"
+		case types.Uint16:
+			return "UInt16"
+		case types.Uint32:
+			return "UInt32"
+		case types.Uint64:
+			return "UInt64"
+		case types.Float32:
+			return "Float"
+		case types.Float64, types.UntypedFloat:
+			return "Double"
+		case types.String, types.UntypedString:
+			return "String"
+		default:
+			g.errorf("unsupported type: %s", typ)
+			return "TODO"
+		}
+	case *types.Slice:
+		if isByteSliceSlice(typ) {
+			return "[Data]?"
+		}
+		if isStringSlice(typ) {
+			return "[String]?"
+		}
+		if e, ok := typ.Elem().(*types.Basic); ok && e.Kind() == types.Uint8 {
+			return "Data?"
+		}
+		g.errorf("unsupported type: %s", typ)
+		return "TODO"
+	case *types.Map:
+		if isStringMap(typ) {
+			return "[String: String]?"
+		}
+		g.errorf("unsupported type: %s", typ)
+		return "TODO"
+	case *types.Pointer:
+		if _, ok := typ.Elem().(*types.Named); ok {
+			return strings.TrimSuffix(g.objcType(typ.Elem()), "* _Nullable") + "?"
+		}
+		g.errorf("unsupported pointer to type: %s", typ)
+		return "TODO"
+	case *types.Named:
+		n := typ.Obj()
+		switch typ.Underlying().(type) {
+		case *types.Interface:
+			return g.namePrefixOf(n.Pkg()) + n.Name() + "?"
+		}
+		g.errorf("unsupported, named type %s", typ)
+		return "TODO"
+	default:
+		g.errorf("unsupported type: %#+v, %s", typ, typ)
+		return "TODO"
+	}
+}
+
+// swiftZero returns the Swift zero value literal used to seed output
+// parameters of non-nullable types.
+func (g *ObjcGen) swiftZero(typ types.Type) string {
+	if t, ok := typ.(*types.Basic); ok {
+		switch t.Kind() {
+		case types.Bool, types.UntypedBool:
+			return "false"
+		}
+	}
+	return "0"
+}
+
+// swiftName escapes Swift keywords with backticks.
+func swiftName(s string) string {
+	if swiftKeywords[s] {
+		return "`" + s + "`"
+	}
+	return s
+}
+
+var swiftKeywords = func() map[string]bool {
+	words := []string{
+		"as", "break", "case", "catch", "class", "continue", "default",
+		"defer", "do", "else", "enum", "extension", "fallthrough",
+		"false", "for", "func", "guard", "if", "import", "in", "init",
+		"inout", "internal", "is", "let", "nil", "operator", "private",
+		"protocol", "public", "repeat", "rethrows", "return", "self",
+		"static", "struct", "subscript", "super", "switch", "throw",
+		"throws", "true", "try", "var", "where", "while",
+	}
+	m := make(map[string]bool)
+	for _, w := range words {
+		m[w] = true
+	}
+	return m
+}()
@@ -0,0 +1,601 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bind
+
+import (
+	"fmt"
+	"go/types"
+	"strings"
+)
+
+// WasmGen generates the WebAssembly bindings selected with -lang=wasm
+// and built by gomobile bind -target=wasm: a Go main package that
+// registers the bound API on a JavaScript global through syscall/js,
+// using the same proxy/refnum model as the other bindings, plus a
+// JavaScript wrapper and TypeScript declarations mirroring the ones
+// the Node.js backend produces. Byte slices cross the bridge as
+// Uint8Arrays and Go errors are thrown as Error with a GoError name;
+// implementing Go interfaces from JavaScript is not supported.
+type WasmGen struct {
+	*Generator
+}
+
+// GenWasmMain generates the Go main package registering the exports.
+func (g *WasmGen) GenWasmMain() error {
+	var pkgPath string
+	if g.Pkg != nil {
+		pkgPath = g.Pkg.Path()
+	}
+	g.Printf(gobindPreamble)
+	g.Printf("//go:build js && wasm\n\n")
+	g.Printf("// WebAssembly entry point for the Go package %s. It registers\n", pkgPath)
+	g.Printf("// the bound API on the JavaScript global %q object.\n", g.wasmPkgName())
+	g.Printf("package main\n\n")
+	g.Printf("import (\n")
+	g.Printf("	\"sync\"\n")
+	g.Printf("	\"syscall/js\"\n")
+	if g.Pkg != nil {
+		g.Printf("\n	%q\n", pkgPath)
+	}
+	g.Printf(")\n\n")
+	g.Printf("%s", wasmGoSupport)
+
+	g.Printf("func main() {\n")
+	g.Indent()
+	g.Printf("exports := js.Global().Get(\"Object\").New()\n")
+	g.Printf("exports.Set(\"destroyRef\", js.FuncOf(func(this js.Value, args []js.Value) interface{} {\n")
+	g.Printf("	destroyRef(args[0])\n")
+	g.Printf("	return nil\n")
+	g.Printf("}))\n")
+
+	for _, f := range g.funcs {
+		if !g.isWasmSigSupported(f.Type()) {
+			g.Printf("// skipped function %s with unsupported parameter or result types\n", f.Name())
+			continue
+		}
+		g.genWasmExport(f.Name(), f.Type().(*types.Signature), g.wasmQual()+f.Name(), false)
+	}
+
+	for _, v := range g.vars {
+		g.genWasmVar(v)
+	}
+
+	for _, s := range g.structs {
+		n := s.obj.Name()
+		g.Printf("exports.Set(\"new_%s\", js.FuncOf(func(this js.Value, args []js.Value) interface{} {\n", n)
+		g.Printf("	return newRef(new(%s%s))\n", g.wasmQual(), n)
+		g.Printf("}))\n")
+		recv := fmt.Sprintf("deref(args[0]).(*%s%s)", g.wasmQual(), n)
+		for _, f := range exportedFields(s.t) {
+			t, ok := g.wasmType(f.Type())
+			if !ok {
+				g.Printf("// skipped field %s.%s with unsupported type %s\n", n, f.Name(), f.Type())
+				continue
+			}
+			g.Printf("exports.Set(\"%s_%s_Get\", js.FuncOf(func(this js.Value, args []js.Value) interface{} {\n", n, f.Name())
+			g.Indent()
+			g.Printf("r0 := %s.%s\n", recv, f.Name())
+			g.Printf("return %s\n", fmt.Sprintf(t.goRet, "r0"))
+			g.Outdent()
+			g.Printf("}))\n")
+			g.Printf("exports.Set(\"%s_%s_Set\", js.FuncOf(func(this js.Value, args []js.Value) interface{} {\n", n, f.Name())
+			g.Indent()
+			g.Printf("%s.%s = %s\n", recv, f.Name(), fmt.Sprintf(t.goParam, "args[1]"))
+			g.Printf("return nil\n")
+			g.Outdent()
+			g.Printf("}))\n")
+		}
+		for _, m := range exportedMethodSet(types.NewPointer(s.obj.Type())) {
+			if !g.isWasmSigSupported(m.Type()) {
+				g.Printf("// skipped method %s.%s with unsupported parameter or result types\n", n, m.Name())
+				continue
+			}
+			g.genWasmExport(fmt.Sprintf("%s_%s", n, m.Name()), m.Type().(*types.Signature), recv+"."+m.Name(), true)
+		}
+	}
+
+	g.Printf("js.Global().Set(%q, exports)\n", g.wasmPkgName())
+	g.Printf("select {}\n")
+	g.Outdent()
+	g.Printf("}\n")
+	if len(g.err) > 0 {
+		return g.err
+	}
+	return nil
+}
+
+// genWasmExport emits one exports.Set call wrapping a package function
+// or method. call is the Go expression to invoke, without arguments;
+// for methods the receiver is resolved from args[0] and parameters
+// start at args[1].
+func (g *WasmGen) genWasmExport(name string, sig *types.Signature, call string, method bool) {
+	params := sig.Params()
+	res := sig.Results()
+	g.Printf("exports.Set(%q, js.FuncOf(func(this js.Value, args []js.Value) interface{} {\n", name)
+	g.Indent()
+	var args []string
+	off := 0
+	if method {
+		off = 1
+	}
+	for i := 0; i < params.Len(); i++ {
+		t, _ := g.wasmType(params.At(i).Type())
+		args = append(args, fmt.Sprintf(t.goParam, fmt.Sprintf("args[%d]", i+off)))
+	}
+	expr := fmt.Sprintf("%s(%s)", call, strings.Join(args, ", "))
+	retIdx := -1
+	throws := false
+	switch res.Len() {
+	case 1:
+		if isErrorType(res.At(0).Type()) {
+			throws = true
+		} else {
+			retIdx = 0
+		}
+	case 2:
+		retIdx = 0
+		throws = true
+	}
+	var retType wasmTypeInfo
+	if retIdx == 0 {
+		retType, _ = g.wasmType(res.At(0).Type())
+	}
+	switch {
+	case throws && retIdx == 0:
+		g.Printf("r0, err := %s\n", expr)
+		g.Printf("if err != nil {\n")
+		g.Printf("	return map[string]interface{}{\"error\": err.Error()}\n")
+		g.Printf("}\n")
+		g.Printf("return map[string]interface{}{\"value\": %s}\n", fmt.Sprintf(retType.goRet, "r0"))
+	case throws:
+		g.Printf("if err := %s; err != nil {\n", expr)
+		g.Printf("	return map[string]interface{}{\"error\": err.Error()}\n")
+		g.Printf("}\n")
+		g.Printf("return map[string]interface{}{}\n")
+	case retIdx == 0:
+		g.Printf("r0 := %s\n", expr)
+		g.Printf("return %s\n", fmt.Sprintf(retType.goRet, "r0"))
+	default:
+		g.Printf("%s\n", expr)
+		g.Printf("return nil\n")
+	}
+	g.Outdent()
+	g.Printf("}))\n")
+}
+
+func (g *WasmGen) genWasmVar(o *types.Var) {
+	t, ok := g.wasmType(o.Type())
+	if !ok {
+		g.Printf("// skipped variable %s with unsupported type %s\n", o.Name(), o.Type())
+		return
+	}
+	g.Printf("exports.Set(\"get_%s\", js.FuncOf(func(this js.Value, args []js.Value) interface{} {\n", o.Name())
+	g.Indent()
+	g.Printf("r0 := %s%s\n", g.wasmQual(), o.Name())
+	g.Printf("return %s\n", fmt.Sprintf(t.goRet, "r0"))
+	g.Outdent()
+	g.Printf("}))\n")
+	g.Printf("exports.Set(\"set_%s\", js.FuncOf(func(this js.Value, args []js.Value) interface{} {\n", o.Name())
+	g.Indent()
+	g.Printf("%s%s = %s\n", g.wasmQual(), o.Name(), fmt.Sprintf(t.goParam, "args[0]"))
+	g.Printf("return nil\n")
+	g.Outdent()
+	g.Printf("}))\n")
+}
+
+// GenWasmJS generates the JavaScript wrapper module.
+func (g *WasmGen) GenWasmJS() error {
+	pkgName := g.wasmPkgName()
+	g.Printf(gobindPreamble)
+	g.Printf("'use strict';\n\n")
+	g.Printf(wasmJSSupport, pkgName)
+
+	for _, c := range g.constants {
+		if _, ok := c.Type().(*types.Basic); !ok {
+			continue
+		}
+		g.Printf("const %s = %s;\n", c.Name(), c.Val().ExactString())
+	}
+	if len(g.constants) > 0 {
+		g.Printf("\n")
+	}
+
+	exports := []string{"load"}
+	for _, c := range g.constants {
+		if _, ok := c.Type().(*types.Basic); ok {
+			exports = append(exports, c.Name())
+		}
+	}
+	for _, s := range g.structs {
+		n := s.obj.Name()
+		g.Printf("class %s {\n", n)
+		g.Indent()
+		g.Printf("constructor(refnum) {\n")
+		g.Printf("	this._refnum = refnum === undefined ? addon.new_%s() : refnum;\n", n)
+		g.Printf("}\n\n")
+		g.Printf("destroy() {\n")
+		g.Printf("	addon.destroyRef(this._refnum);\n")
+		g.Printf("}\n\n")
+		for _, f := range exportedFields(s.t) {
+			t, ok := g.wasmType(f.Type())
+			if !ok {
+				continue
+			}
+			g.Printf("get %s() {\n", lowerFirst(f.Name()))
+			g.Printf("	return %s;\n", fmt.Sprintf(t.fromAddon, fmt.Sprintf("addon.%s_%s_Get(this._refnum)", n, f.Name())))
+			g.Printf("}\n\n")
+			g.Printf("set %s(v) {\n", lowerFirst(f.Name()))
+			g.Printf("	addon.%s_%s_Set(this._refnum, %s);\n", n, f.Name(), fmt.Sprintf(t.toAddon, "v"))
+			g.Printf("}\n\n")
+		}
+		for _, m := range exportedMethodSet(types.NewPointer(s.obj.Type())) {
+			if !g.isWasmSigSupported(m.Type()) {
+				g.Printf("// skipped method %s.%s with unsupported parameter or result types\n\n", n, m.Name())
+				continue
+			}
+			g.genWasmJSFunc(m, fmt.Sprintf("addon.%s_%s", n, m.Name()), true)
+		}
+		g.Outdent()
+		g.Printf("}\n\n")
+		exports = append(exports, n)
+	}
+	for _, f := range g.funcs {
+		if !g.isWasmSigSupported(f.Type()) {
+			g.Printf("// skipped function %s with unsupported parameter or result types\n\n", f.Name())
+			continue
+		}
+		g.genWasmJSFunc(f, "addon."+f.Name(), false)
+		exports = append(exports, lowerFirst(f.Name()))
+	}
+
+	g.Printf("module.exports = {\n")
+	g.Indent()
+	for _, e := range exports {
+		g.Printf("%s,\n", e)
+	}
+	g.Outdent()
+	g.Printf("};\n")
+	if len(g.err) > 0 {
+		return g.err
+	}
+	return nil
+}
+
+func (g *WasmGen) genWasmJSFunc(f *types.Func, call string, method bool) {
+	sig := f.Type().(*types.Signature)
+	params := sig.Params()
+	res := sig.Results()
+	var decl, args []string
+	if method {
+		args = append(args, "this._refnum")
+	}
+	for i := 0; i < params.Len(); i++ {
+		t, _ := g.wasmType(params.At(i).Type())
+		pn := basicParamName(params, i)
+		decl = append(decl, pn)
+		args = append(args, fmt.Sprintf(t.toAddon, pn))
+	}
+	name := lowerFirst(f.Name())
+	if method {
+		g.Printf("%s(%s) {\n", name, strings.Join(decl, ", "))
+	} else {
+		g.Printf("function %s(%s) {\n", name, strings.Join(decl, ", "))
+	}
+	g.Indent()
+	expr := fmt.Sprintf("%s(%s)", call, strings.Join(args, ", "))
+	retIdx := -1
+	throws := false
+	switch res.Len() {
+	case 1:
+		if isErrorType(res.At(0).Type()) {
+			throws = true
+		} else {
+			retIdx = 0
+		}
+	case 2:
+		retIdx = 0
+		throws = true
+	}
+	switch {
+	case throws:
+		g.Printf("const r = %s;\n", expr)
+		g.Printf("if (r.error !== undefined) {\n")
+		g.Printf("	throw goError(r.error);\n")
+		g.Printf("}\n")
+		if retIdx == 0 {
+			t, _ := g.wasmType(res.At(0).Type())
+			g.Printf("return %s;\n", fmt.Sprintf(t.fromAddon, "r.value"))
+		}
+	case retIdx == 0:
+		t, _ := g.wasmType(res.At(0).Type())
+		g.Printf("return %s;\n", fmt.Sprintf(t.fromAddon, expr))
+	default:
+		g.Printf("%s;\n", expr)
+	}
+	g.Outdent()
+	g.Printf("}\n\n")
+}
+
+// GenWasmTS generates the TypeScript declarations for the wrapper.
+func (g *WasmGen) GenWasmTS() error {
+	g.Printf(gobindPreamble)
+	g.Printf("export function load(source: Response | PromiseLike<Response> | BufferSource): Promise<void>;\n\n")
+	for _, c := range g.constants {
+		if t, ok := c.Type().(*types.Basic); ok {
+			g.Printf("export const %s: %s;\n", c.Name(), g.wasmTSBasicType(t))
+		}
+	}
+	if len(g.constants) > 0 {
+		g.Printf("\n")
+	}
+	for _, s := range g.structs {
+		n := s.obj.Name()
+		g.Printf("export class %s {\n", n)
+		g.Indent()
+		g.Printf("constructor();\n")
+		g.Printf("destroy(): void;\n")
+		for _, f := range exportedFields(s.t) {
+			if t, ok := g.wasmType(f.Type()); ok {
+				g.Printf("%s: %s;\n", lowerFirst(f.Name()), t.ts)
+			}
+		}
+		for _, m := range exportedMethodSet(types.NewPointer(s.obj.Type())) {
+			if g.isWasmSigSupported(m.Type()) {
+				g.genWasmTSFunc(m, "")
+			}
+		}
+		g.Outdent()
+		g.Printf("}\n\n")
+	}
+	for _, f := range g.funcs {
+		if g.isWasmSigSupported(f.Type()) {
+			g.genWasmTSFunc(f, "export function ")
+		}
+	}
+	if len(g.err) > 0 {
+		return g.err
+	}
+	return nil
+}
+
+func (g *WasmGen) genWasmTSFunc(f *types.Func, prefix string) {
+	sig := f.Type().(*types.Signature)
+	params := sig.Params()
+	var decl []string
+	for i := 0; i < params.Len(); i++ {
+		t, _ := g.wasmType(params.At(i).Type())
+		decl = append(decl, fmt.Sprintf("%s: %s", basicParamName(params, i), t.ts))
+	}
+	res := sig.Results()
+	ret := "void"
+	if res.Len() == 2 || res.Len() == 1 && !isErrorType(res.At(0).Type()) {
+		t, _ := g.wasmType(res.At(0).Type())
+		ret = t.ts
+	}
+	g.Printf("%s%s(%s): %s;\n", prefix, lowerFirst(f.Name()), strings.Join(decl, ", "), ret)
+}
+
+func (g *WasmGen) wasmTSBasicType(t *types.Basic) string {
+	switch t.Kind() {
+	case types.Bool, types.UntypedBool:
+		return "boolean"
+	case types.String, types.UntypedString:
+		return "string"
+	default:
+		return "number"
+	}
+}
+
+func (g *WasmGen) wasmPkgName() string {
+	if g.Pkg == nil {
+		return "universe"
+	}
+	return g.Pkg.Name()
+}
+
+// wasmQual returns the selector prefix for names in the bound package.
+func (g *WasmGen) wasmQual() string {
+	if g.Pkg == nil {
+		return ""
+	}
+	return g.Pkg.Name() + "."
+}
+
+// wasmTypeInfo describes how a Go type crosses the bridge: the Go-side
+// conversions between js.Value and the Go type (with %s substituted by
+// the value), the JavaScript-side conversions and the TypeScript
+// spelling.
+type wasmTypeInfo struct {
+	goParam   string
+	goRet     string
+	toAddon   string
+	fromAddon string
+	ts        string
+}
+
+func (g *WasmGen) wasmType(t types.Type) (wasmTypeInfo, bool) {
+	pass := func(goParam string) (wasmTypeInfo, bool) {
+		return wasmTypeInfo{goParam: goParam, goRet: "%s", toAddon: "%s", fromAddon: "%s", ts: "number"}, true
+	}
+	switch t := t.(type) {
+	case *types.Basic:
+		switch t.Kind() {
+		case types.Bool, types.UntypedBool:
+			info, _ := pass("%s.Bool()")
+			info.ts = "boolean"
+			return info, true
+		case types.Int, types.UntypedInt, types.UntypedRune:
+			return pass("%s.Int()")
+		case types.Int8:
+			return pass("int8(%s.Int())")
+		case types.Int16:
+			return pass("int16(%s.Int())")
+		case types.Int32:
+			return pass("int32(%s.Int())")
+		case types.Int64:
+			return pass("int64(%s.Int())")
+		case types.Uint8:
+			return pass("byte(%s.Int())")
+		case types.Float32:
+			return pass("float32(%s.Float())")
+		case types.Float64, types.UntypedFloat:
+			return pass("%s.Float()")
+		case types.String, types.UntypedString:
+			info, _ := pass("%s.String()")
+			info.ts = "string"
+			return info, true
+		}
+	case *types.Slice:
+		if e, ok := t.Elem().(*types.Basic); ok && e.Kind() == types.Uint8 {
+			return wasmTypeInfo{
+				goParam:   "wasmToBytes(%s)",
+				goRet:     "wasmFromBytes(%s)",
+				toAddon:   "%s",
+				fromAddon: "%s",
+				ts:        "Uint8Array",
+			}, true
+		}
+	case *types.Pointer:
+		if n, ok := t.Elem().(*types.Named); ok {
+			return g.wasmNamedType(n, true)
+		}
+	case *types.Named:
+		if isErrorType(t) {
+			return pass("%s.Int()")
+		}
+		return g.wasmNamedType(t, false)
+	}
+	return wasmTypeInfo{}, false
+}
+
+func (g *WasmGen) wasmNamedType(t *types.Named, pointer bool) (wasmTypeInfo, bool) {
+	n := t.Obj()
+	if n.Pkg() != g.Pkg {
+		return wasmTypeInfo{}, false
+	}
+	if _, ok := t.Underlying().(*types.Interface); ok {
+		return wasmTypeInfo{
+			goParam:   fmt.Sprintf("deref(%%s).(%s%s)", g.wasmQual(), n.Name()),
+			goRet:     "newRef(%s)",
+			toAddon:   "%s",
+			fromAddon: "%s",
+			ts:        "number",
+		}, true
+	}
+	if _, ok := t.Underlying().(*types.Struct); !ok {
+		return wasmTypeInfo{}, false
+	}
+	info := wasmTypeInfo{
+		toAddon:   "%s._refnum",
+		fromAddon: "new " + n.Name() + "(%s)",
+		ts:        n.Name(),
+	}
+	if pointer {
+		info.goParam = fmt.Sprintf("deref(%%s).(*%s%s)", g.wasmQual(), n.Name())
+		info.goRet = "newRef(%s)"
+	} else {
+		// Values are copied into a fresh reference so the proxy has an
+		// addressable object.
+		info.goParam = fmt.Sprintf("*deref(%%s).(*%s%s)", g.wasmQual(), n.Name())
+		info.goRet = "newRefCopy(%s)"
+	}
+	return info, true
+}
+
+// isWasmSigSupported reports whether the wasm generator can handle the
+// signature.
+func (g *WasmGen) isWasmSigSupported(t types.Type) bool {
+	if !g.isSigSupported(t) {
+		return false
+	}
+	sig := t.(*types.Signature)
+	params := sig.Params()
+	for i := 0; i < params.Len(); i++ {
+		if _, ok := g.wasmType(params.At(i).Type()); !ok {
+			return false
+		}
+	}
+	res := sig.Results()
+	for i := 0; i < res.Len(); i++ {
+		if _, ok := g.wasmType(res.At(i).Type()); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+const wasmGoSupport = `var (
+	refsMu  sync.Mutex
+	refs          = make(map[int32]interface{})
+	nextRef int32 = 1
+)
+
+func newRef(v interface{}) int32 {
+	refsMu.Lock()
+	defer refsMu.Unlock()
+	r := nextRef
+	nextRef++
+	refs[r] = v
+	return r
+}
+
+// newRefCopy stores a pointer to a copy of v, so field and method
+// access through the proxy has an addressable value.
+func newRefCopy[T any](v T) int32 {
+	return newRef(&v)
+}
+
+func deref(v js.Value) interface{} {
+	refsMu.Lock()
+	defer refsMu.Unlock()
+	return refs[int32(v.Int())]
+}
+
+func destroyRef(v js.Value) {
+	refsMu.Lock()
+	defer refsMu.Unlock()
+	delete(refs, int32(v.Int()))
+}
+
+func wasmToBytes(v js.Value) []byte {
+	b := make([]byte, v.Get("length").Int())
+	js.CopyBytesToGo(b, v)
+	return b
+}
+
+func wasmFromBytes(b []byte) js.Value {
+	v := js.Global().Get("Uint8Array").New(len(b))
+	js.CopyBytesToJS(v, b)
+	return v
+}
+
+`
+
+const wasmJSSupport = `let addon;
+
+/**
+ * Instantiates the wasm module and resolves once the Go side has
+ * registered its exports on the global %[1]q object. The Go runtime
+ * from wasm_exec.js must be loaded first, so globalThis.Go exists.
+ */
+async function load(source) {
+	const go = new globalThis.Go();
+	let result;
+	if (source instanceof Response || typeof source.then === 'function') {
+		result = await WebAssembly.instantiateStreaming(source, go.importObject);
+	} else {
+		result = await WebAssembly.instantiate(source, go.importObject);
+	}
+	go.run(result.instance);
+	addon = globalThis[%[1]q];
+}
+
+function goError(message) {
+	const e = new Error(message);
+	e.name = 'GoError';
+	return e;
+}
+
+`
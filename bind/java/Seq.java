@@ -15,14 +15,13 @@ import java.util.Collections;
 import java.util.IdentityHashMap;
 import java.util.HashSet;
 import java.util.Set;
-import java.util.logging.Logger;
 
 import go.Universe;
 
 // Seq is a sequence of machine-dependent encoded values.
 // Used by automatically generated language bindings to talk to Go.
 public class Seq {
-	private static Logger log = Logger.getLogger("GoSeq");
+	private static java.util.logging.Logger log = java.util.logging.Logger.getLogger("GoSeq");
 
 	// also known to bind/seq/ref.go and bind/objc/seq_darwin.m
 	private static final int NULL_REFNUM = 41;
@@ -30,15 +29,98 @@ public class Seq {
 	// use single Ref for null Object
 	public static final Ref nullRef = new Ref(NULL_REFNUM, null);
 
-	// The singleton GoRefQueue
-	private static final GoRefQueue goRefQueue = new GoRefQueue();
+	// The singleton tracker decrementing Go reference counts for
+	// reclaimed proxy objects.
+	private static final GoRefTracker goRefTracker = newGoRefTracker();
+
+	// newGoRefTracker returns a GoRefCleaner backed by
+	// java.lang.ref.Cleaner where the class is available (API level 33
+	// and later), and otherwise falls back to a GoRefQueue. Setting the
+	// go.seq.cleaner system property to "off" forces the fallback.
+	private static GoRefTracker newGoRefTracker() {
+		if (!"off".equals(System.getProperty("go.seq.cleaner"))) {
+			try {
+				return new GoRefCleaner();
+			} catch (Exception e) {
+				// Cleaner is unavailable; use the reference queue.
+			}
+		}
+		return new GoRefQueue();
+	}
 
 	static {
-		System.loadLibrary("gojni");
-		init();
+		Thread watchdog = startInitWatchdog();
+		try {
+			System.loadLibrary("gojni");
+			runLoadHook();
+			init();
+		} finally {
+			if (watchdog != null) {
+				watchdog.interrupt();
+			}
+		}
 		Universe.touch();
 	}
 
+	// startInitWatchdog guards runtime initialization against hangs,
+	// such as a deadlock in a Go package init. If the go.seq.inittimeout
+	// system property is set to a positive number of milliseconds and
+	// initialization does not complete in time, the watchdog logs the
+	// stack traces of all live threads and throws a RuntimeException
+	// instead of letting the app freeze silently at startup.
+	private static Thread startInitWatchdog() {
+		long parsed;
+		try {
+			parsed = Long.parseLong(System.getProperty("go.seq.inittimeout", "0"));
+		} catch (NumberFormatException e) {
+			parsed = 0;
+		}
+		if (parsed <= 0) {
+			return null;
+		}
+		final long millis = parsed;
+		Thread watchdog = new Thread(new Runnable() {
+			@Override public void run() {
+				try {
+					Thread.sleep(millis);
+				} catch (InterruptedException e) {
+					return; // Initialization completed.
+				}
+				StringBuilder msg = new StringBuilder("Go runtime initialization did not complete within " + millis + "ms");
+				for (java.util.Map.Entry<Thread, StackTraceElement[]> e : Thread.getAllStackTraces().entrySet()) {
+					msg.append("\n").append(e.getKey());
+					for (StackTraceElement frame : e.getValue()) {
+						msg.append("\n\tat ").append(frame);
+					}
+				}
+				logError(msg.toString(), null);
+				throw new RuntimeException("Go runtime initialization timed out after " + millis + "ms");
+			}
+		}, "GoSeqInitWatchdog");
+		watchdog.setDaemon(true);
+		watchdog.start();
+		return watchdog;
+	}
+
+	// runLoadHook runs the class named by the go.seq.loadhook system
+	// property, if any, after the native library is loaded but before
+	// the Go runtime is initialized. The class must be public,
+	// implement Runnable and have a public no-arg constructor. Host
+	// apps use it to run custom initialization at load time, typically
+	// a native method of their own that receives the JavaVM through
+	// the go_seq_on_load hook or JNI_OnLoad of their own library.
+	private static void runLoadHook() {
+		String name = System.getProperty("go.seq.loadhook", "");
+		if (name.isEmpty()) {
+			return;
+		}
+		try {
+			((Runnable)Class.forName(name).newInstance()).run();
+		} catch (Exception e) {
+			throw new RuntimeException("failed to run load hook " + name, e);
+		}
+	}
+
 	// setContext sets the context in the go-library to be used in RunOnJvm.
 	public static void setContext(Context context) {
 		setContext((java.lang.Object)context);
@@ -49,6 +131,80 @@ public class Seq {
 	// Empty method to run class initializer
 	public static void touch() {}
 
+	// A Logger receives structured events from the Seq runtime:
+	// reference creation and destruction, runtime errors and unhandled
+	// exceptions from Go callbacks. The level is one of "debug",
+	// "error" or "fatal". The exception is null unless the event was
+	// caused by one.
+	public interface Logger {
+		void log(String level, String message, Throwable exception);
+	}
+
+	private static volatile Logger logger;
+
+	// setLogger routes Seq runtime events to the given logger instead
+	// of java.util.logging. Pass null to restore the default. Reference
+	// creation and destruction events are only delivered to a custom
+	// logger.
+	public static void setLogger(Logger l) {
+		logger = l;
+	}
+
+	static void logError(String message, Throwable exception) {
+		Logger l = logger;
+		if (l != null) {
+			l.log("error", message, exception);
+		} else if (exception != null) {
+			log.log(java.util.logging.Level.SEVERE, message, exception);
+		} else {
+			log.severe(message);
+		}
+	}
+
+	// setLogLevel sets the log level of the named Go module, using
+	// log/slog level values: -4 debug, 0 info, 4 warn, 8 error. It only
+	// affects bound packages that derive their log handlers from
+	// seq.LevelVar.
+	public static native void setLogLevel(String module, int level);
+
+	// A GoRuntimeException carries a Go error when the bindings are
+	// generated with -exceptions=unchecked. The original error object
+	// is the cause.
+	public static final class GoRuntimeException extends RuntimeException {
+		public GoRuntimeException(Throwable cause) {
+			super(cause);
+		}
+	}
+
+	// A LogReceiver receives lines of Go program output captured from
+	// stdout, stderr and the Go log package. The priority is an
+	// android.util.Log priority constant.
+	public interface LogReceiver {
+		void onLogLine(int priority, String line);
+	}
+
+	// setLogTag sets the logcat tag for Go program output. The default
+	// is "GoLog" unless overridden at build time with the gomobile
+	// bind -logtag flag.
+	public static native void setLogTag(String tag);
+
+	// setLogMinPriority drops Go program output below the given
+	// android.util.Log priority before it reaches logcat or the log
+	// receiver.
+	public static native void setLogMinPriority(int priority);
+
+	// setLogReceiver routes Go program output to the given receiver
+	// instead of logcat, so apps can feed it into their own logging
+	// pipeline. Pass null to restore logcat delivery.
+	public static native void setLogReceiver(LogReceiver receiver);
+
+	static void logDebug(String message) {
+		Logger l = logger;
+		if (l != null) {
+			l.log("debug", message, null);
+		}
+	}
+
 	private Seq() {
 	}
 
@@ -72,7 +228,9 @@ public class Seq {
 	}
 
 	// trackGoRef tracks a Go reference and decrements its refcount
-	// when the given GoObject wrapper is garbage collected.
+	// when the given GoObject wrapper is garbage collected. The
+	// returned handle can be passed to closeGoRef to release the
+	// reference early.
 	//
 	// TODO(crawshaw): We could cut down allocations for frequently
 	// sent Go objects by maintaining a map to weak references. This
@@ -81,11 +239,21 @@ public class Seq {
 	// of any Java debugging session.
 	//
 	// When we have real code, examine the tradeoffs.
-	public static void trackGoRef(int refnum, GoObject obj) {
+	public static Object trackGoRef(int refnum, GoObject obj) {
 		if (refnum > 0) {
 			throw new RuntimeException("trackGoRef called with Java refnum " + refnum);
 		}
-		goRefQueue.track(refnum, obj);
+		return goRefTracker.track(refnum, obj);
+	}
+
+	// closeGoRef releases a tracked Go reference without waiting for
+	// the garbage collector, given the handle returned by trackGoRef.
+	// It is idempotent and ignores null handles. The generated close
+	// methods of proxy classes call it.
+	public static void closeGoRef(Object handle) {
+		if (handle != null) {
+			goRefTracker.close(handle);
+		}
 	}
 
 	public static Ref getRef(int refnum) {
@@ -101,6 +269,36 @@ public class Seq {
 	// Informs the Go ref tracker that Java is done with this refnum.
 	public static native void destroyRef(int refnum);
 
+	// errorIs reports whether target matches any error in the Go unwrap
+	// chain of err, following the semantics of Go's errors.Is. Both
+	// exceptions must originate from Go; for other exceptions only
+	// reference equality is checked.
+	public static boolean errorIs(Throwable err, Throwable target) {
+		if (err == target) {
+			return true;
+		}
+		if (err instanceof Proxy && target instanceof Proxy) {
+			return errorIs(((Proxy)err).incRefnum(), ((Proxy)target).incRefnum());
+		}
+		return false;
+	}
+
+	private static native boolean errorIs(int refnum, int targetRefnum);
+
+	// errorChain describes each error in the Go unwrap chain of err,
+	// outermost first, on the form "type: message". For exceptions that
+	// do not originate from Go, the chain is the exception itself.
+	public static String[] errorChain(Throwable err) {
+		if (!(err instanceof Proxy)) {
+			return new String[]{err.toString()};
+		}
+		// The entries are joined with the unit separator (0x1f) on the
+		// Go side.
+		return errorChain(((Proxy)err).incRefnum()).split("\u001f", -1);
+	}
+
+	private static native String errorChain(int refnum);
+
 	// decRef is called from seq.FinalizeRef
 	static void decRef(int refnum) {
 		tracker.dec(refnum);
@@ -192,6 +390,7 @@ public class Seq {
 			if (ref == null) {
 				ref = new Ref(refnum, o);
 				javaObjs.put(refnum, ref);
+				logDebug("created ref " + refnum + " for " + o.getClass().getName());
 			}
 			ref.inc();
 			return refnum;
@@ -213,7 +412,7 @@ public class Seq {
 			if (refnum <= 0) {
 				// We don't keep track of the Go object.
 				// This must not happen.
-				log.severe("dec request for Go object "+ refnum);
+				logError("dec request for Go object "+ refnum, null);
 				return;
 			}
 			if (refnum == Seq.nullRef.refnum) {
@@ -228,6 +427,7 @@ public class Seq {
 			if (obj.refcnt <= 0) {
 				javaObjs.remove(refnum);
 				javaRefs.remove(obj.obj);
+				logDebug("destroyed ref " + refnum);
 			}
 		}
 
@@ -247,15 +447,81 @@ public class Seq {
 		}
 	}
 
+	// A GoRefTracker decrements the Go reference count of a proxy
+	// object after the Java GC has reclaimed it. track returns a
+	// handle that close accepts to release the reference early; close
+	// is idempotent.
+	interface GoRefTracker {
+		Object track(int refnum, GoObject obj);
+		void close(Object handle);
+	}
+
+	// GoRefCleaner tracks proxy objects with a java.lang.ref.Cleaner,
+	// which reclaims Go references promptly without a dedicated queue
+	// thread. The Cleaner API is accessed through reflection so the
+	// class compiles and loads on API levels that lack it; construction
+	// fails there and the caller falls back to GoRefQueue.
+	static class GoRefCleaner implements GoRefTracker {
+		private final Object cleaner;
+		private final java.lang.reflect.Method register;
+		private final java.lang.reflect.Method clean;
+
+		GoRefCleaner() throws Exception {
+			Class<?> cl = Class.forName("java.lang.ref.Cleaner");
+			cleaner = cl.getMethod("create").invoke(null);
+			register = cl.getMethod("register", Object.class, Runnable.class);
+			clean = Class.forName("java.lang.ref.Cleaner$Cleanable").getMethod("clean");
+		}
+
+		@Override public Object track(int refnum, GoObject obj) {
+			// The clean action must not reference obj, or it would
+			// never become phantom reachable.
+			final int num = refnum;
+			try {
+				return register.invoke(cleaner, obj, new Runnable() {
+					@Override public void run() {
+						destroyRef(num);
+						logDebug("destroyed Go ref " + num);
+					}
+				});
+			} catch (Exception e) {
+				throw new RuntimeException("failed to register with Cleaner", e);
+			}
+		}
+
+		@Override public void close(Object handle) {
+			// Cleanable.clean runs the action at most once, making
+			// close idempotent and safe against the later GC pass.
+			try {
+				clean.invoke(handle);
+			} catch (Exception e) {
+				throw new RuntimeException("failed to clean Go ref", e);
+			}
+		}
+	}
+
 	// GoRefQueue is a queue of GoRefs that are no longer live. An internal thread
 	// processes the queue and decrement the reference count on the Go side.
-	static class GoRefQueue extends ReferenceQueue<GoObject> {
+	static class GoRefQueue extends ReferenceQueue<GoObject> implements GoRefTracker {
 		// The set of tracked GoRefs. If we don't hold on to the GoRef instances, the Java GC
 		// will not add them to the queue when their referents are reclaimed.
 		private final Collection<GoRef> refs = Collections.synchronizedCollection(new HashSet<GoRef>());
 
-		void track(int refnum, GoObject obj) {
-			refs.add(new GoRef(refnum, obj, this));
+		@Override public Object track(int refnum, GoObject obj) {
+			GoRef ref = new GoRef(refnum, obj, this);
+			refs.add(ref);
+			return ref;
+		}
+
+		@Override public void close(Object handle) {
+			// Whoever removes the GoRef from the set destroys the
+			// reference, making close and the queue thread race-free.
+			GoRef ref = (GoRef)handle;
+			if (refs.remove(ref)) {
+				destroyRef(ref.refnum);
+				ref.clear();
+				logDebug("destroyed Go ref " + ref.refnum);
+			}
 		}
 
 		GoRefQueue() {
@@ -264,8 +530,10 @@ public class Seq {
 					while (true) {
 						try {
 							GoRef ref = (GoRef)remove();
-							refs.remove(ref);
-							destroyRef(ref.refnum);
+							if (refs.remove(ref)) {
+								destroyRef(ref.refnum);
+								logDebug("destroyed Go ref " + ref.refnum);
+							}
 							ref.clear();
 						} catch (InterruptedException e) {
 							// Ignore
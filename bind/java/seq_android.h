@@ -30,6 +30,19 @@ typedef struct nbyteslice {
 	void *ptr;
 	jsize len;
 } nbyteslice;
+typedef struct nbytesliceslice {
+	nbyteslice *ptr;
+	jsize len;
+} nbytesliceslice;
+typedef struct nstringslice {
+	nstring *ptr;
+	jsize len;
+} nstringslice;
+typedef struct nstringmap {
+	nstring *keys;
+	nstring *vals;
+	jsize len;
+} nstringmap;
 typedef jlong nint;
 
 extern void go_seq_dec_ref(int32_t ref);
@@ -39,18 +52,64 @@ extern void go_seq_inc_ref(int32_t ref);
 extern int32_t go_seq_unwrap(jint refnum);
 extern int32_t go_seq_to_refnum(JNIEnv *env, jobject o);
 extern int32_t go_seq_to_refnum_go(JNIEnv *env, jobject o);
+// go_seq_peek_refnum_go reads the refnum of a Go-backed proxy without
+// touching the reference count, for //gomobile:fastpath receivers.
+extern int32_t go_seq_peek_refnum_go(JNIEnv *env, jobject o, jfieldID refnum_fld);
 extern jobject go_seq_from_refnum(JNIEnv *env, int32_t refnum, jclass proxy_class, jmethodID proxy_cons);
 
 extern void go_seq_maybe_throw_exception(JNIEnv *env, jobject msg);
+// go_seq_maybe_throw_unchecked throws the exception wrapped in a
+// Seq.GoRuntimeException, for bindings generated with
+// -exceptions=unchecked.
+extern void go_seq_maybe_throw_unchecked(JNIEnv *env, jobject exc);
+// go_seq_register_error_class records the Java exception class
+// generated for the named Go error type.
+extern void go_seq_register_error_class(const char *name, jclass clazz, jmethodID cons);
+// go_seq_wrap_error converts an error refnum to the exception class
+// registered for its dynamic type, falling back to the given generic
+// error proxy class.
+extern jobject go_seq_wrap_error(JNIEnv *env, int32_t refnum, jclass fallback_clazz, jmethodID fallback_cons);
+// go_seq_throw_null throws a NullPointerException naming the offending
+// parameter.
+extern void go_seq_throw_null(JNIEnv *env, const char *name);
 // go_seq_get_exception returns any pending exception and clears the exception status.
 extern jobject go_seq_get_exception(JNIEnv *env);
 
 extern jbyteArray go_seq_to_java_bytearray(JNIEnv *env, nbyteslice s, int copy);
+// go_seq_to_java_bytearray_nocopy copies a Go-pinned backing array
+// into a Java array and releases the pin. JNI arrays always copy; the
+// variant only exists so //gobind:nocopy functions work on Android.
+extern jbyteArray go_seq_to_java_bytearray_nocopy(JNIEnv *env, nbyteslice s);
 extern nbyteslice go_seq_from_java_bytearray(JNIEnv *env, jbyteArray s, int copy);
 extern void go_seq_release_byte_array(JNIEnv *env, jbyteArray arr, jbyte* ptr);
+// go_seq_to_java_byte2array takes ownership of the array and its
+// elements and frees them. go_seq_from_java_byte2array returns a
+// malloc'ed deep copy, freed by the Go side in one pass.
+extern jobjectArray go_seq_to_java_byte2array(JNIEnv *env, nbytesliceslice s);
+extern nbytesliceslice go_seq_from_java_byte2array(JNIEnv *env, jobjectArray a);
+
+// go_seq_send_log delivers a captured line of Go program output to the
+// Java log receiver. The line is freed after use.
+extern void go_seq_send_log(int32_t priority, nstring line);
 
 extern jstring go_seq_to_java_string(JNIEnv *env, nstring str);
 extern nstring go_seq_from_java_string(JNIEnv *env, jstring s);
+// go_seq_to_java_string2array takes ownership of the array and its
+// elements and frees them. go_seq_from_java_string2array returns a
+// malloc'ed deep copy, freed by the Go side in one pass.
+extern jobjectArray go_seq_to_java_string2array(JNIEnv *env, nstringslice s);
+extern nstringslice go_seq_from_java_string2array(JNIEnv *env, jobjectArray a);
+// The list and map helpers marshal Go slices and maps as java.util
+// collections with copy-in/copy-out semantics. The to_java variants
+// take ownership of the array and its elements and free them; the
+// from_java variants return malloc'ed deep copies, freed by the Go
+// side in one pass.
+extern jobject go_seq_to_java_stringlist(JNIEnv *env, nstringslice s);
+extern nstringslice go_seq_from_java_stringlist(JNIEnv *env, jobject list);
+extern jobject go_seq_to_java_bytelist(JNIEnv *env, nbytesliceslice s);
+extern nbytesliceslice go_seq_from_java_bytelist(JNIEnv *env, jobject list);
+extern jobject go_seq_to_java_stringmap(JNIEnv *env, nstringmap m);
+extern nstringmap go_seq_from_java_stringmap(JNIEnv *env, jobject map);
 
 // push_local_frame retrieves or creates the JNIEnv* for the current thread
 // and pushes a JNI reference frame. Must be matched with call to pop_local_frame.
@@ -58,6 +117,11 @@ extern JNIEnv *go_seq_push_local_frame(jint cap);
 // Pop the current local frame, releasing all JNI local references in it
 extern void go_seq_pop_local_frame(JNIEnv *env);
 
+// go_seq_on_load is a weak no-op hook called when the Go library is
+// initialized, before the Go runtime starts. Host apps can override it
+// with their own native initialization.
+extern void go_seq_on_load(JavaVM *vm, JNIEnv *env);
+
 // Return a global reference to the given class. Return NULL and clear exception if not found.
 extern jclass go_seq_find_class(const char *name);
 extern jmethodID go_seq_get_static_method_id(jclass clazz, const char *name, const char *sig);
@@ -9,16 +9,30 @@
 #include "ref.h"
 #include "Universe.objc.h"
 
+// GoSeqLogHandler receives structured events from the Seq runtime:
+// reference creation and destruction, runtime errors and fatal
+// conditions. The level is one of @"debug", @"info" or @"fatal".
+typedef void (^GoSeqLogHandler)(NSString *level, NSString *message);
+// go_seq_set_log_handler routes Seq runtime events to the given
+// handler instead of NSLog. Pass NULL to restore the default.
+extern void go_seq_set_log_handler(GoSeqLogHandler handler);
+extern void go_seq_log(NSString *level, NSString *format, ...);
+// go_seq_set_log_level sets the log level of the named Go module,
+// using log/slog level values: -4 debug, 0 info, 4 warn, 8 error. It
+// only affects bound packages that derive their log handlers from
+// seq.LevelVar.
+extern void go_seq_set_log_level(NSString *module, int level);
+
 #ifdef DEBUG
-#define LOG_DEBUG(...) NSLog(__VA_ARGS__);
+#define LOG_DEBUG(...) go_seq_log(@"debug", __VA_ARGS__);
 #else
 #define LOG_DEBUG(...) ;
 #endif
 
-#define LOG_INFO(...) NSLog(__VA_ARGS__);
+#define LOG_INFO(...) go_seq_log(@"info", __VA_ARGS__);
 #define LOG_FATAL(...)                                                         \
   {                                                                            \
-    NSLog(__VA_ARGS__);                                                        \
+    go_seq_log(@"fatal", __VA_ARGS__);                                         \
     @throw                                                                     \
         [NSException exceptionWithName:NSInternalInconsistencyException        \
                                 reason:[NSString stringWithFormat:__VA_ARGS__] \
@@ -34,7 +48,20 @@ typedef struct nbyteslice {
 	void *ptr;
 	int len;
 } nbyteslice;
-typedef int nint;
+typedef struct nbytesliceslice {
+	nbyteslice *ptr;
+	int len;
+} nbytesliceslice;
+typedef struct nstringslice {
+	nstring *ptr;
+	int len;
+} nstringslice;
+typedef struct nstringmap {
+	nstring *keys;
+	nstring *vals;
+	int len;
+} nstringmap;
+typedef int64_t nint;
 
 extern void init_seq();
 // go_seq_dec_ref decrements the reference count for the
@@ -55,9 +82,52 @@ extern GoSeqRef *go_seq_from_refnum(int32_t refnum);
 extern id go_seq_objc_from_refnum(int32_t refnum);
 
 extern nbyteslice go_seq_from_objc_bytearray(NSData *data, int copy);
+// go_seq_from_objc_byte2array returns a malloc'ed deep copy, freed by
+// the Go side in one pass.
+extern nbytesliceslice go_seq_from_objc_byte2array(NSArray<NSData *> *a);
 extern nstring go_seq_from_objc_string(NSString *s);
+// go_seq_from_objc_string2array returns a malloc'ed deep copy, freed
+// by the Go side in one pass.
+extern nstringslice go_seq_from_objc_string2array(NSArray<NSString *> *a);
+// go_seq_from_objc_stringdict returns a malloc'ed deep copy of the
+// dictionary, freed by the Go side in one pass.
+extern nstringmap go_seq_from_objc_stringdict(NSDictionary<NSString *, NSString *> *d);
+
+// go_seq_objc_error_is reports whether target matches any error in the
+// Go unwrap chain of err, following the semantics of Go's errors.Is.
+// Both errors must originate from Go; for other errors only pointer
+// equality is checked.
+extern BOOL go_seq_objc_error_is(NSError *err, NSError *target);
+// go_seq_objc_error_chain describes each error in the Go unwrap chain
+// of err, outermost first, on the form "type: message". For errors
+// that do not originate from Go, the chain is the error's description.
+extern NSArray<NSString *> *go_seq_objc_error_chain(NSError *err);
+// go_seq_objc_error_chain_refnum is the variant of
+// go_seq_objc_error_chain that starts from a Go refnum, for use before
+// the proxy NSError is fully constructed.
+extern NSArray<NSString *> *go_seq_objc_error_chain_refnum(int32_t refnum);
+// GoSeqErrorChainKey is the NSError userInfo key under which the Go
+// unwrap chain of the error is stored, outermost first.
+extern NSString *const GoSeqErrorChainKey;
+// go_seq_objc_error_info looks up the error domain and code the Go
+// side registered for the error pinned by refnum. It returns NO and
+// leaves domain and code untouched when no registered sentinel error
+// matches.
+extern BOOL go_seq_objc_error_info(int32_t refnum, NSString **domain, NSInteger *code);
 
 extern NSData *go_seq_to_objc_bytearray(nbyteslice, int copy);
+// go_seq_to_objc_bytearray_nocopy wraps a Go-pinned backing array
+// without copying; the NSData deallocator releases the pin.
+extern NSData *go_seq_to_objc_bytearray_nocopy(nbyteslice s);
+// go_seq_to_objc_byte2array takes ownership of the array and its
+// elements.
+extern NSArray<NSData *> *go_seq_to_objc_byte2array(nbytesliceslice s);
 extern NSString *go_seq_to_objc_string(nstring str);
+// go_seq_to_objc_string2array takes ownership of the array and its
+// elements.
+extern NSArray<NSString *> *go_seq_to_objc_string2array(nstringslice s);
+// go_seq_to_objc_stringdict takes ownership of the key and value
+// arrays and their elements.
+extern NSDictionary<NSString *, NSString *> *go_seq_to_objc_stringdict(nstringmap m);
 
 #endif // __GO_SEQ_DARWIN_HDR__
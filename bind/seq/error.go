@@ -0,0 +1,125 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package seq
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"reflect"
+	"sync"
+)
+
+// ErrorIs reports whether target matches any error in the unwrap chain
+// of the Go error pinned by the err refnum, following the semantics of
+// errors.Is. Both refnums must refer to Go objects and are released
+// after use, balancing the increment from the calling side.
+func ErrorIs(err, target int32) bool {
+	errRef, targetRef := FromRefNum(err), FromRefNum(target)
+	if errRef == nil || targetRef == nil {
+		return false
+	}
+	e, _ := errRef.Get().(error)
+	t, _ := targetRef.Get().(error)
+	if e == nil || t == nil {
+		return false
+	}
+	return errors.Is(e, t)
+}
+
+// errorCode pairs a sentinel error value with the domain and code the
+// generated bindings assigned to it.
+type errorCode struct {
+	err    error
+	domain string
+	code   int32
+}
+
+var (
+	errorCodesMu sync.Mutex
+	errorCodes   []errorCode
+)
+
+// RegisterErrorCode associates a sentinel error value with an error
+// domain and a stable code. The generated bindings call it from init
+// for every exported error variable of a bound package.
+func RegisterErrorCode(err error, domain string, code int32) {
+	if err == nil {
+		return
+	}
+	errorCodesMu.Lock()
+	defer errorCodesMu.Unlock()
+	errorCodes = append(errorCodes, errorCode{err, domain, code})
+}
+
+// ErrorInfo resolves the domain and code registered for the Go error
+// pinned by refnum, matching against the registered sentinel errors
+// with the semantics of errors.Is. It returns "", 0 when no sentinel
+// matches.
+func ErrorInfo(refnum int32) (domain string, code int32) {
+	ref := FromRefNum(refnum)
+	if ref == nil {
+		return "", 0
+	}
+	e, _ := ref.Get().(error)
+	if e == nil {
+		return "", 0
+	}
+	errorCodesMu.Lock()
+	defer errorCodesMu.Unlock()
+	for _, ec := range errorCodes {
+		if errors.Is(e, ec.err) {
+			return ec.domain, ec.code
+		}
+	}
+	return "", 0
+}
+
+// ErrorTypeName returns the dynamic type of the Go error pinned by
+// refnum on the form "pkgname.TypeName", or "" if the type is unnamed
+// or the refnum refers to a foreign object. The host bindings use it
+// to surface an error as the exception class generated for its type.
+// Unlike the other lookups, the refnum is not released: the caller
+// keeps its reference and hands it on to the exception object it
+// constructs from the same refnum.
+func ErrorTypeName(refnum int32) string {
+	if refnum > 0 {
+		// A foreign object; only Go errors have bound types.
+		return ""
+	}
+	ref := FromRefNum(refnum)
+	if ref == nil {
+		return ""
+	}
+	e, _ := ref.Peek().(error)
+	if e == nil {
+		return ""
+	}
+	t := reflect.TypeOf(e)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Name() == "" || t.PkgPath() == "" {
+		return ""
+	}
+	return path.Base(t.PkgPath()) + "." + t.Name()
+}
+
+// ErrorChain describes each error in the unwrap chain of the Go error
+// pinned by refnum, outermost first, on the form "type: message". The
+// refnum is released after use.
+func ErrorChain(refnum int32) []string {
+	ref := FromRefNum(refnum)
+	if ref == nil {
+		return nil
+	}
+	e, _ := ref.Get().(error)
+	var chain []string
+	for e != nil {
+		chain = append(chain, fmt.Sprintf("%T: %s", e, e.Error()))
+		e = errors.Unwrap(e)
+	}
+	return chain
+}
@@ -0,0 +1,28 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package seq
+
+import "github.com/sagernet/gomobile/internal/mobileinit"
+
+// Routing of captured Go program output (stdout, stderr and the log
+// package) on Android. The host side adjusts it at runtime through the
+// generated Seq API.
+
+// SetLogTag sets the logcat tag for Go program output.
+func SetLogTag(tag string) {
+	mobileinit.SetLogTag(tag)
+}
+
+// SetLogMinPriority drops Go program output below the given
+// android/log.h priority before it reaches logcat or the log receiver.
+func SetLogMinPriority(priority int32) {
+	mobileinit.SetLogMinPriority(priority)
+}
+
+// SetLogReceiver routes Go program output to f instead of logcat.
+// Pass nil to restore logcat delivery.
+func SetLogReceiver(f func(priority int32, line string)) {
+	mobileinit.SetLogReceiver(f)
+}
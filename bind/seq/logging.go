@@ -0,0 +1,44 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package seq
+
+import (
+	"log/slog"
+	"sync"
+)
+
+// Per-module log levels, adjustable from the host side at runtime so
+// verbose logging can be enabled for a single module without a
+// rebuild. Levels follow log/slog conventions: -4 debug, 0 info,
+// 4 warn, 8 error.
+
+var logLevels struct {
+	sync.Mutex
+	vars map[string]*slog.LevelVar
+}
+
+// LevelVar returns the slog.LevelVar controlling the log level of the
+// named module, creating it at slog.LevelInfo if needed. Bound
+// packages derive their slog handlers from it; the host adjusts it at
+// runtime through the generated Seq API.
+func LevelVar(module string) *slog.LevelVar {
+	logLevels.Lock()
+	defer logLevels.Unlock()
+	if logLevels.vars == nil {
+		logLevels.vars = make(map[string]*slog.LevelVar)
+	}
+	v, ok := logLevels.vars[module]
+	if !ok {
+		v = new(slog.LevelVar)
+		logLevels.vars[module] = v
+	}
+	return v
+}
+
+// SetLogLevel sets the log level of the named module. It is called
+// from the host side through the generated Seq API.
+func SetLogLevel(module string, level int32) {
+	LevelVar(module).Set(slog.Level(level))
+}
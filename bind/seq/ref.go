@@ -123,6 +123,22 @@ func (r *Ref) Get() interface{} {
 	return o.obj
 }
 
+// Peek returns the underlying object without releasing the
+// reference. It is used for lookups on a refnum whose single
+// reference is owned by the caller and passed on afterwards, such as
+// resolving the exception class for an error crossing to the host
+// language.
+func (r *Ref) Peek() interface{} {
+	refnum := r.Bind_Num
+	refs.Lock()
+	o, ok := refs.objs[refnum]
+	refs.Unlock()
+	if !ok {
+		panic(fmt.Sprintf("unknown ref %d", refnum))
+	}
+	return o.obj
+}
+
 // Inc increments the reference count for a refnum. Called from Bind_proxy_refnum
 // functions.
 func Inc(num int32) {
@@ -0,0 +1,50 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+#ifndef __GO_SEQ_DESKTOP_HDR__
+#define __GO_SEQ_DESKTOP_HDR__
+
+#include <stdint.h>
+#include <stdio.h>
+// For abort()
+#include <stdlib.h>
+
+#define LOG_INFO(...) fprintf(stderr, __VA_ARGS__)
+#define LOG_FATAL(...)              \
+  {                                 \
+    fprintf(stderr, __VA_ARGS__);   \
+    fputc('\n', stderr);            \
+    abort();                        \
+  }
+
+// Platform specific types. Values are malloc'ed; ownership transfers
+// with the value in both directions, matching the Darwin convention.
+typedef struct nstring {
+	// UTF8 encoded string.
+	void *ptr;
+	int len;
+} nstring;
+typedef struct nbyteslice {
+	void *ptr;
+	int len;
+} nbyteslice;
+typedef struct nbytesliceslice {
+	nbyteslice *ptr;
+	int len;
+} nbytesliceslice;
+typedef struct nstringslice {
+	nstring *ptr;
+	int len;
+} nstringslice;
+typedef struct nstringmap {
+	nstring *keys;
+	nstring *vals;
+	int len;
+} nstringmap;
+typedef int64_t nint;
+
+extern void go_seq_dec_ref(int32_t ref);
+extern void go_seq_inc_ref(int32_t ref);
+
+#endif // __GO_SEQ_DESKTOP_HDR__
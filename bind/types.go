@@ -103,10 +103,99 @@ func exportedFields(T *types.Struct) []*types.Var {
 	return fields
 }
 
+// isByteSlice reports whether t is []byte.
+func isByteSlice(t types.Type) bool {
+	s, ok := t.(*types.Slice)
+	if !ok {
+		return false
+	}
+	b, ok := s.Elem().(*types.Basic)
+	return ok && b.Kind() == types.Uint8
+}
+
+// isByteSliceSlice reports whether t is [][]byte, which is marshaled
+// as a native array of byte slices.
+func isByteSliceSlice(t types.Type) bool {
+	s, ok := t.(*types.Slice)
+	if !ok {
+		return false
+	}
+	e, ok := s.Elem().(*types.Slice)
+	if !ok {
+		return false
+	}
+	b, ok := e.Elem().(*types.Basic)
+	return ok && b.Kind() == types.Uint8
+}
+
+// isStringSlice reports whether t is []string, which is marshaled as a
+// native array of strings.
+func isStringSlice(t types.Type) bool {
+	s, ok := t.(*types.Slice)
+	if !ok {
+		return false
+	}
+	b, ok := s.Elem().(*types.Basic)
+	return ok && b.Kind() == types.String
+}
+
+// isStringMap reports whether t is map[string]string, which is
+// marshaled as native arrays of keys and values.
+func isStringMap(t types.Type) bool {
+	m, ok := t.(*types.Map)
+	if !ok {
+		return false
+	}
+	k, ok := m.Key().(*types.Basic)
+	if !ok || k.Kind() != types.String {
+		return false
+	}
+	e, ok := m.Elem().(*types.Basic)
+	return ok && e.Kind() == types.String
+}
+
+// isNullSubstituted reports whether a null foreign value of type t is
+// silently substituted with an empty Go value at the boundary:
+// strings, slices and maps.
+func isNullSubstituted(t types.Type) bool {
+	switch t := t.(type) {
+	case *types.Basic:
+		return t.Kind() == types.String || t.Kind() == types.UntypedString
+	case *types.Slice, *types.Map:
+		return true
+	}
+	return false
+}
+
+// embeddedBoundStruct returns the type T if f is an embedded field of
+// bound struct type T held by value. Such fields cannot be marshaled
+// directly, but the embedded part can be exposed through a pointer to it.
+func embeddedBoundStruct(f *types.Var) (*types.Named, bool) {
+	if !f.Embedded() {
+		return nil, false
+	}
+	nt, ok := f.Type().(*types.Named)
+	if !ok {
+		return nil, false
+	}
+	if _, ok := nt.Underlying().(*types.Struct); !ok {
+		return nil, false
+	}
+	return nt, true
+}
+
 func isErrorType(t types.Type) bool {
 	return types.Identical(t, types.Universe.Lookup("error").Type())
 }
 
+// implementsError reports whether a pointer to t implements the error
+// interface. It is used to generate exception classes for exported Go
+// error types.
+func implementsError(t types.Type) bool {
+	errIntf := types.Universe.Lookup("error").Type().Underlying().(*types.Interface)
+	return types.Implements(types.NewPointer(t), errIntf)
+}
+
 func isExported(t types.Type) bool {
 	if isErrorType(t) {
 		return true
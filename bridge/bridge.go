@@ -0,0 +1,107 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package bridge defines host-implemented service interfaces for bound
+// libraries.
+//
+// A bound library cannot reach platform facilities such as the
+// clipboard or the share sheet directly; the host app implements the
+// interfaces defined here through the generated bindings and registers
+// the implementations at startup. Library code then uses the package
+// level helpers without per-host glue code.
+package bridge // import "github.com/sagernet/gomobile/bridge"
+
+import (
+	"errors"
+	"sync"
+)
+
+// Clipboard places text or binary data on the host clipboard.
+type Clipboard interface {
+	// SetText places plain text on the clipboard.
+	SetText(text string) error
+	// SetData places binary data with the given MIME type on the
+	// clipboard.
+	SetData(mimeType string, data []byte) error
+}
+
+// ShareSheet invokes the platform share sheet with the given content.
+type ShareSheet interface {
+	// ShareText presents the share sheet for plain text.
+	ShareText(text string) error
+	// ShareData presents the share sheet for binary data with the
+	// given MIME type.
+	ShareData(mimeType string, data []byte) error
+}
+
+var (
+	mu         sync.RWMutex
+	clipboard  Clipboard
+	shareSheet ShareSheet
+)
+
+// ErrNotRegistered is returned by the package level helpers when the
+// host app has not registered an adapter for the requested service.
+var ErrNotRegistered = errors.New("bridge: no host adapter registered")
+
+// RegisterClipboard registers the host clipboard adapter. It is
+// typically called by the host app before any library code runs.
+func RegisterClipboard(c Clipboard) {
+	mu.Lock()
+	clipboard = c
+	mu.Unlock()
+}
+
+// RegisterShareSheet registers the host share sheet adapter.
+func RegisterShareSheet(s ShareSheet) {
+	mu.Lock()
+	shareSheet = s
+	mu.Unlock()
+}
+
+// SetClipboardText places plain text on the host clipboard.
+func SetClipboardText(text string) error {
+	mu.RLock()
+	c := clipboard
+	mu.RUnlock()
+	if c == nil {
+		return ErrNotRegistered
+	}
+	return c.SetText(text)
+}
+
+// SetClipboardData places binary data with the given MIME type on the
+// host clipboard.
+func SetClipboardData(mimeType string, data []byte) error {
+	mu.RLock()
+	c := clipboard
+	mu.RUnlock()
+	if c == nil {
+		return ErrNotRegistered
+	}
+	return c.SetData(mimeType, data)
+}
+
+// ShareText presents the platform share sheet for plain text.
+func ShareText(text string) error {
+	mu.RLock()
+	s := shareSheet
+	mu.RUnlock()
+	if s == nil {
+		return ErrNotRegistered
+	}
+	return s.ShareText(text)
+}
+
+// ShareData presents the platform share sheet for binary data with the
+// given MIME type.
+func ShareData(mimeType string, data []byte) error {
+	mu.RLock()
+	s := shareSheet
+	mu.RUnlock()
+	if s == nil {
+		return ErrNotRegistered
+	}
+	return s.ShareData(mimeType, data)
+}
@@ -30,6 +30,7 @@ func genPkg(lang string, p *types.Package, astFiles []*ast.File, allPkg []*types
 	conf := &bind.GeneratorConfig{
 		Fset:   fset,
 		Pkg:    p,
+		Files:  astFiles,
 		AllPkg: allPkg,
 	}
 	var pname string
@@ -47,10 +48,13 @@ func genPkg(lang string, p *types.Package, astFiles []*ast.File, allPkg []*types
 		Files:   astFiles,
 	}
 	switch lang {
-	case "java":
+	case "java", "kotlin":
 		g := &bind.JavaGen{
-			JavaPkg:   *javaPkg,
-			Generator: generator,
+			JavaPkg:             *javaPkg,
+			RejectNulls:         *nullPolicy == "reject",
+			Annotations:         *annotations,
+			UncheckedExceptions: *exceptions == "unchecked",
+			Generator:           generator,
 		}
 		g.Init(classes)
 
@@ -68,6 +72,13 @@ func genPkg(lang string, p *types.Package, astFiles []*ast.File, allPkg []*types
 			io.Copy(w, &buf)
 			closer()
 		}
+		if lang == "kotlin" {
+			buf.Reset()
+			w, closer := writer(filepath.Join("java", pkgDir, fname[:len(fname)-len(".java")]+".kt"))
+			processErr(g.GenKotlin())
+			io.Copy(w, &buf)
+			closer()
+		}
 		buf.Reset()
 		w, closer = writer(filepath.Join("src", "gobind", pname+"_android.c"))
 		processErr(g.GenC())
@@ -114,6 +125,151 @@ func genPkg(lang string, p *types.Package, astFiles []*ast.File, allPkg []*types
 			copyFile(filepath.Join("src", "gobind", "seq_android.go"), filepath.Join(javaDir, "seq_android.go.support"))
 			copyFile(filepath.Join("src", "gobind", "seq_android.h"), filepath.Join(javaDir, "seq_android.h"))
 		}
+	case "python":
+		g := &bind.PythonGen{
+			Generator: generator,
+		}
+		g.Init()
+		w, closer := writer(filepath.Join("python", fname))
+		processErr(g.GenPython())
+		io.Copy(w, &buf)
+		closer()
+		if p != nil {
+			buf.Reset()
+			w, closer = writer(filepath.Join("python", "pyproject.toml"))
+			processErr(g.GenPyProject())
+			io.Copy(w, &buf)
+			closer()
+		}
+	case "java-ffm":
+		g := &bind.FfmGen{
+			Generator: generator,
+		}
+		g.Init()
+		w, closer := writer(filepath.Join("javaffm", pname, fname))
+		processErr(g.GenJavaFfm())
+		io.Copy(w, &buf)
+		closer()
+	case "wasm":
+		g := &bind.WasmGen{
+			Generator: generator,
+		}
+		g.Init()
+		wasmDir := filepath.Join("wasm", pname)
+		w, closer := writer(filepath.Join(wasmDir, fname))
+		processErr(g.GenWasmMain())
+		io.Copy(w, &buf)
+		closer()
+		for _, f := range []struct {
+			name string
+			gen  func() error
+		}{
+			{"index.js", g.GenWasmJS},
+			{"index.d.ts", g.GenWasmTS},
+		} {
+			buf.Reset()
+			w, closer = writer(filepath.Join(wasmDir, f.name))
+			processErr(f.gen())
+			io.Copy(w, &buf)
+			closer()
+		}
+	case "cpp":
+		g := &bind.CppGen{
+			Generator: generator,
+		}
+		g.Init()
+		w, closer := writer(filepath.Join("cpp", pname, fname))
+		processErr(g.GenCppHeader())
+		io.Copy(w, &buf)
+		closer()
+	case "rust":
+		g := &bind.RustGen{
+			Generator: generator,
+		}
+		g.Init()
+		rustDir := filepath.Join("rust", pname)
+		w, closer := writer(filepath.Join(rustDir, "src", fname))
+		processErr(g.GenRust())
+		io.Copy(w, &buf)
+		closer()
+		buf.Reset()
+		w, closer = writer(filepath.Join(rustDir, "Cargo.toml"))
+		processErr(g.GenCargoToml())
+		io.Copy(w, &buf)
+		closer()
+	case "dart":
+		g := &bind.DartGen{
+			Generator: generator,
+		}
+		g.Init()
+		dartDir := filepath.Join("dart", pname)
+		w, closer := writer(filepath.Join(dartDir, "lib", fname))
+		processErr(g.GenDart())
+		io.Copy(w, &buf)
+		closer()
+		buf.Reset()
+		w, closer = writer(filepath.Join(dartDir, "pubspec.yaml"))
+		processErr(g.GenPubspec())
+		io.Copy(w, &buf)
+		closer()
+	case "node":
+		g := &bind.NodeGen{
+			Generator: generator,
+		}
+		g.Init()
+		nodeDir := filepath.Join("node", pname)
+		w, closer := writer(filepath.Join(nodeDir, fname))
+		processErr(g.GenNodeC())
+		io.Copy(w, &buf)
+		closer()
+		for _, f := range []struct {
+			name string
+			gen  func() error
+		}{
+			{"index.js", g.GenNodeJS},
+			{"index.d.ts", g.GenNodeTS},
+			{"binding.gyp", g.GenNodeGyp},
+			{"package.json", g.GenNodePackageJSON},
+		} {
+			buf.Reset()
+			w, closer = writer(filepath.Join(nodeDir, f.name))
+			processErr(f.gen())
+			io.Copy(w, &buf)
+			closer()
+		}
+	case "php":
+		g := &bind.PhpGen{
+			Generator: generator,
+		}
+		g.Init()
+		w, closer := writer(filepath.Join("php", pname, fname))
+		processErr(g.GenPhp())
+		io.Copy(w, &buf)
+		closer()
+	case "lua":
+		g := &bind.LuaGen{
+			Generator: generator,
+		}
+		g.Init()
+		w, closer := writer(filepath.Join("lua", pname, fname))
+		processErr(g.GenLua())
+		io.Copy(w, &buf)
+		closer()
+	case "ruby":
+		g := &bind.RubyGen{
+			Generator: generator,
+		}
+		g.Init()
+		rubyDir := filepath.Join("ruby", pname)
+		w, closer := writer(filepath.Join(rubyDir, "lib", fname))
+		processErr(g.GenRuby())
+		io.Copy(w, &buf)
+		closer()
+		buf.Reset()
+		w, closer = writer(filepath.Join(rubyDir, pname+".gemspec"))
+		processErr(g.GenGemspec())
+		io.Copy(w, &buf)
+		closer()
 	case "go":
 		w, closer := writer(filepath.Join("src", "gobind", fname))
 		conf.Writer = w
@@ -127,16 +283,35 @@ func genPkg(lang string, p *types.Package, astFiles []*ast.File, allPkg []*types
 		genPkgH(w, "seq")
 		io.Copy(w, &buf)
 		closer()
+		g := &bind.DesktopGen{
+			Generator: generator,
+		}
+		g.Init()
+		buf.Reset()
+		w, closer = writer(filepath.Join("src", "gobind", pname+"_desktop.h"))
+		processErr(g.GenH())
+		io.Copy(w, &buf)
+		closer()
+		buf.Reset()
+		w, closer = writer(filepath.Join("src", "gobind", pname+"_desktop.c"))
+		processErr(g.GenC(pname))
+		io.Copy(w, &buf)
+		closer()
 		dir, err := packageDir("github.com/sagernet/gomobile/bind")
 		if err != nil {
 			errorf("unable to import bind: %v", err)
 			return
 		}
 		copyFile(filepath.Join("src", "gobind", "seq.go"), filepath.Join(dir, "seq.go.support"))
-	case "objc":
+		copyFile(filepath.Join("src", "gobind", "seq_desktop.h"), filepath.Join(dir, "seq_desktop.h"))
+		copyFile(filepath.Join("src", "gobind", "seq_desktop.c"), filepath.Join(dir, "seq_desktop.c.support"))
+		copyFile(filepath.Join("src", "gobind", "seq_desktop.go"), filepath.Join(dir, "seq_desktop.go.support"))
+	case "objc", "swift":
 		g := &bind.ObjcGen{
-			Generator: generator,
-			Prefix:    *prefix,
+			Generator:   generator,
+			Prefix:      *prefix,
+			RejectNulls: *nullPolicy == "reject",
+			SwiftNames:  *swiftNames && lang == "objc",
 		}
 		g.Init(otypes)
 		w, closer := writer(filepath.Join("src", "gobind", pname+"_darwin.h"))
@@ -154,6 +329,15 @@ func genPkg(lang string, p *types.Package, astFiles []*ast.File, allPkg []*types
 		processErr(g.GenM())
 		io.Copy(w, &buf)
 		closer()
+		sname := strings.Title(fname[:len(fname)-2]) + ".swift"
+		w, closer = writer(filepath.Join("src", "gobind", sname))
+		if lang == "swift" {
+			processErr(g.GenSwift())
+		} else {
+			processErr(g.GenSwiftOverlay())
+		}
+		io.Copy(w, &buf)
+		closer()
 		if p == nil {
 			// Copy support files
 			dir, err := packageDir("github.com/sagernet/gomobile/bind/objc")
@@ -179,6 +363,9 @@ func genPkgH(w io.Writer, pname string) {
 #endif
 #ifdef __GOBIND_DARWIN__
 #include "%[1]s_darwin.h"
+#endif
+#if !defined(__GOBIND_ANDROID__) && !defined(__GOBIND_DARWIN__)
+#include "%[1]s_desktop.h"
 #endif`, pname)
 }
 
@@ -350,7 +537,7 @@ func copyFile(dst, src string) {
 
 func defaultFileName(lang string, pkg *types.Package) string {
 	switch lang {
-	case "java":
+	case "java", "kotlin":
 		if pkg == nil {
 			return "Universe.java"
 		}
@@ -362,7 +549,52 @@ func defaultFileName(lang string, pkg *types.Package) string {
 			return "go_main.go"
 		}
 		return "go_" + pkg.Name() + "main.go"
-	case "objc":
+	case "python":
+		if pkg == nil {
+			return "universe.py"
+		}
+		return pkg.Name() + ".py"
+	case "node":
+		if pkg == nil {
+			return "universe_node.c"
+		}
+		return pkg.Name() + "_node.c"
+	case "dart":
+		if pkg == nil {
+			return "universe.dart"
+		}
+		return pkg.Name() + ".dart"
+	case "rust":
+		return "lib.rs"
+	case "ruby":
+		if pkg == nil {
+			return "universe.rb"
+		}
+		return pkg.Name() + ".rb"
+	case "lua":
+		if pkg == nil {
+			return "universe.lua"
+		}
+		return pkg.Name() + ".lua"
+	case "php":
+		if pkg == nil {
+			return "universe.php"
+		}
+		return pkg.Name() + ".php"
+	case "cpp":
+		if pkg == nil {
+			return "universe.hpp"
+		}
+		return pkg.Name() + ".hpp"
+	case "wasm":
+		return "main.go"
+	case "java-ffm":
+		if pkg == nil {
+			return "Universe.java"
+		}
+		firstRune, size := utf8.DecodeRuneInString(pkg.Name())
+		return string(unicode.ToUpper(firstRune)) + pkg.Name()[size:] + ".java"
+	case "objc", "swift":
 		if pkg == nil {
 			return "Universe.m"
 		}
@@ -24,14 +24,18 @@ import (
 )
 
 var (
-	lang          = flag.String("lang", "", "target languages for bindings, either java, go, or objc. If empty, all languages are generated.")
+	lang          = flag.String("lang", "", "target languages for bindings: java, go, objc, swift, kotlin, python, node, dart, rust, ruby, lua, php, cpp, wasm or java-ffm. If empty, go, java and objc are generated.")
 	outdir        = flag.String("outdir", "", "result will be written to the directory instead of stdout.")
 	javaPkg       = flag.String("javapkg", "", "custom Java package path prefix. Valid only with -lang=java.")
 	libname       = flag.String("libname", "gojni", "custom library name. Valid only with -lang=java.")
 	prefix        = flag.String("prefix", "", "custom Objective-C name prefix. Valid only with -lang=objc.")
+	swiftNames    = flag.Bool("swiftnames", false, "annotate the Objective-C bindings with NS_SWIFT_NAME so the imported Swift API drops the name prefix. Valid only with -lang=objc; ignored with -lang=swift, whose overlay references the prefixed names.")
 	bootclasspath = flag.String("bootclasspath", "", "Java bootstrap classpath.")
 	classpath     = flag.String("classpath", "", "Java classpath.")
 	tags          = flag.String("tags", "", "build tags.")
+	nullPolicy    = flag.String("nullpolicy", "empty", "treatment of null strings and slices passed as arguments from the foreign side: empty converts them to empty Go values, reject raises an error.")
+	annotations   = flag.Bool("annotations", false, "annotate the Java bindings with androidx.annotation @NonNull and @Nullable derived from Go nil semantics. Valid only with -lang=java.")
+	exceptions    = flag.String("exceptions", "checked", "kind of Java exceptions thrown for Go errors: checked throws java.lang.Exception, unchecked throws RuntimeException subclasses. Valid only with -lang=java.")
 )
 
 var usage = `The Gobind tool generates Java language bindings for Go.
@@ -41,6 +45,13 @@ For usage details, see doc.go.`
 func main() {
 	flag.Parse()
 
+	if *nullPolicy != "empty" && *nullPolicy != "reject" {
+		log.Fatalf(`unsupported -nullpolicy value %q; must be "empty" or "reject"`, *nullPolicy)
+	}
+	if *exceptions != "checked" && *exceptions != "unchecked" {
+		log.Fatalf(`unsupported -exceptions value %q; must be "checked" or "unchecked"`, *exceptions)
+	}
+
 	run()
 	os.Exit(exitStatus)
 }
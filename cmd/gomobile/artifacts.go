@@ -0,0 +1,161 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+)
+
+// An artifact describes a single file produced by a bind.
+type artifact struct {
+	File   string `json:"file"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// An artifactsManifest lists every file produced by a bind along with
+// the versions of the tools that produced them. Release pipelines can
+// publish (and sign) the manifest alongside the binaries.
+type artifactsManifest struct {
+	Target          string     `json:"target"`
+	Platforms       []string   `json:"platforms,omitempty"`
+	Architectures   []string   `json:"architectures,omitempty"`
+	Flags           []string   `json:"flags,omitempty"`
+	GoVersion       string     `json:"goVersion,omitempty"`
+	GomobileVersion string     `json:"gomobileVersion,omitempty"`
+	Environment     []string   `json:"environment,omitempty"`
+	Artifacts       []artifact `json:"artifacts"`
+	Headers         []string   `json:"headers,omitempty"`
+}
+
+// writeArtifactsManifest writes artifacts.json next to the bind output
+// named by buildO.
+func writeArtifactsManifest(target string) error {
+	if buildN {
+		return nil
+	}
+	m, err := artifactsReport(target)
+	if err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(filepath.Dir(buildO), "artifacts.json"), func(w io.Writer) error {
+		e := json.NewEncoder(w)
+		e.SetIndent("", "\t")
+		return e.Encode(m)
+	})
+}
+
+// printArtifactsReport writes the manifest to standard output for the
+// -json flag, so wrapper tools and CI pipelines can consume the build
+// result without parsing logs.
+func printArtifactsReport(target string) error {
+	if buildN {
+		return nil
+	}
+	m, err := artifactsReport(target)
+	if err != nil {
+		return err
+	}
+	e := json.NewEncoder(os.Stdout)
+	e.SetIndent("", "\t")
+	return e.Encode(m)
+}
+
+// artifactsReport describes the output named by buildO. Directory
+// outputs (xcframeworks) are walked; file outputs additionally include
+// companion files such as the sources jar.
+func artifactsReport(target string) (*artifactsManifest, error) {
+	var files []string
+	info, err := os.Stat(buildO)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		err := filepath.Walk(buildO, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+			files = append(files, path)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		files = append(files, buildO)
+		ext := filepath.Ext(buildO)
+		if sources := buildO[:len(buildO)-len(ext)] + "-sources.jar"; ext == ".aar" {
+			if _, err := os.Stat(sources); err == nil {
+				files = append(files, sources)
+			}
+		}
+	}
+
+	dir := filepath.Dir(buildO)
+	m := &artifactsManifest{
+		Target:    target,
+		Flags:     os.Args[1:],
+		GoVersion: goVersion,
+	}
+	if targets, err := parseBuildTarget(target); err == nil {
+		seen := map[string]bool{}
+		for _, t := range targets {
+			if !seen[t.platform] {
+				seen[t.platform] = true
+				m.Platforms = append(m.Platforms, t.platform)
+			}
+			m.Architectures = append(m.Architectures, t.platform+"/"+t.arch)
+		}
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		m.GomobileVersion = info.Main.Version
+	}
+	if buildHermetic {
+		m.Environment = hostEnv()
+	}
+	for _, file := range files {
+		a, err := hashArtifact(dir, file)
+		if err != nil {
+			return nil, err
+		}
+		m.Artifacts = append(m.Artifacts, a)
+		if filepath.Ext(file) == ".h" {
+			m.Headers = append(m.Headers, a.File)
+		}
+	}
+
+	return m, nil
+}
+
+func hashArtifact(dir, file string) (artifact, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return artifact{}, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return artifact{}, err
+	}
+	rel, err := filepath.Rel(dir, file)
+	if err != nil {
+		rel = file
+	}
+	return artifact{
+		File:   filepath.ToSlash(rel),
+		Size:   size,
+		SHA256: hex.EncodeToString(h.Sum(nil)),
+	}, nil
+}
@@ -25,14 +25,20 @@ import (
 var cmdBind = &command{
 	run:   runBind,
 	Name:  "bind",
-	Usage: "[-target android|" + strings.Join(applePlatforms, "|") + "] [-bootclasspath <path>] [-classpath <path>] [-o output] [build flags] [package]",
+	Usage: "[-target android|wasm|" + strings.Join(applePlatforms, "|") + "] [-bootclasspath <path>] [-classpath <path>] [-o output] [build flags] [package]",
 	Short: "build a library for Android and iOS",
 	Long: `
 Bind generates language bindings for the package named by the import
 path, and compiles a library for the named target system.
 
-The -target flag takes either android (the default), or one or more
+The -target flag takes android (the default), wasm, or one or more
 comma-delimited Apple platforms (` + strings.Join(applePlatforms, ", ") + `).
+Platform families can be mixed in one invocation, e.g.
+-target=android,ios: the packages are loaded and typechecked once and
+every family is built from the shared analysis. In a mixed run -o must
+name a directory (default .); each family writes its default artifact
+into an android, apple or wasm subdirectory, and flags tied to one
+family apply to that family's output.
 
 For -target android, the bind command produces an AAR (Android ARchive)
 file that archives the precompiled Java API stub classes, the compiled
@@ -57,16 +63,206 @@ can be selected by specifying target type with the architecture name. E.g.,
 
 For Apple -target platforms, gomobile must be run on an OS X machine with
 Xcode installed. The generated Objective-C types can be prefixed with the
--prefix flag.
+-prefix flag. On iOS and macOS, an additional arm64e (pointer
+authentication) slice can be requested explicitly, e.g.
+-target=ios/arm64,ios/arm64e, and is lipo'd into the framework next to
+the arm64 slice.
 
 For -target android, the -bootclasspath and -classpath flags are used to
 control the bootstrap classpath and the classpath for Go wrappers to Java
 classes.
 
+For -target wasm, the bind command compiles a WebAssembly module with
+GOOS=js and places a generated JavaScript wrapper and TypeScript
+declarations next to it. The wrapper exposes the bound API with the
+same proxy/refnum model as the other bindings; wasm_exec.js from the
+Go distribution must be served alongside. Binding a single package is
+supported for wasm.
+
+The -buildmode flag selects how the Go code is linked into the
+XCFramework slices. The default, c-archive, produces static framework
+slices. With c-shared, each slice is a dynamic framework wrapping a
+c-shared dylib with its install name set to
+@rpath/<Title>.framework/Versions/A/<Title>, for apps that share one Go
+framework across app extensions or want to keep the main binary small.
+Valid only for Apple targets.
+
+For -target=macos, naming the output with a ".dylib" suffix via -o
+produces a bare dylib instead of an XCFramework, with its install name
+set to @rpath/<name>.dylib and the headers installed in an include/
+directory next to it. CLI tools and .NET or other FFI consumers can
+link it directly without framework machinery. Requires
+-buildmode=c-shared.
+
+The -swiftpkg flag writes a Swift Package to the given directory after
+the XCFramework is created: a Package.swift with a binaryTarget, the
+XCFramework zipped with ditto as Swift Package Manager expects, and a
+.checksum file with the SHA-256 that SPM uses to verify url-based
+binary targets. The manifest references the zip by path; publish the
+zip and switch the target to url and checksum to distribute it. Valid
+only for Apple targets.
+
+Each framework slice of the XCFramework carries an Info.plist with the
+bundle identifier, name, version and minimum OS version, so the
+frameworks pass App Store and MDM validation without post-editing. The
+identifier defaults to org.golang.gomobile.<Title> and is overridden
+with the -bundleid flag; -bundleversion sets the
+CFBundleShortVersionString and CFBundleVersion; the minimum OS version
+is taken from the -iosversion, -macosversion and related flags; and
+the repeatable -plistkey KEY=VALUE flag adds further entries. Valid
+only for Apple targets.
+
+Each framework slice of the XCFramework carries a PrivacyInfo.xcprivacy
+privacy manifest in its Resources, as required by the App Store for
+SDKs. By default a minimal manifest declaring no tracking and no
+collected data is embedded; the -xcprivacy flag replaces it with the
+given manifest file for libraries that do collect data or use
+reason-listed APIs.
+
+The -sampleapp flag writes a minimal SwiftUI Xcode project to the
+given directory after the XCFramework is created. The project builds
+an iOS app that imports the framework, embedding (and signing) it for
+-buildmode=c-shared and merely linking it for static builds, so the
+binding can be validated and its size and startup cost measured
+without manual Xcode setup. Valid only for Apple targets.
+
+The -extension-safe flag compiles and links every slice with
+-fapplication-extension, so use of API unavailable to app extensions
+is rejected at compile time and the produced binaries are marked
+app-extension-safe. Use it for frameworks loaded from Network, Share
+and other extension points. Valid only for Apple targets.
+
+The -dsym flag runs dsymutil on each dynamic framework slice and
+places <Title>.framework.<sdk>.dSYM bundles next to the XCFramework,
+so crash reports from the Go code can be symbolicated. The DWARF is
+read from the c-shared dylibs, so -dsym requires -buildmode=c-shared
+and must not be combined with -ldflags=-w, which strips it.
+
+The -manifest flag writes an artifacts.json manifest next to the output,
+listing every produced file with its SHA-256 checksum, size and the tool
+versions used, for release pipelines that publish or sign the binaries.
+
+The -sign flag runs codesign with the given identity on each framework
+of the produced XCFramework, so CI pipelines need no post-processing
+step. Valid only for Apple targets.
+
+The -gradle flag writes a Gradle script plugin to the given path that
+wraps this bind invocation as a gomobileBind task, declaring the Go
+sources as inputs and the AAR as its output and wiring the AAR into the
+dependencies of the module that applies it. Valid only with
+-target=android.
+
+The -prefab flag packages the shared library and the generated C
+headers into the AAR's prefab/ directory, one module per library with
+per-ABI metadata. C++ code in the same app, or in other AARs, can then
+link against the bound Go library via CMake or ndk-build (with
+buildFeatures.prefab enabled) in addition to the Java API. Valid only
+with -target=android.
+
+The -annotations flag annotates parameters and results of the
+generated Java API with androidx.annotation @NonNull and @Nullable,
+derived from Go nil semantics, so Kotlin consumers see null-checked
+types instead of platform types. Generated classes are also marked
+@Keep, telling lint and R8 they are JNI entry points. The annotations
+have class retention only; the AAR gains no runtime dependency, and a
+compile-scope androidx.annotation dependency is declared in the maven
+metadata written by -maven. Valid only with -target=android.
+
+The -kotlin flag writes a companion Kotlin file per bound package to
+the given directory: suspend extension wrappers that run
+error-returning functions and methods on Dispatchers.IO and surface
+errors as exceptions, so coroutine-based apps need not wrap every
+blocking call manually. The AAR cannot carry Kotlin sources, so the
+directory is meant to be added to the app's source set; the files are
+also included in the sources jar. Valid only with -target=android.
+
+The -splitabi flag writes, in addition to the combined AAR, one AAR
+per target ABI holding only that ABI's native library, together with
+an abis.json manifest mapping each ABI to its artifact and library
+size. Builds that select artifacts per ABI then avoid shipping every
+architecture to every device. The per-ABI library sizes are reported
+in the build output. Valid only with -target=android.
+
+The -javadoc flag runs javadoc over the generated Java sources, whose
+comments carry the Go doc comments, and writes a -javadoc.jar next to
+the AAR. IDEs then display documentation when the AAR is consumed from
+a Maven repository; with -maven, the jar is published alongside the
+AAR. Valid only with -target=android.
+
+The -maven flag takes publication coordinates of the form
+groupId:artifactId:version and writes a maven-publish repository layout
+to a maven/ directory next to the AAR: the AAR and the sources jar
+under the coordinate path, a pom declaring aar packaging, and Gradle
+module metadata with artifact checksums. The directory can be pushed
+as-is to Maven Central or an internal repository. Valid only with
+-target=android.
+
+The -service flag writes an out-of-process service scaffold to the
+given directory: an AIDL interface, a Service subclass that hosts the
+Go runtime and is meant to be declared with android:process=":go", and
+a client stub that binds to it. Memory-heavy Go libraries such as
+proxies can then be killed and restarted independently of the UI
+process. Requests cross Binder as a method name plus encoded bytes;
+the service's dispatch method is the single point to wire to the bound
+API. Valid only with -target=android.
+
+The -logtag flag sets the default logcat tag for Go program output
+captured from stdout, stderr and the Go log package, normally "GoLog".
+Apps can change the tag at run time with Seq.setLogTag, filter with
+Seq.setLogMinPriority, or route the output into their own logging
+pipeline with Seq.setLogReceiver. Valid only with -target=android.
+
+The -exceptions flag selects the kind of Java exceptions thrown for Go
+errors. With checked, the default, error-returning methods declare
+throws Exception. With unchecked, the throws clauses are dropped and
+errors without a generated exception class are thrown wrapped in a
+Seq.GoRuntimeException. Either way, exported Go struct types
+implementing error become distinct exception classes, so Java and
+Kotlin code can catch specific failures. Valid only with
+-target=android.
+
+The -watch flag keeps gomobile running after the first build and
+re-runs the bind whenever a source file of the bound packages or of
+their dependencies outside GOROOT and the module cache changes. The
+work directory is kept between rebuilds, so the gobind, compile and
+packaging stages reuse previous outputs where their inputs are
+unchanged. A failing rebuild is reported and watching continues.
+
+Modules that vendor their dependencies are supported. The generated
+wrapper package is compiled inside the user's module, so with
+-mod=vendor in effect its imports resolve through the vendor tree;
+the tree must contain the gomobile bind runtime, and a vendor
+directory that lacks it is diagnosed before any architecture builds.
+
+Bind keeps generated bindings and per-target archives in a persistent
+cache keyed by the toolchain, the sources of the bound packages and
+the build flags, so repeated builds reuse the outputs of unchanged
+stages. The cache lives under $GOMOBILECACHE, or a gomobile directory
+under the user cache directory; set GOMOBILECACHE=off to disable it.
+
+The -xcodephase flag writes a run-script build phase to the given path,
+together with input and output xcfilelists, that re-runs this bind
+invocation when the Go sources change. Valid only for Apple targets.
+
+The -bindingsout flag copies the generated host-language sources, and
+only them, to the given directory: the Java sources for Android
+targets and the Objective-C sources for Apple targets. The directory
+is replaced on each run, so it can be checked into version control for
+clean diffs.
+
+The -hermetic flag restricts build commands to a fixed whitelist of
+host environment variables, reporting any dropped variable that could
+affect the build, so stray CC or CGO_CFLAGS settings on the developer
+machine cannot silently change artifacts. Together with -manifest, the
+effective environment is recorded in artifacts.json for
+reproducibility audits.
+
 The -v flag provides verbose output, including the list of packages built.
 
-The build flags -a, -n, -x, -gcflags, -ldflags, -tags, -trimpath, and -work
-are shared with the build command. For documentation, see 'go help build'.
+The build flags -a, -n, -p, -x, -gcflags, -gotoolchain, -json,
+-ldflags, -tags, -trimpath, and -work are shared with the build
+command. For documentation, see 'go help build' and
+'gomobile help build'.
 `,
 }
 
@@ -77,6 +273,21 @@ func runBind(cmd *command) error {
 	}
 	defer cleanup()
 
+	if !bindWatch {
+		return bindPackages(cmd)
+	}
+	if buildN {
+		return fmt.Errorf("-watch is incompatible with -n")
+	}
+	// The work directory persists across watch rebuilds, so stages
+	// whose inputs did not change reuse their previous outputs.
+	if err := bindPackages(cmd); err != nil {
+		fmt.Fprintf(os.Stderr, "gomobile: %v\n", err)
+	}
+	return watchBind(cmd, func() error { return bindPackages(cmd) })
+}
+
+func bindPackages(cmd *command) error {
 	args := cmd.flag.Args()
 
 	targets, err := parseBuildTarget(buildTarget)
@@ -84,16 +295,27 @@ func runBind(cmd *command) error {
 		return fmt.Errorf(`invalid -target=%q: %v`, buildTarget, err)
 	}
 
-	if isAndroidPlatform(targets[0].platform) {
-		if bindPrefix != "" {
-			return fmt.Errorf("-prefix is supported only for Apple targets")
-		}
-		if _, err := ndkRoot(targets[0]); err != nil {
-			return err
-		}
-	} else {
-		if bindJavaPkg != "" {
-			return fmt.Errorf("-javapkg is supported only for android target")
+	groups := groupTargetFamilies(targets)
+
+	if err := validateBindFlags(targets); err != nil {
+		return err
+	}
+	if len(groups) > 1 {
+		// The post-bind conveniences operate on a single artifact and
+		// have no per-family layout.
+		for _, f := range []struct {
+			name string
+			set  bool
+		}{
+			{"bindingsout", bindBindingsOut != ""},
+			{"gradle", bindGradle != ""},
+			{"maven", bindMaven != ""},
+			{"service", bindService != ""},
+			{"xcodephase", bindXcodePhase != ""},
+		} {
+			if f.set {
+				return fmt.Errorf("-%s is not supported when -target mixes platform families", f.name)
+			}
 		}
 	}
 
@@ -125,6 +347,88 @@ func runBind(cmd *command) error {
 		}
 	}
 
+	if err := checkVendoredDeps(); err != nil {
+		return err
+	}
+
+	cacheInit(targets[0], pkgs)
+
+	if len(groups) == 1 {
+		if err := bindTargetFamily(gobind, pkgs, targets); err != nil {
+			return err
+		}
+	} else {
+		// One invocation builds every family, sharing the loaded and
+		// typechecked packages; each family gets its own subdirectory
+		// under the output directory with its default artifact name.
+		outDir := buildO
+		if outDir == "" {
+			outDir = "."
+		}
+		if outDir != "." && filepath.Ext(outDir) != "" {
+			return fmt.Errorf("-o must name a directory when -target mixes platform families")
+		}
+		for _, group := range groups {
+			dir := filepath.Join(outDir, platformFamily(group[0].platform))
+			if !buildN {
+				if err := os.MkdirAll(dir, 0755); err != nil {
+					return err
+				}
+			}
+			switch {
+			case isAndroidPlatform(group[0].platform):
+				buildO = filepath.Join(dir, pkgs[0].Name+".aar")
+			case isApplePlatform(group[0].platform):
+				buildO = filepath.Join(dir, strings.Title(pkgs[0].Name)+".xcframework")
+			case isWasmPlatform(group[0].platform):
+				buildO = filepath.Join(dir, pkgs[0].Name+".wasm")
+			}
+			if err := bindTargetFamily(gobind, pkgs, group); err != nil {
+				return err
+			}
+		}
+		// The post-bind steps (-manifest, -json) cover the whole tree.
+		buildO = outDir
+	}
+	if bindBindingsOut != "" {
+		if err := copyBindings(bindBindingsOut, targets); err != nil {
+			return err
+		}
+	}
+	if bindGradle != "" {
+		if err := writeGradleScript(bindGradle, pkgs); err != nil {
+			return err
+		}
+	}
+	if bindMaven != "" {
+		if err := writeMavenArtifacts(bindMaven); err != nil {
+			return err
+		}
+	}
+	if bindService != "" {
+		if err := writeServiceScaffold(bindService, pkgs); err != nil {
+			return err
+		}
+	}
+	if bindXcodePhase != "" {
+		if err := writeXcodePhase(bindXcodePhase, pkgs); err != nil {
+			return err
+		}
+	}
+	if bindManifest {
+		if err := writeArtifactsManifest(buildTarget); err != nil {
+			return err
+		}
+	}
+	if buildJSON {
+		return printArtifactsReport(buildTarget)
+	}
+	return nil
+}
+
+// bindTargetFamily runs the platform-specific bind for one family of
+// targets, writing the artifact named by buildO.
+func bindTargetFamily(gobind string, pkgs []*packages.Package, targets []targetInfo) error {
 	switch {
 	case isAndroidPlatform(targets[0].platform):
 		return goAndroidBind(bindLibName, gobind, pkgs, targets)
@@ -133,17 +437,142 @@ func runBind(cmd *command) error {
 			return fmt.Errorf("-target=%q requires Xcode", buildTarget)
 		}
 		return goAppleBind(gobind, pkgs, targets)
+	case isWasmPlatform(targets[0].platform):
+		return goWasmBind(gobind, pkgs, targets)
 	default:
 		return fmt.Errorf(`invalid -target=%q`, buildTarget)
 	}
 }
 
+// validateBindFlags rejects flag combinations that no target in the
+// build can use. A flag tied to one platform family is accepted as
+// long as that family is among the targets, so a mixed-family bind can
+// carry android- and Apple-only flags at once.
+func validateBindFlags(targets []targetInfo) error {
+	var hasAndroid, hasApple bool
+	var androidTarget targetInfo
+	for _, t := range targets {
+		switch {
+		case isAndroidPlatform(t.platform):
+			if !hasAndroid {
+				androidTarget = t
+			}
+			hasAndroid = true
+		case isApplePlatform(t.platform):
+			hasApple = true
+		}
+	}
+
+	if bindBuildmode != "c-archive" && bindBuildmode != "c-shared" {
+		return fmt.Errorf(`unsupported -buildmode value %q; must be "c-archive" or "c-shared"`, bindBuildmode)
+	}
+	if bindBuildmode != "c-archive" && !hasApple {
+		return fmt.Errorf("-buildmode is supported only for Apple targets")
+	}
+	if bindSwiftPkg != "" && !hasApple {
+		return fmt.Errorf("-swiftpkg is supported only for Apple targets")
+	}
+	if bindXcprivacy != "" && !hasApple {
+		return fmt.Errorf("-xcprivacy is supported only for Apple targets")
+	}
+	if len(bindPlistKeys) > 0 && !hasApple {
+		return fmt.Errorf("-plistkey is supported only for Apple targets")
+	}
+	if bindExtensionSafe && !hasApple {
+		return fmt.Errorf("-extension-safe is supported only for Apple targets")
+	}
+	if bindSampleApp != "" && !hasApple {
+		return fmt.Errorf("-sampleapp is supported only for Apple targets")
+	}
+	if bindDsym {
+		if !hasApple {
+			return fmt.Errorf("-dsym is supported only for Apple targets")
+		}
+		if bindBuildmode != "c-shared" {
+			return fmt.Errorf("-dsym requires -buildmode=c-shared; static archives carry their debug info into the app link")
+		}
+	}
+	if bindPrefix != "" && !hasApple {
+		return fmt.Errorf("-prefix is supported only for Apple targets")
+	}
+	if bindSign != "" && !hasApple {
+		return fmt.Errorf("-sign is supported only for Apple targets")
+	}
+	if bindXcodePhase != "" && !hasApple {
+		return fmt.Errorf("-xcodephase is supported only for Apple targets")
+	}
+
+	if bindJavaPkg != "" && !hasAndroid {
+		return fmt.Errorf("-javapkg is supported only for android target")
+	}
+	if bindGradle != "" && !hasAndroid {
+		return fmt.Errorf("-gradle is supported only for android target")
+	}
+	if bindPrefab && !hasAndroid {
+		return fmt.Errorf("-prefab is supported only for android target")
+	}
+	if bindMaven != "" && !hasAndroid {
+		return fmt.Errorf("-maven is supported only for android target")
+	}
+	if bindAnnotations && !hasAndroid {
+		return fmt.Errorf("-annotations is supported only for android target")
+	}
+	if bindKotlin != "" && !hasAndroid {
+		return fmt.Errorf("-kotlin is supported only for android target")
+	}
+	if bindSplitABI && !hasAndroid {
+		return fmt.Errorf("-splitabi is supported only for android target")
+	}
+	if bindJavadoc && !hasAndroid {
+		return fmt.Errorf("-javadoc is supported only for android target")
+	}
+	if bindService != "" && !hasAndroid {
+		return fmt.Errorf("-service is supported only for android target")
+	}
+	if bindLogTag != "" && !hasAndroid {
+		return fmt.Errorf("-logtag is supported only for android target")
+	}
+	if bindExceptions != "" && !hasAndroid {
+		return fmt.Errorf("-exceptions is supported only for android target")
+	}
+
+	if hasAndroid {
+		if _, err := ndkRoot(androidTarget); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 var (
-	bindPrefix        string // -prefix
-	bindJavaPkg       string // -javapkg
-	bindClasspath     string // -classpath
-	bindBootClasspath string // -bootclasspath
-	bindLibName       string // -libname
+	bindPrefix        string       // -prefix
+	bindJavaPkg       string       // -javapkg
+	bindClasspath     string       // -classpath
+	bindBootClasspath string       // -bootclasspath
+	bindLibName       string       // -libname
+	bindManifest      bool         // -manifest
+	bindSign          string       // -sign
+	bindGradle        string       // -gradle
+	bindXcodePhase    string       // -xcodephase
+	bindBuildmode     string       // -buildmode
+	bindSwiftPkg      string       // -swiftpkg
+	bindXcprivacy     string       // -xcprivacy
+	bindDsym          bool         // -dsym
+	bindBindingsOut   string       // -bindingsout
+	bindBundleVersion string       // -bundleversion
+	bindPlistKeys     keyValueFlag // -plistkey
+	bindExtensionSafe bool         // -extension-safe
+	bindSampleApp     string       // -sampleapp
+	bindPrefab        bool         // -prefab
+	bindMaven         string       // -maven
+	bindAnnotations   bool         // -annotations
+	bindKotlin        string       // -kotlin
+	bindSplitABI      bool         // -splitabi
+	bindJavadoc       bool         // -javadoc
+	bindService       string       // -service
+	bindLogTag        string       // -logtag
+	bindExceptions    string       // -exceptions
+	bindWatch         bool         // -watch
 )
 
 func init() {
@@ -155,6 +584,29 @@ func init() {
 	cmdBind.flag.StringVar(&bindClasspath, "classpath", "", "The classpath for imported Java classes. Valid only with -target=android.")
 	cmdBind.flag.StringVar(&bindBootClasspath, "bootclasspath", "", "The bootstrap classpath for imported Java classes. Valid only with -target=android.")
 	cmdBind.flag.StringVar(&bindLibName, "libname", "gojni", "The name of the generated shared library. Valid only with -target=android.")
+	cmdBind.flag.BoolVar(&bindManifest, "manifest", false, "Write an artifacts.json manifest with the SHA-256, size and tool versions of every produced file.")
+	cmdBind.flag.StringVar(&bindSign, "sign", "", "The code-signing identity passed to codesign for each framework of the produced XCFramework. Valid only for Apple targets.")
+	cmdBind.flag.StringVar(&bindGradle, "gradle", "", "Write a Gradle script plugin to the given path that wraps this bind invocation as an incremental task. Valid only with -target=android.")
+	cmdBind.flag.StringVar(&bindXcodePhase, "xcodephase", "", "Write an Xcode run-script phase to the given path, with input and output file lists, that re-runs this bind invocation when the Go sources change. Valid only for Apple targets.")
+	cmdBind.flag.StringVar(&bindBindingsOut, "bindingsout", "", "Copy the generated host-language sources (and only them) to the given directory.")
+	cmdBind.flag.StringVar(&bindBuildmode, "buildmode", "c-archive", "Link mode for the XCFramework slices: c-archive for static frameworks, c-shared for dynamic frameworks. Valid only for Apple targets.")
+	cmdBind.flag.StringVar(&bindSwiftPkg, "swiftpkg", "", "Write a Swift Package with a binaryTarget for the produced XCFramework to the given directory, together with the zipped artifact and its checksum. Valid only for Apple targets.")
+	cmdBind.flag.StringVar(&bindXcprivacy, "xcprivacy", "", "Embed the given PrivacyInfo.xcprivacy privacy manifest in each framework slice. If empty, a minimal manifest declaring no tracking and no collected data is embedded. Valid only for Apple targets.")
+	cmdBind.flag.BoolVar(&bindDsym, "dsym", false, "Run dsymutil on each framework slice and emit the .dSYM bundles next to the XCFramework. Requires -buildmode=c-shared. Valid only for Apple targets.")
+	cmdBind.flag.StringVar(&bindBundleVersion, "bundleversion", "1.0", "The CFBundleShortVersionString and CFBundleVersion written to each framework's Info.plist. Valid only for Apple targets.")
+	cmdBind.flag.Var(&bindPlistKeys, "plistkey", "An additional KEY=VALUE pair written to each framework's Info.plist. May be repeated. Valid only for Apple targets.")
+	cmdBind.flag.BoolVar(&bindExtensionSafe, "extension-safe", false, "Restrict the framework to app-extension-safe API and mark the binary extension-safe, so it can be linked from app extensions. Valid only for Apple targets.")
+	cmdBind.flag.StringVar(&bindSampleApp, "sampleapp", "", "Write a minimal SwiftUI Xcode sample project referencing the produced XCFramework to the given directory. Valid only for Apple targets.")
+	cmdBind.flag.BoolVar(&bindPrefab, "prefab", false, "Package the shared library and generated C headers into the AAR's prefab/ directory for NDK consumers. Valid only with -target=android.")
+	cmdBind.flag.StringVar(&bindMaven, "maven", "", "Write a maven-publish repository layout for the AAR under the given groupId:artifactId:version coordinates. Valid only with -target=android.")
+	cmdBind.flag.BoolVar(&bindAnnotations, "annotations", false, "Annotate the generated Java API with androidx.annotation @NonNull and @Nullable derived from Go nil semantics. Valid only with -target=android.")
+	cmdBind.flag.StringVar(&bindKotlin, "kotlin", "", "Write a companion Kotlin file per package with suspend wrappers over the Java API to the given directory. Valid only with -target=android.")
+	cmdBind.flag.BoolVar(&bindSplitABI, "splitabi", false, "Also write one AAR per ABI, each holding a single native library, plus an abis.json manifest. Valid only with -target=android.")
+	cmdBind.flag.BoolVar(&bindJavadoc, "javadoc", false, "Run javadoc over the generated Java sources and write a -javadoc.jar next to the AAR. Valid only with -target=android.")
+	cmdBind.flag.StringVar(&bindService, "service", "", "Write an out-of-process service scaffold (AIDL interface, :go process Service and client stub) to the given directory. Valid only with -target=android.")
+	cmdBind.flag.StringVar(&bindLogTag, "logtag", "", "Set the default logcat tag for Go program output. Valid only with -target=android.")
+	cmdBind.flag.StringVar(&bindExceptions, "exceptions", "", "Kind of Java exceptions thrown for Go errors: checked (default) or unchecked. Valid only with -target=android.")
+	cmdBind.flag.BoolVar(&bindWatch, "watch", false, "Rebuild whenever a source file of the bound packages changes.")
 }
 
 func bootClasspath() (string, error) {
@@ -215,10 +667,50 @@ func writeFile(filename string, generate func(io.Writer) error) error {
 	return generate(f)
 }
 
+// copyBindings copies the generated host-language sources to dir for
+// the -bindingsout flag: the Java sources for Android targets and the
+// Objective-C sources for Apple targets. The directory is replaced so
+// stale bindings from earlier runs cannot linger.
+func copyBindings(dir string, targets []targetInfo) error {
+	if buildN {
+		printcmd("cp -r <bindings> %s", dir)
+		return nil
+	}
+	if err := removeAll(dir); err != nil {
+		return err
+	}
+	if isAndroidPlatform(targets[0].platform) {
+		return doCopyAll(dir, filepath.Join(tmpdir, "java"))
+	}
+	if isWasmPlatform(targets[0].platform) {
+		return doCopyAll(dir, filepath.Join(tmpdir, "wasm"))
+	}
+	if err := mkdir(dir); err != nil {
+		return err
+	}
+	gobindDir := filepath.Join(tmpdir, targets[0].platform, "src", "gobind")
+	entries, err := os.ReadDir(gobindDir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if ext := filepath.Ext(e.Name()); ext != ".h" && ext != ".m" {
+			continue
+		}
+		if err := copyFile(filepath.Join(dir, e.Name()), filepath.Join(gobindDir, e.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func packagesConfig(t targetInfo) *packages.Config {
 	config := &packages.Config{}
 	// Add CGO_ENABLED=1 explicitly since Cgo is disabled when GOOS is different from host OS.
-	config.Env = append(os.Environ(), "GOARCH="+t.arch, "GOOS="+platformOS(t.platform), "CGO_ENABLED=1")
+	config.Env = append(hostEnv(), "GOARCH="+archGo(t.arch), "GOOS="+platformOS(t.platform), "CGO_ENABLED=1")
+	if buildGoToolchain != "" {
+		config.Env = append(config.Env, "GOTOOLCHAIN="+buildGoToolchain)
+	}
 	tags := append(buildTags[:], platformTags(t.platform)...)
 
 	if len(tags) > 0 {
@@ -230,7 +722,7 @@ func packagesConfig(t targetInfo) *packages.Config {
 // getModuleVersions returns a module information at the directory src.
 func getModuleVersions(targetPlatform string, targetArch string, src string) (*modfile.File, error) {
 	cmd := exec.Command("go", "list")
-	cmd.Env = append(os.Environ(), "GOOS="+platformOS(targetPlatform), "GOARCH="+targetArch)
+	cmd.Env = append(hostEnv(), "GOOS="+platformOS(targetPlatform), "GOARCH="+archGo(targetArch))
 
 	tags := append(buildTags[:], platformTags(targetPlatform)...)
 
@@ -382,7 +874,90 @@ func areGoModulesUsed() (bool, error) {
 			return
 		}
 		outstr := strings.TrimSpace(string(out))
+		if outstr == os.DevNull {
+			// Module mode without a go.mod. In a workspace the bound
+			// packages resolve through go.work, but the synthesized
+			// build directory must live inside one of the workspace
+			// modules for its imports to resolve.
+			work, err := goWorkFile()
+			if err != nil {
+				areGoModulesUsedResult.err = err
+				return
+			}
+			if work != "" {
+				areGoModulesUsedResult.err = fmt.Errorf("the current directory is inside workspace %s but not inside one of its modules; run gomobile from a module listed in the workspace", work)
+			}
+			return
+		}
 		areGoModulesUsedResult.used = outstr != ""
 	})
 	return areGoModulesUsedResult.used, areGoModulesUsedResult.err
 }
+
+// checkVendoredDeps verifies that a module vendoring its dependencies
+// carries the bind runtime the generated wrapper package imports. The
+// wrapper is compiled inside the user's module, so with -mod=vendor in
+// effect its imports resolve through the vendor tree only; a missing
+// runtime would otherwise surface as a cryptic compile error per
+// architecture.
+func checkVendoredDeps() error {
+	used, err := areGoModulesUsed()
+	if err != nil || !used {
+		return err
+	}
+	out, err := exec.Command("go", "env", "GOMOD").Output()
+	if err != nil {
+		return err
+	}
+	gomod := strings.TrimSpace(string(out))
+	if gomod == "" || gomod == os.DevNull {
+		return nil
+	}
+	vendorDir := filepath.Join(filepath.Dir(gomod), "vendor")
+	if _, err := os.Stat(filepath.Join(vendorDir, "modules.txt")); err != nil {
+		return nil
+	}
+	// An explicit -mod flag other than vendor disables the vendor tree.
+	if flags := goEnv("GOFLAGS"); strings.Contains(flags, "-mod=") && !strings.Contains(flags, "-mod=vendor") {
+		return nil
+	}
+	if _, err := os.Stat(filepath.Join(vendorDir, "github.com", "sagernet", "gomobile", "bind", "seq")); err != nil {
+		return fmt.Errorf("this module vendors its dependencies, but the vendor tree does not contain the gomobile bind runtime; add a blank import of github.com/sagernet/gomobile/bind to a package in your module and re-run 'go mod vendor'")
+	}
+	return nil
+}
+
+var (
+	goWorkResult struct {
+		file string
+		err  error
+	}
+	goWorkOnce sync.Once
+)
+
+// goWorkFile returns the path of the go.work file in effect, or the
+// empty string when workspace mode is off. Child builds receive it
+// explicitly, so workspace resolution survives environments that
+// scrub the inherited variables.
+func goWorkFile() (string, error) {
+	goWorkOnce.Do(func() {
+		out, err := exec.Command("go", "env", "GOWORK").Output()
+		if err != nil {
+			goWorkResult.err = err
+			return
+		}
+		if f := strings.TrimSpace(string(out)); f != "" && f != "off" {
+			goWorkResult.file = f
+		}
+	})
+	return goWorkResult.file, goWorkResult.err
+}
+
+// appendGoWorkEnv appends an explicit GOWORK to a child build
+// environment when workspace mode is in effect.
+func appendGoWorkEnv(env []string) []string {
+	if work, err := goWorkFile(); err == nil && work != "" {
+		env = append(env, "GOWORK="+work)
+	}
+	return env
+}
@@ -6,12 +6,15 @@ package main
 
 import (
 	"archive/zip"
+	"debug/elf"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/sagernet/gomobile/internal/sdkpath"
@@ -24,10 +27,25 @@ func goAndroidBind(libName string, gobind string, pkgs []*packages.Package, targ
 		return fmt.Errorf("this command requires the Android SDK to be installed: %w", err)
 	}
 
-	// Run gobind to generate the bindings
+	// The default logcat tag lives in a linker-settable variable so
+	// -logtag needs no generated code.
+	if bindLogTag != "" {
+		xflag := "-X github.com/sagernet/gomobile/internal/mobileinit.logTag=" + bindLogTag
+		if !strings.Contains(buildLdflagsAndroid, xflag) {
+			buildLdflagsAndroid = joinFlags(buildLdflagsAndroid, xflag)
+		}
+	}
+
+	// Run gobind to generate the bindings. The kotlin language also
+	// generates the Java bindings, plus a companion Kotlin file per
+	// package.
+	lang := "go,java"
+	if bindKotlin != "" {
+		lang = "go,kotlin"
+	}
 	cmd := exec.Command(
 		gobind,
-		"-lang=go,java",
+		"-lang="+lang,
 		"-outdir="+tmpdir,
 	)
 	cmd.Env = append(cmd.Env, "GOOS=android")
@@ -47,17 +65,33 @@ func goAndroidBind(libName string, gobind string, pkgs []*packages.Package, targ
 	if libName != "" {
 		cmd.Args = append(cmd.Args, "-libname="+libName)
 	}
+	if bindAnnotations {
+		cmd.Args = append(cmd.Args, "-annotations")
+	}
+	if bindExceptions != "" {
+		cmd.Args = append(cmd.Args, "-exceptions="+bindExceptions)
+	}
 	for _, p := range pkgs {
 		cmd.Args = append(cmd.Args, p.PkgPath)
 	}
-	if err := runCmd(cmd); err != nil {
-		return err
+	// The gobind output only depends on the sources and the flags, so
+	// reuse it from the cache when possible.
+	gobindKey := cachePath("gobind-android", cmd.Args[1:]...)
+	srcDir := filepath.Join(tmpdir, "src")
+	javaDir := filepath.Join(tmpdir, "java")
+	if !cacheGetDir(gobindKey+"-src", srcDir) || !cacheGetDir(gobindKey+"-java", javaDir) {
+		if err := runCmd(cmd); err != nil {
+			return err
+		}
+		cachePutDir(gobindKey+"-src", srcDir)
+		cachePutDir(gobindKey+"-java", javaDir)
 	}
 
 	androidDir := filepath.Join(tmpdir, "android")
 
 	// Generate binding code and java source code only when processing the first package.
 	var wg errgroup.Group
+	wg.SetLimit(buildP)
 	for _, t := range targets {
 		t := t
 		wg.Go(func() error {
@@ -72,7 +106,42 @@ func goAndroidBind(libName string, gobind string, pkgs []*packages.Package, targ
 	if err := buildAAR(libName, jsrc, androidDir, pkgs, targets); err != nil {
 		return err
 	}
-	return buildSrcJar(jsrc)
+	if err := buildSrcJar(jsrc); err != nil {
+		return err
+	}
+	if bindJavadoc {
+		if err := buildJavadocJar(jsrc); err != nil {
+			return err
+		}
+	}
+	if bindKotlin != "" {
+		return copyKotlin(bindKotlin, jsrc)
+	}
+	return nil
+}
+
+// copyKotlin copies the companion Kotlin files generated alongside the
+// Java bindings to dst for the -kotlin flag, preserving the package
+// directory layout. The AAR cannot carry Kotlin sources for consumers,
+// so apps add the directory to a source set instead.
+func copyKotlin(dst, src string) error {
+	if buildN {
+		printcmd("cp %s/**/*.kt %s", src, dst)
+		return nil
+	}
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || filepath.Ext(path) != ".kt" {
+			return nil
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		return copyFile(filepath.Join(dst, rel), path)
+	})
 }
 
 func buildSrcJar(src string) error {
@@ -94,6 +163,87 @@ func buildSrcJar(src string) error {
 	return writeJar(out, src)
 }
 
+// buildJavadocJar runs javadoc over the generated Java sources, whose
+// comments carry the Go doc comments, and writes the result next to
+// the AAR as a -javadoc.jar, so IDEs display documentation when the
+// AAR is consumed from a Maven repository.
+func buildJavadocJar(srcDir string) (err error) {
+	var srcFiles []string
+	if buildN {
+		srcFiles = []string{"*.java"}
+	} else {
+		err := filepath.Walk(srcDir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if filepath.Ext(path) == ".java" {
+				srcFiles = append(srcFiles, filepath.Join(".", path[len(srcDir):]))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	dst := filepath.Join(tmpdir, "javadoc-output")
+	if !buildN {
+		if err := os.MkdirAll(dst, 0700); err != nil {
+			return err
+		}
+	}
+
+	bClspath, err := bootClasspath()
+	if err != nil {
+		return err
+	}
+	clspath := bClspath
+	if bindClasspath != "" {
+		clspath += string(filepath.ListSeparator) + bindClasspath
+	}
+	if bindAnnotations {
+		stubs, err := buildAnnotationStubs(bClspath)
+		if err != nil {
+			return err
+		}
+		clspath += string(filepath.ListSeparator) + stubs
+	}
+
+	args := []string{
+		"-d", dst,
+		"-quiet",
+		"-notimestamp",
+		"-Xdoclint:none",
+		"-classpath", clspath,
+	}
+	args = append(args, srcFiles...)
+
+	javadoc := exec.Command("javadoc", args...)
+	javadoc.Dir = srcDir
+	if err := runCmd(javadoc); err != nil {
+		return err
+	}
+
+	var out io.Writer = ioutil.Discard
+	if !buildN {
+		ext := filepath.Ext(buildO)
+		f, err := os.Create(buildO[:len(buildO)-len(ext)] + "-javadoc.jar")
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+		}()
+		out = f
+	}
+	if buildX {
+		printcmd("jar c -C %s .", dst)
+	}
+	return writeJar(out, dst)
+}
+
 // AAR is the format for the binary distribution of an Android Library Project
 // and it is a ZIP archive with extension .aar.
 // http://tools.android.com/tech-docs/new-build-system/aar-format
@@ -112,16 +262,65 @@ func buildSrcJar(src string) error {
 //	aidl (optional, not relevant)
 //
 // javac and jar commands are needed to build classes.jar.
-func buildAAR(libName string, srcDir, androidDir string, pkgs []*packages.Package, targets []targetInfo) (err error) {
-	var out io.Writer = ioutil.Discard
+func buildAAR(libName string, srcDir, androidDir string, pkgs []*packages.Package, targets []targetInfo) error {
 	if buildO == "" {
 		buildO = pkgs[0].Name + ".aar"
 	}
 	if !strings.HasSuffix(buildO, ".aar") {
 		return fmt.Errorf("output file name %q does not end in '.aar'", buildO)
 	}
+	if err := writeAAR(buildO, libName, srcDir, androidDir, pkgs, targets); err != nil {
+		return err
+	}
+	if !bindSplitABI {
+		return nil
+	}
+	base := buildO[:len(buildO)-len(".aar")]
+	for _, t := range targets {
+		abi := ndk.Toolchain(t.arch).abi
+		if err := writeAAR(base+"-"+abi+".aar", libName, srcDir, androidDir, pkgs, []targetInfo{t}); err != nil {
+			return err
+		}
+	}
+	return writeABIMetadata(base, libName, androidDir, targets)
+}
+
+// writeABIMetadata writes an abis.json next to the split AARs mapping
+// each ABI to its artifact and native library size, and reports the
+// per-ABI sizes, for the -splitabi flag.
+func writeABIMetadata(base, libName, androidDir string, targets []targetInfo) error {
+	type abiInfo struct {
+		ABI  string `json:"abi"`
+		AAR  string `json:"aar"`
+		Size int64  `json:"size"`
+	}
+	var abis []abiInfo
+	for _, t := range targets {
+		abi := ndk.Toolchain(t.arch).abi
+		info := abiInfo{ABI: abi, AAR: filepath.Base(base) + "-" + abi + ".aar"}
+		if !buildN {
+			fi, err := os.Stat(filepath.Join(androidDir, "src", "main", "jniLibs", abi, "lib"+libName+".so"))
+			if err != nil {
+				return err
+			}
+			info.Size = fi.Size()
+			fmt.Fprintf(os.Stderr, "lib%s.so %s: %d bytes\n", libName, abi, info.Size)
+		}
+		abis = append(abis, info)
+	}
+	return writeFile(base+"-abis.json", func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(abis)
+	})
+}
+
+// writeAAR writes one AAR holding the native libraries for the given
+// targets to the named file.
+func writeAAR(aarFile, libName string, srcDir, androidDir string, pkgs []*packages.Package, targets []targetInfo) (err error) {
+	var out io.Writer = ioutil.Discard
 	if !buildN {
-		f, err := os.Create(buildO)
+		f, err := os.Create(aarFile)
 		if err != nil {
 			return err
 		}
@@ -144,14 +343,23 @@ func buildAAR(libName string, srcDir, androidDir string, pkgs []*packages.Packag
 	if err != nil {
 		return err
 	}
+	targetSdk := ""
+	if buildAndroidTarget != 0 {
+		targetSdk = fmt.Sprintf(" android:targetSdkVersion=\"%d\"", buildAndroidTarget)
+	}
 	const manifestFmt = `<manifest xmlns:android="http://schemas.android.com/apk/res/android" package=%q>
-<uses-sdk android:minSdkVersion="%d"/></manifest>`
-	fmt.Fprintf(w, manifestFmt, "go."+pkgs[0].Name+".gojni", buildAndroidAPI)
+<uses-sdk android:minSdkVersion="%d"%s/></manifest>`
+	fmt.Fprintf(w, manifestFmt, "go."+pkgs[0].Name+".gojni", buildAndroidAPI, targetSdk)
 
 	w, err = aarwcreate("proguard.txt")
 	if err != nil {
 		return err
 	}
+	// Consumer rules applied by R8 in apps depending on the AAR. The
+	// generated proxies and the Seq runtime are reached from JNI, so
+	// they must survive shrinking, and the native methods are resolved
+	// by name against the shared library, so neither they nor their
+	// holding classes may be renamed.
 	fmt.Fprintln(w, `-keep class go.** { *; }`)
 	if bindJavaPkg != "" {
 		fmt.Fprintln(w, `-keep class `+bindJavaPkg+`.** { *; }`)
@@ -160,6 +368,10 @@ func buildAAR(libName string, srcDir, androidDir string, pkgs []*packages.Packag
 			fmt.Fprintln(w, `-keep class `+p.Name+`.** { *; }`)
 		}
 	}
+	fmt.Fprintln(w, `-keepclasseswithmembernames,includedescriptorclasses class * {`)
+	fmt.Fprintln(w, `    native <methods>;`)
+	fmt.Fprintln(w, `}`)
+	fmt.Fprintln(w, `-dontwarn go.**`)
 
 	w, err = aarwcreate("classes.jar")
 	if err != nil {
@@ -214,6 +426,53 @@ func buildAAR(libName string, srcDir, androidDir string, pkgs []*packages.Packag
 		}
 	}
 
+	// Include resource bundles declared by the bound packages and
+	// their dependencies under assets/.
+	if !buildN && len(targets) > 0 {
+		env := make([]string, len(androidEnv[targets[0].arch]))
+		copy(env, androidEnv[targets[0].arch])
+		env = append(env, fmt.Sprintf("GOPATH=%s%c%s", tmpdir, filepath.ListSeparator, goEnv("GOPATH")))
+		pkgPaths := make([]string, len(pkgs))
+		for i, p := range pkgs {
+			pkgPaths[i] = p.PkgPath
+		}
+		bundles, err := extractResourceBundles(env, filepath.Join(tmpdir, "src", "gobind"), pkgPaths, buildTags)
+		if err != nil {
+			return fmt.Errorf("failed to collect resource bundles: %v", err)
+		}
+		for _, b := range bundles {
+			base := filepath.Base(b)
+			err := filepath.Walk(b, func(path string, info os.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+				if info.IsDir() {
+					return nil
+				}
+				f, err := os.Open(path)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				name := "assets/" + base + filepath.ToSlash(path[len(b):])
+				if orig, exists := files[name]; exists {
+					return fmt.Errorf("resource bundle %s asset name conflict: %s already added from package %s",
+						base, name, orig)
+				}
+				files[name] = b
+				w, err := aarwcreate(name)
+				if err != nil {
+					return err
+				}
+				_, err = io.Copy(w, f)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
 	for _, t := range targets {
 		toolchain := ndk.Toolchain(t.arch)
 		lib := toolchain.abi + "/lib" + libName + ".so"
@@ -233,6 +492,12 @@ func buildAAR(libName string, srcDir, androidDir string, pkgs []*packages.Packag
 		}
 	}
 
+	if bindPrefab {
+		if err := writePrefab(aarwcreate, libName, androidDir, targets); err != nil {
+			return err
+		}
+	}
+
 	// TODO(hyangah): do we need to use aapt to create R.txt?
 	w, err = aarwcreate("R.txt")
 	if err != nil {
@@ -247,6 +512,108 @@ func buildAAR(libName string, srcDir, androidDir string, pkgs []*packages.Packag
 	return aarw.Close()
 }
 
+// writePrefab adds a prefab/ directory to the AAR exposing the
+// c-shared library and its generated C headers as a Prefab module, so
+// C++ code in the same app or other AARs can link against the bound Go
+// library via CMake or ndk-build in addition to the Java API. See
+// https://google.github.io/prefab/ for the layout and schema.
+func writePrefab(aarwcreate func(string) (io.Writer, error), libName, androidDir string, targets []targetInfo) error {
+	writeJSON := func(name string, v interface{}) error {
+		w, err := aarwcreate(name)
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+	copyInto := func(name, src string) error {
+		w, err := aarwcreate(name)
+		if err != nil {
+			return err
+		}
+		if buildN {
+			return nil
+		}
+		r, err := os.Open(src)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		_, err = io.Copy(w, r)
+		return err
+	}
+
+	err := writeJSON("prefab/prefab.json", map[string]interface{}{
+		"schema_version": 2,
+		"name":           libName,
+		"version":        "1.0",
+		"dependencies":   []string{},
+	})
+	if err != nil {
+		return err
+	}
+	moduleDir := "prefab/modules/" + libName
+	err = writeJSON(moduleDir+"/module.json", map[string]interface{}{
+		"export_libraries": []string{},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Install the generated C headers. The Darwin and Objective-C
+	// headers do not apply to NDK consumers.
+	headers, err := filepath.Glob(filepath.Join(tmpdir, "src", "gobind", "*.h"))
+	if err != nil {
+		return err
+	}
+	for _, h := range headers {
+		base := filepath.Base(h)
+		if strings.HasSuffix(base, "_darwin.h") || strings.HasSuffix(base, ".objc.h") || base == "ref.h" {
+			continue
+		}
+		if err := copyInto(moduleDir+"/include/"+base, h); err != nil {
+			return err
+		}
+	}
+
+	// One library per ABI, with its metadata.
+	ndkVer := 0
+	if root, err := ndkRoot(targets...); err == nil {
+		if v := ndkVersion(root); v != "" {
+			major := v
+			if i := strings.IndexByte(v, '.'); i >= 0 {
+				major = v[:i]
+			}
+			ndkVer, _ = strconv.Atoi(major)
+		}
+	}
+	for _, t := range targets {
+		toolchain := ndk.Toolchain(t.arch)
+		api := buildAndroidAPI
+		if api < toolchain.minAPI {
+			api = toolchain.minAPI
+		}
+		libDir := moduleDir + "/libs/android." + toolchain.abi
+		err := writeJSON(libDir+"/abi.json", map[string]interface{}{
+			"abi":    toolchain.abi,
+			"api":    api,
+			"ndk":    ndkVer,
+			"stl":    "none",
+			"static": false,
+		})
+		if err != nil {
+			return err
+		}
+		lib := "lib" + libName + ".so"
+		src := filepath.Join(androidDir, "src/main/jniLibs", toolchain.abi, lib)
+		if err := copyInto(libDir+"/"+lib, src); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 const (
 	javacTargetVer = "1.8"
 	minAndroidAPI  = 16
@@ -291,8 +658,19 @@ func buildJar(w io.Writer, srcDir string) error {
 		"-target", javacTargetVer,
 		"-bootclasspath", bClspath,
 	}
-	if bindClasspath != "" {
-		args = append(args, "-classpath", bindClasspath)
+	clspath := bindClasspath
+	if bindAnnotations {
+		stubs, err := buildAnnotationStubs(bClspath)
+		if err != nil {
+			return err
+		}
+		if clspath != "" {
+			clspath += string(filepath.ListSeparator)
+		}
+		clspath += stubs
+	}
+	if clspath != "" {
+		args = append(args, "-classpath", clspath)
 	}
 
 	args = append(args, srcFiles...)
@@ -309,6 +687,66 @@ func buildJar(w io.Writer, srcDir string) error {
 	return writeJar(w, dst)
 }
 
+// buildAnnotationStubs compiles minimal androidx.annotation @NonNull,
+// @Nullable and @Keep declarations into a class directory for the
+// -annotations flag and returns its path, so javac can resolve the
+// annotations without androidx on the classpath. The annotations have
+// class retention and the stubs are not packaged into classes.jar, so
+// consumers resolving real androidx artifacts see no duplicate classes.
+func buildAnnotationStubs(bootClspath string) (string, error) {
+	srcDir := filepath.Join(tmpdir, "annotation-stubs")
+	dst := filepath.Join(tmpdir, "annotation-classes")
+	if !buildN {
+		if err := os.MkdirAll(dst, 0700); err != nil {
+			return "", err
+		}
+	}
+	for _, name := range []string{"NonNull", "Nullable", "Keep"} {
+		name := name
+		err := writeFile(filepath.Join(srcDir, "androidx", "annotation", name+".java"), func(w io.Writer) error {
+			_, err := fmt.Fprintf(w, annotationStubJava, name)
+			return err
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+	javac := exec.Command("javac",
+		"-d", dst,
+		"-source", javacTargetVer,
+		"-target", javacTargetVer,
+		"-bootclasspath", bootClspath,
+		filepath.Join("androidx", "annotation", "NonNull.java"),
+		filepath.Join("androidx", "annotation", "Nullable.java"),
+		filepath.Join("androidx", "annotation", "Keep.java"),
+	)
+	javac.Dir = srcDir
+	if err := runCmd(javac); err != nil {
+		return "", err
+	}
+	return dst, nil
+}
+
+const annotationStubJava = `// Code generated by gomobile bind. DO NOT EDIT.
+package androidx.annotation;
+
+import java.lang.annotation.Retention;
+import java.lang.annotation.RetentionPolicy;
+import java.lang.annotation.Target;
+
+import static java.lang.annotation.ElementType.CONSTRUCTOR;
+import static java.lang.annotation.ElementType.FIELD;
+import static java.lang.annotation.ElementType.LOCAL_VARIABLE;
+import static java.lang.annotation.ElementType.METHOD;
+import static java.lang.annotation.ElementType.PARAMETER;
+import static java.lang.annotation.ElementType.TYPE;
+
+@Retention(RetentionPolicy.CLASS)
+@Target({TYPE, CONSTRUCTOR, METHOD, PARAMETER, FIELD, LOCAL_VARIABLE})
+public @interface %s {
+}
+`
+
 func writeJar(w io.Writer, dir string) error {
 	if buildN {
 		return nil
@@ -361,6 +799,7 @@ func buildAndroidSO(libName string, outputDir string, arch string) error {
 	// Add the generated packages to GOPATH for reverse bindings.
 	gopath := fmt.Sprintf("GOPATH=%s%c%s", tmpdir, filepath.ListSeparator, goEnv("GOPATH"))
 	env = append(env, gopath)
+	env = appendGoWorkEnv(env)
 
 	modulesUsed, err := areGoModulesUsed()
 	if err != nil {
@@ -382,15 +821,48 @@ func buildAndroidSO(libName string, outputDir string, arch string) error {
 	}
 
 	toolchain := ndk.Toolchain(arch)
+	lib := filepath.Join(outputDir, "src", "main", "jniLibs", toolchain.abi, "lib"+libName+".so")
+	libKey := cachePath("android-so", append([]string{
+		"arch=" + arch,
+		"tags=" + strings.Join(buildTags, ","),
+		"gcflags=" + gcflagsFor("android"),
+		"ldflags=" + ldflagsFor("android"),
+		fmt.Sprintf("trimpath=%t", buildTrimpath),
+	}, env...)...)
+	if cacheGetFile(libKey, lib) {
+		return verifyPageAlignment(lib)
+	}
 	if err := goBuildAt(
 		srcDir,
 		".",
 		env,
 		"-buildmode=c-shared",
-		"-o="+filepath.Join(outputDir, "src", "main", "jniLibs", toolchain.abi, "lib"+libName+".so"),
+		"-o="+lib,
 	); err != nil {
 		return err
 	}
+	cachePutFile(libKey, lib)
 
+	return verifyPageAlignment(lib)
+}
+
+// verifyPageAlignment checks that every loadable segment of the shared
+// library at path is aligned to 16KB pages, as Google Play requires on
+// devices with 16KB page sizes. The -no16kpages flag disables the
+// check along with the linker flag that establishes the alignment.
+func verifyPageAlignment(path string) error {
+	if buildN || buildNo16KPages {
+		return nil
+	}
+	f, err := elf.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	for _, p := range f.Progs {
+		if p.Type == elf.PT_LOAD && p.Align < 16384 {
+			return fmt.Errorf("%s: load segment aligned to %d bytes, but Google Play requires 16KB-aligned libraries; pass -no16kpages to disable the alignment", path, p.Align)
+		}
+	}
 	return nil
 }
@@ -6,6 +6,8 @@ package main
 
 import (
 	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
@@ -24,6 +26,10 @@ func goAppleBind(gobind string, pkgs []*packages.Package, targets []targetInfo)
 	var name string
 	var title string
 
+	if strings.HasSuffix(buildO, ".dylib") {
+		return goAppleBindDylib(gobind, pkgs, targets)
+	}
+
 	if buildO == "" {
 		name = pkgs[0].Name
 		title = strings.Title(name)
@@ -48,6 +54,7 @@ func goAppleBind(gobind string, pkgs []*packages.Package, targets []targetInfo)
 
 	// Run the gobind command for each platform
 	var gobindWG errgroup.Group
+	gobindWG.SetLimit(buildP)
 	for platform, outDir := range outDirsForPlatform {
 		platform := platform
 		outDir := outDir
@@ -95,7 +102,9 @@ func goAppleBind(gobind string, pkgs []*packages.Package, targets []targetInfo)
 	}
 
 	// Build archive files.
+	var resourceBundles []string
 	var buildWG errgroup.Group
+	buildWG.SetLimit(buildP)
 	for _, t := range targets {
 		t := t
 		buildWG.Go(func() error {
@@ -120,6 +129,7 @@ func goAppleBind(gobind string, pkgs []*packages.Package, targets []targetInfo)
 			// Add the generated packages to GOPATH for reverse bindings.
 			gopath := fmt.Sprintf("GOPATH=%s%c%s", outDir, filepath.ListSeparator, goEnv("GOPATH"))
 			env = append(env, gopath)
+			env = appendGoWorkEnv(env)
 
 			// Build platform-specific tags
 			tags := append(buildTags[:], platformTags(t.platform)...)
@@ -133,24 +143,39 @@ func goAppleBind(gobind string, pkgs []*packages.Package, targets []targetInfo)
 				return fmt.Errorf("%s/%s: %v", t.platform, t.arch, err)
 			}
 
-			// Extract and merge external static libraries from CGO LDFLAGS
 			pkgPaths := make([]string, len(pkgs))
 			for i, p := range pkgs {
 				pkgPaths[i] = p.PkgPath
 			}
-			externalLibraries, err := extractExternalStaticLibraries(env, outSrcDir, pkgPaths, tags)
-			if err != nil {
-				return fmt.Errorf("failed to extract external libraries for %s/%s: %v", t.platform, t.arch, err)
-			}
-			if len(externalLibraries) > 0 {
-				archivePath := appleArchiveFilepath(name, t)
-				mergedPath := archivePath + ".merged"
-				if err := mergeStaticLibraries(archivePath, externalLibraries, mergedPath); err != nil {
-					return fmt.Errorf("failed to merge static libraries for %s/%s: %v", t.platform, t.arch, err)
+			// Extract and merge external static libraries from CGO
+			// LDFLAGS. A c-shared dylib already links them in, so the
+			// merge is only needed for static archives.
+			if bindBuildmode == "c-archive" {
+				externalLibraries, err := extractExternalStaticLibraries(env, outSrcDir, pkgPaths, tags)
+				if err != nil {
+					return fmt.Errorf("failed to extract external libraries for %s/%s: %v", t.platform, t.arch, err)
 				}
-				if err := os.Rename(mergedPath, archivePath); err != nil {
-					return fmt.Errorf("failed to rename merged library: %v", err)
+				if len(externalLibraries) > 0 {
+					archivePath := appleArchiveFilepath(name, t)
+					mergedPath := archivePath + ".merged"
+					if err := mergeStaticLibraries(archivePath, externalLibraries, mergedPath); err != nil {
+						return fmt.Errorf("failed to merge static libraries for %s/%s: %v", t.platform, t.arch, err)
+					}
+					if err := os.Rename(mergedPath, archivePath); err != nil {
+						return fmt.Errorf("failed to rename merged library: %v", err)
+					}
+				}
+			}
+
+			// Collect resource bundles declared by the bound packages and
+			// their dependencies. The set is identical across targets, so
+			// scan with the first target's build configuration only.
+			if !buildN && t == targets[0] {
+				bundles, err := extractResourceBundles(env, outSrcDir, pkgPaths, tags)
+				if err != nil {
+					return fmt.Errorf("failed to collect resource bundles for %s/%s: %v", t.platform, t.arch, err)
 				}
+				resourceBundles = bundles
 			}
 
 			return nil
@@ -185,6 +210,9 @@ func goAppleBind(gobind string, pkgs []*packages.Package, targets []targetInfo)
 			if err := runCmd(fatCmd); err != nil {
 				return err
 			}
+			if err := appleSetInstallName(titlePath, title); err != nil {
+				return err
+			}
 			continue
 		}
 
@@ -209,6 +237,9 @@ func goAppleBind(gobind string, pkgs []*packages.Package, targets []targetInfo)
 		if err := runCmd(lipoCmd); err != nil {
 			return err
 		}
+		if err := appleSetInstallName(titlePath, title); err != nil {
+			return err
+		}
 
 		fileBases := make([]string, len(pkgs)+1)
 		for i, pkg := range pkgs {
@@ -263,12 +294,37 @@ func goAppleBind(gobind string, pkgs []*packages.Package, targets []targetInfo)
 			return err
 		}
 		err = writeFile(filepath.Join(frameworkDir, "Resources", "Info.plist"), func(w io.Writer) error {
-			_, err := w.Write([]byte(appleBindInfoPlist))
-			return err
+			data := appleBindPlistData(title, t.platform)
+			if bindBuildmode == "c-shared" {
+				return appleBindDylibInfoPlistTmpl.Execute(w, data)
+			}
+			return appleBindInfoPlistTmpl.Execute(w, data)
 		})
 		if err != nil {
 			return err
 		}
+		// Embed the privacy manifest required by the App Store for
+		// SDKs: the user-provided one, or a minimal default declaring
+		// no tracking and no collected data.
+		privacyPath := filepath.Join(frameworkDir, "Resources", "PrivacyInfo.xcprivacy")
+		if bindXcprivacy != "" {
+			if err := copyFile(privacyPath, bindXcprivacy); err != nil {
+				return err
+			}
+		} else {
+			err = writeFile(privacyPath, func(w io.Writer) error {
+				_, err := w.Write([]byte(appleBindPrivacyManifest))
+				return err
+			})
+			if err != nil {
+				return err
+			}
+		}
+		for _, b := range resourceBundles {
+			if err := doCopyAll(filepath.Join(frameworkDir, "Resources", filepath.Base(b)), b); err != nil {
+				return err
+			}
+		}
 
 		var mmVals = struct {
 			Module  string
@@ -289,6 +345,12 @@ func goAppleBind(gobind string, pkgs []*packages.Package, targets []targetInfo)
 		}
 	}
 
+	if bindDsym {
+		if err := appleExtractDsyms(frameworkDirs, title); err != nil {
+			return err
+		}
+	}
+
 	// Finally combine all frameworks to an XCFramework
 	xcframeworkArgs := []string{"-create-xcframework"}
 
@@ -306,18 +368,356 @@ func goAppleBind(gobind string, pkgs []*packages.Package, targets []targetInfo)
 
 	xcframeworkArgs = append(xcframeworkArgs, "-output", buildO)
 	cmd := exec.Command("xcodebuild", xcframeworkArgs...)
-	err = runCmd(cmd)
-	return err
+	if err := runCmd(cmd); err != nil {
+		return err
+	}
+	if bindSign != "" {
+		if err := signXCFramework(buildO, bindSign); err != nil {
+			return err
+		}
+	}
+	if bindSwiftPkg != "" {
+		if err := writeSwiftPackage(buildO, title, bindSwiftPkg); err != nil {
+			return err
+		}
+	}
+	if bindSampleApp != "" {
+		if err := writeSampleApp(bindSampleApp, title); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// goAppleBindDylib produces a bare dylib with the headers installed in
+// an include/ directory next to it, instead of an XCFramework, when -o
+// names a .dylib. CLI tools and .NET or other FFI consumers link it
+// directly without framework machinery. Only -target=macos is
+// supported.
+func goAppleBindDylib(gobind string, pkgs []*packages.Package, targets []targetInfo) error {
+	for _, t := range targets {
+		if t.platform != "macos" {
+			return fmt.Errorf(`".dylib" output is supported only for -target=macos`)
+		}
+	}
+	if bindBuildmode != "c-shared" {
+		return fmt.Errorf(`".dylib" output requires -buildmode=c-shared`)
+	}
+	name := strings.TrimSuffix(filepath.Base(buildO), ".dylib")
+	if name == "" {
+		return fmt.Errorf("dylib name missing in %q", buildO)
+	}
+	title := strings.Title(pkgs[0].Name)
+
+	if err := removeAll(buildO); err != nil {
+		return err
+	}
+
+	// Run the gobind command once; all slices share the sources.
+	outDir := filepath.Join(tmpdir, "macos")
+	cmd := exec.Command(
+		gobind,
+		"-lang=go,objc",
+		"-outdir="+outDir,
+	)
+	cmd.Env = append(cmd.Env, "GOOS=darwin")
+	cmd.Env = append(cmd.Env, "CGO_ENABLED=1")
+	tags := append(buildTags[:], platformTags("macos")...)
+	tags = append(tags, buildTagsMacOS...)
+	cmd.Args = append(cmd.Args, "-tags="+strings.Join(tags, ","))
+	if bindPrefix != "" {
+		cmd.Args = append(cmd.Args, "-prefix="+bindPrefix)
+	}
+	for _, p := range pkgs {
+		cmd.Args = append(cmd.Args, p.PkgPath)
+	}
+	if err := runCmd(cmd); err != nil {
+		return err
+	}
+
+	// Build one dylib per architecture and lipo them together.
+	gobindDir := filepath.Join(outDir, "src", "gobind")
+	var archives []string
+	for _, t := range targets {
+		env := make([]string, len(appleEnv[t.String()]))
+		copy(env, appleEnv[t.String()])
+		gopath := fmt.Sprintf("GOPATH=%s%c%s", outDir, filepath.ListSeparator, goEnv("GOPATH"))
+		env = append(env, gopath)
+		env = appendGoWorkEnv(env)
+		out := appleArchiveFilepath(name, t)
+		if err := goAppleBindArchive(out, env, gobindDir, tags); err != nil {
+			return fmt.Errorf("%s/%s: %v", t.platform, t.arch, err)
+		}
+		archives = append(archives, out)
+	}
+	lipoArgs := append([]string{"lipo"}, archives...)
+	lipoArgs = append(lipoArgs, "-create", "-output", buildO)
+	if err := runCmd(exec.Command("xcrun", lipoArgs...)); err != nil {
+		return err
+	}
+	installName := "@rpath/" + filepath.Base(buildO)
+	cmd = exec.Command("xcrun", "install_name_tool", "-id", installName, buildO)
+	if err := runCmd(cmd); err != nil {
+		return err
+	}
+
+	// Install the headers next to the dylib.
+	includeDir := filepath.Join(filepath.Dir(buildO), "include")
+	if err := mkdir(includeDir); err != nil {
+		return err
+	}
+	fileBases := make([]string, len(pkgs)+1)
+	for i, pkg := range pkgs {
+		fileBases[i] = bindPrefix + strings.Title(pkg.Name)
+	}
+	fileBases[len(fileBases)-1] = "Universe"
+	for _, fileBase := range fileBases {
+		err := copyFile(
+			filepath.Join(includeDir, fileBase+".objc.h"),
+			filepath.Join(gobindDir, fileBase+".objc.h"),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	if err := copyFile(filepath.Join(includeDir, "ref.h"), filepath.Join(gobindDir, "ref.h")); err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(includeDir, title+".h"), func(w io.Writer) error {
+		return appleBindHeaderTmpl.Execute(w, map[string]interface{}{
+			"pkgs": pkgs, "title": title, "bases": fileBases,
+		})
+	})
+}
+
+// appleExtractDsyms runs dsymutil on the fat dylib of each framework
+// slice and writes <Title>.framework.<sdk>.dSYM bundles next to the
+// XCFramework, for the -dsym flag. frameworkDirs holds one entry per
+// target, so duplicates from multi-arch platforms are skipped.
+func appleExtractDsyms(frameworkDirs []string, title string) error {
+	outDir := filepath.Dir(buildO)
+	seen := make(map[string]bool)
+	for _, dir := range frameworkDirs {
+		if seen[dir] {
+			continue
+		}
+		seen[dir] = true
+		sdk := filepath.Base(filepath.Dir(dir))
+		binPath := filepath.Join(dir, "Versions", "A", title)
+		dsymPath := filepath.Join(outDir, title+".framework."+sdk+".dSYM")
+		cmd := exec.Command("xcrun", "dsymutil", binPath, "-o", dsymPath)
+		if err := runCmd(cmd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeSwiftPackage writes a Swift Package with a binaryTarget for the
+// XCFramework at path to outDir, for the -swiftpkg flag. The
+// XCFramework is zipped with ditto, which preserves the symlinks Swift
+// Package Manager expects, and the SHA-256 checksum SPM uses to verify
+// url-based binary targets is written next to it.
+func writeSwiftPackage(path, title, outDir string) error {
+	if err := mkdir(outDir); err != nil {
+		return err
+	}
+	zipPath := filepath.Join(outDir, title+".xcframework.zip")
+	zipCmd := exec.Command("ditto", "-c", "-k", "--sequesterRsrc", "--keep-parent", path, zipPath)
+	if err := runCmd(zipCmd); err != nil {
+		return err
+	}
+	checksum := "$CHECKSUM"
+	if !buildN {
+		f, err := os.Open(zipPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		h := sha256.New()
+		if _, err := io.Copy(h, f); err != nil {
+			return err
+		}
+		checksum = hex.EncodeToString(h.Sum(nil))
+	}
+	err := writeFile(filepath.Join(outDir, title+".xcframework.zip.checksum"), func(w io.Writer) error {
+		_, err := fmt.Fprintln(w, checksum)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(outDir, "Package.swift"), func(w io.Writer) error {
+		return swiftPackageTmpl.Execute(w, map[string]interface{}{
+			"Title":    title,
+			"Checksum": checksum,
+		})
+	})
+}
+
+var swiftPackageTmpl = template.Must(template.New("swiftpkg").Parse(`// swift-tools-version:5.3
+// Code generated by gomobile bind. DO NOT EDIT.
+import PackageDescription
+
+let package = Package(
+    name: "{{.Title}}",
+    products: [
+        .library(name: "{{.Title}}", targets: ["{{.Title}}"]),
+    ],
+    targets: [
+        // To distribute the framework, upload {{.Title}}.xcframework.zip
+        // and replace the path with:
+        //   url: "https://example.com/{{.Title}}.xcframework.zip",
+        //   checksum: "{{.Checksum}}"
+        .binaryTarget(
+            name: "{{.Title}}",
+            path: "{{.Title}}.xcframework.zip"
+        ),
+    ]
+)
+`))
+
+// signXCFramework runs codesign with the given identity on each
+// framework of the XCFramework at path, for the -sign flag.
+func signXCFramework(path, identity string) error {
+	if buildN {
+		printcmd("codesign --force --sign %s %s/*/*.framework", identity, path)
+		return nil
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		frameworks, err := filepath.Glob(filepath.Join(path, e.Name(), "*.framework"))
+		if err != nil {
+			return err
+		}
+		for _, framework := range frameworks {
+			cmd := exec.Command("codesign", "--force", "--sign", identity, framework)
+			if err := runCmd(cmd); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// appleBindPlistData assembles the template data shared by the static
+// and dynamic framework Info.plists from the bind flags. The minimum
+// OS version key and value depend on the platform of the slice being
+// written.
+func appleBindPlistData(title, platform string) map[string]interface{} {
+	bundleID := buildBundleID
+	if bundleID == "" {
+		bundleID = "org.golang.gomobile." + title
+	}
+	minKey, minVersion := "MinimumOSVersion", buildIOSVersion
+	switch platform {
+	case "macos":
+		minKey, minVersion = "LSMinimumSystemVersion", buildMacOSVersion
+	case "tvos", "tvossimulator":
+		minVersion = buildTVOSVersion
+	case "visionos", "visionossimulator":
+		minVersion = buildVisionOSVersion
+	case "watchos", "watchossimulator":
+		minVersion = buildWatchOSVersion
+	}
+	extra := make([][2]string, 0, len(bindPlistKeys))
+	for _, kv := range bindPlistKeys {
+		k, v, _ := strings.Cut(kv, "=")
+		extra = append(extra, [2]string{k, v})
+	}
+	return map[string]interface{}{
+		"Title":      title,
+		"BundleID":   bundleID,
+		"Version":    bindBundleVersion,
+		"MinKey":     minKey,
+		"MinVersion": minVersion,
+		"Extra":      extra,
+	}
 }
 
-const appleBindInfoPlist = `<?xml version="1.0" encoding="UTF-8"?>
-    <!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
-    <plist version="1.0">
-      <dict>
-      </dict>
-    </plist>
+// appleBindInfoPlistTmpl is the Info.plist for static framework
+// slices. Static frameworks have no bundle executable, but App Store
+// and MDM validation still require identifier and version metadata.
+var appleBindInfoPlistTmpl = template.Must(template.New("plist").Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+  <dict>
+    <key>CFBundleIdentifier</key>
+    <string>{{.BundleID}}</string>
+    <key>CFBundleInfoDictionaryVersion</key>
+    <string>6.0</string>
+    <key>CFBundleName</key>
+    <string>{{.Title}}</string>
+    <key>CFBundlePackageType</key>
+    <string>FMWK</string>
+    <key>CFBundleShortVersionString</key>
+    <string>{{.Version}}</string>
+    <key>CFBundleVersion</key>
+    <string>{{.Version}}</string>
+    <key>{{.MinKey}}</key>
+    <string>{{.MinVersion}}</string>
+{{range .Extra}}    <key>{{index . 0}}</key>
+    <string>{{index . 1}}</string>
+{{end}}  </dict>
+</plist>
+`))
+
+// appleBindPrivacyManifest is the default privacy manifest embedded in
+// each framework slice when -xcprivacy is not set. It declares no
+// tracking, no collected data and no use of reason-listed APIs.
+const appleBindPrivacyManifest = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+  <dict>
+    <key>NSPrivacyTracking</key>
+    <false/>
+    <key>NSPrivacyTrackingDomains</key>
+    <array/>
+    <key>NSPrivacyCollectedDataTypes</key>
+    <array/>
+    <key>NSPrivacyAccessedAPITypes</key>
+    <array/>
+  </dict>
+</plist>
 `
 
+// appleBindDylibInfoPlistTmpl is the Info.plist for dynamic framework
+// slices. Unlike static frameworks, xcodebuild and the loader require
+// the bundle executable and identifier to be declared.
+var appleBindDylibInfoPlistTmpl = template.Must(template.New("dylibplist").Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+  <dict>
+    <key>CFBundleDevelopmentRegion</key>
+    <string>en</string>
+    <key>CFBundleExecutable</key>
+    <string>{{.Title}}</string>
+    <key>CFBundleIdentifier</key>
+    <string>{{.BundleID}}</string>
+    <key>CFBundleInfoDictionaryVersion</key>
+    <string>6.0</string>
+    <key>CFBundleName</key>
+    <string>{{.Title}}</string>
+    <key>CFBundlePackageType</key>
+    <string>FMWK</string>
+    <key>CFBundleShortVersionString</key>
+    <string>{{.Version}}</string>
+    <key>CFBundleVersion</key>
+    <string>{{.Version}}</string>
+    <key>{{.MinKey}}</key>
+    <string>{{.MinVersion}}</string>
+{{range .Extra}}    <key>{{index . 0}}</key>
+    <string>{{index . 1}}</string>
+{{end}}  </dict>
+</plist>
+`))
+
 var appleModuleMapTmpl = template.Must(template.New("iosmmap").Parse(`framework module "{{.Module}}" {
 	header "ref.h"
 {{range .Headers}}    header "{{.}}"
@@ -326,11 +726,37 @@ var appleModuleMapTmpl = template.Must(template.New("iosmmap").Parse(`framework
 }`))
 
 func appleArchiveFilepath(name string, t targetInfo) string {
-	return filepath.Join(tmpdir, name+"-"+t.platform+"-"+t.arch+".a")
+	ext := ".a"
+	if bindBuildmode == "c-shared" {
+		ext = ".dylib"
+	}
+	return filepath.Join(tmpdir, name+"-"+t.platform+"-"+t.arch+ext)
+}
+
+// appleSetInstallName rewrites the install name of a c-shared dylib so
+// the framework is loadable via @rpath from apps and app extensions.
+// Static archives have no install name and are left alone.
+func appleSetInstallName(path, title string) error {
+	if bindBuildmode != "c-shared" {
+		return nil
+	}
+	installName := "@rpath/" + title + ".framework/Versions/A/" + title
+	cmd := exec.Command("xcrun", "install_name_tool", "-id", installName, path)
+	return runCmd(cmd)
 }
 
 func goAppleBindArchive(out string, env []string, gosrc string, tags []string) error {
-	cmd := exec.Command("go", "build", "-buildmode=c-archive", "-o", out)
+	key := cachePath("apple-archive", append([]string{
+		"buildmode=" + bindBuildmode,
+		"tags=" + strings.Join(tags, ","),
+		"gcflags=" + gcflagsFor(getenv(env, "GOOS")),
+		"ldflags=" + ldflagsFor(getenv(env, "GOOS")),
+		fmt.Sprintf("trimpath=%t", buildTrimpath),
+	}, env...)...)
+	if cacheGetFile(key, out) {
+		return nil
+	}
+	cmd := exec.Command("go", "build", "-buildmode="+bindBuildmode, "-o", out)
 	if len(tags) > 0 {
 		cmd.Args = append(cmd.Args, "-tags="+strings.Join(tags, ","))
 	}
@@ -340,11 +766,11 @@ func goAppleBindArchive(out string, env []string, gosrc string, tags []string) e
 	if buildX {
 		cmd.Args = append(cmd.Args, "-x")
 	}
-	if buildGcflags != "" {
-		cmd.Args = append(cmd.Args, "-gcflags", buildGcflags)
+	if gcflags := gcflagsFor(getenv(env, "GOOS")); gcflags != "" {
+		cmd.Args = append(cmd.Args, "-gcflags", gcflags)
 	}
-	if buildLdflags != "" {
-		cmd.Args = append(cmd.Args, "-ldflags", buildLdflags)
+	if ldflags := ldflagsFor(getenv(env, "GOOS")); ldflags != "" {
+		cmd.Args = append(cmd.Args, "-ldflags", ldflags)
 	}
 	if buildTrimpath {
 		cmd.Args = append(cmd.Args, "-trimpath")
@@ -360,8 +786,12 @@ func goAppleBindArchive(out string, env []string, gosrc string, tags []string) e
 	if gmc, err := goModCachePath(); err == nil {
 		env = append([]string{"GOMODCACHE=" + gmc}, env...)
 	}
-	cmd.Env = append(os.Environ(), env...)
-	return runCmd(cmd)
+	cmd.Env = append(hostEnv(), env...)
+	if err := runCmd(cmd); err != nil {
+		return err
+	}
+	cachePutFile(key, out)
+	return nil
 }
 
 // extractExternalStaticLibraries extracts static library paths from CGO LDFLAGS
@@ -373,7 +803,7 @@ func extractExternalStaticLibraries(env []string, gosrc string, pkgPaths []strin
 		cmd.Args = append(cmd.Args, "-tags="+strings.Join(tags, ","))
 	}
 	cmd.Args = append(cmd.Args, pkgPaths...)
-	cmd.Env = append(os.Environ(), env...)
+	cmd.Env = append(hostEnv(), env...)
 	cmd.Dir = gosrc
 
 	stdout, err := cmd.StdoutPipe()
@@ -0,0 +1,85 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// goWasmBind generates the WebAssembly bindings and builds the .wasm
+// module with GOOS=js GOARCH=wasm. The generated JavaScript wrapper
+// and TypeScript declarations are placed next to the output module;
+// wasm_exec.js from the Go distribution must be served alongside.
+func goWasmBind(gobind string, pkgs []*packages.Package, targets []targetInfo) error {
+	if len(pkgs) != 1 {
+		return fmt.Errorf("binding multiple packages for wasm is not supported")
+	}
+	pkg := pkgs[0]
+
+	// Run gobind to generate the bindings.
+	cmd := exec.Command(
+		gobind,
+		"-lang=wasm",
+		"-outdir="+tmpdir,
+	)
+	if len(buildTags) > 0 {
+		cmd.Args = append(cmd.Args, "-tags="+strings.Join(buildTags, ","))
+	}
+	cmd.Args = append(cmd.Args, pkg.PkgPath)
+	if err := runCmd(cmd); err != nil {
+		return err
+	}
+
+	srcDir := filepath.Join(tmpdir, "wasm", pkg.Name)
+
+	modulesUsed, err := areGoModulesUsed()
+	if err != nil {
+		return err
+	}
+	if modulesUsed {
+		// Copy the generated main package into the invoking module, so
+		// the bound package and its dependencies resolve during the
+		// build.
+		newSrcDir, _ := filepath.Abs(filepath.Join(".", "build", "wasm"))
+		os.MkdirAll(newSrcDir, 0755)
+		if !buildN {
+			if err := doCopyAll(newSrcDir, srcDir); err != nil {
+				return err
+			}
+		}
+		defer os.RemoveAll(newSrcDir)
+		srcDir = newSrcDir
+	}
+
+	if buildO == "" {
+		buildO = pkg.Name + ".wasm"
+	}
+	if !strings.HasSuffix(buildO, ".wasm") {
+		return fmt.Errorf("output file name %q does not end in '.wasm'", buildO)
+	}
+	out, err := filepath.Abs(buildO)
+	if err != nil {
+		return err
+	}
+
+	env := appendGoWorkEnv(append(hostEnv(), "GOOS=js", "GOARCH=wasm", "CGO_ENABLED=0"))
+	if err := goBuildAt(srcDir, ".", env, "-o="+out); err != nil {
+		return err
+	}
+
+	outDir := filepath.Dir(buildO)
+	for _, name := range []string{"index.js", "index.d.ts"} {
+		if err := copyFile(filepath.Join(outDir, name), filepath.Join(tmpdir, "wasm", pkg.Name, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -14,6 +14,7 @@ import (
 	"os"
 	"os/exec"
 	"regexp"
+	"runtime"
 	"slices"
 	"strconv"
 	"strings"
@@ -55,6 +56,17 @@ specify -target ios,macos,maccatalyst. A subset of instruction sets can be
 selectged by specifying the platform with an architecture name. E.g.
 -target=ios/arm64,maccatalyst/arm64.
 
+On Android, an additional riscv64 library can be requested explicitly,
+e.g. -target=android/arm64,android/riscv64, for upcoming RISC-V
+devices. It needs NDK r27 or newer and API level 35, and is never part
+of the platform defaults.
+
+On iOS and macOS, an additional arm64e (pointer authentication) slice
+can be requested explicitly, e.g. -target=ios/arm64,ios/arm64e. Go has
+no arm64e port, so the Go code in such a slice is compiled as arm64
+while the C glue code is built for arm64e; the slice is never part of
+the platform defaults.
+
 If the package directory contains an assets subdirectory, its contents
 are copied into the output.
 
@@ -62,7 +74,17 @@ Flag -iosversion sets the minimal version of the iOS SDK to compile against.
 The default version is 13.0.
 
 Flag -androidapi sets the Android API version to compile against.
-The default and minimum is 16.
+The default and minimum is 16. The level must be provided by the NDK,
+and is declared as minSdkVersion in the manifest packaged into an AAR.
+
+Flag -androidtarget sets the targetSdkVersion declared in the manifest
+packaged into an AAR. It must not be lower than the -androidapi level;
+when unset, the manifest declares only minSdkVersion.
+
+Android native libraries are linked with 16KB-aligned load segments,
+as Google Play requires for devices with 16KB page sizes, and the
+alignment of the produced libraries is verified. The -no16kpages flag
+disables both the linker flag and the check.
 
 The -bundleid flag is required for -target ios and sets the bundle ID to use
 with the app.
@@ -70,6 +92,38 @@ with the app.
 The -o flag specifies the output file name. If not specified, the
 output file name depends on the package built.
 
+The -work flag preserves the work directory (a gomobile-work-*
+directory under the system temp dir) instead of deleting it, and
+prints its location together with a map of the entries it contains:
+the generated bindings, the staging trees and the intermediate
+per-target archives. 'gomobile clean' reports and prunes preserved
+work directories.
+
+The -p flag limits how many targets are compiled concurrently; it
+defaults to the number of CPUs. Lower it on small CI machines, where
+one go build and C toolchain invocation per architecture at once can
+exhaust memory.
+
+The -gcflags-android, -gcflags-apple, -ldflags-android and
+-ldflags-apple flags append platform-specific compiler and linker
+flags to the shared -gcflags and -ldflags values, following the
+-tags-macos convention. They let one invocation strip Android
+libraries while passing, say, -checklinkname to the Apple link only,
+which matters for bind runs that mix platform families.
+
+The -gotoolchain flag sets GOTOOLCHAIN for every go command gomobile
+runs, e.g. -gotoolchain=go1.22.5, so release artifacts can be pinned
+to an exact compiler independently of the toolchain that built the
+gomobile binary. Values are interpreted by the go command; see
+https://go.dev/doc/toolchain. When unset, the GOTOOLCHAIN environment
+variable and go env configuration apply as usual.
+
+The -json flag prints a machine-readable report of the produced
+artifacts to standard output: paths, sizes, SHA-256 checksums,
+generated header locations, and the targeted platforms and
+architectures along with the flags of the invocation. CI pipelines
+and wrapper tools can consume the report instead of parsing logs.
+
 The -v flag provides verbose output, including the list of packages built.
 
 The build flags -a, -i, -n, -x, -gcflags, -ldflags, -tags, -trimpath, and -work are
@@ -79,6 +133,9 @@ shared with the build command. For documentation, see 'go help build'.
 
 func runBuild(cmd *command) (err error) {
 	_, err = runBuildImpl(cmd)
+	if err == nil && buildJSON && buildO != "" {
+		err = printArtifactsReport(buildTarget)
+	}
 	return
 }
 
@@ -97,6 +154,11 @@ func runBuildImpl(cmd *command) (*packages.Package, error) {
 	if err != nil {
 		return nil, fmt.Errorf(`invalid -target=%q: %v`, buildTarget, err)
 	}
+	// Build produces a single app; only bind knows how to lay out one
+	// artifact per platform family.
+	if len(groupTargetFamilies(targets)) > 1 {
+		return nil, fmt.Errorf(`cannot mix android, Apple and wasm platforms in -target=%q; use gomobile bind`, buildTarget)
+	}
 
 	var buildPath string
 	switch len(args) {
@@ -234,38 +296,58 @@ func printcmd(format string, args ...interface{}) {
 
 // "Build flags", used by multiple commands.
 var (
-	buildA            bool        // -a
-	buildI            bool        // -i
-	buildN            bool        // -n
-	buildV            bool        // -v
-	buildX            bool        // -x
-	buildO            string      // -o
-	buildGcflags      string      // -gcflags
-	buildLdflags      string      // -ldflags
-	buildTarget       string      // -target
-	buildTrimpath     bool        // -trimpath
-	buildWork         bool        // -work
-	buildBundleID     string      // -bundleid
-	buildIOSVersion   string      // -iosversion
-	buildMacOSVersion string      // -macosversion
-	buildTVOSVersion  string      // -tvosversion
-	buildAndroidAPI   int         // -androidapi
-	buildTags         stringsFlag // -tags
-	buildTagsMacOS    stringsFlag // -tags-macos
-	buildTagsNotMacos stringsFlag // -tags-not-macos
-	buildVCS          bool        // -buildvcs
+	buildA               bool        // -a
+	buildI               bool        // -i
+	buildN               bool        // -n
+	buildV               bool        // -v
+	buildX               bool        // -x
+	buildO               string      // -o
+	buildGcflags         string      // -gcflags
+	buildGcflagsAndroid  string      // -gcflags-android
+	buildGcflagsApple    string      // -gcflags-apple
+	buildLdflags         string      // -ldflags
+	buildLdflagsAndroid  string      // -ldflags-android
+	buildLdflagsApple    string      // -ldflags-apple
+	buildTarget          string      // -target
+	buildTrimpath        bool        // -trimpath
+	buildWork            bool        // -work
+	buildBundleID        string      // -bundleid
+	buildIOSVersion      string      // -iosversion
+	buildMacOSVersion    string      // -macosversion
+	buildTVOSVersion     string      // -tvosversion
+	buildVisionOSVersion string      // -visionosversion
+	buildWatchOSVersion  string      // -watchosversion
+	buildAndroidAPI      int         // -androidapi
+	buildAndroidTarget   int         // -androidtarget
+	buildNo16KPages      bool        // -no16kpages
+	buildTags            stringsFlag // -tags
+	buildTagsMacOS       stringsFlag // -tags-macos
+	buildTagsNotMacos    stringsFlag // -tags-not-macos
+	buildVCS             bool        // -buildvcs
+	buildHermetic        bool        // -hermetic
+	buildJSON            bool        // -json
+	buildP               int         // -p
+	buildGoToolchain     string      // -gotoolchain
 )
 
 func addBuildFlags(cmd *command) {
 	cmd.flag.StringVar(&buildO, "o", "", "")
 	cmd.flag.StringVar(&buildGcflags, "gcflags", "", "")
+	cmd.flag.StringVar(&buildGcflagsAndroid, "gcflags-android", "", "")
+	cmd.flag.StringVar(&buildGcflagsApple, "gcflags-apple", "", "")
 	cmd.flag.StringVar(&buildLdflags, "ldflags", "", "")
+	cmd.flag.StringVar(&buildLdflagsAndroid, "ldflags-android", "", "")
+	cmd.flag.StringVar(&buildLdflagsApple, "ldflags-apple", "", "")
 	cmd.flag.StringVar(&buildTarget, "target", "android", "")
 	cmd.flag.StringVar(&buildBundleID, "bundleid", "", "")
 	cmd.flag.StringVar(&buildIOSVersion, "iosversion", "13.0", "")
 	cmd.flag.StringVar(&buildMacOSVersion, "macosversion", "10.15", "")
 	cmd.flag.StringVar(&buildTVOSVersion, "tvosversion", "16.0", "")
+	cmd.flag.StringVar(&buildVisionOSVersion, "visionosversion", "1.0", "")
+	cmd.flag.StringVar(&buildWatchOSVersion, "watchosversion", "9.0", "")
 	cmd.flag.IntVar(&buildAndroidAPI, "androidapi", minAndroidAPI, "")
+	cmd.flag.IntVar(&buildAndroidTarget, "androidtarget", 0, "")
+	cmd.flag.BoolVar(&buildNo16KPages, "no16kpages", false, "")
 
 	cmd.flag.BoolVar(&buildA, "a", false, "")
 	cmd.flag.BoolVar(&buildI, "i", false, "")
@@ -274,6 +356,10 @@ func addBuildFlags(cmd *command) {
 	cmd.flag.Var(&buildTagsMacOS, "tags-macos", "")
 	cmd.flag.Var(&buildTagsNotMacos, "tags-not-macos", "")
 	cmd.flag.BoolVar(&buildVCS, "buildvcs", true, "")
+	cmd.flag.BoolVar(&buildHermetic, "hermetic", false, "")
+	cmd.flag.BoolVar(&buildJSON, "json", false, "")
+	cmd.flag.IntVar(&buildP, "p", runtime.NumCPU(), "")
+	cmd.flag.StringVar(&buildGoToolchain, "gotoolchain", "", "")
 }
 
 func addBuildFlagsNVXWork(cmd *command) {
@@ -298,6 +384,40 @@ func init() {
 	addBuildFlagsNVXWork(cmdClean)
 }
 
+// gcflagsFor and ldflagsFor return the compiler and linker flags for a
+// child build with the given GOOS: the shared -gcflags/-ldflags value
+// with the matching per-family override appended. Apple builds run
+// with GOOS=darwin or GOOS=ios.
+func gcflagsFor(goos string) string {
+	switch goos {
+	case "android":
+		return joinFlags(buildGcflags, buildGcflagsAndroid)
+	case "darwin", "ios":
+		return joinFlags(buildGcflags, buildGcflagsApple)
+	}
+	return buildGcflags
+}
+
+func ldflagsFor(goos string) string {
+	switch goos {
+	case "android":
+		return joinFlags(buildLdflags, buildLdflagsAndroid)
+	case "darwin", "ios":
+		return joinFlags(buildLdflags, buildLdflagsApple)
+	}
+	return buildLdflags
+}
+
+func joinFlags(base, extra string) string {
+	if base == "" {
+		return extra
+	}
+	if extra == "" {
+		return base
+	}
+	return base + " " + extra
+}
+
 func goBuild(src string, env []string, args ...string) error {
 	return goCmd("build", []string{src}, env, args...)
 }
@@ -334,15 +454,21 @@ func goCmdAt(at string, subcmd string, srcs []string, env []string, args ...stri
 	if buildX {
 		cmd.Args = append(cmd.Args, "-x")
 	}
-	if buildGcflags != "" {
-		cmd.Args = append(cmd.Args, "-gcflags", buildGcflags)
+	goosEnv := getenv(env, "GOOS")
+	if gcflags := gcflagsFor(goosEnv); gcflags != "" {
+		cmd.Args = append(cmd.Args, "-gcflags", gcflags)
 	}
-	if buildLdflags != "" {
-		cmd.Args = append(cmd.Args, "-ldflags", buildLdflags)
+	if ldflags := ldflagsFor(goosEnv); ldflags != "" {
+		cmd.Args = append(cmd.Args, "-ldflags", ldflags)
 	}
 	if buildTrimpath {
 		cmd.Args = append(cmd.Args, "-trimpath")
 	}
+	if buildP != runtime.NumCPU() {
+		// Forward a non-default -p so the limit also covers the
+		// compiles inside each go build.
+		cmd.Args = append(cmd.Args, "-p", strconv.Itoa(buildP))
+	}
 	if buildWork {
 		cmd.Args = append(cmd.Args, "-work")
 	}
@@ -390,6 +516,7 @@ func goModTidyAt(at string, env []string) error {
 //	android/arm64,android/386,android/amd64
 //	ios,iossimulator,maccatalyst
 //	macos/amd64
+//	android,ios (bind only; build commands take a single platform family)
 func parseBuildTarget(buildTarget string) ([]targetInfo, error) {
 	if buildTarget == "" {
 		return nil, fmt.Errorf(`invalid target ""`)
@@ -413,22 +540,14 @@ func parseBuildTarget(buildTarget string) ([]targetInfo, error) {
 		}
 	}
 
-	var isAndroid, isApple bool
 	for _, target := range strings.Split(buildTarget, ",") {
 		tuple := strings.SplitN(target, "/", 2)
 		platform := tuple[0]
 		hasArch := len(tuple) == 2
 
-		if isAndroidPlatform(platform) {
-			isAndroid = true
-		} else if isApplePlatform(platform) {
-			isApple = true
-		} else {
+		if platformFamily(platform) == "" {
 			return nil, fmt.Errorf("unsupported platform: %q", platform)
 		}
-		if isAndroid && isApple {
-			return nil, fmt.Errorf(`cannot mix android and Apple platforms`)
-		}
 
 		if hasArch {
 			arch := tuple[1]
@@ -450,6 +569,14 @@ func parseBuildTarget(buildTarget string) ([]targetInfo, error) {
 		addPlatform("tvossimulator")
 	}
 
+	if buildTarget == "visionos" {
+		addPlatform("visionossimulator")
+	}
+
+	if buildTarget == "watchos" {
+		addPlatform("watchossimulator")
+	}
+
 	return targets, nil
 }
 
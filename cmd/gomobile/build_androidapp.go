@@ -84,6 +84,9 @@ func goAndroidBuild(pkg *packages.Package, targets []targetInfo) (map[string]boo
 		if err != nil {
 			return nil, err
 		}
+		if err := verifyPageAlignment(libAbsPath); err != nil {
+			return nil, err
+		}
 		nmpkgs[t.arch], err = extractPkgs(toolchain.Path(ndkRoot, "nm"), libAbsPath)
 		if err != nil {
 			return nil, err
@@ -0,0 +1,219 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// The persistent build cache keeps generated bindings and per-target
+// archives between bind runs, keyed by the toolchain, the content of
+// the bound packages' sources and the flags of each stage. It lives
+// under $GOMOBILECACHE, or a gomobile directory under the user cache
+// directory; GOMOBILECACHE=off disables it.
+var (
+	cacheRoot string
+	cacheSeed string // fingerprint shared by every stage; empty disables the cache
+)
+
+// cacheInit computes the cache fingerprint for the bound packages. It
+// hashes the go version, the module's go.mod and go.sum, and the
+// content of every source file of the packages and their dependencies
+// outside GOROOT and the module cache. Any failure leaves the cache
+// disabled; a bind run without the cache is always correct.
+func cacheInit(target targetInfo, pkgs []*packages.Package) {
+	if buildN {
+		return
+	}
+	root := cacheLocation()
+	if root == "" {
+		return
+	}
+
+	h := sha256.New()
+	version, err := exec.Command("go", "version").Output()
+	if err != nil {
+		return
+	}
+	h.Write(version)
+	io.WriteString(h, buildGoToolchain)
+
+	if out, err := exec.Command("go", "env", "GOMOD").Output(); err == nil {
+		if gomod := strings.TrimSpace(string(out)); gomod != "" && gomod != os.DevNull {
+			if err := hashFileInto(h, gomod); err != nil {
+				return
+			}
+			// go.sum pins the content of every module dependency.
+			hashFileInto(h, strings.TrimSuffix(gomod, ".mod")+".sum")
+		}
+	}
+	if work, err := goWorkFile(); err == nil && work != "" {
+		if err := hashFileInto(h, work); err != nil {
+			return
+		}
+		hashFileInto(h, work+".sum")
+	}
+
+	patterns := make([]string, len(pkgs))
+	for i, p := range pkgs {
+		patterns[i] = p.PkgPath
+	}
+	config := packagesConfig(target)
+	config.Mode = packages.NeedName | packages.NeedFiles | packages.NeedImports | packages.NeedDeps
+	loaded, err := packages.Load(config, patterns...)
+	if err != nil {
+		return
+	}
+	goroot := goEnv("GOROOT")
+	modcache := goEnv("GOMODCACHE")
+	var files []string
+	packages.Visit(loaded, nil, func(pkg *packages.Package) {
+		for _, list := range [][]string{pkg.GoFiles, pkg.OtherFiles} {
+			for _, f := range list {
+				if strings.HasPrefix(f, goroot) || (modcache != "" && strings.HasPrefix(f, modcache)) {
+					continue
+				}
+				files = append(files, f)
+			}
+		}
+	})
+	sort.Strings(files)
+	for _, f := range files {
+		io.WriteString(h, f)
+		if err := hashFileInto(h, f); err != nil {
+			return
+		}
+	}
+
+	cacheRoot = root
+	cacheSeed = hex.EncodeToString(h.Sum(nil))
+}
+
+// cacheLocation returns the root of the persistent build cache, or
+// the empty string when the cache is disabled.
+func cacheLocation() string {
+	if root := os.Getenv("GOMOBILECACHE"); root == "off" {
+		return ""
+	} else if root != "" {
+		return root
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "gomobile")
+}
+
+func hashFileInto(h io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(h, f)
+	return err
+}
+
+// cachePath returns the cache location for one stage output, derived
+// from the shared fingerprint and the stage-specific extras, usually
+// the flags and environment of the command that produces the output.
+// Extras carrying run-local paths (-outdir, GOPATH) are skipped so the
+// key is stable across work directories. The empty string means the
+// cache is disabled.
+func cachePath(stage string, extra ...string) string {
+	if cacheSeed == "" {
+		return ""
+	}
+	h := sha256.New()
+	io.WriteString(h, cacheSeed)
+	for _, e := range extra {
+		if strings.HasPrefix(e, "GOPATH=") || strings.HasPrefix(e, "-outdir=") {
+			continue
+		}
+		io.WriteString(h, e)
+		h.Write([]byte{0})
+	}
+	return filepath.Join(cacheRoot, stage, hex.EncodeToString(h.Sum(nil)))
+}
+
+// cacheGetFile copies the cached file at path to dst and reports
+// whether it was present.
+func cacheGetFile(path, dst string) bool {
+	if path == "" {
+		return false
+	}
+	if _, err := os.Stat(path); err != nil {
+		return false
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return false
+	}
+	return copyFile(dst, path) == nil
+}
+
+// cachePutFile stores a copy of src at path. Failures are ignored;
+// the cache only ever saves work.
+func cachePutFile(path, src string) {
+	if path == "" {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+	tmp := path + ".tmp"
+	if err := copyFile(tmp, src); err != nil {
+		os.Remove(tmp)
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+	}
+}
+
+// cacheGetDir replaces dst with the cached directory at path and
+// reports whether it was present.
+func cacheGetDir(path, dst string) bool {
+	if path == "" {
+		return false
+	}
+	if st, err := os.Stat(path); err != nil || !st.IsDir() {
+		return false
+	}
+	if err := os.RemoveAll(dst); err != nil {
+		return false
+	}
+	return doCopyAll(dst, path) == nil
+}
+
+// cachePutDir stores a copy of the directory src at path.
+func cachePutDir(path, src string) {
+	if path == "" {
+		return
+	}
+	if _, err := os.Stat(path); err == nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+	tmp := path + ".tmp"
+	os.RemoveAll(tmp)
+	if err := doCopyAll(tmp, src); err != nil {
+		os.RemoveAll(tmp)
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.RemoveAll(tmp)
+	}
+}
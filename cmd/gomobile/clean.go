@@ -6,19 +6,40 @@ package main
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
 )
 
 var cmdClean = &command{
 	run:   runClean,
 	Name:  "clean",
-	Usage: "",
-	Short: "remove object files and cached gomobile files",
+	Usage: "[-ndk] [-cache] [-all]",
+	Short: "report and prune cached gomobile files",
 	Long: `
-Clean removes object files and cached NDK files downloaded by gomobile init
+Clean reports what gomobile stores on disk — the toolchain files under
+$GOPATH/pkg/gomobile, NDKs downloaded by gomobile ndk install, the
+persistent build cache and leftover work directories — along with
+their sizes.
+
+Nothing is removed unless a pruning flag is given: -ndk removes the
+downloaded NDKs, -cache removes the persistent build cache, and -all
+removes every reported location. The -n flag prints the removals
+without executing them.
 `,
 }
 
+var (
+	cleanNDK   bool // -ndk
+	cleanCache bool // -cache
+	cleanAll   bool // -all
+)
+
+func init() {
+	cmdClean.flag.BoolVar(&cleanNDK, "ndk", false, "Remove downloaded NDKs.")
+	cmdClean.flag.BoolVar(&cleanCache, "cache", false, "Remove the persistent build cache.")
+	cmdClean.flag.BoolVar(&cleanAll, "all", false, "Remove every reported location.")
+}
+
 func runClean(cmd *command) (err error) {
 	gopaths := filepath.SplitList(goEnv("GOPATH"))
 	if len(gopaths) == 0 {
@@ -28,5 +49,74 @@ func runClean(cmd *command) (err error) {
 	if buildX {
 		fmt.Fprintln(xout, "GOMOBILE="+gomobilepath)
 	}
-	return removeAll(gomobilepath)
+
+	ndkDir := filepath.Join(gomobilepath, "ndk")
+	cacheDir := cacheLocation()
+	workDirs, _ := filepath.Glob(filepath.Join(os.TempDir(), "gomobile-work-*"))
+
+	ndkSize := dirSize(ndkDir)
+	fmt.Printf("%-13s %10s  %s\n", "toolchain:", formatSize(dirSize(gomobilepath)-ndkSize), gomobilepath)
+	fmt.Printf("%-13s %10s  %s\n", "ndk:", formatSize(ndkSize), ndkDir)
+	if cacheDir != "" {
+		fmt.Printf("%-13s %10s  %s\n", "build cache:", formatSize(dirSize(cacheDir)), cacheDir)
+	}
+	var workSize int64
+	for _, dir := range workDirs {
+		workSize += dirSize(dir)
+	}
+	fmt.Printf("%-13s %10s  %d directories\n", "work dirs:", formatSize(workSize), len(workDirs))
+
+	if !cleanNDK && !cleanCache && !cleanAll {
+		fmt.Println("Nothing removed; use -ndk, -cache or -all to prune.")
+		return nil
+	}
+	if cleanNDK && !cleanAll {
+		if err := removeAll(ndkDir); err != nil {
+			return err
+		}
+	}
+	if (cleanCache || cleanAll) && cacheDir != "" {
+		if err := removeAll(cacheDir); err != nil {
+			return err
+		}
+	}
+	if cleanAll {
+		for _, dir := range workDirs {
+			if err := removeAll(dir); err != nil {
+				return err
+			}
+		}
+		if err := removeAll(gomobilepath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dirSize returns the total size of the regular files under dir; a
+// missing directory counts as zero.
+func dirSize(dir string) int64 {
+	var total int64
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.Mode().IsRegular() {
+			total += info.Size()
+		}
+		return nil
+	})
+	return total
+}
+
+func formatSize(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1f GB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
 }
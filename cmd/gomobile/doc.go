@@ -23,9 +23,12 @@ Commands:
 
 	bind        build a library for Android and iOS
 	build       compile android APK and iOS app
-	clean       remove object files and cached gomobile files
+	clean       report and prune cached gomobile files
+	doctor      diagnose the build environment
+	gradle-init write a Gradle plugin that runs gomobile bind
 	init        build OpenAL for Android
 	install     compile android APK and install on device
+	ndk         manage the Android NDK used by bind and build
 	version     print version
 
 Use 'gomobile help [command]' for more information about that command.
@@ -34,13 +37,19 @@ Use 'gomobile help [command]' for more information about that command.
 
 Usage:
 
-	gomobile bind [-target android|ios|iossimulator|macos|maccatalyst] [-bootclasspath <path>] [-classpath <path>] [-o output] [build flags] [package]
+	gomobile bind [-target android|wasm|ios|iossimulator|macos|maccatalyst|tvos|tvossimulator|visionos|visionossimulator|watchos|watchossimulator] [-bootclasspath <path>] [-classpath <path>] [-o output] [build flags] [package]
 
 Bind generates language bindings for the package named by the import
 path, and compiles a library for the named target system.
 
-The -target flag takes either android (the default), or one or more
-comma-delimited Apple platforms (ios, iossimulator, macos, maccatalyst).
+The -target flag takes android (the default), wasm, or one or more
+comma-delimited Apple platforms (ios, iossimulator, macos, maccatalyst, tvos, tvossimulator, visionos, visionossimulator, watchos, watchossimulator).
+Platform families can be mixed in one invocation, e.g.
+-target=android,ios: the packages are loaded and typechecked once and
+every family is built from the shared analysis. In a mixed run -o must
+name a directory (default .); each family writes its default artifact
+into an android, apple or wasm subdirectory, and flags tied to one
+family apply to that family's output.
 
 For -target android, the bind command produces an AAR (Android ARchive)
 file that archives the precompiled Java API stub classes, the compiled
@@ -52,9 +61,10 @@ example, in Android Studio (1.2+), an AAR file can be imported using
 the module import wizard (File > New > New Module > Import .JAR or
 .AAR package), and setting it as a new dependency
 (File > Project Structure > Dependencies).  This requires 'javac'
-(version 1.7+) and Android SDK (API level 16 or newer) to build the
-library for Android. The environment variable ANDROID_HOME must be set
-to the path to Android SDK. Use the -javapkg flag to specify the Java
+(version 1.8+) and Android SDK (API level 16 or newer) to build the
+library for Android. The ANDROID_HOME and ANDROID_NDK_HOME environment
+variables can be used to specify the Android SDK and NDK if they are
+not in the default locations. Use the -javapkg flag to specify the Java
 package prefix for the generated classes.
 
 By default, -target=android builds shared libraries for all supported
@@ -64,29 +74,219 @@ can be selected by specifying target type with the architecture name. E.g.,
 
 For Apple -target platforms, gomobile must be run on an OS X machine with
 Xcode installed. The generated Objective-C types can be prefixed with the
--prefix flag.
+-prefix flag. On iOS and macOS, an additional arm64e (pointer
+authentication) slice can be requested explicitly, e.g.
+-target=ios/arm64,ios/arm64e, and is lipo'd into the framework next to
+the arm64 slice.
 
 For -target android, the -bootclasspath and -classpath flags are used to
 control the bootstrap classpath and the classpath for Go wrappers to Java
 classes.
 
+For -target wasm, the bind command compiles a WebAssembly module with
+GOOS=js and places a generated JavaScript wrapper and TypeScript
+declarations next to it. The wrapper exposes the bound API with the
+same proxy/refnum model as the other bindings; wasm_exec.js from the
+Go distribution must be served alongside. Binding a single package is
+supported for wasm.
+
+The -buildmode flag selects how the Go code is linked into the
+XCFramework slices. The default, c-archive, produces static framework
+slices. With c-shared, each slice is a dynamic framework wrapping a
+c-shared dylib with its install name set to
+@rpath/<Title>.framework/Versions/A/<Title>, for apps that share one Go
+framework across app extensions or want to keep the main binary small.
+Valid only for Apple targets.
+
+For -target=macos, naming the output with a ".dylib" suffix via -o
+produces a bare dylib instead of an XCFramework, with its install name
+set to @rpath/<name>.dylib and the headers installed in an include/
+directory next to it. CLI tools and .NET or other FFI consumers can
+link it directly without framework machinery. Requires
+-buildmode=c-shared.
+
+The -swiftpkg flag writes a Swift Package to the given directory after
+the XCFramework is created: a Package.swift with a binaryTarget, the
+XCFramework zipped with ditto as Swift Package Manager expects, and a
+.checksum file with the SHA-256 that SPM uses to verify url-based
+binary targets. The manifest references the zip by path; publish the
+zip and switch the target to url and checksum to distribute it. Valid
+only for Apple targets.
+
+Each framework slice of the XCFramework carries an Info.plist with the
+bundle identifier, name, version and minimum OS version, so the
+frameworks pass App Store and MDM validation without post-editing. The
+identifier defaults to org.golang.gomobile.<Title> and is overridden
+with the -bundleid flag; -bundleversion sets the
+CFBundleShortVersionString and CFBundleVersion; the minimum OS version
+is taken from the -iosversion, -macosversion and related flags; and
+the repeatable -plistkey KEY=VALUE flag adds further entries. Valid
+only for Apple targets.
+
+Each framework slice of the XCFramework carries a PrivacyInfo.xcprivacy
+privacy manifest in its Resources, as required by the App Store for
+SDKs. By default a minimal manifest declaring no tracking and no
+collected data is embedded; the -xcprivacy flag replaces it with the
+given manifest file for libraries that do collect data or use
+reason-listed APIs.
+
+The -sampleapp flag writes a minimal SwiftUI Xcode project to the
+given directory after the XCFramework is created. The project builds
+an iOS app that imports the framework, embedding (and signing) it for
+-buildmode=c-shared and merely linking it for static builds, so the
+binding can be validated and its size and startup cost measured
+without manual Xcode setup. Valid only for Apple targets.
+
+The -extension-safe flag compiles and links every slice with
+-fapplication-extension, so use of API unavailable to app extensions
+is rejected at compile time and the produced binaries are marked
+app-extension-safe. Use it for frameworks loaded from Network, Share
+and other extension points. Valid only for Apple targets.
+
+The -dsym flag runs dsymutil on each dynamic framework slice and
+places <Title>.framework.<sdk>.dSYM bundles next to the XCFramework,
+so crash reports from the Go code can be symbolicated. The DWARF is
+read from the c-shared dylibs, so -dsym requires -buildmode=c-shared
+and must not be combined with -ldflags=-w, which strips it.
+
+The -manifest flag writes an artifacts.json manifest next to the output,
+listing every produced file with its SHA-256 checksum, size and the tool
+versions used, for release pipelines that publish or sign the binaries.
+
+The -sign flag runs codesign with the given identity on each framework
+of the produced XCFramework, so CI pipelines need no post-processing
+step. Valid only for Apple targets.
+
+The -gradle flag writes a Gradle script plugin to the given path that
+wraps this bind invocation as a gomobileBind task, declaring the Go
+sources as inputs and the AAR as its output and wiring the AAR into the
+dependencies of the module that applies it. Valid only with
+-target=android.
+
+The -prefab flag packages the shared library and the generated C
+headers into the AAR's prefab/ directory, one module per library with
+per-ABI metadata. C++ code in the same app, or in other AARs, can then
+link against the bound Go library via CMake or ndk-build (with
+buildFeatures.prefab enabled) in addition to the Java API. Valid only
+with -target=android.
+
+The -annotations flag annotates parameters and results of the
+generated Java API with androidx.annotation @NonNull and @Nullable,
+derived from Go nil semantics, so Kotlin consumers see null-checked
+types instead of platform types. Generated classes are also marked
+@Keep, telling lint and R8 they are JNI entry points. The annotations
+have class retention only; the AAR gains no runtime dependency, and a
+compile-scope androidx.annotation dependency is declared in the maven
+metadata written by -maven. Valid only with -target=android.
+
+The -kotlin flag writes a companion Kotlin file per bound package to
+the given directory: suspend extension wrappers that run
+error-returning functions and methods on Dispatchers.IO and surface
+errors as exceptions, so coroutine-based apps need not wrap every
+blocking call manually. The AAR cannot carry Kotlin sources, so the
+directory is meant to be added to the app's source set; the files are
+also included in the sources jar. Valid only with -target=android.
+
+The -splitabi flag writes, in addition to the combined AAR, one AAR
+per target ABI holding only that ABI's native library, together with
+an abis.json manifest mapping each ABI to its artifact and library
+size. Builds that select artifacts per ABI then avoid shipping every
+architecture to every device. The per-ABI library sizes are reported
+in the build output. Valid only with -target=android.
+
+The -javadoc flag runs javadoc over the generated Java sources, whose
+comments carry the Go doc comments, and writes a -javadoc.jar next to
+the AAR. IDEs then display documentation when the AAR is consumed from
+a Maven repository; with -maven, the jar is published alongside the
+AAR. Valid only with -target=android.
+
+The -maven flag takes publication coordinates of the form
+groupId:artifactId:version and writes a maven-publish repository layout
+to a maven/ directory next to the AAR: the AAR and the sources jar
+under the coordinate path, a pom declaring aar packaging, and Gradle
+module metadata with artifact checksums. The directory can be pushed
+as-is to Maven Central or an internal repository. Valid only with
+-target=android.
+
+The -service flag writes an out-of-process service scaffold to the
+given directory: an AIDL interface, a Service subclass that hosts the
+Go runtime and is meant to be declared with android:process=":go", and
+a client stub that binds to it. Memory-heavy Go libraries such as
+proxies can then be killed and restarted independently of the UI
+process. Requests cross Binder as a method name plus encoded bytes;
+the service's dispatch method is the single point to wire to the bound
+API. Valid only with -target=android.
+
+The -logtag flag sets the default logcat tag for Go program output
+captured from stdout, stderr and the Go log package, normally "GoLog".
+Apps can change the tag at run time with Seq.setLogTag, filter with
+Seq.setLogMinPriority, or route the output into their own logging
+pipeline with Seq.setLogReceiver. Valid only with -target=android.
+
+The -exceptions flag selects the kind of Java exceptions thrown for Go
+errors. With checked, the default, error-returning methods declare
+throws Exception. With unchecked, the throws clauses are dropped and
+errors without a generated exception class are thrown wrapped in a
+Seq.GoRuntimeException. Either way, exported Go struct types
+implementing error become distinct exception classes, so Java and
+Kotlin code can catch specific failures. Valid only with
+-target=android.
+
+The -watch flag keeps gomobile running after the first build and
+re-runs the bind whenever a source file of the bound packages or of
+their dependencies outside GOROOT and the module cache changes. The
+work directory is kept between rebuilds, so the gobind, compile and
+packaging stages reuse previous outputs where their inputs are
+unchanged. A failing rebuild is reported and watching continues.
+
+Modules that vendor their dependencies are supported. The generated
+wrapper package is compiled inside the user's module, so with
+-mod=vendor in effect its imports resolve through the vendor tree;
+the tree must contain the gomobile bind runtime, and a vendor
+directory that lacks it is diagnosed before any architecture builds.
+
+Bind keeps generated bindings and per-target archives in a persistent
+cache keyed by the toolchain, the sources of the bound packages and
+the build flags, so repeated builds reuse the outputs of unchanged
+stages. The cache lives under $GOMOBILECACHE, or a gomobile directory
+under the user cache directory; set GOMOBILECACHE=off to disable it.
+
+The -xcodephase flag writes a run-script build phase to the given path,
+together with input and output xcfilelists, that re-runs this bind
+invocation when the Go sources change. Valid only for Apple targets.
+
+The -bindingsout flag copies the generated host-language sources, and
+only them, to the given directory: the Java sources for Android
+targets and the Objective-C sources for Apple targets. The directory
+is replaced on each run, so it can be checked into version control for
+clean diffs.
+
+The -hermetic flag restricts build commands to a fixed whitelist of
+host environment variables, reporting any dropped variable that could
+affect the build, so stray CC or CGO_CFLAGS settings on the developer
+machine cannot silently change artifacts. Together with -manifest, the
+effective environment is recorded in artifacts.json for
+reproducibility audits.
+
 The -v flag provides verbose output, including the list of packages built.
 
-The build flags -a, -n, -x, -gcflags, -ldflags, -tags, -trimpath, and -work
-are shared with the build command. For documentation, see 'go help build'.
+The build flags -a, -n, -p, -x, -gcflags, -gotoolchain, -json,
+-ldflags, -tags, -trimpath, and -work are shared with the build
+command. For documentation, see 'go help build' and
+'gomobile help build'.
 
 # Compile android APK and iOS app
 
 Usage:
 
-	gomobile build [-target android|ios|iossimulator|macos|maccatalyst] [-o output] [-bundleid bundleID] [build flags] [package]
+	gomobile build [-target android|ios|iossimulator|macos|maccatalyst|tvos|tvossimulator|visionos|visionossimulator|watchos|watchossimulator] [-o output] [-bundleid bundleID] [build flags] [package]
 
 Build compiles and encodes the app named by the import path.
 
 The named package must define a main function.
 
 The -target flag takes either android (the default), or one or more
-comma-delimited Apple platforms (ios, iossimulator, macos, maccatalyst).
+comma-delimited Apple platforms (ios, iossimulator, macos, maccatalyst, tvos, tvossimulator, visionos, visionossimulator, watchos, watchossimulator).
 
 For -target android, if an AndroidManifest.xml is defined in the
 package directory, it is added to the APK output. Otherwise, a default
@@ -106,6 +306,17 @@ specify -target ios,macos,maccatalyst. A subset of instruction sets can be
 selectged by specifying the platform with an architecture name. E.g.
 -target=ios/arm64,maccatalyst/arm64.
 
+On Android, an additional riscv64 library can be requested explicitly,
+e.g. -target=android/arm64,android/riscv64, for upcoming RISC-V
+devices. It needs NDK r27 or newer and API level 35, and is never part
+of the platform defaults.
+
+On iOS and macOS, an additional arm64e (pointer authentication) slice
+can be requested explicitly, e.g. -target=ios/arm64,ios/arm64e. Go has
+no arm64e port, so the Go code in such a slice is compiled as arm64
+while the C glue code is built for arm64e; the slice is never part of
+the platform defaults.
+
 If the package directory contains an assets subdirectory, its contents
 are copied into the output.
 
@@ -113,7 +324,17 @@ Flag -iosversion sets the minimal version of the iOS SDK to compile against.
 The default version is 13.0.
 
 Flag -androidapi sets the Android API version to compile against.
-The default and minimum is 16.
+The default and minimum is 16. The level must be provided by the NDK,
+and is declared as minSdkVersion in the manifest packaged into an AAR.
+
+Flag -androidtarget sets the targetSdkVersion declared in the manifest
+packaged into an AAR. It must not be lower than the -androidapi level;
+when unset, the manifest declares only minSdkVersion.
+
+Android native libraries are linked with 16KB-aligned load segments,
+as Google Play requires for devices with 16KB page sizes, and the
+alignment of the produced libraries is verified. The -no16kpages flag
+disables both the linker flag and the check.
 
 The -bundleid flag is required for -target ios and sets the bundle ID to use
 with the app.
@@ -121,18 +342,99 @@ with the app.
 The -o flag specifies the output file name. If not specified, the
 output file name depends on the package built.
 
+The -work flag preserves the work directory (a gomobile-work-*
+directory under the system temp dir) instead of deleting it, and
+prints its location together with a map of the entries it contains:
+the generated bindings, the staging trees and the intermediate
+per-target archives. 'gomobile clean' reports and prunes preserved
+work directories.
+
+The -p flag limits how many targets are compiled concurrently; it
+defaults to the number of CPUs. Lower it on small CI machines, where
+one go build and C toolchain invocation per architecture at once can
+exhaust memory.
+
+The -gcflags-android, -gcflags-apple, -ldflags-android and
+-ldflags-apple flags append platform-specific compiler and linker
+flags to the shared -gcflags and -ldflags values, following the
+-tags-macos convention. They let one invocation strip Android
+libraries while passing, say, -checklinkname to the Apple link only,
+which matters for bind runs that mix platform families.
+
+The -gotoolchain flag sets GOTOOLCHAIN for every go command gomobile
+runs, e.g. -gotoolchain=go1.22.5, so release artifacts can be pinned
+to an exact compiler independently of the toolchain that built the
+gomobile binary. Values are interpreted by the go command; see
+https://go.dev/doc/toolchain. When unset, the GOTOOLCHAIN environment
+variable and go env configuration apply as usual.
+
+The -json flag prints a machine-readable report of the produced
+artifacts to standard output: paths, sizes, SHA-256 checksums,
+generated header locations, and the targeted platforms and
+architectures along with the flags of the invocation. CI pipelines
+and wrapper tools can consume the report instead of parsing logs.
+
 The -v flag provides verbose output, including the list of packages built.
 
 The build flags -a, -i, -n, -x, -gcflags, -ldflags, -tags, -trimpath, and -work are
 shared with the build command. For documentation, see 'go help build'.
 
-# Remove object files and cached gomobile files
+# Report and prune cached gomobile files
+
+Usage:
+
+	gomobile clean [-ndk] [-cache] [-all]
+
+Clean reports what gomobile stores on disk — the toolchain files under
+$GOPATH/pkg/gomobile, NDKs downloaded by gomobile ndk install, the
+persistent build cache and leftover work directories — along with
+their sizes.
+
+Nothing is removed unless a pruning flag is given: -ndk removes the
+downloaded NDKs, -cache removes the persistent build cache, and -all
+removes every reported location. The -n flag prints the removals
+without executing them.
+
+# Diagnose the build environment
 
 Usage:
 
-	gomobile clean
+	gomobile doctor
+
+Doctor checks the tools and SDKs gomobile depends on and prints a
+report with a suggested fix for every failing check: the Go toolchain
+and cgo, a host C compiler, the Android SDK, its accepted licenses and
+platform, the NDK, the Java compiler, and Xcode on Darwin hosts.
 
-Clean removes object files and cached NDK files downloaded by gomobile init.
+Most bind failures are environment problems; run doctor before
+debugging a cryptic build error.
+
+# Write a Gradle plugin that runs gomobile bind
+
+Usage:
+
+	gomobile gradle-init [-dir project]
+
+Gradle-init writes a Gradle plugin into an Android project, under
+buildSrc, that runs gomobile bind as a regular task of the Android
+build. Unlike the script written by the bind -gradle flag, the plugin
+is configured from the applying module's build script, declares the Go
+sources as task inputs so the bind only re-runs when they change, and
+wires the produced AAR into the module's dependencies.
+
+Apply and configure it in an application or library module:
+
+	plugins {
+	    id 'org.golang.gomobile'
+	}
+
+	gomobile {
+	    packages = ['golang.org/x/example/basic']
+	    srcDirs = ['../go/basic']
+	}
+
+The -dir flag sets the project directory to write to. It defaults to
+the current directory.
 
 # Build OpenAL for Android
 
@@ -159,6 +461,30 @@ The build flags -a, -i, -n, -x, -gcflags, -ldflags, -tags, -trimpath, and -work
 shared with the build command.
 For documentation, see 'go help build'.
 
+# Manage the Android NDK used by bind and build
+
+Usage:
+
+	gomobile ndk [-checksum sha1] install <version>
+
+Ndk manages the copy of the Android NDK that gomobile bind and
+gomobile build compile against.
+
+The install command downloads the given NDK release, such as r27c,
+from the Android download server, extracts it under the gomobile
+cache directory and records the version. Later bind and build
+invocations use the recorded NDK even if ANDROID_NDK_HOME or the
+Android SDK point at a different one, so builds use the same
+toolchain on every machine. Running install again with another
+version switches the pin.
+
+The optional -checksum flag verifies the SHA-1 of the downloaded
+archive against the value published on the NDK downloads page. The
+archive is cached and reused on reinstallation.
+
+The cached NDK lives under $GOPATH/pkg/gomobile and is removed by
+gomobile clean and gomobile init.
+
 # Print version
 
 Usage:
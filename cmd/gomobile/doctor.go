@@ -0,0 +1,166 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/sagernet/gomobile/internal/sdkpath"
+)
+
+var cmdDoctor = &command{
+	run:   runDoctor,
+	Name:  "doctor",
+	Usage: "",
+	Short: "diagnose the build environment",
+	Long: `
+Doctor checks the tools and SDKs gomobile depends on and prints a
+report with a suggested fix for every failing check: the Go toolchain
+and cgo, a host C compiler, the Android SDK, its accepted licenses and
+platform, the NDK, the Java compiler, and Xcode on Darwin hosts.
+
+Most bind failures are environment problems; run doctor before
+debugging a cryptic build error.
+`,
+}
+
+// A doctorCheck probes one prerequisite. run returns a short detail
+// string shown on success and an error describing the problem; fix is
+// the suggested remedy printed on failure.
+type doctorCheck struct {
+	name string
+	run  func() (string, error)
+	fix  string
+}
+
+func runDoctor(cmd *command) error {
+	checks := []doctorCheck{
+		{
+			name: "go",
+			run: func() (string, error) {
+				out, err := exec.Command("go", "version").CombinedOutput()
+				if err != nil {
+					return "", fmt.Errorf("cannot run the go tool: %v", err)
+				}
+				return strings.TrimSpace(string(out)), nil
+			},
+			fix: "install Go from https://go.dev/dl/ and add it to PATH",
+		},
+		{
+			name: "cgo",
+			run: func() (string, error) {
+				out, err := exec.Command("go", "env", "CGO_ENABLED").Output()
+				if err != nil {
+					return "", fmt.Errorf("go env failed: %v", err)
+				}
+				if v := strings.TrimSpace(string(out)); v != "1" {
+					return "", fmt.Errorf("CGO_ENABLED=%s", v)
+				}
+				return "enabled", nil
+			},
+			fix: "set CGO_ENABLED=1; gomobile needs cgo for every target",
+		},
+		{
+			name: "C compiler",
+			run: func() (string, error) {
+				ccs := []string{"clang", "gcc"}
+				if out, err := exec.Command("go", "env", "CC").Output(); err == nil {
+					if cc := strings.TrimSpace(string(out)); cc != "" {
+						ccs = []string{cc}
+					}
+				}
+				for _, cc := range ccs {
+					if path, err := exec.LookPath(cc); err == nil {
+						return path, nil
+					}
+				}
+				return "", fmt.Errorf("none of %s found in PATH", strings.Join(ccs, ", "))
+			},
+			fix: "install clang or gcc so cgo can compile the support code",
+		},
+		{
+			name: "Android SDK",
+			run: func() (string, error) {
+				return sdkpath.AndroidHome()
+			},
+			fix: "install the Android SDK and set ANDROID_HOME to its location",
+		},
+		{
+			name: "SDK licenses",
+			run: func() (string, error) {
+				home, err := sdkpath.AndroidHome()
+				if err != nil {
+					return "", err
+				}
+				if _, err := os.Stat(filepath.Join(home, "licenses")); err != nil {
+					return "", fmt.Errorf("no licenses directory in %s", home)
+				}
+				return "accepted", nil
+			},
+			fix: "run sdkmanager --licenses and accept the SDK licenses",
+		},
+		{
+			name: "Android platform",
+			run: func() (string, error) {
+				return sdkpath.AndroidAPIPath(minAndroidAPI)
+			},
+			fix: fmt.Sprintf("install an SDK platform at API level %d or higher with sdkmanager", minAndroidAPI),
+		},
+		{
+			name: "Android NDK",
+			run: func() (string, error) {
+				return ndkRoot()
+			},
+			fix: "install the NDK with gomobile ndk install <version>, with sdkmanager, or set ANDROID_NDK_HOME",
+		},
+		{
+			name: "javac",
+			run: func() (string, error) {
+				return exec.LookPath("javac")
+			},
+			fix: "install a JDK and add javac to PATH; it is required to build AARs",
+		},
+	}
+	if runtime.GOOS == "darwin" {
+		checks = append(checks, doctorCheck{
+			name: "Xcode",
+			run: func() (string, error) {
+				out, err := exec.Command("xcrun", "xcodebuild", "-version").Output()
+				if err != nil {
+					return "", fmt.Errorf("xcrun xcodebuild failed: %v", err)
+				}
+				version, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+				return version, nil
+			},
+			fix: "install Xcode and select it with xcode-select --switch",
+		})
+	}
+
+	failed := 0
+	for _, c := range checks {
+		detail, err := c.run()
+		if err != nil {
+			failed++
+			fmt.Printf("%-18s FAIL: %v\n", c.name+":", err)
+			fmt.Printf("%-18s fix: %s\n", "", c.fix)
+			continue
+		}
+		status := "ok"
+		if detail != "" {
+			status += " (" + detail + ")"
+		}
+		fmt.Printf("%-18s %s\n", c.name+":", status)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(checks))
+	}
+	fmt.Println("No problems found.")
+	return nil
+}
@@ -12,6 +12,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 
 	"github.com/sagernet/gomobile/internal/sdkpath"
 )
@@ -32,7 +33,43 @@ func isApplePlatform(platform string) bool {
 	return contains(applePlatforms, platform)
 }
 
-var applePlatforms = []string{"ios", "iossimulator", "macos", "maccatalyst", "tvos", "tvossimulator"}
+func isWasmPlatform(platform string) bool {
+	return platform == "wasm"
+}
+
+// platformFamily returns the toolchain family a platform belongs to:
+// android, apple or wasm.
+func platformFamily(platform string) string {
+	switch {
+	case isAndroidPlatform(platform):
+		return "android"
+	case isApplePlatform(platform):
+		return "apple"
+	case isWasmPlatform(platform):
+		return "wasm"
+	}
+	return ""
+}
+
+// groupTargetFamilies splits targets into per-family groups, in the
+// order the families first appear in the list.
+func groupTargetFamilies(targets []targetInfo) [][]targetInfo {
+	index := map[string]int{}
+	var groups [][]targetInfo
+	for _, t := range targets {
+		family := platformFamily(t.platform)
+		i, ok := index[family]
+		if !ok {
+			i = len(groups)
+			index[family] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], t)
+	}
+	return groups
+}
+
+var applePlatforms = []string{"ios", "iossimulator", "macos", "maccatalyst", "tvos", "tvossimulator", "visionos", "visionossimulator", "watchos", "watchossimulator"}
 
 func platformArchs(platform string) []string {
 	switch platform {
@@ -46,14 +83,53 @@ func platformArchs(platform string) []string {
 		return []string{"arm64"}
 	case "tvossimulator":
 		return []string{"arm64", "amd64"}
+	case "visionos":
+		return []string{"arm64"}
+	case "visionossimulator":
+		// The visionOS simulator runs on Apple silicon only; there is
+		// no x86_64 slice in the xrsimulator SDK.
+		return []string{"arm64"}
+	case "watchos":
+		return []string{"arm64"}
+	case "watchossimulator":
+		return []string{"arm64", "amd64"}
 	case "android":
 		return []string{"arm", "arm64", "386", "amd64"}
+	case "wasm":
+		return []string{"wasm"}
 	default:
 		panic(fmt.Sprintf("unexpected platform: %s", platform))
 	}
 }
 
+// appleArchs returns the architectures a build environment is prepared
+// for on an Apple platform. arm64e (pointer authentication) slices are
+// supported on iOS and macOS device targets, but are only built when
+// requested explicitly in -target; they are never part of the platform
+// defaults.
+func appleArchs(platform string) []string {
+	switch platform {
+	case "ios", "macos":
+		return append(platformArchs(platform), "arm64e")
+	}
+	return platformArchs(platform)
+}
+
+// androidArchs returns the architectures an Android build environment
+// is prepared for. riscv64 needs NDK r27 or newer, so it is only built
+// when requested explicitly in -target; it is never part of the
+// platform defaults.
+func androidArchs() []string {
+	return append(platformArchs("android"), "riscv64")
+}
+
 func isSupportedArch(platform, arch string) bool {
+	if isApplePlatform(platform) {
+		return contains(appleArchs(platform), arch)
+	}
+	if isAndroidPlatform(platform) {
+		return contains(androidArchs(), arch)
+	}
 	return contains(platformArchs(platform), arch)
 }
 
@@ -72,6 +148,12 @@ func platformOS(platform string) string {
 		return "darwin"
 	case "tvos", "tvossimulator":
 		return "darwin"
+	case "visionos", "visionossimulator":
+		return "darwin"
+	case "watchos", "watchossimulator":
+		return "darwin"
+	case "wasm":
+		return "js"
 	default:
 		panic(fmt.Sprintf("unexpected platform: %s", platform))
 	}
@@ -89,8 +171,18 @@ func platformTags(platform string) []string {
 		return []string{"ios", "tvos"}
 	case "tvossimulator":
 		return []string{"ios", "tvos", "tvossimulator"}
+	case "visionos":
+		return []string{"ios", "visionos"}
+	case "visionossimulator":
+		return []string{"ios", "visionos", "visionossimulator"}
+	case "watchos":
+		return []string{"ios", "watchos"}
+	case "watchossimulator":
+		return []string{"ios", "watchos", "watchossimulator"}
 	case "macos":
 		return []string{"macos"}
+	case "wasm":
+		return []string{"wasm"}
 	case "maccatalyst":
 		// Mac Catalyst is a subset of iOS APIs made available on macOS
 		// designed to ease porting apps developed for iPad to macOS.
@@ -120,6 +212,10 @@ func contains(haystack []string, needle string) bool {
 }
 
 func buildEnvInit() (cleanup func(), err error) {
+	if buildP < 1 {
+		return nil, fmt.Errorf("invalid -p=%d: must be at least 1", buildP)
+	}
+
 	// Find gomobilepath.
 	gopath := goEnv("GOPATH")
 	for _, p := range filepath.SplitList(gopath) {
@@ -141,7 +237,7 @@ func buildEnvInit() (cleanup func(), err error) {
 
 	cleanupFn := func() {
 		if buildWork {
-			fmt.Printf("WORK=%s\n", tmpdir)
+			printWorkLayout()
 			return
 		}
 		removeAll(tmpdir)
@@ -166,6 +262,40 @@ func buildEnvInit() (cleanup func(), err error) {
 	return cleanupFn, nil
 }
 
+// printWorkLayout prints the location of the work directory preserved
+// by -work along with a map of its well-known entries, so the
+// generated code can be found without spelunking through the tree.
+// Only entries that exist are listed; the set depends on the command
+// and the target platform.
+func printWorkLayout() {
+	fmt.Printf("WORK=%s\n", tmpdir)
+	entries := []struct{ rel, desc string }{
+		{"src/gobind", "generated Go bindings with the C and Java glue"},
+		{"java", "generated Java and Kotlin sources"},
+		{"android", "AAR staging tree (classes and per-ABI jniLibs)"},
+		{"javadoc-output", "javadoc generated from the Java sources"},
+		{"wasm", "generated wasm wrapper project"},
+	}
+	for _, platform := range applePlatforms {
+		entries = append(entries,
+			struct{ rel, desc string }{filepath.Join(platform, "src", "gobind"), "generated Go and Objective-C bindings for " + platform},
+			struct{ rel, desc string }{platform, "per-SDK framework staging for " + platform},
+		)
+	}
+	for _, e := range entries {
+		if _, err := os.Stat(filepath.Join(tmpdir, e.rel)); err == nil {
+			fmt.Printf("\t%-24s %s\n", e.rel, e.desc)
+		}
+	}
+	// Intermediate per-target archives live at the top level.
+	for _, pattern := range []string{"*.a", "*.dylib"} {
+		matches, _ := filepath.Glob(filepath.Join(tmpdir, pattern))
+		for _, m := range matches {
+			fmt.Printf("\t%-24s %s\n", filepath.Base(m), "intermediate archive")
+		}
+	}
+}
+
 func envInit() (err error) {
 	// Setup the cross-compiler environments.
 	if ndkRoot, err := ndkRoot(); err == nil {
@@ -173,6 +303,9 @@ func envInit() (err error) {
 		if buildAndroidAPI < minAndroidAPI {
 			return fmt.Errorf("gomobile requires Android API level >= %d", minAndroidAPI)
 		}
+		if buildAndroidTarget != 0 && buildAndroidTarget < buildAndroidAPI {
+			return fmt.Errorf("-androidtarget %d is lower than -androidapi %d", buildAndroidTarget, buildAndroidAPI)
+		}
 		for arch, toolchain := range ndk {
 			clang := toolchain.Path(ndkRoot, "clang")
 			clangpp := toolchain.Path(ndkRoot, "clang++")
@@ -184,12 +317,24 @@ func envInit() (err error) {
 					// script only exists in r19c.
 					tools = append(tools, clangpp+".cmd")
 				}
+				missing := false
 				for _, tool := range tools {
 					_, err = os.Stat(tool)
 					if err != nil {
+						// riscv64 appeared in NDK r27; leave it out of
+						// the build environments on older NDKs.
+						// Requesting it explicitly is diagnosed against
+						// the NDK's supported ABIs.
+						if arch == "riscv64" {
+							missing = true
+							break
+						}
 						return fmt.Errorf("No compiler for %s was found in the NDK (tried %s). Make sure your NDK version is >= r19c. Use `sdkmanager --update` to update it.", arch, tool)
 					}
 				}
+				if missing {
+					continue
+				}
 			}
 			androidEnv[arch] = []string{
 				"GOOS=android",
@@ -201,6 +346,11 @@ func envInit() (err error) {
 			if arch == "arm" {
 				androidEnv[arch] = append(androidEnv[arch], "GOARM=7")
 			}
+			if !buildNo16KPages {
+				// Google Play requires 16KB-aligned native
+				// libraries on devices with 16KB page sizes.
+				androidEnv[arch] = append(androidEnv[arch], "CGO_LDFLAGS=-Wl,-z,max-page-size=16384")
+			}
 		}
 	}
 
@@ -211,7 +361,7 @@ func envInit() (err error) {
 	appleNM = "nm"
 	appleEnv = make(map[string][]string)
 	for _, platform := range applePlatforms {
-		for _, arch := range platformArchs(platform) {
+		for _, arch := range appleArchs(platform) {
 			var env []string
 			var goos, sdk, clang, cflags string
 			var err error
@@ -240,6 +390,31 @@ func envInit() (err error) {
 				clang, cflags, err = envClang(sdk)
 				cflags += " -target x86_64-apple-tvos" + buildTVOSVersion + "-simulator"
 				cflags += " -fembed-bitcode"
+			case "visionos":
+				goos = "ios"
+				sdk = "xros"
+				clang, cflags, err = envClang(sdk)
+				// Note: bitcode is not supported on visionOS, so no
+				// -fembed-bitcode here.
+				cflags += " -target arm64-apple-xros" + buildVisionOSVersion
+			case "visionossimulator":
+				goos = "ios"
+				sdk = "xrsimulator"
+				clang, cflags, err = envClang(sdk)
+				cflags += " -target arm64-apple-xros" + buildVisionOSVersion + "-simulator"
+			case "watchos":
+				// Go has no arm64_32 port, so only 64-bit watch
+				// hardware (Apple Watch Series 9 / watchOS 9+) is
+				// supported.
+				goos = "ios"
+				sdk = "watchos"
+				clang, cflags, err = envClang(sdk)
+				cflags += " -target arm64-apple-watchos" + buildWatchOSVersion
+			case "watchossimulator":
+				goos = "ios"
+				sdk = "watchsimulator"
+				clang, cflags, err = envClang(sdk)
+				cflags += " -target " + archClang(arch) + "-apple-watchos" + buildWatchOSVersion + "-simulator"
 			case "maccatalyst":
 				// Mac Catalyst is a subset of iOS APIs made available on macOS
 				// designed to ease porting apps developed for iPad to macOS.
@@ -274,7 +449,7 @@ func envInit() (err error) {
 				goos = "darwin"
 				sdk = "macosx" // Note: the SDK is called "macosx", not "macos"
 				clang, cflags, err = envClang(sdk)
-				if arch == "arm64" {
+				if arch == "arm64" || arch == "arm64e" {
 					cflags += " -fembed-bitcode"
 				}
 
@@ -287,9 +462,16 @@ func envInit() (err error) {
 				return err
 			}
 
+			// -extension-safe restricts the slices to app-extension-safe
+			// API and marks the produced binaries extension-safe at link
+			// time.
+			if bindExtensionSafe {
+				cflags += " -fapplication-extension"
+			}
+
 			env = append(env,
 				"GOOS="+goos,
-				"GOARCH="+arch,
+				"GOARCH="+archGo(arch),
 				"GOFLAGS="+"-tags="+strings.Join(platformTags(platform), ","),
 				"CC="+clang,
 				"CXX="+clang+"++",
@@ -318,6 +500,8 @@ func abi(goarch string) string {
 		return "x86"
 	case "amd64":
 		return "x86_64"
+	case "riscv64":
+		return "riscv64"
 	default:
 		return ""
 	}
@@ -417,6 +601,15 @@ func ndkRoot(targets ...targetInfo) (string, error) {
 		return "$NDK_PATH", nil
 	}
 
+	// An NDK pinned with gomobile ndk install takes priority over the
+	// environment, so every machine builds with the same toolchain.
+	if root := pinnedNDKRoot(); root != "" {
+		if err := checkNDKRoot(root, targets); err != nil {
+			return "", fmt.Errorf("pinned NDK %s is unusable: %w", root, err)
+		}
+		return root, nil
+	}
+
 	// Try the ANDROID_NDK_HOME variable.  This approach is deprecated, but it
 	// has the highest priority because it represents an explicit user choice.
 	if ndkRoot := os.Getenv("ANDROID_NDK_HOME"); ndkRoot != "" {
@@ -477,12 +670,25 @@ func envClang(sdkName string) (clang, cflags string, err error) {
 	return clang, "-isysroot " + sdk, nil
 }
 
+// archGo maps a target architecture to the GOARCH used to compile the
+// Go code for it. Go has no arm64e port, so arm64e slices build the Go
+// objects as arm64 and rely on the C toolchain for pointer
+// authentication in the generated glue code.
+func archGo(arch string) string {
+	if arch == "arm64e" {
+		return "arm64"
+	}
+	return arch
+}
+
 func archClang(goarch string) string {
 	switch goarch {
 	case "arm":
 		return "armv7"
 	case "arm64":
 		return "arm64"
+	case "arm64e":
+		return "arm64e"
 	case "386":
 		return "i386"
 	case "amd64":
@@ -492,10 +698,61 @@ func archClang(goarch string) string {
 	}
 }
 
-// environ merges os.Environ and the given "key=value" pairs.
-// If a key is in both os.Environ and kv, kv takes precedence.
+// hermeticEnvAllowed lists the host environment variables preserved in
+// -hermetic mode. Everything else, notably stray CC, CXX and CGO_*
+// settings on the developer machine, is dropped so it cannot silently
+// change artifacts.
+var hermeticEnvAllowed = []string{
+	"HOME", "USER", "LOGNAME", "SHELL", "TERM", "PWD",
+	"PATH", "TMPDIR", "TEMP", "TMP", "SYSTEMROOT", "SYSTEMDRIVE",
+	"GOPATH", "GOROOT", "GOCACHE", "GOMODCACHE", "GOFLAGS", "GOTOOLCHAIN", "GOWORK",
+	"GOPROXY", "GOPRIVATE", "GONOPROXY", "GONOSUMDB", "GOSUMDB",
+	"ANDROID_HOME", "ANDROID_NDK_HOME", "JAVA_HOME", "DEVELOPER_DIR",
+}
+
+var (
+	hermeticOnce sync.Once
+	hermeticHost []string
+)
+
+// hostEnv returns the host environment for build commands. In
+// -hermetic mode only the variables listed in hermeticEnvAllowed are
+// preserved, and every dropped variable that could affect the build is
+// reported once so overridden host settings do not go unnoticed.
+func hostEnv() []string {
+	if !buildHermetic {
+		return os.Environ()
+	}
+	hermeticOnce.Do(func() {
+		allowed := make(map[string]bool, len(hermeticEnvAllowed))
+		for _, k := range hermeticEnvAllowed {
+			allowed[k] = true
+		}
+		for _, ev := range os.Environ() {
+			k, _, ok := strings.Cut(ev, "=")
+			if !ok || k == "" {
+				continue
+			}
+			if allowed[k] {
+				hermeticHost = append(hermeticHost, ev)
+				continue
+			}
+			if k == "CC" || k == "CXX" || strings.HasPrefix(k, "CGO_") || strings.HasPrefix(k, "GO") {
+				fmt.Fprintf(os.Stderr, "gomobile: hermetic build ignores host %s\n", k)
+			}
+		}
+	})
+	return hermeticHost
+}
+
+// environ merges the host environment and the given "key=value" pairs.
+// If a key is in both, kv takes precedence. A -gotoolchain value pins
+// GOTOOLCHAIN for every child command unless kv overrides it.
 func environ(kv []string) []string {
-	cur := os.Environ()
+	if buildGoToolchain != "" {
+		kv = append([]string{"GOTOOLCHAIN=" + buildGoToolchain}, kv...)
+	}
+	cur := hostEnv()
 	new := make([]string, 0, len(cur)+len(kv))
 
 	envs := make(map[string]string, len(cur))
@@ -639,6 +896,13 @@ var ndk = ndkConfig{
 		toolPrefix:  "x86_64-linux-android",
 		clangPrefix: "x86_64-linux-android",
 	},
+	"riscv64": {
+		arch:        "riscv64",
+		abi:         "riscv64",
+		minAPI:      35,
+		toolPrefix:  "riscv64-linux-android",
+		clangPrefix: "riscv64-linux-android",
+	},
 }
 
 func xcodeAvailable() bool {
@@ -0,0 +1,109 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"golang.org/x/tools/go/packages"
+)
+
+var gradleScriptTmpl = template.Must(template.New("gradle").Parse(`// Code generated by gomobile. DO NOT EDIT.
+
+// Gradle script plugin wrapping 'gomobile bind' as an incremental task.
+// Apply it from an Android application or library module:
+//
+//   apply from: '{{.ScriptName}}'
+//
+// The gomobileBind task declares the Go sources as inputs and only
+// re-runs the bind when they change, and the produced AAR is wired
+// into the applying module's dependencies.
+
+task gomobileBind(type: Exec) {
+{{range .SrcDirs}}	inputs.dir {{.}}
+{{end}}	outputs.file {{.AAR}}
+	commandLine {{.CommandLine}}
+}
+
+tasks.matching { it.name == 'preBuild' }.configureEach { it.dependsOn gomobileBind }
+
+dependencies {
+	implementation files({{.AAR}})
+}
+`))
+
+// writeGradleScript writes a Gradle script plugin to path that wraps
+// the current bind invocation as a gomobileBind task with dependency
+// tracking, for the -gradle flag. It must run after the AAR has been
+// built, when buildO holds the final output name.
+func writeGradleScript(path string, pkgs []*packages.Package) error {
+	aar, err := filepath.Abs(buildO)
+	if err != nil {
+		return err
+	}
+	args := []string{"gomobile", "bind", "-target=" + buildTarget, "-o", aar}
+	if len(buildTags) > 0 {
+		args = append(args, "-tags="+strings.Join(buildTags, ","))
+	}
+	if bindJavaPkg != "" {
+		args = append(args, "-javapkg="+bindJavaPkg)
+	}
+	if bindClasspath != "" {
+		args = append(args, "-classpath="+bindClasspath)
+	}
+	if bindBootClasspath != "" {
+		args = append(args, "-bootclasspath="+bindBootClasspath)
+	}
+	if bindLibName != "gojni" {
+		args = append(args, "-libname="+bindLibName)
+	}
+	var srcDirs []string
+	seen := make(map[string]bool)
+	for _, p := range pkgs {
+		args = append(args, p.PkgPath)
+		for _, f := range p.GoFiles {
+			dir := filepath.Dir(f)
+			if !seen[dir] {
+				seen[dir] = true
+				srcDirs = append(srcDirs, gradleQuote(dir))
+			}
+		}
+	}
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		quoted[i] = gradleQuote(a)
+	}
+	data := struct {
+		ScriptName  string
+		SrcDirs     []string
+		AAR         string
+		CommandLine string
+	}{
+		ScriptName:  filepath.Base(path),
+		SrcDirs:     srcDirs,
+		AAR:         gradleQuote(aar),
+		CommandLine: strings.Join(quoted, ", "),
+	}
+	var buf bytes.Buffer
+	if err := gradleScriptTmpl.Execute(&buf, data); err != nil {
+		return err
+	}
+	if buildN {
+		printcmd("write %s", path)
+		return nil
+	}
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// gradleQuote returns s as a single-quoted Groovy string literal.
+func gradleQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `'`, `\'`)
+	return "'" + s + "'"
+}
@@ -0,0 +1,139 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io"
+	"path/filepath"
+)
+
+var cmdGradleInit = &command{
+	run:   runGradleInit,
+	Name:  "gradle-init",
+	Usage: "[-dir project]",
+	Short: "write a Gradle plugin that runs gomobile bind",
+	Long: `
+Gradle-init writes a Gradle plugin into an Android project, under
+buildSrc, that runs gomobile bind as a regular task of the Android
+build. Unlike the script written by the bind -gradle flag, the plugin
+is configured from the applying module's build script, declares the Go
+sources as task inputs so the bind only re-runs when they change, and
+wires the produced AAR into the module's dependencies.
+
+Apply and configure it in an application or library module:
+
+	plugins {
+	    id 'org.golang.gomobile'
+	}
+
+	gomobile {
+	    packages = ['golang.org/x/example/basic']
+	    srcDirs = ['../go/basic']
+	}
+
+The -dir flag sets the project directory to write to. It defaults to
+the current directory.
+`,
+}
+
+var gradleInitDir string // -dir
+
+func init() {
+	cmdGradleInit.flag.StringVar(&gradleInitDir, "dir", ".", "Android project directory to write the plugin to")
+}
+
+func runGradleInit(cmd *command) error {
+	files := []struct {
+		path    string
+		content string
+	}{
+		{filepath.Join("buildSrc", "build.gradle"), gradleInitBuildScript},
+		{filepath.Join("buildSrc", "src", "main", "groovy", "org", "golang", "gomobile", "GomobilePlugin.groovy"), gradleInitPlugin},
+		{filepath.Join("buildSrc", "src", "main", "resources", "META-INF", "gradle-plugins", "org.golang.gomobile.properties"), gradleInitProperties},
+	}
+	for _, f := range files {
+		f := f
+		err := writeFile(filepath.Join(gradleInitDir, f.path), func(w io.Writer) error {
+			_, err := io.WriteString(w, f.content)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const gradleInitBuildScript = `// Code generated by gomobile gradle-init. DO NOT EDIT.
+plugins {
+    id 'groovy'
+}
+
+dependencies {
+    implementation gradleApi()
+    implementation localGroovy()
+}
+`
+
+const gradleInitProperties = `# Code generated by gomobile gradle-init. DO NOT EDIT.
+implementation-class=org.golang.gomobile.GomobilePlugin
+`
+
+const gradleInitPlugin = `// Code generated by gomobile gradle-init. DO NOT EDIT.
+package org.golang.gomobile
+
+import org.gradle.api.Plugin
+import org.gradle.api.Project
+
+// GomobileExtension configures the gomobileBind task from the
+// applying module's build script.
+class GomobileExtension {
+    // Go import paths of the packages to bind.
+    List<String> packages = []
+    // Directories holding the Go sources. Their .go files are the
+    // task inputs; the bind only re-runs when they change.
+    List<String> srcDirs = []
+    // Path of the produced AAR, relative to the module directory.
+    String output = 'libs/gomobile.aar'
+    // Extra arguments passed to gomobile bind, such as
+    // ['-androidapi', '21'].
+    List<String> bindArgs = []
+    // Path of the gomobile binary.
+    String gomobile = 'gomobile'
+}
+
+// GomobilePlugin registers the gomobileBind task, makes the Android
+// build depend on it and adds the produced AAR to the module's
+// dependencies.
+class GomobilePlugin implements Plugin<Project> {
+    void apply(Project project) {
+        def ext = project.extensions.create('gomobile', GomobileExtension)
+        def bind = project.tasks.register('gomobileBind') {
+            group = 'build'
+            description = 'Builds the Go packages into an Android AAR with gomobile bind.'
+        }
+        project.afterEvaluate {
+            def output = project.file(ext.output)
+            bind.configure {
+                ext.srcDirs.each { dir ->
+                    inputs.files(project.fileTree(dir: dir, include: '**/*.go'))
+                }
+                outputs.file(output)
+                doLast {
+                    output.parentFile.mkdirs()
+                    project.exec {
+                        commandLine([ext.gomobile, 'bind', '-target=android',
+                                     '-o', output.absolutePath] + ext.bindArgs + ext.packages)
+                    }
+                }
+            }
+            project.dependencies.add('implementation', project.files(output))
+            project.tasks.matching { it.name == 'preBuild' }.configureEach {
+                it.dependsOn(bind)
+            }
+        }
+    }
+}
+`
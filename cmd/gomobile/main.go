@@ -169,8 +169,11 @@ var commands = []*command{
 	cmdBind,
 	cmdBuild,
 	cmdClean,
+	cmdDoctor,
+	cmdGradleInit,
 	cmdInit,
 	cmdInstall,
+	cmdNdk,
 	cmdVersion,
 }
 
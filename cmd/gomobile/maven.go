@@ -0,0 +1,184 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// writeMavenArtifacts writes a maven-publish layout for the produced
+// AAR to a maven/ directory next to it, for the -maven flag: the AAR
+// and the sources jar copied under groupId/artifactId/version, a pom
+// and Gradle module metadata. CI can push the directory straight to
+// Maven Central or an internal repository. It must run after the AAR
+// and the sources jar have been built.
+func writeMavenArtifacts(coords string) error {
+	parts := strings.Split(coords, ":")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return fmt.Errorf("invalid -maven coordinates %q; must be groupId:artifactId:version", coords)
+	}
+	group, artifact, version := parts[0], parts[1], parts[2]
+
+	outDir := filepath.Dir(buildO)
+	repoDir := filepath.Join(outDir, "maven", filepath.FromSlash(strings.ReplaceAll(group, ".", "/")), artifact, version)
+	base := artifact + "-" + version
+
+	aar := filepath.Join(repoDir, base+".aar")
+	if err := copyFile(aar, buildO); err != nil {
+		return err
+	}
+	ext := filepath.Ext(buildO)
+	srcJar := buildO[:len(buildO)-len(ext)] + "-sources.jar"
+	if err := copyFile(filepath.Join(repoDir, base+"-sources.jar"), srcJar); err != nil {
+		return err
+	}
+	if bindJavadoc {
+		docJar := buildO[:len(buildO)-len(ext)] + "-javadoc.jar"
+		if err := copyFile(filepath.Join(repoDir, base+"-javadoc.jar"), docJar); err != nil {
+			return err
+		}
+	}
+
+	err := writeFile(filepath.Join(repoDir, base+".pom"), func(w io.Writer) error {
+		return mavenPomTmpl.Execute(w, map[string]interface{}{
+			"Group":       group,
+			"Artifact":    artifact,
+			"Version":     version,
+			"Annotations": bindAnnotations,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	return writeFile(filepath.Join(repoDir, base+".module"), func(w io.Writer) error {
+		aarFile, err := mavenFileEntry(base+".aar", aar)
+		if err != nil {
+			return err
+		}
+		variant := func(name, usage string) map[string]interface{} {
+			v := map[string]interface{}{
+				"name": name,
+				"attributes": map[string]interface{}{
+					"org.gradle.category":        "library",
+					"org.gradle.libraryelements": "aar",
+					"org.gradle.usage":           usage,
+				},
+				"files": []interface{}{aarFile},
+			}
+			if bindAnnotations {
+				// The generated API references androidx.annotation;
+				// declare it so consumers resolve the real artifact.
+				v["dependencies"] = []interface{}{
+					map[string]interface{}{
+						"group":   "androidx.annotation",
+						"module":  "annotation",
+						"version": map[string]interface{}{"requires": androidxAnnotationVersion},
+					},
+				}
+			}
+			return v
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(map[string]interface{}{
+			"formatVersion": "1.1",
+			"component": map[string]interface{}{
+				"group":   group,
+				"module":  artifact,
+				"version": version,
+				"attributes": map[string]interface{}{
+					"org.gradle.status": "release",
+				},
+			},
+			"createdBy": map[string]interface{}{
+				"gomobile": map[string]interface{}{},
+			},
+			"variants": []interface{}{
+				variant("releaseApiElements-published", "java-api"),
+				variant("releaseRuntimeElements-published", "java-runtime"),
+			},
+		})
+	})
+}
+
+// mavenFileEntry describes an artifact in Gradle module metadata, with
+// the size and checksums Gradle verifies on resolution.
+func mavenFileEntry(name, path string) (map[string]interface{}, error) {
+	entry := map[string]interface{}{
+		"name": name,
+		"url":  name,
+	}
+	if buildN {
+		return entry, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	hashes := map[string]hash.Hash{
+		"sha512": sha512.New(),
+		"sha256": sha256.New(),
+		"sha1":   sha1.New(),
+		"md5":    md5.New(),
+	}
+	var writers []io.Writer
+	for _, h := range hashes {
+		writers = append(writers, h)
+	}
+	size, err := io.Copy(io.MultiWriter(writers...), f)
+	if err != nil {
+		return nil, err
+	}
+	entry["size"] = size
+	for name, h := range hashes {
+		entry[name] = hex.EncodeToString(h.Sum(nil))
+	}
+	return entry, nil
+}
+
+var mavenPomTmpl = template.Must(template.New("pom").Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<project xmlns="http://maven.apache.org/POM/4.0.0"
+    xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"
+    xsi:schemaLocation="http://maven.apache.org/POM/4.0.0 http://maven.apache.org/xsd/maven-4.0.0.xsd">
+  <!-- This module was also published with a richer model, Gradle metadata,  -->
+  <!-- which should be used instead. Do not delete the following line which  -->
+  <!-- is to indicate to Gradle or any Gradle module metadata file consumer  -->
+  <!-- that they should prefer consuming it instead. -->
+  <!-- do_not_remove: published-with-gradle-metadata -->
+  <modelVersion>4.0.0</modelVersion>
+  <groupId>{{.Group}}</groupId>
+  <artifactId>{{.Artifact}}</artifactId>
+  <version>{{.Version}}</version>
+  <packaging>aar</packaging>
+{{- if .Annotations}}
+  <dependencies>
+    <dependency>
+      <groupId>androidx.annotation</groupId>
+      <artifactId>annotation</artifactId>
+      <version>` + androidxAnnotationVersion + `</version>
+      <scope>compile</scope>
+    </dependency>
+  </dependencies>
+{{- end}}
+</project>
+`))
+
+// androidxAnnotationVersion is the androidx.annotation artifact version
+// declared for AARs built with -annotations.
+const androidxAnnotationVersion = "1.7.1"
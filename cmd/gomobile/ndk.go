@@ -0,0 +1,233 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"archive/zip"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var cmdNdk = &command{
+	run:   runNdk,
+	Name:  "ndk",
+	Usage: "[-checksum sha1] install <version>",
+	Short: "manage the Android NDK used by bind and build",
+	Long: `
+Ndk manages the copy of the Android NDK that gomobile bind and
+gomobile build compile against.
+
+The install command downloads the given NDK release, such as r27c,
+from the Android download server, extracts it under the gomobile
+cache directory and records the version. Later bind and build
+invocations use the recorded NDK even if ANDROID_NDK_HOME or the
+Android SDK point at a different one, so builds use the same
+toolchain on every machine. Running install again with another
+version switches the pin.
+
+The optional -checksum flag verifies the SHA-1 of the downloaded
+archive against the value published on the NDK downloads page. The
+archive is cached and reused on reinstallation.
+
+The cached NDK lives under $GOPATH/pkg/gomobile and is removed by
+gomobile clean and gomobile init.
+`,
+}
+
+var ndkChecksum string // -checksum
+
+func init() {
+	cmdNdk.flag.StringVar(&ndkChecksum, "checksum", "", "expected SHA-1 of the downloaded NDK archive")
+}
+
+func runNdk(cmd *command) error {
+	args := cmd.flag.Args()
+	if len(args) != 2 || args[0] != "install" {
+		return fmt.Errorf("usage: gomobile ndk install <version>")
+	}
+	return installNDK(args[1])
+}
+
+func installNDK(version string) error {
+	if !strings.HasPrefix(version, "r") {
+		return fmt.Errorf("invalid NDK version %q; expected a release name such as r27c", version)
+	}
+	gopaths := filepath.SplitList(goEnv("GOPATH"))
+	if len(gopaths) == 0 {
+		return fmt.Errorf("GOPATH is not set")
+	}
+	gomobilepath = filepath.Join(gopaths[0], "pkg", "gomobile")
+	ndkDir := filepath.Join(gomobilepath, "ndk")
+	if err := mkdir(ndkDir); err != nil {
+		return err
+	}
+	root := filepath.Join(ndkDir, "android-ndk-"+version)
+	if _, err := os.Stat(filepath.Join(root, "source.properties")); err != nil || buildN {
+		archive, err := fetchNDK(ndkDir, version)
+		if err != nil {
+			return err
+		}
+		if err := extractNDK(archive, ndkDir); err != nil {
+			return err
+		}
+		if !buildN && ndkVersion(root) == "" {
+			return fmt.Errorf("NDK extracted to %s has no version; the archive may be corrupt", root)
+		}
+	}
+	return writeFile(filepath.Join(ndkDir, "version"), func(w io.Writer) error {
+		_, err := io.WriteString(w, version+"\n")
+		return err
+	})
+}
+
+// fetchNDK downloads the NDK release archive for the host into dir,
+// reusing a previously downloaded copy if present. When the -checksum
+// flag is given, the SHA-1 of the archive is verified against it.
+func fetchNDK(dir, version string) (string, error) {
+	name := fmt.Sprintf("android-ndk-%s-%s.zip", version, goos)
+	url := "https://dl.google.com/android/repository/" + name
+	archive := filepath.Join(dir, name)
+	if buildX || buildN {
+		printcmd("curl -o %s %s", archive, url)
+	}
+	if buildN {
+		return archive, nil
+	}
+	if _, err := os.Stat(archive); err != nil {
+		resp, err := http.Get(url)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("download %s: %v", url, resp.Status)
+		}
+		f, err := os.Create(archive + ".partial")
+		if err != nil {
+			return "", err
+		}
+		if _, err := io.Copy(f, resp.Body); err != nil {
+			f.Close()
+			return "", err
+		}
+		if err := f.Close(); err != nil {
+			return "", err
+		}
+		if err := os.Rename(archive+".partial", archive); err != nil {
+			return "", err
+		}
+	}
+	if ndkChecksum != "" {
+		f, err := os.Open(archive)
+		if err != nil {
+			return "", err
+		}
+		defer f.Close()
+		h := sha1.New()
+		if _, err := io.Copy(h, f); err != nil {
+			return "", err
+		}
+		sum := hex.EncodeToString(h.Sum(nil))
+		if !strings.EqualFold(sum, ndkChecksum) {
+			return "", fmt.Errorf("%s: SHA-1 %s does not match -checksum %s", archive, sum, ndkChecksum)
+		}
+	}
+	return archive, nil
+}
+
+// extractNDK unpacks the NDK archive into dir. The archive holds a
+// single android-ndk-<version> directory at its root. Symbolic links,
+// which the NDK toolchain layout relies on, are recreated rather than
+// followed.
+func extractNDK(archive, dir string) error {
+	if buildX || buildN {
+		printcmd("unzip -d %s %s", dir, archive)
+	}
+	if buildN {
+		return nil
+	}
+	r, err := zip.OpenReader(archive)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	for _, f := range r.File {
+		if !filepath.IsLocal(filepath.FromSlash(f.Name)) {
+			return fmt.Errorf("%s: illegal path %q", archive, f.Name)
+		}
+		dst := filepath.Join(dir, filepath.FromSlash(f.Name))
+		mode := f.Mode()
+		switch {
+		case mode.IsDir():
+			if err := os.MkdirAll(dst, 0755); err != nil {
+				return err
+			}
+		case mode&os.ModeSymlink != 0:
+			rc, err := f.Open()
+			if err != nil {
+				return err
+			}
+			target, err := io.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				return err
+			}
+			if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+				return err
+			}
+			os.Remove(dst)
+			if err := os.Symlink(string(target), dst); err != nil {
+				return err
+			}
+		default:
+			if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+				return err
+			}
+			w, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode.Perm())
+			if err != nil {
+				return err
+			}
+			rc, err := f.Open()
+			if err != nil {
+				w.Close()
+				return err
+			}
+			_, err = io.Copy(w, rc)
+			rc.Close()
+			if cerr := w.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// pinnedNDKRoot returns the root of the NDK recorded by gomobile ndk
+// install, or "" if no version is pinned or its files are gone.
+func pinnedNDKRoot() string {
+	gopaths := filepath.SplitList(goEnv("GOPATH"))
+	if len(gopaths) == 0 {
+		return ""
+	}
+	ndkDir := filepath.Join(gopaths[0], "pkg", "gomobile", "ndk")
+	data, err := os.ReadFile(filepath.Join(ndkDir, "version"))
+	if err != nil {
+		return ""
+	}
+	root := filepath.Join(ndkDir, "android-ndk-"+strings.TrimSpace(string(data)))
+	if _, err := os.Stat(filepath.Join(root, "source.properties")); err != nil {
+		return ""
+	}
+	return root
+}
@@ -0,0 +1,81 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// extractResourceBundles returns the resource bundle directories
+// declared by pkgs and their transitive dependencies. A package
+// declares a bundle by shipping a directory with a .bundle suffix
+// next to its Go sources, for resources its cgo libraries need at
+// runtime such as certificates or ML models. The bundles are copied
+// into the framework Resources on Apple targets and under assets/ in
+// the AAR on Android.
+func extractResourceBundles(env []string, gosrc string, pkgPaths []string, tags []string) ([]string, error) {
+	cmd := exec.Command("go", "list", "-deps", "-f", "{{.Dir}}")
+	if len(tags) > 0 {
+		cmd.Args = append(cmd.Args, "-tags="+strings.Join(tags, ","))
+	}
+	cmd.Args = append(cmd.Args, pkgPaths...)
+	cmd.Env = append(hostEnv(), env...)
+	cmd.Dir = gosrc
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("go list stdout: %w", err)
+	}
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("go list start failed: %w", err)
+	}
+
+	declaredBy := make(map[string]string)
+	var bundles []string
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		dir := strings.TrimSpace(scanner.Text())
+		if dir == "" {
+			continue
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			if !e.IsDir() || !strings.HasSuffix(e.Name(), ".bundle") {
+				continue
+			}
+			if orig, exists := declaredBy[e.Name()]; exists {
+				if orig == dir {
+					continue
+				}
+				_ = cmd.Wait()
+				return nil, fmt.Errorf("resource bundle name conflict: %s declared by both %s and %s", e.Name(), orig, dir)
+			}
+			declaredBy[e.Name()] = dir
+			bundles = append(bundles, filepath.Join(dir, e.Name()))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		_ = cmd.Wait()
+		return nil, fmt.Errorf("failed to parse go list output: %w", err)
+	}
+	if err := cmd.Wait(); err != nil {
+		if errMsg := strings.TrimSpace(stderr.String()); errMsg != "" {
+			return nil, fmt.Errorf("go list failed: %w: %s", err, errMsg)
+		}
+		return nil, fmt.Errorf("go list failed: %w", err)
+	}
+
+	return bundles, nil
+}
@@ -0,0 +1,305 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io"
+	"path/filepath"
+	"text/template"
+)
+
+// writeSampleApp writes a minimal SwiftUI Xcode project referencing
+// the produced XCFramework to dir, for the -sampleapp flag. The
+// project builds an iOS app that imports the framework, with the
+// framework embedded (and signed) for dynamic builds and merely linked
+// for static ones, so new users can validate the binding and measure
+// size and startup without manual Xcode setup. It must run after the
+// XCFramework has been built, when buildO holds the final output name.
+func writeSampleApp(dir, title string) error {
+	out, err := filepath.Abs(buildO)
+	if err != nil {
+		return err
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	framework := out
+	if rel, err := filepath.Rel(absDir, out); err == nil {
+		framework = filepath.ToSlash(rel)
+	}
+	bundleID := buildBundleID
+	if bundleID == "" {
+		bundleID = "org.golang.gomobile." + title
+	}
+	data := map[string]interface{}{
+		"Title":      title,
+		"Framework":  framework,
+		"BundleID":   bundleID + ".sample",
+		"IOSVersion": buildIOSVersion,
+		"Embed":      bindBuildmode == "c-shared",
+	}
+	if err := mkdir(filepath.Join(dir, title+"Sample.xcodeproj")); err != nil {
+		return err
+	}
+	if err := mkdir(filepath.Join(dir, title+"Sample")); err != nil {
+		return err
+	}
+	err = writeFile(filepath.Join(dir, title+"Sample.xcodeproj", "project.pbxproj"), func(w io.Writer) error {
+		return samplePbxprojTmpl.Execute(w, data)
+	})
+	if err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(dir, title+"Sample", "App.swift"), func(w io.Writer) error {
+		return sampleAppSwiftTmpl.Execute(w, data)
+	})
+}
+
+var sampleAppSwiftTmpl = template.Must(template.New("sampleswift").Parse(`// Code generated by gomobile bind. DO NOT EDIT.
+import SwiftUI
+import {{.Title}}
+
+@main
+struct {{.Title}}SampleApp: App {
+    // Recorded when the process enters Swift, for rough startup
+    // measurements against the framework load time.
+    static let launched = Date()
+
+    var body: some Scene {
+        WindowGroup {
+            ContentView()
+        }
+    }
+}
+
+struct ContentView: View {
+    var body: some View {
+        // Call into the bound API here to validate the binding, e.g.:
+        //   Text({{.Title}}SomeFunction())
+        Text("{{.Title}}.xcframework loaded")
+    }
+}
+`))
+
+var samplePbxprojTmpl = template.Must(template.New("samplepbx").Parse(`// !$*UTF8*$!
+{
+	archiveVersion = 1;
+	classes = {
+	};
+	objectVersion = 56;
+	objects = {
+
+/* Begin PBXBuildFile section */
+		A00000000000000000000010 /* App.swift in Sources */ = {isa = PBXBuildFile; fileRef = A00000000000000000000020 /* App.swift */; };
+		A00000000000000000000011 /* {{.Title}}.xcframework in Frameworks */ = {isa = PBXBuildFile; fileRef = A00000000000000000000021 /* {{.Title}}.xcframework */; };
+{{- if .Embed}}
+		A00000000000000000000012 /* {{.Title}}.xcframework in Embed Frameworks */ = {isa = PBXBuildFile; fileRef = A00000000000000000000021 /* {{.Title}}.xcframework */; settings = {ATTRIBUTES = (CodeSignOnCopy, RemoveHeadersOnCopy, ); }; };
+{{- end}}
+/* End PBXBuildFile section */
+
+/* Begin PBXFileReference section */
+		A00000000000000000000020 /* App.swift */ = {isa = PBXFileReference; lastKnownFileType = sourcecode.swift; path = App.swift; sourceTree = "<group>"; };
+		A00000000000000000000021 /* {{.Title}}.xcframework */ = {isa = PBXFileReference; lastKnownFileType = wrapper.xcframework; name = {{.Title}}.xcframework; path = "{{.Framework}}"; sourceTree = "<group>"; };
+		A00000000000000000000022 /* {{.Title}}Sample.app */ = {isa = PBXFileReference; explicitFileType = wrapper.application; includeInIndex = 0; path = "{{.Title}}Sample.app"; sourceTree = BUILT_PRODUCTS_DIR; };
+/* End PBXFileReference section */
+
+/* Begin PBXFrameworksBuildPhase section */
+		A00000000000000000000030 /* Frameworks */ = {
+			isa = PBXFrameworksBuildPhase;
+			buildActionMask = 2147483647;
+			files = (
+				A00000000000000000000011 /* {{.Title}}.xcframework in Frameworks */,
+			);
+			runOnlyForDeploymentPostprocessing = 0;
+		};
+/* End PBXFrameworksBuildPhase section */
+{{- if .Embed}}
+
+/* Begin PBXCopyFilesBuildPhase section */
+		A00000000000000000000031 /* Embed Frameworks */ = {
+			isa = PBXCopyFilesBuildPhase;
+			buildActionMask = 2147483647;
+			dstPath = "";
+			dstSubfolderSpec = 10;
+			files = (
+				A00000000000000000000012 /* {{.Title}}.xcframework in Embed Frameworks */,
+			);
+			name = "Embed Frameworks";
+			runOnlyForDeploymentPostprocessing = 0;
+		};
+/* End PBXCopyFilesBuildPhase section */
+{{- end}}
+
+/* Begin PBXGroup section */
+		A00000000000000000000040 = {
+			isa = PBXGroup;
+			children = (
+				A00000000000000000000041 /* {{.Title}}Sample */,
+				A00000000000000000000021 /* {{.Title}}.xcframework */,
+				A00000000000000000000042 /* Products */,
+			);
+			sourceTree = "<group>";
+		};
+		A00000000000000000000041 /* {{.Title}}Sample */ = {
+			isa = PBXGroup;
+			children = (
+				A00000000000000000000020 /* App.swift */,
+			);
+			path = "{{.Title}}Sample";
+			sourceTree = "<group>";
+		};
+		A00000000000000000000042 /* Products */ = {
+			isa = PBXGroup;
+			children = (
+				A00000000000000000000022 /* {{.Title}}Sample.app */,
+			);
+			name = Products;
+			sourceTree = "<group>";
+		};
+/* End PBXGroup section */
+
+/* Begin PBXNativeTarget section */
+		A00000000000000000000050 /* {{.Title}}Sample */ = {
+			isa = PBXNativeTarget;
+			buildConfigurationList = A00000000000000000000060;
+			buildPhases = (
+				A00000000000000000000032 /* Sources */,
+				A00000000000000000000030 /* Frameworks */,
+{{- if .Embed}}
+				A00000000000000000000031 /* Embed Frameworks */,
+{{- end}}
+			);
+			buildRules = (
+			);
+			dependencies = (
+			);
+			name = "{{.Title}}Sample";
+			productName = "{{.Title}}Sample";
+			productReference = A00000000000000000000022 /* {{.Title}}Sample.app */;
+			productType = "com.apple.product-type.application";
+		};
+/* End PBXNativeTarget section */
+
+/* Begin PBXProject section */
+		A00000000000000000000051 /* Project object */ = {
+			isa = PBXProject;
+			attributes = {
+				LastUpgradeCheck = 1500;
+			};
+			buildConfigurationList = A00000000000000000000061;
+			compatibilityVersion = "Xcode 14.0";
+			developmentRegion = en;
+			hasScannedForEncodings = 0;
+			knownRegions = (
+				en,
+				Base,
+			);
+			mainGroup = A00000000000000000000040;
+			productRefGroup = A00000000000000000000042 /* Products */;
+			projectDirPath = "";
+			projectRoot = "";
+			targets = (
+				A00000000000000000000050 /* {{.Title}}Sample */,
+			);
+		};
+/* End PBXProject section */
+
+/* Begin PBXSourcesBuildPhase section */
+		A00000000000000000000032 /* Sources */ = {
+			isa = PBXSourcesBuildPhase;
+			buildActionMask = 2147483647;
+			files = (
+				A00000000000000000000010 /* App.swift in Sources */,
+			);
+			runOnlyForDeploymentPostprocessing = 0;
+		};
+/* End PBXSourcesBuildPhase section */
+
+/* Begin XCBuildConfiguration section */
+		A00000000000000000000070 /* Debug */ = {
+			isa = XCBuildConfiguration;
+			buildSettings = {
+				IPHONEOS_DEPLOYMENT_TARGET = {{.IOSVersion}};
+				SDKROOT = iphoneos;
+				SWIFT_OPTIMIZATION_LEVEL = "-Onone";
+				SWIFT_VERSION = 5.0;
+			};
+			name = Debug;
+		};
+		A00000000000000000000071 /* Release */ = {
+			isa = XCBuildConfiguration;
+			buildSettings = {
+				IPHONEOS_DEPLOYMENT_TARGET = {{.IOSVersion}};
+				SDKROOT = iphoneos;
+				SWIFT_VERSION = 5.0;
+				VALIDATE_PRODUCT = YES;
+			};
+			name = Release;
+		};
+		A00000000000000000000072 /* Debug */ = {
+			isa = XCBuildConfiguration;
+			buildSettings = {
+				CODE_SIGN_STYLE = Automatic;
+				CURRENT_PROJECT_VERSION = 1;
+				GENERATE_INFOPLIST_FILE = YES;
+				INFOPLIST_KEY_UIApplicationSceneManifest_Generation = YES;
+				INFOPLIST_KEY_UILaunchScreen_Generation = YES;
+				LD_RUNPATH_SEARCH_PATHS = (
+					"$(inherited)",
+					"@executable_path/Frameworks",
+				);
+				MARKETING_VERSION = 1.0;
+				PRODUCT_BUNDLE_IDENTIFIER = "{{.BundleID}}";
+				PRODUCT_NAME = "$(TARGET_NAME)";
+				TARGETED_DEVICE_FAMILY = "1,2";
+			};
+			name = Debug;
+		};
+		A00000000000000000000073 /* Release */ = {
+			isa = XCBuildConfiguration;
+			buildSettings = {
+				CODE_SIGN_STYLE = Automatic;
+				CURRENT_PROJECT_VERSION = 1;
+				GENERATE_INFOPLIST_FILE = YES;
+				INFOPLIST_KEY_UIApplicationSceneManifest_Generation = YES;
+				INFOPLIST_KEY_UILaunchScreen_Generation = YES;
+				LD_RUNPATH_SEARCH_PATHS = (
+					"$(inherited)",
+					"@executable_path/Frameworks",
+				);
+				MARKETING_VERSION = 1.0;
+				PRODUCT_BUNDLE_IDENTIFIER = "{{.BundleID}}";
+				PRODUCT_NAME = "$(TARGET_NAME)";
+				TARGETED_DEVICE_FAMILY = "1,2";
+			};
+			name = Release;
+		};
+/* End XCBuildConfiguration section */
+
+/* Begin XCConfigurationList section */
+		A00000000000000000000060 /* Build configuration list for PBXNativeTarget "{{.Title}}Sample" */ = {
+			isa = XCConfigurationList;
+			buildConfigurations = (
+				A00000000000000000000072 /* Debug */,
+				A00000000000000000000073 /* Release */,
+			);
+			defaultConfigurationIsVisible = 0;
+			defaultConfigurationName = Release;
+		};
+		A00000000000000000000061 /* Build configuration list for PBXProject */ = {
+			isa = XCConfigurationList;
+			buildConfigurations = (
+				A00000000000000000000070 /* Debug */,
+				A00000000000000000000071 /* Release */,
+			);
+			defaultConfigurationIsVisible = 0;
+			defaultConfigurationName = Release;
+		};
+/* End XCConfigurationList section */
+	};
+	rootObject = A00000000000000000000051 /* Project object */;
+}
+`))
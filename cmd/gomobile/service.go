@@ -0,0 +1,166 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// writeServiceScaffold writes an out-of-process service scaffold to
+// dir for the -service flag: an AIDL interface, a Service subclass
+// hosting the Go runtime in an isolated :go process, and a client
+// stub that binds to it. Memory-heavy Go libraries can then be killed
+// and restarted independently of the UI process. The scaffold
+// marshals requests as method name plus encoded bytes; the dispatch
+// method is the single point to wire to the bound API.
+func writeServiceScaffold(dir string, pkgs []*packages.Package) error {
+	javaPkg := pkgs[0].Name
+	if bindJavaPkg != "" {
+		javaPkg = bindJavaPkg + "." + pkgs[0].Name
+	}
+	pkgDir := strings.ReplaceAll(javaPkg, ".", "/")
+	data := map[string]interface{}{
+		"JavaPkg": javaPkg,
+	}
+	files := []struct {
+		path string
+		tmpl *template.Template
+	}{
+		{filepath.Join("aidl", pkgDir, "IGoRemote.aidl"), serviceAidlTmpl},
+		{filepath.Join("java", pkgDir, "GoRemoteService.java"), serviceJavaTmpl},
+		{filepath.Join("java", pkgDir, "GoRemote.java"), serviceClientTmpl},
+	}
+	for _, f := range files {
+		f := f
+		err := writeFile(filepath.Join(dir, f.path), func(w io.Writer) error {
+			return f.tmpl.Execute(w, data)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var serviceAidlTmpl = template.Must(template.New("serviceaidl").Parse(`// Code generated by gomobile bind. DO NOT EDIT.
+package {{.JavaPkg}};
+
+// Binder interface to the Go library running in the :go process.
+// Requests carry a method name and an encoded payload; the encoding
+// (e.g. protobuf or JSON) is up to the dispatch implementation.
+interface IGoRemote {
+    byte[] invoke(String method, in byte[] request);
+}
+`))
+
+var serviceJavaTmpl = template.Must(template.New("servicejava").Parse(`// Code generated by gomobile bind. DO NOT EDIT.
+//
+// Host Service for the Go library, meant to run in an isolated
+// process. Declare it in AndroidManifest.xml as:
+//
+//   <service
+//       android:name="{{.JavaPkg}}.GoRemoteService"
+//       android:process=":go"
+//       android:exported="false" />
+package {{.JavaPkg}};
+
+import android.app.Service;
+import android.content.Intent;
+import android.os.IBinder;
+import android.os.RemoteException;
+
+public class GoRemoteService extends Service {
+    private final IGoRemote.Stub binder = new IGoRemote.Stub() {
+        @Override public byte[] invoke(String method, byte[] request) throws RemoteException {
+            return dispatch(method, request);
+        }
+    };
+
+    @Override public void onCreate() {
+        super.onCreate();
+        go.Seq.setContext(getApplicationContext());
+    }
+
+    @Override public IBinder onBind(Intent intent) {
+        return binder;
+    }
+
+    // dispatch routes a request to the bound Go API. Wire each method
+    // name to a call into the generated bindings, e.g.:
+    //
+    //   if (method.equals("fetch")) {
+    //       return Mypackage.fetch(request);
+    //   }
+    protected byte[] dispatch(String method, byte[] request) throws RemoteException {
+        throw new RemoteException("unknown method: " + method);
+    }
+}
+`))
+
+var serviceClientTmpl = template.Must(template.New("serviceclient").Parse(`// Code generated by gomobile bind. DO NOT EDIT.
+package {{.JavaPkg}};
+
+import android.content.ComponentName;
+import android.content.Context;
+import android.content.Intent;
+import android.content.ServiceConnection;
+import android.os.IBinder;
+import android.os.RemoteException;
+
+import java.util.concurrent.CountDownLatch;
+import java.util.concurrent.TimeUnit;
+
+// Client stub for the Go library hosted in the :go process. Calls
+// block while the request crosses Binder; the service process can be
+// killed and restarted without affecting the UI process, at the cost
+// of in-flight calls failing with RemoteException.
+public final class GoRemote implements AutoCloseable {
+    private final Context context;
+    private final CountDownLatch bound = new CountDownLatch(1);
+    private volatile IGoRemote remote;
+
+    private final ServiceConnection connection = new ServiceConnection() {
+        @Override public void onServiceConnected(ComponentName name, IBinder service) {
+            remote = IGoRemote.Stub.asInterface(service);
+            bound.countDown();
+        }
+
+        @Override public void onServiceDisconnected(ComponentName name) {
+            remote = null;
+        }
+    };
+
+    public GoRemote(Context context) {
+        this.context = context.getApplicationContext();
+        Intent intent = new Intent(this.context, GoRemoteService.class);
+        this.context.bindService(intent, connection, Context.BIND_AUTO_CREATE);
+    }
+
+    public byte[] invoke(String method, byte[] request) throws RemoteException {
+        try {
+            if (!bound.await(10, TimeUnit.SECONDS)) {
+                throw new RemoteException("timed out binding to GoRemoteService");
+            }
+        } catch (InterruptedException e) {
+            Thread.currentThread().interrupt();
+            throw new RemoteException("interrupted binding to GoRemoteService");
+        }
+        IGoRemote r = remote;
+        if (r == null) {
+            throw new RemoteException("GoRemoteService disconnected");
+        }
+        return r.invoke(method, request);
+    }
+
+    @Override public void close() {
+        context.unbindService(connection);
+    }
+}
+`))
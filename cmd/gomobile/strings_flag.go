@@ -4,7 +4,10 @@
 
 package main
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 type stringsFlag []string
 
@@ -60,3 +63,19 @@ func splitQuotedFields(s string) ([]string, error) {
 func (v *stringsFlag) String() string {
 	return "<stringsFlag>"
 }
+
+// keyValueFlag collects repeated KEY=VALUE flag values, appending on
+// each use instead of replacing.
+type keyValueFlag []string
+
+func (v *keyValueFlag) Set(s string) error {
+	if !strings.Contains(s, "=") {
+		return fmt.Errorf("%q is not of the form KEY=VALUE", s)
+	}
+	*v = append(*v, s)
+	return nil
+}
+
+func (v *keyValueFlag) String() string {
+	return strings.Join(*v, ",")
+}
@@ -0,0 +1,106 @@
+// Copyright 2025 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// watchInterval is the polling interval of gomobile bind -watch.
+const watchInterval = time.Second
+
+// watchBind polls the source files of the bound packages and calls
+// rebind whenever one of them changes. It shares the work directory
+// with the initial build, so stages whose inputs are unchanged reuse
+// their previous outputs. A failing rebuild is reported and watching
+// continues; the loop runs until the process is interrupted.
+func watchBind(cmd *command, rebind func() error) error {
+	last, err := watchedFiles(cmd)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "gomobile: watching %d files\n", len(last))
+	for {
+		time.Sleep(watchInterval)
+		cur, err := watchedFiles(cmd)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "gomobile: %v\n", err)
+			continue
+		}
+		changed := changedFile(last, cur)
+		last = cur
+		if changed == "" {
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "gomobile: %s changed; rebuilding\n", changed)
+		start := time.Now()
+		if err := rebind(); err != nil {
+			fmt.Fprintf(os.Stderr, "gomobile: %v\n", err)
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "gomobile: rebuilt in %v\n", time.Since(start).Round(time.Millisecond))
+	}
+}
+
+// watchedFiles returns a fingerprint of every source file of the bound
+// packages and their dependencies, keyed by path. Files under GOROOT
+// and the module cache are skipped; they do not change during an edit
+// loop.
+func watchedFiles(cmd *command) (map[string]string, error) {
+	args := cmd.flag.Args()
+	if len(args) == 0 {
+		args = []string{"."}
+	}
+	targets, err := parseBuildTarget(buildTarget)
+	if err != nil {
+		return nil, err
+	}
+	config := packagesConfig(targets[0])
+	config.Mode = packages.NeedName | packages.NeedFiles | packages.NeedImports | packages.NeedDeps
+	pkgs, err := packages.Load(config, args...)
+	if err != nil {
+		return nil, err
+	}
+	goroot := goEnv("GOROOT")
+	modcache := goEnv("GOMODCACHE")
+	files := make(map[string]string)
+	visit := func(pkg *packages.Package) {
+		for _, list := range [][]string{pkg.GoFiles, pkg.OtherFiles} {
+			for _, f := range list {
+				if strings.HasPrefix(f, goroot) || (modcache != "" && strings.HasPrefix(f, modcache)) {
+					continue
+				}
+				st, err := os.Stat(f)
+				if err != nil {
+					continue
+				}
+				files[f] = fmt.Sprintf("%d %d", st.Size(), st.ModTime().UnixNano())
+			}
+		}
+	}
+	packages.Visit(pkgs, nil, visit)
+	return files, nil
+}
+
+// changedFile returns the path of one file whose fingerprint differs
+// between the two snapshots, or the empty string if they match.
+func changedFile(old, cur map[string]string) string {
+	for f, fp := range cur {
+		if old[f] != fp {
+			return f
+		}
+	}
+	for f := range old {
+		if _, ok := cur[f]; !ok {
+			return f
+		}
+	}
+	return ""
+}
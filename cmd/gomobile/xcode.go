@@ -0,0 +1,78 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+const xcodePhaseScriptHeader = `#!/bin/sh
+# Code generated by gomobile. DO NOT EDIT.
+#
+# Add this script as a Run Script build phase in Xcode and point the
+# phase at the file lists written next to it:
+#
+#   Input File Lists:  %[1]s-input.xcfilelist
+#   Output File Lists: %[1]s-output.xcfilelist
+#
+# Xcode then re-runs the bind only when the Go sources change, keeping
+# the xcframework fresh without manual rebuilds.
+
+set -e
+`
+
+// writeXcodePhase writes a run-script build phase to path, along with
+// the input and output xcfilelists Xcode uses for dependency tracking,
+// for the -xcodephase flag. It must run after the xcframework has been
+// built, when buildO holds the final output name.
+func writeXcodePhase(path string, pkgs []*packages.Package) error {
+	out, err := filepath.Abs(buildO)
+	if err != nil {
+		return err
+	}
+	args := []string{"gomobile", "bind", "-target=" + buildTarget, "-o", out}
+	if len(buildTags) > 0 {
+		args = append(args, "-tags="+strings.Join(buildTags, ","))
+	}
+	if bindPrefix != "" {
+		args = append(args, "-prefix="+bindPrefix)
+	}
+	if bindSign != "" {
+		args = append(args, "-sign="+bindSign)
+	}
+	var srcs []string
+	for _, p := range pkgs {
+		args = append(args, p.PkgPath)
+		srcs = append(srcs, p.GoFiles...)
+	}
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		quoted[i] = shellQuote(a)
+	}
+	base := strings.TrimSuffix(path, ".sh")
+	script := fmt.Sprintf(xcodePhaseScriptHeader, filepath.Base(base)) +
+		"exec " + strings.Join(quoted, " ") + "\n"
+	if buildN {
+		printcmd("write %s", path)
+		return nil
+	}
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(base+"-input.xcfilelist", []byte(strings.Join(srcs, "\n")+"\n"), 0644); err != nil {
+		return err
+	}
+	return os.WriteFile(base+"-output.xcfilelist", []byte(out+"\n"), 0644)
+}
+
+// shellQuote returns s as a single-quoted shell word.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, `'`, `'\''`) + "'"
+}
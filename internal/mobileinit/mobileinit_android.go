@@ -0,0 +1,151 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mobileinit
+
+/*
+To view the log output run:
+adb logcat GoLog:I *:S
+*/
+
+// Android redirects stdout and stderr to /dev/null.
+// As these are common debugging utilities in Go,
+// we redirect them to logcat.
+
+/*
+#cgo LDFLAGS: -llog
+
+#include <android/log.h>
+#include <stdlib.h>
+*/
+import "C"
+
+import (
+	"bufio"
+	"log"
+	"os"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// logTag is the logcat tag for Go program output. It can be overridden
+// at build time through the gomobile bind -logtag flag and at run time
+// through SetLogTag.
+var logTag = "GoLog"
+
+// logConfig holds the run-time adjustable log routing state. The host
+// side adjusts it through the generated Seq API.
+var logConfig struct {
+	sync.Mutex
+	ctag        *C.char
+	minPriority int32
+	receiver    func(priority int32, line string)
+}
+
+// SetLogTag sets the logcat tag for Go program output. The previous
+// tag is intentionally leaked; tags change rarely and freeing it would
+// race with concurrent log writes.
+func SetLogTag(tag string) {
+	logConfig.Lock()
+	defer logConfig.Unlock()
+	logConfig.ctag = C.CString(tag)
+}
+
+// SetLogMinPriority drops Go program output below the given
+// android/log.h priority (2 verbose, 3 debug, 4 info, 5 warn, 6 error)
+// before it reaches logcat or the log receiver.
+func SetLogMinPriority(priority int32) {
+	logConfig.Lock()
+	defer logConfig.Unlock()
+	logConfig.minPriority = priority
+}
+
+// SetLogReceiver routes Go program output to f instead of logcat, so
+// apps can feed it into their own logging pipeline. Pass nil to
+// restore logcat delivery.
+func SetLogReceiver(f func(priority int32, line string)) {
+	logConfig.Lock()
+	defer logConfig.Unlock()
+	logConfig.receiver = f
+}
+
+// logLine delivers one line of Go program output, either to the
+// receiver or to logcat.
+func logLine(priority int32, line string) {
+	logConfig.Lock()
+	ctag := logConfig.ctag
+	min := logConfig.minPriority
+	receiver := logConfig.receiver
+	logConfig.Unlock()
+	if priority < min {
+		return
+	}
+	if receiver != nil {
+		receiver(priority, line)
+		return
+	}
+	cstr := C.CString(line)
+	C.__android_log_write(C.int(priority), ctag, cstr)
+	C.free(unsafe.Pointer(cstr))
+}
+
+type infoWriter struct{}
+
+func (infoWriter) Write(p []byte) (n int, err error) {
+	logLine(int32(C.ANDROID_LOG_INFO), string(p))
+	return len(p), nil
+}
+
+func lineLog(f *os.File, priority int32) {
+	const logSize = 1024 // matches android/log.h.
+	r := bufio.NewReaderSize(f, logSize)
+	for {
+		line, _, err := r.ReadLine()
+		str := string(line)
+		if err != nil {
+			str += " " + err.Error()
+		}
+		logLine(priority, str)
+		if err != nil {
+			break
+		}
+	}
+}
+
+var (
+	// Store the writer end of the redirected stderr and stdout
+	// so that they are not garbage collected and closed.
+	stderr, stdout *os.File
+)
+
+func init() {
+	logConfig.ctag = C.CString(logTag)
+	logConfig.minPriority = int32(C.ANDROID_LOG_VERBOSE)
+
+	log.SetOutput(infoWriter{})
+	// android logcat includes all of log.Lshortfile like information so
+	// skip it here.
+	log.SetFlags(log.Ltime)
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		panic(err)
+	}
+	stderr = w
+	if err := syscall.Dup3(int(w.Fd()), int(os.Stderr.Fd()), 0); err != nil {
+		panic(err)
+	}
+	go lineLog(r, int32(C.ANDROID_LOG_ERROR))
+
+	r, w, err = os.Pipe()
+	if err != nil {
+		panic(err)
+	}
+	stdout = w
+	if err := syscall.Dup3(int(w.Fd()), int(os.Stdout.Fd()), 0); err != nil {
+		panic(err)
+	}
+	go lineLog(r, int32(C.ANDROID_LOG_INFO))
+}